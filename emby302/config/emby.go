@@ -58,6 +58,8 @@ type Emby struct {
 	DownloadStrategy DlStrategy `yaml:"download-strategy"`
 	// LocalMediaRoot 本地媒体根路径
 	LocalMediaRoot string `yaml:"local-media-root"`
+	// PrewarmLinks 请求 PlaybackInfo 时是否在后台预热直链 (剧集同时预热下一集)
+	PrewarmLinks bool `yaml:"prewarm-links"`
 }
 
 func (e *Emby) Init() error {
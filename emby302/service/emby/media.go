@@ -29,11 +29,8 @@ import (
 // MediaSourceIdSegment 自定义 MediaSourceId 的分隔符
 const MediaSourceIdSegment = "[[_]]"
 
-// getEmbyFileLocalPath 获取 Emby 指定媒体的 Path 参数
-//
-// uri 中必须有 query 参数 MediaSourceId,
-// 如果没有携带该参数, 可能会请求到多个媒体, 默认返回第一个媒体的本地路径
-func getEmbyFileLocalPath(itemInfo ItemInfo) (string, error) {
+// apiKeyHeader 使用客户端的 api key 构造请求 Emby 接口的请求头
+func apiKeyHeader(itemInfo ItemInfo) http.Header {
 	var header http.Header
 	switch itemInfo.ApiKeyType {
 	case Header:
@@ -43,6 +40,15 @@ func getEmbyFileLocalPath(itemInfo ItemInfo) (string, error) {
 		// 如果是 query 格式的 api key, 则往请求头中补充信息
 		header = http.Header{HeaderFullAuthName: []string{"Token=" + itemInfo.ApiKey}}
 	}
+	return header
+}
+
+// getEmbyFileLocalPath 获取 Emby 指定媒体的 Path 参数
+//
+// uri 中必须有 query 参数 MediaSourceId,
+// 如果没有携带该参数, 可能会请求到多个媒体, 默认返回第一个媒体的本地路径
+func getEmbyFileLocalPath(itemInfo ItemInfo) (string, error) {
+	header := apiKeyHeader(itemInfo)
 
 	innerRequest := func(method string) (*http.Response, error) {
 		resp, err := https.Request(method, config.C.Emby.Host+itemInfo.PlaybackInfoUri).Header(header).Do()
@@ -89,6 +89,9 @@ func TransferPlaybackInfo(c *gin.Context) {
 
 	var haveReturned = errors.New("have returned")
 	resChans := make([]chan []*jsons.Item, 0, mediaSources.Len())
+	// 预热直链使用客户端的请求头, 和播放时请求 stream 接口的 UA 保持一致
+	prewarmHeader := c.Request.Header.Clone()
+	hasRemoteSource := false
 	err = mediaSources.RangeArr(func(_ int, source *jsons.Item) error {
 		simplifyMediaName(source)
 
@@ -123,6 +126,9 @@ func TransferPlaybackInfo(c *gin.Context) {
 			source.Attr("Path").Set(urls.Unescape(path))
 		}
 		logs.Info("Path 解码后: %s", path)
+		// 客户端请求 stream 之前在后台解析好直链
+		prewarmLink(path, prewarmHeader)
+		hasRemoteSource = true
 		// 转换直链链接
 		source.Put("SupportsDirectPlay", jsons.FromValue(true))
 		source.Put("SupportsDirectStream", jsons.FromValue(true))
@@ -172,6 +178,9 @@ func TransferPlaybackInfo(c *gin.Context) {
 	if err == haveReturned {
 		return
 	}
+	if hasRemoteSource {
+		go prewarmNextEpisode(itemInfo, prewarmHeader)
+	}
 
 	// defer func() {
 	// 	// 缓存 12h
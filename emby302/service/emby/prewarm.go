@@ -0,0 +1,151 @@
+package emby
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"Q115-STRM/emby302/config"
+	"Q115-STRM/emby302/util/https"
+	"Q115-STRM/emby302/util/logs"
+)
+
+// PrewarmLinkTTL 预热的直链保留时间, 超过后播放时重新解析
+const PrewarmLinkTTL = 5 * time.Minute
+
+// prewarmedLink 预热得到的直链
+type prewarmedLink struct {
+	link     string
+	expireAt time.Time
+}
+
+var (
+	// prewarmedLinks 预热的直链, key: strm 地址 + UA
+	prewarmedLinks sync.Map
+	// prewarming 正在预热的 key, 防止客户端重复请求 PlaybackInfo 时重复解析
+	prewarming sync.Map
+)
+
+// prewarmKey 直链和 UA 绑定, 不同客户端分开预热
+func prewarmKey(strmUrl string, header http.Header) string {
+	return strmUrl + "|" + header.Get("User-Agent")
+}
+
+// getPrewarmedLink 获取预热的直链
+func getPrewarmedLink(strmUrl string, header http.Header) (string, bool) {
+	value, ok := prewarmedLinks.Load(prewarmKey(strmUrl, header))
+	if !ok {
+		return "", false
+	}
+	pl := value.(prewarmedLink)
+	if time.Now().After(pl.expireAt) {
+		return "", false
+	}
+	return pl.link, true
+}
+
+// prewarmLink 在后台解析 strm 的直链并缓存
+//
+// 解析时 qmediasync 也会缓存网盘直链, 即使预热结果过期了, 播放时也能很快拿到直链
+func prewarmLink(embyPath string, header http.Header) {
+	if !config.C.Emby.PrewarmLinks {
+		return
+	}
+	strmUrl := readStrmUrl(embyPath)
+	if !isRemoteStrm(strmUrl) {
+		return
+	}
+	if _, ok := getPrewarmedLink(strmUrl, header); ok {
+		return
+	}
+	key := prewarmKey(strmUrl, header)
+	if _, loaded := prewarming.LoadOrStore(key, struct{}{}); loaded {
+		return
+	}
+	go func() {
+		defer prewarming.Delete(key)
+		start := time.Now()
+		finalPath := getFinalRedirectLink(strmUrl, header)
+		if finalPath == withForceFlag(strmUrl) {
+			// 解析失败时返回的是原始链接, 不缓存
+			logs.Warn("预热直链失败: %s", strmUrl)
+			return
+		}
+		now := time.Now()
+		prewarmedLinks.Store(key, prewarmedLink{link: finalPath, expireAt: now.Add(PrewarmLinkTTL)})
+		// 顺便清理过期的预热直链
+		prewarmedLinks.Range(func(k, v any) bool {
+			if now.After(v.(prewarmedLink).expireAt) {
+				prewarmedLinks.Delete(k)
+			}
+			return true
+		})
+		logs.Success("预热直链成功, 耗时 %v: %s => %s", time.Since(start), strmUrl, finalPath)
+	}()
+}
+
+// fetchEmbyJson 请求 Emby 接口并解析 json 响应
+func fetchEmbyJson(uri string, header http.Header, v any) error {
+	resp, err := https.Get(config.C.Emby.Host + uri).Header(header).Do()
+	if err != nil {
+		return fmt.Errorf("请求 Emby 接口异常, error: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("请求 Emby 接口异常, status: %s", resp.Status)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// prewarmNextEpisode 如果播放的是剧集, 预热下一集的直链
+func prewarmNextEpisode(itemInfo ItemInfo, header http.Header) {
+	if !config.C.Emby.PrewarmLinks {
+		return
+	}
+	apiHeader := apiKeyHeader(itemInfo)
+
+	// 1 查询剧集所属的电视剧
+	var items struct {
+		Items []struct {
+			Id       string
+			Type     string
+			SeriesId string
+		}
+	}
+	if err := fetchEmbyJson("/Items?Ids="+url.QueryEscape(itemInfo.Id)+"&Fields=SeriesId", apiHeader, &items); err != nil {
+		logs.Warn("预热下一集直链失败, 查询剧集信息异常: %v", err)
+		return
+	}
+	if len(items.Items) == 0 || items.Items[0].Type != "Episode" || items.Items[0].SeriesId == "" {
+		return
+	}
+
+	// 2 按顺序查询电视剧的所有剧集, 找到下一集
+	var episodes struct {
+		Items []struct {
+			Id           string
+			MediaSources []struct {
+				Path string
+			}
+		}
+	}
+	uri := fmt.Sprintf("/Shows/%s/Episodes?Fields=MediaSources", url.PathEscape(items.Items[0].SeriesId))
+	if err := fetchEmbyJson(uri, apiHeader, &episodes); err != nil {
+		logs.Warn("预热下一集直链失败, 查询剧集列表异常: %v", err)
+		return
+	}
+	for i, episode := range episodes.Items {
+		if episode.Id != itemInfo.Id || i+1 >= len(episodes.Items) {
+			continue
+		}
+		next := episodes.Items[i+1]
+		if len(next.MediaSources) > 0 && next.MediaSources[0].Path != "" {
+			logs.Info("预热下一集直链: %s", next.MediaSources[0].Path)
+			prewarmLink(next.MediaSources[0].Path, header)
+		}
+		return
+	}
+}
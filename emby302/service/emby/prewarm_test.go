@@ -0,0 +1,70 @@
+package emby
+
+import (
+	"Q115-STRM/emby302/config"
+	"Q115-STRM/internal/helpers"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestPrewarmNextEpisode(t *testing.T) {
+	helpers.AppLogger = &helpers.QLogger{Logger: log.New(io.Discard, "", 0)}
+
+	var linkRequests atomic.Int32
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/Items":
+			if r.Header.Get(QueryTokenName) != "key" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			fmt.Fprint(w, `{"Items":[{"Id":"2","Type":"Episode","SeriesId":"9"}]}`)
+		case "/Shows/9/Episodes":
+			fmt.Fprintf(w, `{"Items":[{"Id":"1","MediaSources":[{"Path":"%[1]s/115/newurl?pickcode=ep1"}]},{"Id":"2","MediaSources":[{"Path":"%[1]s/115/newurl?pickcode=ep2"}]},{"Id":"3","MediaSources":[{"Path":"%[1]s/115/newurl?pickcode=ep3"}]}]}`, server.URL)
+		case "/115/newurl":
+			linkRequests.Add(1)
+			// 直链和 UA 绑定
+			http.Redirect(w, r, "/cdn/"+r.URL.Query().Get("pickcode")+"?force="+r.URL.Query().Get("force")+"&ua="+r.UserAgent(), http.StatusFound)
+		default:
+			w.WriteHeader(http.StatusOK)
+		}
+	}))
+	defer server.Close()
+
+	oldConfig := config.C
+	config.C = &config.Config{Emby: &config.Emby{Host: server.URL, PrewarmLinks: true}}
+	defer func() { config.C = oldConfig }()
+
+	header := http.Header{"User-Agent": []string{"player"}}
+	itemInfo := ItemInfo{Id: "2", ApiKeyType: Header, ApiKeyName: QueryTokenName, ApiKey: "key"}
+	prewarmNextEpisode(itemInfo, header)
+
+	next := server.URL + "/115/newurl?pickcode=ep3"
+	var link string
+	for deadline := time.Now().Add(2 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		if l, ok := getPrewarmedLink(next, header); ok {
+			link = l
+			break
+		}
+	}
+	if want := server.URL + "/cdn/ep3?force=1&ua=player"; link != want {
+		t.Fatalf("prewarmed link = %q, want %q", link, want)
+	}
+	if _, ok := getPrewarmedLink(next, http.Header{"User-Agent": []string{"other"}}); ok {
+		t.Fatal("prewarmed link shared between user agents")
+	}
+
+	// 已经预热过的链接不重复解析
+	prewarmLink(next, header)
+	time.Sleep(50 * time.Millisecond)
+	if n := linkRequests.Load(); n != 1 {
+		t.Fatalf("link requests = %d, want 1", n)
+	}
+}
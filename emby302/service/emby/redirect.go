@@ -92,29 +92,17 @@ func Redirect2OpenlistLink(c *gin.Context) {
 		return
 	}
 
-	// logs.Info("检查 %s 是否nfs协议的strm文件", embyPath)
-	strmUrl := ""
-	// nfs协议开头，说明是一个nfs文件路径，打开该路径读取strm内容，然后跳转到strm内的地址
-	if strings.HasPrefix(embyPath, "nfs:") && strings.HasSuffix(embyPath, ".strm") {
-		logs.Info("检查到 nfs 协议的 strm文件：%s", embyPath)
-		// 打开nfs文件
-		f, err := os.Open(embyPath)
-		if err == nil {
-			// 读取strm内容
-			buf := bytes.NewBufferString("")
-			io.Copy(buf, f)
-			strmUrl = buf.String()
-			logs.Success("读取到 strm 文件 %s 的内容: %s", embyPath, strmUrl)
-			f.Close()
-		}
-	}
-	if strmUrl == "" {
-		strmUrl = embyPath
-	}
+	strmUrl := readStrmUrl(embyPath)
 	isProxyUrl := ""
 	// 4 如果是远程地址 (strm) 且不包含qmediasync的本地代理播放链接, 重定向处理
-	if urls.IsRemote(strmUrl) || strings.HasPrefix(strmUrl, "http") || strings.HasPrefix(strmUrl, "nfs:") {
-		finalPath := getFinalRedirectLink(strmUrl, c.Request.Header.Clone())
+	if isRemoteStrm(strmUrl) {
+		// 优先使用 PlaybackInfo 阶段预热的直链
+		finalPath, ok := getPrewarmedLink(strmUrl, c.Request.Header)
+		if ok {
+			logs.Info("使用预热的直链: %s", finalPath)
+		} else {
+			finalPath = getFinalRedirectLink(strmUrl, c.Request.Header.Clone())
+		}
 		if !strings.Contains(finalPath, "/proxy-115") {
 			logs.Success("重定向 strm: %s", finalPath)
 			c.Header(cache.HeaderKeyExpired, cache.Duration(time.Minute*10))
@@ -247,13 +235,45 @@ func checkErr(c *gin.Context, err error) bool {
 	return true
 }
 
+// readStrmUrl 读取 nfs 协议 strm 文件中的地址, 其他路径原样返回
+func readStrmUrl(embyPath string) string {
+	// nfs协议开头，说明是一个nfs文件路径，打开该路径读取strm内容，然后跳转到strm内的地址
+	if strings.HasPrefix(embyPath, "nfs:") && strings.HasSuffix(embyPath, ".strm") {
+		logs.Info("检查到 nfs 协议的 strm文件：%s", embyPath)
+		// 打开nfs文件
+		f, err := os.Open(embyPath)
+		if err == nil {
+			defer f.Close()
+			// 读取strm内容
+			buf := bytes.NewBufferString("")
+			io.Copy(buf, f)
+			if strmUrl := buf.String(); strmUrl != "" {
+				logs.Success("读取到 strm 文件 %s 的内容: %s", embyPath, strmUrl)
+				return strmUrl
+			}
+		}
+	}
+	return embyPath
+}
+
+// isRemoteStrm 判断 strm 地址是否需要重定向到远程直链
+func isRemoteStrm(strmUrl string) bool {
+	return urls.IsRemote(strmUrl) || strings.HasPrefix(strmUrl, "http") || strings.HasPrefix(strmUrl, "nfs:")
+}
+
+// withForceFlag 115 的 qmediasync 链接加上直链播放标记
+func withForceFlag(originLink string) string {
+	if !strings.Contains(originLink, "smartstrm") && (strings.Contains(originLink, "115/newurl") || strings.Contains(originLink, "115/url")) {
+		originLink += "&force=1"
+	}
+	return originLink
+}
+
 // getFinalRedirectLink 尝试对带有重定向的原始链接进行内部请求, 返回最终链接
 //
 // 请求中途出现任何失败都会返回原始链接
 func getFinalRedirectLink(originLink string, header http.Header) string {
-	if !strings.Contains(originLink, "smartstrm") && (strings.Contains(originLink, "115/newurl") || strings.Contains(originLink, "115/url")) {
-		originLink += "&force=1"
-	}
+	originLink = withForceFlag(originLink)
 	finalLink, resp, err := https.Get(originLink).Header(header).DoRedirect()
 	if err != nil {
 		logs.Warn("内部重定向失败: %v", err)
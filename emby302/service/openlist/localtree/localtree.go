@@ -0,0 +1,226 @@
+package localtree
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"Q115-STRM/emby302/config"
+	"Q115-STRM/emby302/service/openlist"
+	"Q115-STRM/emby302/util/logs"
+)
+
+const (
+	// DirName 本地目录树所在的目录名, 位于 config.BasePath 下
+	DirName = "openlist-local-tree"
+
+	// PerPage 遍历 openlist 时每页的数据量
+	PerPage = 200
+)
+
+// Synchronizer 将 openlist 目录树同步到本地磁盘
+type Synchronizer struct {
+
+	// baseDir 本地目录树根路径
+	baseDir string
+
+	// cfg 目录树生成配置
+	cfg *config.LocalTreeGen
+
+	// running 标记是否正在同步, 避免两轮同步重叠
+	running atomic.Bool
+
+	// failed 标记本轮遍历是否出现错误, 出错时不执行删除
+	failed atomic.Bool
+
+	// written 本轮写入的文件数
+	written atomic.Int64
+
+	// keepMu 保护 keep
+	keepMu sync.Mutex
+
+	// keep 本轮遍历到的本地路径, 不在其中的本地文件会被删除
+	keep map[string]struct{}
+}
+
+// Init 按照配置启动本地目录树同步, 未启用时不做任何事
+func Init() error {
+	cfg := config.C.Openlist.LocalTreeGen
+	if cfg == nil || !cfg.Enable {
+		return nil
+	}
+
+	baseDir := filepath.Join(config.BasePath, DirName)
+	if err := os.MkdirAll(baseDir, os.ModePerm); err != nil {
+		return fmt.Errorf("创建本地目录树根目录失败: %w", err)
+	}
+
+	s := &Synchronizer{baseDir: baseDir, cfg: cfg}
+	go func() {
+		ticker := time.NewTicker(time.Duration(cfg.RefreshInterval) * time.Minute)
+		defer ticker.Stop()
+		for {
+			s.Sync()
+			<-ticker.C
+		}
+	}()
+	logs.Info("openlist 本地目录树同步已启动, 目录: %s, 刷新间隔: %d 分钟", baseDir, cfg.RefreshInterval)
+	return nil
+}
+
+// Sync 执行一轮完整的同步
+func (s *Synchronizer) Sync() {
+	if !s.running.CompareAndSwap(false, true) {
+		logs.Warn("上一轮 openlist 本地目录树同步尚未完成, 跳过本轮")
+		return
+	}
+	defer s.running.Store(false)
+
+	start := time.Now()
+	s.failed.Store(false)
+	s.written.Store(0)
+	s.keep = map[string]struct{}{s.baseDir: {}}
+
+	threads := max(s.cfg.Threads, 1)
+	sem := make(chan struct{}, threads)
+	wg := sync.WaitGroup{}
+	var visit func(dir string)
+	visit = func(dir string) {
+		defer wg.Done()
+		sem <- struct{}{}
+		subDirs := s.syncDir(dir)
+		<-sem
+		for _, subDir := range subDirs {
+			wg.Add(1)
+			go visit(subDir)
+		}
+	}
+	wg.Add(1)
+	visit("/")
+	wg.Wait()
+
+	if s.failed.Load() {
+		logs.Warn("openlist 本地目录树同步过程中出现错误, 本轮不删除本地文件, 写入 %d 个文件, 耗时: %v", s.written.Load(), time.Since(start))
+		return
+	}
+
+	removed, err := removeStale(s.baseDir, s.keep, s.inScope, s.cfg.AutoRemoveMaxCount)
+	if err != nil {
+		logs.Warn("openlist 本地目录树删除过期文件失败: %v", err)
+	}
+	logs.Success("openlist 本地目录树同步完成, 写入 %d 个文件, 删除 %d 个文件, 耗时: %v", s.written.Load(), removed, time.Since(start))
+}
+
+// syncDir 遍历 openlist 的一个目录, 同步其中的文件, 返回需要继续遍历的子目录
+func (s *Synchronizer) syncDir(dir string) []string {
+	subDirs := make([]string, 0)
+	walker := openlist.WalkFsList(dir, PerPage)
+	for {
+		page, err := walker.Next()
+		if errors.Is(err, openlist.ErrWalkEOF) {
+			break
+		}
+		if err != nil {
+			logs.Error("遍历 openlist 目录失败: %s, err: %v", dir, err)
+			s.failed.Store(true)
+			break
+		}
+
+		for _, f := range page.Content {
+			p := path.Join(dir, f.Name)
+			if f.IsDir {
+				if s.cfg.IsValidPrefix(p) {
+					s.markKeep(s.localPath(p))
+					subDirs = append(subDirs, p)
+				}
+				continue
+			}
+			if !s.inScope(p) {
+				continue
+			}
+			if err := s.syncFile(p, f); err != nil {
+				logs.Error("同步 openlist 文件失败: %s, err: %v", p, err)
+			}
+		}
+	}
+	return subDirs
+}
+
+// inScope 判断 openlist 路径是否处在扫描前缀中
+func (s *Synchronizer) inScope(p string) bool {
+	for _, prefix := range s.cfg.ScanPrefixes {
+		if strings.HasPrefix(p, strings.TrimSpace(prefix)) {
+			return true
+		}
+	}
+	return false
+}
+
+// localPath 将 openlist 路径转换为本地路径
+func (s *Synchronizer) localPath(p string) string {
+	return filepath.Join(s.baseDir, filepath.FromSlash(p))
+}
+
+// markKeep 记录本轮需要保留的本地路径
+func (s *Synchronizer) markKeep(localPath string) {
+	s.keepMu.Lock()
+	defer s.keepMu.Unlock()
+	s.keep[localPath] = struct{}{}
+}
+
+// removeStale 删除 baseDir 中不在 keep 内且处于扫描范围的文件, 返回删除的文件数
+//
+// 待删除文件数超过 maxCount 时认为 openlist 挂载异常, 不删除任何文件
+func removeStale(baseDir string, keep map[string]struct{}, inScope func(string) bool, maxCount int) (int, error) {
+	staleFiles, staleDirs := make([]string, 0), make([]string, 0)
+	err := filepath.WalkDir(baseDir, func(localPath string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if _, ok := keep[localPath]; ok {
+			return nil
+		}
+		rel, err := filepath.Rel(baseDir, localPath)
+		if err != nil {
+			return err
+		}
+		if !inScope("/" + filepath.ToSlash(rel)) {
+			return nil
+		}
+		if d.IsDir() {
+			staleDirs = append(staleDirs, localPath)
+		} else {
+			staleFiles = append(staleFiles, localPath)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("遍历本地目录树失败: %w", err)
+	}
+
+	if len(staleFiles) > maxCount {
+		return 0, fmt.Errorf("待删除文件数 %d 超过上限 %d, 可能是 openlist 挂载异常, 跳过删除", len(staleFiles), maxCount)
+	}
+
+	removed := 0
+	for _, f := range staleFiles {
+		if err := os.Remove(f); err != nil {
+			return removed, fmt.Errorf("删除文件 %s 失败: %w", f, err)
+		}
+		removed++
+	}
+
+	// 先删除深层目录, 非空目录 (含有未同步的子目录) 保留
+	sort.Slice(staleDirs, func(i, j int) bool { return len(staleDirs[i]) > len(staleDirs[j]) })
+	for _, d := range staleDirs {
+		os.Remove(d)
+	}
+	return removed, nil
+}
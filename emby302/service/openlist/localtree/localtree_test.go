@@ -0,0 +1,53 @@
+package localtree
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRemoveStale(t *testing.T) {
+	baseDir := t.TempDir()
+	files := []string{
+		"电影/G/a.mkv",
+		"电影/G/旧电影/b.mkv",
+		"电影/H/c.mkv",
+		"电视剧/d.strm",
+	}
+	for _, f := range files {
+		p := filepath.Join(baseDir, filepath.FromSlash(f))
+		os.MkdirAll(filepath.Dir(p), os.ModePerm)
+		os.WriteFile(p, []byte("x"), os.ModePerm)
+	}
+	keep := map[string]struct{}{baseDir: {}}
+	for _, p := range []string{"电影", "电影/G", "电影/G/a.mkv", "电视剧"} {
+		keep[filepath.Join(baseDir, filepath.FromSlash(p))] = struct{}{}
+	}
+	inScope := func(p string) bool {
+		return strings.HasPrefix(p, "/电影/G") || strings.HasPrefix(p, "/电视剧")
+	}
+	exists := func(p string) bool {
+		_, err := os.Stat(filepath.Join(baseDir, filepath.FromSlash(p)))
+		return err == nil
+	}
+
+	// 超过上限时不删除
+	if removed, err := removeStale(baseDir, keep, inScope, 1); err == nil || removed != 0 {
+		t.Fatalf("removeStale over limit = %d, %v, want error", removed, err)
+	}
+	if !exists("电影/G/旧电影/b.mkv") || !exists("电视剧/d.strm") {
+		t.Fatal("files removed although the limit was exceeded")
+	}
+
+	removed, err := removeStale(baseDir, keep, inScope, 2)
+	if err != nil || removed != 2 {
+		t.Fatalf("removeStale = %d, %v, want 2", removed, err)
+	}
+	if exists("电影/G/旧电影") || exists("电视剧/d.strm") {
+		t.Error("stale files or dirs still exist")
+	}
+	if !exists("电影/G/a.mkv") || !exists("电影/H/c.mkv") || !exists("电视剧") {
+		t.Error("kept or out of scope files were removed")
+	}
+}
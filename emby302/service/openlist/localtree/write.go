@@ -0,0 +1,190 @@
+package localtree
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"Q115-STRM/emby302/config"
+	"Q115-STRM/emby302/service/lib/ffmpeg"
+	"Q115-STRM/emby302/service/music"
+	"Q115-STRM/emby302/service/openlist"
+	"Q115-STRM/emby302/util/https"
+	"Q115-STRM/emby302/util/logs"
+	"Q115-STRM/emby302/util/mp4s"
+)
+
+// DefaultVirtualDuration 未开启 ffmpeg 或解析失败时虚拟容器写入的时长
+const DefaultVirtualDuration = 3 * time.Hour
+
+// syncFile 根据容器类型将 openlist 文件写入本地
+//
+// 未开启 ffmpeg 时音乐容器使用 strm 代替, 其余不属于任何容器的文件原样下载
+func (s *Synchronizer) syncFile(p string, f openlist.FsGet) error {
+	ext := path.Ext(f.Name)
+	container := strings.TrimPrefix(ext, ".")
+	if s.cfg.IsIgnore(container) {
+		return nil
+	}
+
+	localPath := s.localPath(p)
+	switch {
+	case s.cfg.IsStrm(container), s.cfg.IsMusic(container) && !s.cfg.FFmpegEnable:
+		return s.writeStrm(strings.TrimSuffix(localPath, ext)+".strm", p, f)
+	case s.cfg.IsVirtual(container):
+		return s.writeVirtual(localPath, p, f)
+	case s.cfg.IsMusic(container):
+		return s.writeMusic(localPath, strings.TrimSuffix(localPath, ext)+".nfo", p, f)
+	default:
+		return s.download(localPath, p, f)
+	}
+}
+
+// rawUrl 生成 openlist 文件的下载地址
+func rawUrl(p string, f openlist.FsGet) string {
+	u := strings.TrimSuffix(config.C.Openlist.Host, "/") + "/d" + (&url.URL{Path: p}).EscapedPath()
+	if f.Sign != "" {
+		u += "?sign=" + url.QueryEscape(f.Sign)
+	}
+	return u
+}
+
+// upToDate 判断本地文件是否已经是远程文件的最新版本
+//
+// 写入本地文件后会将修改时间设置为远程修改时间, 远程修改时间为空时只判断文件是否存在
+func upToDate(localPath string, f openlist.FsGet) bool {
+	stat, err := os.Stat(localPath)
+	if err != nil || stat.IsDir() {
+		return false
+	}
+	return f.Modified.IsZero() || stat.ModTime().Unix() == f.Modified.Unix()
+}
+
+// finishWrite 设置本地文件的修改时间并计数
+func (s *Synchronizer) finishWrite(localPath string, f openlist.FsGet) {
+	if !f.Modified.IsZero() {
+		os.Chtimes(localPath, f.Modified, f.Modified)
+	}
+	s.written.Add(1)
+}
+
+// writeStrm 写入内容为 openlist 下载地址的 strm 文件, 内容未变化时不重写
+func (s *Synchronizer) writeStrm(localPath, p string, f openlist.FsGet) error {
+	s.markKeep(localPath)
+	content := []byte(rawUrl(p, f))
+	if old, err := os.ReadFile(localPath); err == nil && bytes.Equal(old, content) {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(localPath), os.ModePerm); err != nil {
+		return fmt.Errorf("创建目录失败: %w", err)
+	}
+	if err := os.WriteFile(localPath, content, os.ModePerm); err != nil {
+		return fmt.Errorf("写入 strm 文件失败: %w", err)
+	}
+	s.finishWrite(localPath, f)
+	return nil
+}
+
+// writeVirtual 写入与媒体同名的虚拟 mp4, 开启 ffmpeg 时尝试解析真实时长
+func (s *Synchronizer) writeVirtual(localPath, p string, f openlist.FsGet) error {
+	s.markKeep(localPath)
+	if upToDate(localPath, f) {
+		return nil
+	}
+
+	duration := DefaultVirtualDuration
+	if s.cfg.FFmpegEnable {
+		info, err := ffmpeg.InspectInfo(rawUrl(p, f))
+		if err != nil {
+			logs.Warn("ffmpeg 解析视频时长失败, 使用默认时长: %s, err: %v", p, err)
+		} else if info.Duration > 0 {
+			duration = info.Duration
+		}
+	}
+
+	if err := os.MkdirAll(filepath.Dir(localPath), os.ModePerm); err != nil {
+		return fmt.Errorf("创建目录失败: %w", err)
+	}
+	if err := os.WriteFile(localPath, mp4s.GenWithDuration(duration), os.ModePerm); err != nil {
+		return fmt.Errorf("写入虚拟文件失败: %w", err)
+	}
+	s.finishWrite(localPath, f)
+	return nil
+}
+
+// writeMusic 写入带有音乐标签的虚拟 mp3 以及同名 nfo, 需要开启 ffmpeg
+func (s *Synchronizer) writeMusic(localPath, nfoPath, p string, f openlist.FsGet) error {
+	s.markKeep(localPath)
+	s.markKeep(nfoPath)
+	if upToDate(localPath, f) && upToDate(nfoPath, f) {
+		return nil
+	}
+
+	u := rawUrl(p, f)
+	meta, err := ffmpeg.InspectMusic(u)
+	if err != nil {
+		return fmt.Errorf("ffmpeg 解析音乐元数据失败: %w", err)
+	}
+	cover, err := ffmpeg.ExtractMusicCover(u)
+	if err != nil {
+		logs.Warn("ffmpeg 解析音乐封面失败: %s, err: %v", p, err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(localPath), os.ModePerm); err != nil {
+		return fmt.Errorf("创建目录失败: %w", err)
+	}
+	if err := music.WriteFakeMP3(localPath, meta, cover); err != nil {
+		return fmt.Errorf("写入虚拟音乐文件失败: %w", err)
+	}
+	if err := music.WriteNFO(nfoPath, meta); err != nil {
+		return fmt.Errorf("写入音乐 nfo 失败: %w", err)
+	}
+	s.finishWrite(localPath, f)
+	s.finishWrite(nfoPath, f)
+	return nil
+}
+
+// download 将 openlist 文件原样下载到本地
+func (s *Synchronizer) download(localPath, p string, f openlist.FsGet) error {
+	s.markKeep(localPath)
+	if upToDate(localPath, f) {
+		return nil
+	}
+
+	resp, err := https.Get(rawUrl(p, f)).Header(make(http.Header)).Do()
+	if err != nil {
+		return fmt.Errorf("下载文件失败: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("下载文件失败, 错误响应码: %v", resp.Status)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(localPath), os.ModePerm); err != nil {
+		return fmt.Errorf("创建目录失败: %w", err)
+	}
+	tmpPath := localPath + ".tmp"
+	out, err := os.Create(tmpPath)
+	if err != nil {
+		return fmt.Errorf("创建临时文件失败: %w", err)
+	}
+	_, err = io.Copy(out, resp.Body)
+	out.Close()
+	if err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("写入文件失败: %w", err)
+	}
+	if err := os.Rename(tmpPath, localPath); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("重命名临时文件失败: %w", err)
+	}
+	s.finishWrite(localPath, f)
+	return nil
+}
@@ -2,6 +2,7 @@ package baidupan
 
 import (
 	"Q115-STRM/internal/helpers"
+	"Q115-STRM/internal/scheduler"
 	openapiclient "Q115-STRM/openxpanapi"
 	"context"
 	"encoding/json"
@@ -9,12 +10,14 @@ import (
 	"io"
 	"net/http"
 	"os"
+	"slices"
 	"strings"
 	"sync"
 	"time"
 )
 
 type Client struct {
+	AccountId   uint
 	client      *openapiclient.APIClient
 	accessToken string
 }
@@ -39,11 +42,16 @@ func NewBaiDuPanClient(accountId uint, accessToken string) *Client {
 		return client
 	}
 	config := openapiclient.NewConfiguration()
+	// 所有请求经过账号的调度器限速
+	config.HTTPClient = &http.Client{
+		Transport: scheduler.NewTransport(nil, scheduler.Get(scheduler.SOURCE_BAIDUPAN, accountId), isThrottled),
+	}
 	// if !helpers.IsRelease {
 	// 	config.Debug = true
 	// }
 	apiClient := openapiclient.NewAPIClient(config)
 	client := &Client{
+		AccountId:   accountId,
 		client:      apiClient,
 		accessToken: accessToken,
 	}
@@ -51,6 +59,15 @@ func NewBaiDuPanClient(accountId uint, accessToken string) *Client {
 	return client
 }
 
+// 百度网盘的限流错误码
+func isThrottled(resp *http.Response, body []byte) bool {
+	if resp.StatusCode == http.StatusTooManyRequests {
+		return true
+	}
+	errno, ok := scheduler.ResponseCode(body, "errno")
+	return ok && slices.Contains(THROTTLE_ERRNOS, errno)
+}
+
 func RefreshToken(accountId uint, refreshToken string) (*RefreshResponse, error) {
 	// 生成state参数
 	type stateData struct {
@@ -128,6 +128,9 @@ var ErrorMap map[int64]string = map[int64]string{
 	50002: "播单id不存在",
 }
 
+// THROTTLE_ERRNOS 表示触发了限流的错误码：访问超限、命中接口频控
+var THROTTLE_ERRNOS = []int64{20012, 31034}
+
 // fs_id	uint64	文件在云端的唯一标识ID
 // path	string	文件的绝对路径
 // server_filename	string	文件名称
@@ -41,6 +41,14 @@ func GetAccountList(c *gin.Context) {
 		TokenFailedReason string            `json:"token_failed_reason"`
 		BaseUrl           string            `json:"base_url"`
 		AuthType          string            `json:"auth_type"`
+		DirectLink        bool              `json:"direct_link"`
+		Bucket            string            `json:"bucket"`
+		Region            string            `json:"region"`
+		PathStyle         bool              `json:"path_style"`
+		QPS               int               `json:"qps"`
+		QPM               int               `json:"qpm"`
+		QPH               int               `json:"qph"`
+		FailoverOrder     int               `json:"failover_order"`
 	}
 	resp := make([]accountResp, 0, len(accounts))
 	for _, account := range accounts {
@@ -56,6 +64,14 @@ func GetAccountList(c *gin.Context) {
 			CreatedAt:         account.CreatedAt,
 			TokenFailedReason: account.TokenFailedReason,
 			BaseUrl:           account.BaseUrl,
+			DirectLink:        account.DirectLink,
+			Bucket:            account.Bucket,
+			Region:            account.Region,
+			PathStyle:         account.PathStyle,
+			QPS:               account.QPS,
+			QPM:               account.QPM,
+			QPH:               account.QPH,
+			FailoverOrder:     account.FailoverOrder,
 		}
 		switch account.AppId {
 		case "Q115-STRM":
@@ -236,3 +252,163 @@ func CreateOpenListAccount(c *gin.Context) {
 	}
 	c.JSON(http.StatusOK, APIResponse[any]{Code: Success, Message: "创建openlist账号成功", Data: nil})
 }
+
+// CreateWebDAVAccount 创建或更新WebDAV账号
+// @Summary 创建/更新WebDAV账号
+// @Description 使用WebDAV地址和账号密码创建账号，保存前会访问根目录验证，支持OpenList、Nextcloud、CD2等WebDAV服务
+// @Tags 账号管理
+// @Accept json
+// @Produce json
+// @Param id query integer false "账号ID（指定则为更新操作）"
+// @Param name query string false "账号备注，默认使用用户名"
+// @Param base_url query string true "WebDAV地址，例如 http://ip:5244/dav"
+// @Param username query string false "用户名"
+// @Param password query string false "密码"
+// @Param direct_link query boolean false "STRM是否直接使用WebDAV地址，只对没有用户名密码的WebDAV生效，默认通过QMS代理播放"
+// @Success 200 {object} object
+// @Failure 200 {object} object
+// @Router /account/webdav [post]
+// @Security JwtAuth
+// @Security ApiKeyAuth
+func CreateWebDAVAccount(c *gin.Context) {
+	type createWebDAVAccountReq struct {
+		Id         uint   `json:"id" form:"id"`
+		Name       string `json:"name" form:"name"`
+		BaseUrl    string `json:"base_url" form:"base_url"`
+		Username   string `json:"username" form:"username"`
+		Password   string `json:"password" form:"password"`
+		DirectLink bool   `json:"direct_link" form:"direct_link"`
+	}
+	req := &createWebDAVAccountReq{}
+	if err := c.ShouldBind(req); err != nil || req.BaseUrl == "" {
+		c.JSON(http.StatusBadRequest, APIResponse[any]{Code: BadRequest, Message: "请求参数错误", Data: nil})
+		return
+	}
+	// 如果不以http开头则添加http://
+	if !strings.HasPrefix(req.BaseUrl, "http://") && !strings.HasPrefix(req.BaseUrl, "https://") {
+		req.BaseUrl = "http://" + req.BaseUrl
+	}
+	req.BaseUrl = strings.TrimSuffix(req.BaseUrl, "/")
+	if _, err := models.SaveWebDAVAccount(req.Id, req.Name, req.BaseUrl, req.Username, req.Password, req.DirectLink); err != nil {
+		c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: fmt.Sprintf("保存WebDAV账号失败: %s", err.Error()), Data: nil})
+		return
+	}
+	c.JSON(http.StatusOK, APIResponse[any]{Code: Success, Message: "保存WebDAV账号成功", Data: nil})
+}
+
+// CreateS3Account 创建或更新S3账号
+// @Summary 创建/更新S3账号
+// @Description 使用S3兼容对象存储（MinIO、AWS S3等）的地址、存储桶和密钥创建账号，保存前会列出存储桶验证
+// @Tags 账号管理
+// @Accept json
+// @Produce json
+// @Param id query integer false "账号ID（指定则为更新操作）"
+// @Param name query string false "账号备注，默认使用存储桶名称"
+// @Param endpoint query string true "服务地址，例如 http://ip:9000"
+// @Param region query string false "区域，默认us-east-1"
+// @Param bucket query string true "存储桶"
+// @Param access_key query string true "AccessKey"
+// @Param secret_key query string false "SecretKey，更新时为空表示不修改"
+// @Param path_style query boolean false "是否使用路径风格访问（endpoint/bucket/key），MinIO一般需要开启"
+// @Success 200 {object} object
+// @Failure 200 {object} object
+// @Router /account/s3 [post]
+// @Security JwtAuth
+// @Security ApiKeyAuth
+func CreateS3Account(c *gin.Context) {
+	type createS3AccountReq struct {
+		Id        uint   `json:"id" form:"id"`
+		Name      string `json:"name" form:"name"`
+		Endpoint  string `json:"endpoint" form:"endpoint"`
+		Region    string `json:"region" form:"region"`
+		Bucket    string `json:"bucket" form:"bucket"`
+		AccessKey string `json:"access_key" form:"access_key"`
+		SecretKey string `json:"secret_key" form:"secret_key"`
+		PathStyle bool   `json:"path_style" form:"path_style"`
+	}
+	req := &createS3AccountReq{}
+	if err := c.ShouldBind(req); err != nil || req.Endpoint == "" || req.Bucket == "" || req.AccessKey == "" {
+		c.JSON(http.StatusBadRequest, APIResponse[any]{Code: BadRequest, Message: "请求参数错误", Data: nil})
+		return
+	}
+	if req.Id == 0 && req.SecretKey == "" {
+		c.JSON(http.StatusBadRequest, APIResponse[any]{Code: BadRequest, Message: "SecretKey不能为空", Data: nil})
+		return
+	}
+	// 如果不以http开头则添加http://
+	if !strings.HasPrefix(req.Endpoint, "http://") && !strings.HasPrefix(req.Endpoint, "https://") {
+		req.Endpoint = "http://" + req.Endpoint
+	}
+	if _, err := models.SaveS3Account(req.Id, req.Name, req.Endpoint, req.Region, req.Bucket, req.AccessKey, req.SecretKey, req.PathStyle); err != nil {
+		c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: fmt.Sprintf("保存S3账号失败: %s", err.Error()), Data: nil})
+		return
+	}
+	c.JSON(http.StatusOK, APIResponse[any]{Code: Success, Message: "保存S3账号成功", Data: nil})
+}
+
+// Create123Account 创建或更新123云盘账号
+// @Summary 创建/更新123云盘账号
+// @Description 使用123开放平台的clientID和clientSecret创建账号，如果对应的用户已存在则更新
+// @Tags 账号管理
+// @Accept json
+// @Produce json
+// @Param name query string false "账号备注"
+// @Param client_id query string true "123开放平台clientID"
+// @Param client_secret query string true "123开放平台clientSecret"
+// @Success 200 {object} object
+// @Failure 200 {object} object
+// @Router /account/123 [post]
+// @Security JwtAuth
+// @Security ApiKeyAuth
+func Create123Account(c *gin.Context) {
+	type create123AccountReq struct {
+		Name         string `json:"name" form:"name"`
+		ClientId     string `json:"client_id" form:"client_id"`
+		ClientSecret string `json:"client_secret" form:"client_secret"`
+	}
+	req := &create123AccountReq{}
+	if err := c.ShouldBind(req); err != nil {
+		c.JSON(http.StatusBadRequest, APIResponse[any]{Code: BadRequest, Message: "请求参数错误", Data: nil})
+		return
+	}
+	req.ClientId = strings.TrimSpace(req.ClientId)
+	req.ClientSecret = strings.TrimSpace(req.ClientSecret)
+	if req.ClientId == "" || req.ClientSecret == "" {
+		c.JSON(http.StatusBadRequest, APIResponse[any]{Code: BadRequest, Message: "clientID和clientSecret不能为空", Data: nil})
+		return
+	}
+	account, err := models.Create123Account(req.Name, req.ClientId, req.ClientSecret)
+	if err != nil {
+		c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: fmt.Sprintf("保存123云盘账号失败: %s", err.Error()), Data: nil})
+		return
+	}
+	c.JSON(http.StatusOK, APIResponse[any]{Code: Success, Message: "保存123云盘账号成功", Data: map[string]any{"id": account.ID}})
+}
+
+// SetFailoverOrder 设置115直链故障转移的账号顺序
+// @Summary 设置115故障转移顺序
+// @Description 主账号获取直链失败或限流时，按这个顺序使用其他115账号中SHA1相同的文件，没有列出的账号排在最后
+// @Tags 账号管理
+// @Accept json
+// @Produce json
+// @Param account_ids body []integer true "115账号ID，按优先顺序排列"
+// @Success 200 {object} object
+// @Failure 200 {object} object
+// @Router /account/failover-order [post]
+// @Security JwtAuth
+// @Security ApiKeyAuth
+func SetFailoverOrder(c *gin.Context) {
+	var req struct {
+		AccountIds []uint `json:"account_ids"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: "请求参数错误: " + err.Error(), Data: nil})
+		return
+	}
+	if err := models.SetFailoverOrder(req.AccountIds); err != nil {
+		c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: "保存故障转移顺序失败: " + err.Error(), Data: nil})
+		return
+	}
+	helpers.AppLogger.Infof("115直链故障转移顺序已更新: %v", req.AccountIds)
+	c.JSON(http.StatusOK, APIResponse[any]{Code: Success, Message: "保存故障转移顺序成功", Data: req.AccountIds})
+}
@@ -2,8 +2,8 @@ package controllers
 
 import (
 	"Q115-STRM/internal/baidupan"
-	"Q115-STRM/internal/db"
 	"Q115-STRM/internal/helpers"
+	"Q115-STRM/internal/linkcache"
 	"Q115-STRM/internal/models"
 	"context"
 	"encoding/json"
@@ -212,7 +212,7 @@ func GetBaiduPanUrlByPickCode(c *gin.Context) {
 	var account *models.Account
 	if userId == "" {
 		// 查询SyncFile
-		syncFile := models.GetFileByPickCode(pickCode)
+		syncFile := models.GetFileBySourcePickCode(models.SourceTypeBaiduPan, pickCode)
 		if syncFile == nil {
 			c.JSON(http.StatusBadRequest, APIResponse[any]{Code: BadRequest, Message: "文件PickCode不存在", Data: nil})
 			return
@@ -226,39 +226,35 @@ func GetBaiduPanUrlByPickCode(c *gin.Context) {
 	} else {
 		var err error
 		// 通过userId查询账号
-		account, err = models.GetAccountByUserId(userId)
+		account, err = models.GetAccountBySourceUserId(models.SourceTypeBaiduPan, userId)
 		if err != nil {
 			c.JSON(http.StatusBadRequest, APIResponse[any]{Code: BadRequest, Message: "用户ID不存在", Data: nil})
 			return
 		}
 	}
 	ua := c.Request.UserAgent()
-	client := account.GetBaiDuPanClient()
 	cacheKey := fmt.Sprintf("baidupanurl:%s, ua=%s", pickCode, ua)
 	if keyLock.LockWithTimeout(cacheKey, 10*time.Second) {
 		defer keyLock.Unlock(cacheKey)
-		cachedUrl := string(db.Cache.Get(cacheKey))
-		if cachedUrl == "" {
-			fsDetail, err := client.GetFileDetail(context.Background(), pickCode, 1)
+		var cachedUrl string
+		if entry := linkcache.Get(string(models.SourceTypeBaiduPan), pickCode, ua); entry != nil {
+			cachedUrl = entry.Url
+			helpers.AppLogger.Infof("从缓存中查询到百度网盘下载链接: %s => %s", pickCode, cachedUrl)
+		} else {
+			var err error
+			cachedUrl, err = getBaiduPanUrl(account, pickCode)
 			if err != nil {
 				c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: "获取百度网盘文件详情失败", Data: nil})
 				return
 			}
-			cachedUrl = fmt.Sprintf("%s&access_token=%s", fsDetail.Dlink, account.Token)
-			if cachedUrl == "" {
-				c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: "获取百度网盘下载链接失败", Data: nil})
-				return
-			}
 			helpers.AppLogger.Infof("从接口中查询到百度网盘下载链接: %s => %s", pickCode, cachedUrl)
-			// 缓存8小时
-			db.Cache.Set(cacheKey, []byte(cachedUrl), 28800)
-		} else {
-			helpers.AppLogger.Infof("从缓存中查询到百度网盘下载链接: %s => %s", pickCode, cachedUrl)
+			// 最多缓存8小时，链接自带的过期时间更早时以链接为准
+			linkcache.Set(string(models.SourceTypeBaiduPan), pickCode, ua, account.ID, cachedUrl, 8*time.Hour)
 		}
 		// 检查是否开启了本地播放代理，如果开启则跳转到代理链接
 		if models.SettingsGlobal.LocalProxy == 1 {
 			// 跳转到本地代理
-			proxyUrl := fmt.Sprintf("/proxy-115?baidupan=1&url=%s", url.QueryEscape(cachedUrl))
+			proxyUrl := helpers.MakeProxyUrl(cachedUrl, helpers.ProxyFile{Source: string(models.SourceTypeBaiduPan), AccountId: account.ID, FileId: pickCode})
 			helpers.AppLogger.Infof("通过本地代理访问百度网盘下载链接，非qms 8095播放: %s", url.QueryEscape(cachedUrl))
 			c.Redirect(http.StatusFound, proxyUrl)
 			return
@@ -269,3 +265,12 @@ func GetBaiduPanUrlByPickCode(c *gin.Context) {
 	}
 
 }
+
+// 获取百度网盘文件的下载链接，需要带上access_token才能下载
+func getBaiduPanUrl(account *models.Account, pickCode string) (string, error) {
+	fsDetail, err := account.GetBaiDuPanClient().GetFileDetail(context.Background(), pickCode, 1)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s&access_token=%s", fsDetail.Dlink, account.Token), nil
+}
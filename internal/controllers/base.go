@@ -3,12 +3,9 @@ package controllers
 import (
 	"Q115-STRM/internal/helpers"
 	"Q115-STRM/internal/models"
-	"Q115-STRM/internal/v115open"
 	"fmt"
-	"io"
 	"net/http"
 	"strings"
-	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
@@ -113,57 +110,6 @@ func ValidateJWT(tokenString string) (*LoginUser, error) {
 	return claims, nil
 }
 
-func Proxy115(c *gin.Context) {
-	// 获取原始url参数
-	target := c.Request.URL.Query().Get("url")
-	baidupan := c.Request.URL.Query().Get("baidupan")
-	if target == "" {
-		c.JSON(http.StatusBadRequest, APIResponse[any]{Code: BadRequest, Message: "缺少url参数", Data: nil})
-		return
-	}
-	helpers.AppLogger.Infof("反代网盘下载链接: %s", target)
-	// // 只允许反代 cdnfhnfile.115cdn.net 域名
-	// if !strings.HasPrefix(target, "https://cdnfhnfile.115cdn.net/") {
-	// 	c.JSON(http.StatusForbidden, APIResponse[any]{Code: BadRequest, Message: "只允许反代115CDN链接", Data: nil})
-	// 	return
-	// }
-	// 创建请求
-	req, err := http.NewRequest("GET", target, nil)
-	if err != nil {
-		c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: "请求创建失败", Data: nil})
-		return
-	}
-	// 复制客户端的 Range、Cookie、Referer 等头部
-	for k, v := range c.Request.Header {
-		if k == "Range" || k == "Cookie" || k == "Referer" {
-			// helpers.AppLogger.Infof("响应头: %s=%s", k, v)
-			req.Header[k] = v
-		}
-	}
-	if baidupan != "" {
-		req.Header.Set("User-Agent", "pan.baidu.com")
-	} else {
-		req.Header.Set("User-Agent", v115open.DEFAULTUA)
-	}
-	// 发起请求
-	client := &http.Client{Timeout: 60 * time.Second}
-	resp, err := client.Do(req)
-	if err != nil {
-		c.JSON(http.StatusBadGateway, APIResponse[any]{Code: BadRequest, Message: "反代请求失败: " + err.Error(), Data: nil})
-		return
-	}
-	defer resp.Body.Close()
-	// 复制响应头
-	for k, v := range resp.Header {
-		for _, vv := range v {
-			c.Header(k, vv)
-		}
-	}
-	c.Status(resp.StatusCode)
-	// 复制响应内容
-	_, _ = io.Copy(c.Writer, resp.Body)
-}
-
 func Cors() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		method := c.Request.Method               //请求方法
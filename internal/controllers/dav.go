@@ -0,0 +1,139 @@
+package controllers
+
+import (
+	"Q115-STRM/internal/davserver"
+	"Q115-STRM/internal/db"
+	"Q115-STRM/internal/helpers"
+	"Q115-STRM/internal/models"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/net/webdav"
+)
+
+const LIBRARY_DAV_PREFIX = "/dav"
+
+var libraryFS = davserver.NewFileSystem()
+var libraryDAV = &webdav.Handler{
+	Prefix:     LIBRARY_DAV_PREFIX,
+	FileSystem: libraryFS,
+	LockSystem: webdav.NewMemLS(),
+	Logger: func(r *http.Request, err error) {
+		if err != nil && r.Method != "PROPFIND" {
+			helpers.AppLogger.Warnf("[WebDAV服务] %s %s 失败: %v", r.Method, r.URL.Path, err)
+		}
+	},
+}
+
+// WebDAV认证结果的缓存时间，单位秒
+const libraryDAVAuthTTL = 60
+
+// 验证WebDAV请求，支持Basic认证（系统用户名密码，或者密码填写API Key）和api_key参数
+// 验证通过后缓存1分钟，避免每个请求都计算bcrypt，修改密码或API Key后缓存立即失效
+func checkLibraryDAVAuth(c *gin.Context) bool {
+	username, password, ok := c.Request.BasicAuth()
+	if apiKey := c.Query("api_key"); apiKey != "" {
+		username, password, ok = "", apiKey, true
+	}
+	if !ok || password == "" {
+		return false
+	}
+	hash := sha256.Sum256([]byte(username + "\x00" + password))
+	cacheKey := fmt.Sprintf("davauth:%d:%s", models.CredentialVersion(), hex.EncodeToString(hash[:]))
+	if string(db.Cache.Get(cacheKey)) == "1" {
+		return true
+	}
+	valid := false
+	if username != "" {
+		_, err := models.CheckLogin(username, password)
+		valid = err == nil
+	}
+	if !valid {
+		// 密码也可以填写API Key
+		apiKeyModel, err := models.ValidateAPIKey(password)
+		if err != nil {
+			return false
+		}
+		go apiKeyModel.UpdateLastUsedAt()
+	}
+	db.Cache.Set(cacheKey, []byte("1"), libraryDAVAuthTTL)
+	return true
+}
+
+// 媒体库跳转地址的签名有效期，播放器每次打开视频都会重新请求媒体库
+const libraryVideoSignTTL = 6 * time.Hour
+
+// 视频文件跳转到和STRM相同的直链解析地址，同步路径启用签名时使用同步路径的密钥签名
+func libraryVideoUrl(sf *models.SyncFile) (string, error) {
+	account, err := models.GetAccountById(sf.AccountId)
+	if err != nil {
+		return "", fmt.Errorf("账号 %d 不存在: %v", sf.AccountId, err)
+	}
+	ext := filepath.Ext(sf.FileName)
+	params := url.Values{}
+	var videoPath string
+	switch sf.SourceType {
+	case models.SourceType115, models.SourceTypeBaiduPan, models.SourceType123:
+		params.Set("pickcode", sf.PickCode)
+		params.Set("userid", account.UserId)
+		videoPath = fmt.Sprintf("/%s/url/video%s", sf.SourceType, ext)
+	case models.SourceTypeOpenList:
+		params.Set("account_id", fmt.Sprintf("%d", account.ID))
+		params.Set("path", sf.FileId)
+		videoPath = "/openlist/url"
+	case models.SourceTypeWebDAV, models.SourceTypeS3:
+		params.Set("account_id", fmt.Sprintf("%d", account.ID))
+		params.Set("path", sf.FileId)
+		videoPath = fmt.Sprintf("/%s/url/video%s", sf.SourceType, ext)
+	default:
+		return "", fmt.Errorf("不支持的来源类型 %s", sf.SourceType)
+	}
+	if syncPath := models.GetSyncPathById(sf.SyncPathId); syncPath != nil {
+		helpers.SignStrmQuery(params, syncPath.ID, syncPath.GetStrmSignSecret(), time.Now().Add(libraryVideoSignTTL).Unix())
+	}
+	return videoPath + "?" + params.Encode(), nil
+}
+
+// ServeLibraryDAV 以只读WebDAV的方式提供媒体库
+// @Summary 只读WebDAV媒体库
+// @Description 根目录下每个同步路径是一个目录，视频文件302跳转到网盘直链（本地来源直接返回文件），元数据从本地目标目录读取；使用系统用户名密码或者API Key（作为密码或者api_key参数）认证
+// @Tags WebDAV
+// @Produce octet-stream
+// @Param path path string false "媒体库中的路径"
+// @Param api_key query string false "API Key"
+// @Success 200 {string} string "目录属性或者文件内容"
+// @Success 302 {string} string "重定向到直链"
+// @Failure 401 {string} string "认证失败"
+// @Router /dav/{path} [get]
+func ServeLibraryDAV(c *gin.Context) {
+	if !checkLibraryDAVAuth(c) {
+		c.Header("WWW-Authenticate", `Basic realm="QMediaSync"`)
+		c.Status(http.StatusUnauthorized)
+		return
+	}
+	if c.Request.Method == http.MethodGet || c.Request.Method == http.MethodHead {
+		name := strings.TrimPrefix(c.Request.URL.Path, LIBRARY_DAV_PREFIX)
+		if sf := libraryFS.FindVideo(name); sf != nil {
+			if sf.SourceType == models.SourceTypeLocal {
+				http.ServeFile(c.Writer, c.Request, filepath.FromSlash(sf.FileId))
+				return
+			}
+			videoUrl, err := libraryVideoUrl(sf)
+			if err != nil {
+				helpers.AppLogger.Errorf("[WebDAV服务] 生成 %s 的播放地址失败: %v", name, err)
+				c.Status(http.StatusNotFound)
+				return
+			}
+			c.Redirect(http.StatusFound, videoUrl)
+			return
+		}
+	}
+	libraryDAV.ServeHTTP(c.Writer, c.Request)
+}
@@ -0,0 +1,123 @@
+package controllers
+
+import (
+	"Q115-STRM/internal/helpers"
+	"Q115-STRM/internal/linkcache"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetLinkCacheList 查询缓存的网盘直链
+// @Summary 查询直链缓存
+// @Description 分页查询缓存的115、百度网盘、123云盘直链，可以按来源和PickCode过滤
+// @Tags 直链缓存
+// @Accept json
+// @Produce json
+// @Param source query string false "来源：115、baidupan、123，为空查询所有来源"
+// @Param pick_code query string false "文件PickCode"
+// @Param page query integer false "页码，默认1"
+// @Param page_size query integer false "每页数量，默认100"
+// @Success 200 {object} object
+// @Failure 200 {object} object
+// @Router /link-cache [get]
+// @Security JwtAuth
+// @Security ApiKeyAuth
+func GetLinkCacheList(c *gin.Context) {
+	type linkCacheListReq struct {
+		Source   string `json:"source" form:"source"`
+		PickCode string `json:"pick_code" form:"pick_code"`
+		Page     int    `json:"page" form:"page"`
+		PageSize int    `json:"page_size" form:"page_size"`
+	}
+	var req linkCacheListReq
+	if err := c.ShouldBind(&req); err != nil {
+		c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: "请求参数错误", Data: nil})
+		return
+	}
+	if req.Page == 0 {
+		req.Page = 1
+	}
+	if req.PageSize == 0 {
+		req.PageSize = 100
+	}
+	entries, total, err := linkcache.List(req.Source, req.PickCode, req.Page, req.PageSize)
+	if err != nil {
+		c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: "查询直链缓存失败: " + err.Error(), Data: nil})
+		return
+	}
+	c.JSON(http.StatusOK, APIResponse[any]{Code: Success, Message: "查询直链缓存成功", Data: gin.H{
+		"total": total,
+		"list":  entries,
+	}})
+}
+
+// EvictLinkCache 删除文件的缓存直链
+// @Summary 删除文件的直链缓存
+// @Description 删除文件所有UA的缓存直链，下次播放时重新获取
+// @Tags 直链缓存
+// @Accept json
+// @Produce json
+// @Param source body string false "来源：115、baidupan、123，为空不限来源"
+// @Param pick_code body string true "文件PickCode"
+// @Success 200 {object} object
+// @Failure 200 {object} object
+// @Router /link-cache/evict [post]
+// @Security JwtAuth
+// @Security ApiKeyAuth
+func EvictLinkCache(c *gin.Context) {
+	type evictReq struct {
+		Source   string `json:"source" form:"source"`
+		PickCode string `json:"pick_code" form:"pick_code"`
+	}
+	var req evictReq
+	if err := c.ShouldBind(&req); err != nil || req.PickCode == "" {
+		c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: "pick_code 参数不能为空", Data: nil})
+		return
+	}
+	count, err := linkcache.Evict(req.Source, req.PickCode)
+	if err != nil {
+		c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: "删除直链缓存失败: " + err.Error(), Data: nil})
+		return
+	}
+	helpers.AppLogger.Infof("已删除文件的直链缓存: source=%s, pickcode=%s, 共%d条", req.Source, req.PickCode, count)
+	c.JSON(http.StatusOK, APIResponse[any]{Code: Success, Message: "删除直链缓存成功", Data: gin.H{"count": count}})
+}
+
+// PurgeLinkCache 清空直链缓存
+// @Summary 清空直链缓存
+// @Description 清空所有（或指定来源）的缓存直链，expired_only为true时只清理过期的直链
+// @Tags 直链缓存
+// @Accept json
+// @Produce json
+// @Param source body string false "来源：115、baidupan、123，为空清空所有来源"
+// @Param expired_only body boolean false "是否只清理过期的直链"
+// @Success 200 {object} object
+// @Failure 200 {object} object
+// @Router /link-cache/purge [post]
+// @Security JwtAuth
+// @Security ApiKeyAuth
+func PurgeLinkCache(c *gin.Context) {
+	type purgeReq struct {
+		Source      string `json:"source" form:"source"`
+		ExpiredOnly bool   `json:"expired_only" form:"expired_only"`
+	}
+	var req purgeReq
+	if err := c.ShouldBind(&req); err != nil {
+		c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: "请求参数错误", Data: nil})
+		return
+	}
+	var count int64
+	var err error
+	if req.ExpiredOnly {
+		count, err = linkcache.PurgeExpired()
+	} else {
+		count, err = linkcache.Purge(req.Source)
+	}
+	if err != nil {
+		c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: "清空直链缓存失败: " + err.Error(), Data: nil})
+		return
+	}
+	helpers.AppLogger.Infof("已清空直链缓存: source=%s, 只清理过期=%v, 共%d条", req.Source, req.ExpiredOnly, count)
+	c.JSON(http.StatusOK, APIResponse[any]{Code: Success, Message: "清空直链缓存成功", Data: gin.H{"count": count}})
+}
@@ -3,13 +3,13 @@ package controllers
 import (
 	"Q115-STRM/internal/db"
 	"Q115-STRM/internal/helpers"
+	"Q115-STRM/internal/linkcache"
 	"Q115-STRM/internal/models"
 	"Q115-STRM/internal/v115open"
 	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
-	"net/url"
 	"strconv"
 	"sync"
 	"time"
@@ -167,7 +167,6 @@ func Get115FileUrl(c *gin.Context) {
 			c.JSON(http.StatusBadRequest, APIResponse[any]{Code: BadRequest, Message: "账号ID不存在", Data: nil})
 			return
 		}
-		client := account.Get115Client()
 
 		// helpers.AppLogger.Debugf("是否启用本地代理：%d", models.SettingsGlobal.LocalProxy)
 		if req.Force == 0 && models.SettingsGlobal.LocalProxy == 1 {
@@ -175,24 +174,26 @@ func Get115FileUrl(c *gin.Context) {
 			ua = v115open.DEFAULTUA
 			helpers.AppLogger.Infof("因为8095标识=%d, 本地播放代理开关=%d，所以使用默认UA: %s", req.Force, models.SettingsGlobal.LocalProxy, ua)
 		}
-		cachedUrl := string(db.Cache.Get(cacheKey))
-		if cachedUrl == "" {
-			cachedUrl = client.GetDownloadUrl(context.Background(), req.PickCode, ua, true)
+		var cachedUrl string
+		if entry := linkcache.Get(string(models.SourceType115), req.PickCode, ua); entry != nil {
+			cachedUrl = entry.Url
+			helpers.AppLogger.Infof("从缓存中查询到115下载链接: pickcode=%s, ua=%s, 账号=%d => %s", req.PickCode, ua, entry.AccountId, cachedUrl)
+		} else {
+			var servedBy *models.Account
+			cachedUrl, servedBy = get115UrlWithFailover(account, nil, req.PickCode, ua)
 			if cachedUrl == "" {
 				c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: "获取115下载链接失败", Data: nil})
 				return
 			}
-			helpers.AppLogger.Infof("从接口中查询到115下载链接: pickcode=%s, ua=%s => %s", req.PickCode, ua, cachedUrl)
-			// 缓存半小时
-			db.Cache.Set(cacheKey, []byte(cachedUrl), 1800)
-		} else {
-			helpers.AppLogger.Infof("从缓存中查询到115下载链接: pickcode=%s, ua=%s => %s", req.PickCode, ua, cachedUrl)
+			helpers.AppLogger.Infof("从接口中查询到115下载链接: pickcode=%s, ua=%s, 账号=%d（%s） => %s", req.PickCode, ua, servedBy.ID, servedBy.Username, cachedUrl)
+			// 最多缓存半小时，链接自带的过期时间更早时以链接为准，同时记录提供链接的账号
+			linkcache.Set(string(models.SourceType115), req.PickCode, ua, servedBy.ID, cachedUrl, 30*time.Minute)
 		}
 		if req.Force == 0 {
 			if models.SettingsGlobal.LocalProxy == 1 {
 				// 跳转到本地代理
 				helpers.AppLogger.Infof("通过本地代理访问115下载链接，非302播放: %s", cachedUrl)
-				proxyUrl := fmt.Sprintf("/proxy-115?url=%s", url.QueryEscape(cachedUrl))
+				proxyUrl := helpers.MakeProxyUrl(cachedUrl, helpers.ProxyFile{Source: string(models.SourceType115), AccountId: account.ID, FileId: req.PickCode})
 				c.Redirect(http.StatusFound, proxyUrl)
 			} else {
 				helpers.AppLogger.Infof("302重定向到115下载链接，非302播放: %s", cachedUrl)
@@ -231,9 +232,10 @@ func Get115UrlByPickCode(c *gin.Context) {
 	pickCode := req.PickCode
 	userId := req.UserId
 	var account *models.Account
+	var syncFile *models.SyncFile
 	if userId == "" {
 		// 查询SyncFile
-		syncFile := models.GetFileByPickCode(pickCode)
+		syncFile = models.GetFileByPickCode(pickCode)
 		if syncFile == nil {
 			c.JSON(http.StatusBadRequest, APIResponse[any]{Code: BadRequest, Message: "文件PickCode不存在", Data: nil})
 			return
@@ -256,7 +258,6 @@ func Get115UrlByPickCode(c *gin.Context) {
 		// helpers.AppLogger.Infof("通过用户ID查询到115账号: %s", account.Username)
 	}
 	ua := c.Request.UserAgent()
-	client := account.Get115Client()
 	// helpers.AppLogger.Infof("检查是否具有直链播放标记， force=%d", req.Force)
 	cacheKey := fmt.Sprintf("115url:%s, ua=%s", pickCode, ua)
 	// helpers.AppLogger.Infof("准备获取115文件下载链接: pickcode=%s, ua=%s，8095播放=%d 加锁10秒", pickCode, ua, req.Force)
@@ -268,24 +269,26 @@ func Get115UrlByPickCode(c *gin.Context) {
 			ua = v115open.DEFAULTUA
 			helpers.AppLogger.Infof("因为直链标识=%d, 本地播放代理开关=%d，所以使用默认UA: %s", req.Force, models.SettingsGlobal.LocalProxy, ua)
 		}
-		cachedUrl := string(db.Cache.Get(cacheKey))
-		if cachedUrl == "" {
-			cachedUrl = client.GetDownloadUrl(context.Background(), pickCode, ua, true)
+		var cachedUrl string
+		if entry := linkcache.Get(string(models.SourceType115), pickCode, ua); entry != nil {
+			cachedUrl = entry.Url
+			helpers.AppLogger.Infof("从缓存中查询到115下载链接: pickcode=%s, ua=%s, 账号=%d => %s", pickCode, ua, entry.AccountId, cachedUrl)
+		} else {
+			var servedBy *models.Account
+			cachedUrl, servedBy = get115UrlWithFailover(account, syncFile, pickCode, ua)
 			if cachedUrl == "" {
 				c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: "获取115下载链接失败", Data: nil})
 				return
 			}
-			helpers.AppLogger.Infof("从接口中查询到115下载链接: pickcode=%s, ua=%s => %s", pickCode, ua, cachedUrl)
-			// 缓存2小时
-			db.Cache.Set(cacheKey, []byte(cachedUrl), 7200)
-		} else {
-			helpers.AppLogger.Infof("从缓存中查询到115下载链接: pickcode=%s, ua=%s => %s", pickCode, ua, cachedUrl)
+			helpers.AppLogger.Infof("从接口中查询到115下载链接: pickcode=%s, ua=%s, 账号=%d（%s） => %s", pickCode, ua, servedBy.ID, servedBy.Username, cachedUrl)
+			// 最多缓存2小时，链接自带的过期时间更早时以链接为准，同时记录提供链接的账号
+			linkcache.Set(string(models.SourceType115), pickCode, ua, servedBy.ID, cachedUrl, 2*time.Hour)
 		}
 		if req.Force == 0 {
 			if models.SettingsGlobal.LocalProxy == 1 {
 				// 跳转到本地代理
 				helpers.AppLogger.Infof("通过本地代理访问115下载链接，非qms 8095播放: %s", cachedUrl)
-				proxyUrl := fmt.Sprintf("/proxy-115?url=%s", url.QueryEscape(cachedUrl))
+				proxyUrl := helpers.MakeProxyUrl(cachedUrl, helpers.ProxyFile{Source: string(models.SourceType115), AccountId: account.ID, FileId: pickCode})
 				c.Redirect(http.StatusFound, proxyUrl)
 			} else {
 				helpers.AppLogger.Infof("302重定向到115下载链接，非直链qms 8095播放: %s", cachedUrl)
@@ -298,6 +301,46 @@ func Get115UrlByPickCode(c *gin.Context) {
 	}
 }
 
+// 获取115下载链接，主账号失败或者处于限流状态时，使用其他115账号中SHA1相同的文件
+// 返回链接和提供链接的账号
+func get115UrlWithFailover(account *models.Account, syncFile *models.SyncFile, pickCode, ua string) (string, *models.Account) {
+	ctx := context.Background()
+	primaryThrottled := v115open.GetExecutor(account.ID).GetThrottleStatus().IsThrottled
+	if !primaryThrottled {
+		if downloadUrl := account.Get115Client().GetDownloadUrl(ctx, pickCode, ua, true); downloadUrl != "" {
+			return downloadUrl, account
+		}
+	}
+
+	// 通过userid播放时没有查询SyncFile
+	if syncFile == nil {
+		syncFile = models.GetFileByPickCode(pickCode)
+	}
+	if syncFile != nil && syncFile.Sha1 != "" {
+		for _, file := range models.GetFailoverFiles(syncFile.Sha1, account.ID) {
+			if v115open.GetExecutor(file.AccountId).GetThrottleStatus().IsThrottled {
+				helpers.AppLogger.Warnf("115故障转移：账号 %d 处于限流状态，跳过", file.AccountId)
+				continue
+			}
+			downloadUrl := file.Account.Get115Client().GetDownloadUrl(ctx, file.PickCode, ua, true)
+			if downloadUrl == "" {
+				continue
+			}
+			helpers.AppLogger.Warnf("115故障转移：账号 %d 获取下载链接失败（限流=%v），使用账号 %d（%s）中SHA1相同的文件 pickcode=%s",
+				account.ID, primaryThrottled, file.AccountId, file.Account.Username, file.PickCode)
+			return downloadUrl, file.Account
+		}
+	}
+
+	// 没有其他账号可用时，仍然尝试限流中的主账号
+	if primaryThrottled {
+		if downloadUrl := account.Get115Client().GetDownloadUrl(ctx, pickCode, ua, true); downloadUrl != "" {
+			return downloadUrl, account
+		}
+	}
+	return "", nil
+}
+
 // GetLoginQrCodeOpen 获取115开放平台登录二维码
 // @Summary 获取115登录二维码
 // @Description 生成115开放平台登录二维码并异步轮询状态
@@ -505,6 +548,7 @@ func ConfirmOAuthCode(c *gin.Context) {
 }
 
 // GetQueueStats 获取115 OpenAPI请求队列的统计数据
+// 每个115账号一个请求队列，指定account_id时只返回该账号的统计，否则顶层为所有账号的汇总，accounts为各账号的统计
 func GetQueueStats(c *gin.Context) {
 	// 获取查询参数，支持查询不同时间窗口的统计
 	timeWindowStr := c.DefaultQuery("time_window", "3600") // 默认3600秒（1小时）
@@ -513,20 +557,60 @@ func GetQueueStats(c *gin.Context) {
 		c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: "time_window参数无效", Data: nil})
 		return
 	}
+	accountId, err := strconv.ParseUint(c.DefaultQuery("account_id", "0"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: "account_id参数无效", Data: nil})
+		return
+	}
 
 	duration := time.Duration(timeWindow) * time.Second
+	globalCeiling := v115open.GetGlobalCeiling()
 
-	// 获取全局队列执行器
-	executor := v115open.GetGlobalExecutor()
+	if accountId > 0 {
+		responseData := queueStatsData(v115open.GetExecutor(uint(accountId)), duration)
+		responseData["time_window_seconds"] = timeWindow
+		responseData["global_limits"] = globalCeiling.Limits()
+		c.JSON(http.StatusOK, APIResponse[gin.H]{Code: Success, Message: "获取队列统计数据成功", Data: responseData})
+		return
+	}
 
+	// 所有账号的汇总
+	stats := globalCeiling.GetStats(duration)
+	accounts := make([]gin.H, 0)
+	isThrottled := false
+	for _, executor := range v115open.Executors() {
+		accountStats := queueStatsData(executor, duration)
+		isThrottled = isThrottled || accountStats["is_throttled"].(bool)
+		accounts = append(accounts, accountStats)
+	}
+	responseData := gin.H{
+		"total_requests":        stats.TotalRequests,
+		"qps_count":             stats.QPSCount,
+		"qpm_count":             stats.QPMCount,
+		"qph_count":             stats.QPHCount,
+		"throttled_count":       stats.ThrottledCount,
+		"avg_response_time_ms":  stats.AvgResponseTime,
+		"last_throttle_time":    stats.LastThrottleTime,
+		"time_window_seconds":   timeWindow,
+		"is_throttled":          isThrottled, // 是否有账号处于限流状态
+		"reserved_playback_qps": v115open.PLAYBACK_RESERVED_QPS,
+		"global_limits":         globalCeiling.Limits(),
+		"accounts":              accounts,
+	}
+
+	c.JSON(http.StatusOK, APIResponse[gin.H]{Code: Success, Message: "获取队列统计数据成功", Data: responseData})
+}
+
+// 单个账号请求队列的统计数据
+func queueStatsData(executor *v115open.QueueExecutor, duration time.Duration) gin.H {
 	// 获取统计数据
 	stats := executor.GetStats(duration)
 
 	// 获取限流状态
 	throttleStatus := executor.GetThrottleStatus()
 
-	// 构建响应数据
-	responseData := gin.H{
+	return gin.H{
+		"account_id":               executor.AccountId,
 		"total_requests":           stats.TotalRequests,
 		"qps_count":                stats.QPSCount,
 		"qpm_count":                stats.QPMCount,
@@ -536,13 +620,13 @@ func GetQueueStats(c *gin.Context) {
 		"last_throttle_time":       stats.LastThrottleTime,
 		"throttle_wait_time":       stats.ThrottledWaitTime.String(),
 		"throttle_recover_time":    stats.ThrottleRecoverTime,
-		"time_window_seconds":      timeWindow,
 		"is_throttled":             throttleStatus.IsThrottled,
 		"throttled_elapsed_time":   throttleStatus.ElapsedTime.String(),
 		"throttled_remaining_time": throttleStatus.RemainingTime.String(),
+		"reserved_playback_qps":    v115open.PLAYBACK_RESERVED_QPS,
+		"lanes":                    executor.GetLaneStats(),      // 各优先级队列：playback、browse、scrape、sync
+		"adaptive":                 executor.GetAdaptiveStatus(), // 自适应速率：ceiling为配置的上限，learned为当前学习到的速率
 	}
-
-	c.JSON(http.StatusOK, APIResponse[gin.H]{Code: Success, Message: "获取队列统计数据成功", Data: responseData})
 }
 
 // SetQueueRateLimit 设置115 OpenAPI请求队列的速率限制参数
@@ -558,21 +642,33 @@ func SetQueueRateLimit(c *gin.Context) {
 		return
 	}
 
-	// 设置全局执行器的速率限制配置
-	v115open.SetGlobalExecutorConfig(req.QPS, req.QPM, req.QPH)
+	// 设置每个账号默认的速率限制，全局上限同时调整
+	v115open.SetExecutorConfig(req.QPS, req.QPM, req.QPH)
 
 	helpers.AppLogger.Infof("115 OpenAPI队列速率限制已更新: QPS=%d, QPM=%d, QPH=%d", req.QPS, req.QPM, req.QPH)
 
 	c.JSON(http.StatusOK, APIResponse[any]{Code: Success, Message: "速率限制配置成功", Data: gin.H{
-		"qps": req.QPS,
-		"qpm": req.QPM,
-		"qph": req.QPH,
+		"qps":           req.QPS,
+		"qpm":           req.QPM,
+		"qph":           req.QPH,
+		"global_limits": v115open.GetGlobalCeiling().Limits(),
 	}})
 }
 
-// GetRequestStatsByDay 获取指定日期范围内的请求统计（按天分组）
-func GetRequestStatsByDay(c *gin.Context) {
-	// 获取查询参数
+// GetRequestStatsByDay 获取指定来源在指定日期范围内的请求统计（按天分组）
+func GetRequestStatsByDay(source models.SourceType) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		getRequestStatsByDay(c, source)
+	}
+}
+
+func getRequestStatsByDay(c *gin.Context, source models.SourceType) {
+	// 获取查询参数，account_id为0时统计所有账号
+	accountId, err := strconv.ParseUint(c.DefaultQuery("account_id", "0"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: "account_id参数无效", Data: nil})
+		return
+	}
 	startDateStr := c.DefaultQuery("start_date", time.Now().AddDate(0, 0, -7).Format("2006-01-02")) // 默认最近7天
 	endDateStr := c.DefaultQuery("end_date", time.Now().Format("2006-01-02"))
 
@@ -593,17 +689,19 @@ func GetRequestStatsByDay(c *gin.Context) {
 	endTime := endDate.Add(24*time.Hour - time.Second).Unix()
 
 	// 获取按天分组的统计数据
-	dailyStats, err := models.GetDailyRequestStats(startTime, endTime)
+	dailyStats, err := models.GetDailyRequestStats(source, uint(accountId), startTime, endTime)
 	if err != nil {
 		c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: "查询统计数据失败: " + err.Error(), Data: nil})
 		return
 	}
 
 	// 获取总请求数和限流请求数
-	totalCount, _ := models.GetRequestStatsCount(startTime, endTime)
-	throttledCount, _ := models.GetThrottledRequestsCount(startTime, endTime)
+	totalCount, _ := models.GetRequestStatsCount(source, uint(accountId), startTime, endTime)
+	throttledCount, _ := models.GetThrottledRequestsCount(source, uint(accountId), startTime, endTime)
 
 	responseData := gin.H{
+		"source":                source,
+		"account_id":            accountId,
 		"start_date":            startDateStr,
 		"end_date":              endDateStr,
 		"total_requests":        totalCount,
@@ -615,9 +713,20 @@ func GetRequestStatsByDay(c *gin.Context) {
 	c.JSON(http.StatusOK, APIResponse[gin.H]{Code: Success, Message: "获取日统计数据成功", Data: responseData})
 }
 
-// GetRequestStatsByHour 获取指定日期范围内的请求统计（按小时分组）
-func GetRequestStatsByHour(c *gin.Context) {
-	// 获取查询参数
+// GetRequestStatsByHour 获取指定来源在指定日期范围内的请求统计（按小时分组）
+func GetRequestStatsByHour(source models.SourceType) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		getRequestStatsByHour(c, source)
+	}
+}
+
+func getRequestStatsByHour(c *gin.Context, source models.SourceType) {
+	// 获取查询参数，account_id为0时统计所有账号
+	accountId, err := strconv.ParseUint(c.DefaultQuery("account_id", "0"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: "account_id参数无效", Data: nil})
+		return
+	}
 	startDateStr := c.DefaultQuery("start_date", time.Now().AddDate(0, 0, -1).Format("2006-01-02")) // 默认昨天
 	endDateStr := c.DefaultQuery("end_date", time.Now().Format("2006-01-02"))                       // 默认今天
 
@@ -638,17 +747,19 @@ func GetRequestStatsByHour(c *gin.Context) {
 	endTime := endDate.Add(24*time.Hour - time.Second).Unix()
 
 	// 获取按小时分组的统计数据
-	hourlyStats, err := models.GetHourlyRequestStats(startTime, endTime)
+	hourlyStats, err := models.GetHourlyRequestStats(source, uint(accountId), startTime, endTime)
 	if err != nil {
 		c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: "查询统计数据失败: " + err.Error(), Data: nil})
 		return
 	}
 
 	// 获取总请求数和限流请求数
-	totalCount, _ := models.GetRequestStatsCount(startTime, endTime)
-	throttledCount, _ := models.GetThrottledRequestsCount(startTime, endTime)
+	totalCount, _ := models.GetRequestStatsCount(source, uint(accountId), startTime, endTime)
+	throttledCount, _ := models.GetThrottledRequestsCount(source, uint(accountId), startTime, endTime)
 
 	responseData := gin.H{
+		"source":                source,
+		"account_id":            accountId,
 		"start_date":            startDateStr,
 		"end_date":              endDateStr,
 		"total_requests":        totalCount,
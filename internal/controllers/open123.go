@@ -0,0 +1,138 @@
+package controllers
+
+import (
+	"Q115-STRM/internal/helpers"
+	"Q115-STRM/internal/linkcache"
+	"Q115-STRM/internal/models"
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Open123StatusResp 123云盘状态响应
+type Open123StatusResp struct {
+	UserId     int64  `json:"user_id"`
+	Username   string `json:"username"`
+	UsedSpace  int64  `json:"used_space"`
+	TotalSpace int64  `json:"total_space"`
+	Vip        bool   `json:"vip"`
+}
+
+// Get123Status 查询123云盘账号状态
+// @Summary 查询123云盘账号状态
+// @Description 获取指定123云盘账号的用户信息及存储信息
+// @Tags 123云盘
+// @Accept json
+// @Produce json
+// @Param account_id query integer true "账号ID"
+// @Success 200 {object} object
+// @Failure 200 {object} object
+// @Router /123/status [get]
+// @Security JwtAuth
+// @Security ApiKeyAuth
+func Get123Status(c *gin.Context) {
+	type statusReq struct {
+		AccountId uint `json:"account_id" form:"account_id"`
+	}
+	var req statusReq
+	if err := c.ShouldBind(&req); err != nil {
+		c.JSON(http.StatusBadRequest, APIResponse[any]{Code: BadRequest, Message: "参数错误", Data: nil})
+		return
+	}
+	account, err := models.GetAccountById(req.AccountId)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, APIResponse[any]{Code: BadRequest, Message: "账号ID不存在", Data: nil})
+		return
+	}
+	userInfo, err := account.Get123Client().GetUserInfo(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: "获取123云盘用户信息失败: " + err.Error(), Data: nil})
+		return
+	}
+	c.JSON(http.StatusOK, APIResponse[any]{Code: Success, Message: "成功", Data: Open123StatusResp{
+		UserId:     userInfo.UID,
+		Username:   userInfo.Nickname,
+		UsedSpace:  userInfo.SpaceUsed,
+		TotalSpace: userInfo.SpacePermanent + userInfo.SpaceTemp,
+		Vip:        userInfo.Vip,
+	}})
+}
+
+// 通过123云盘文件的fileID（参数名叫pickcode，跟115保持一致）获取下载链接
+func Get123UrlByPickCode(c *gin.Context) {
+	type fileIdReq struct {
+		UserId   string `json:"userid" form:"userid"`
+		PickCode string `json:"pickcode" form:"pickcode"`
+		Force    int    `json:"force" form:"force"`
+	}
+	var req fileIdReq
+	if err := c.ShouldBind(&req); err != nil {
+		c.JSON(http.StatusBadRequest, APIResponse[any]{Code: BadRequest, Message: "参数错误", Data: nil})
+		return
+	}
+	pickCode := req.PickCode
+	userId := req.UserId
+	fileId, err := strconv.ParseInt(pickCode, 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, APIResponse[any]{Code: BadRequest, Message: "文件PickCode格式错误", Data: nil})
+		return
+	}
+	var account *models.Account
+	if userId == "" {
+		// 查询SyncFile
+		syncFile := models.GetFileBySourcePickCode(models.SourceType123, pickCode)
+		if syncFile == nil {
+			c.JSON(http.StatusBadRequest, APIResponse[any]{Code: BadRequest, Message: "文件PickCode不存在", Data: nil})
+			return
+		}
+		account, err = models.GetAccountById(syncFile.AccountId)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, APIResponse[any]{Code: BadRequest, Message: "账号ID不存在", Data: nil})
+			return
+		}
+	} else {
+		// 通过userId查询账号
+		account, err = models.GetAccountBySourceUserId(models.SourceType123, userId)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, APIResponse[any]{Code: BadRequest, Message: "用户ID不存在", Data: nil})
+			return
+		}
+	}
+	client := account.Get123Client()
+	cacheKey := fmt.Sprintf("123url:%s", pickCode)
+	if keyLock.LockWithTimeout(cacheKey, 10*time.Second) {
+		defer keyLock.Unlock(cacheKey)
+		cachedUrl := ""
+		if req.Force == 0 {
+			if entry := linkcache.Get(string(models.SourceType123), pickCode, ""); entry != nil {
+				cachedUrl = entry.Url
+			}
+		}
+		if cachedUrl == "" {
+			cachedUrl, err = client.GetDirectLink(context.Background(), fileId)
+			if err != nil || cachedUrl == "" {
+				helpers.AppLogger.Errorf("获取123云盘下载链接失败: %s %v", pickCode, err)
+				c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: "获取123云盘下载链接失败", Data: nil})
+				return
+			}
+			helpers.AppLogger.Infof("从接口中查询到123云盘下载链接: %s => %s", pickCode, cachedUrl)
+			// 最多缓存30分钟，链接自带的过期时间更早时以链接为准
+			linkcache.Set(string(models.SourceType123), pickCode, "", account.ID, cachedUrl, 30*time.Minute)
+		} else {
+			helpers.AppLogger.Infof("从缓存中查询到123云盘下载链接: %s => %s", pickCode, cachedUrl)
+		}
+		// 检查是否开启了本地播放代理，如果开启则跳转到代理链接
+		if models.SettingsGlobal.LocalProxy == 1 {
+			proxyUrl := helpers.MakeProxyUrl(cachedUrl, helpers.ProxyFile{Source: string(models.SourceType123), AccountId: account.ID, FileId: pickCode})
+			c.Redirect(http.StatusFound, proxyUrl)
+			return
+		}
+		c.Redirect(http.StatusFound, cachedUrl)
+		return
+	}
+	c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: "获取下载链接超时，请稍后重试", Data: nil})
+}
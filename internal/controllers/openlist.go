@@ -16,6 +16,7 @@ import (
 // @Produce json
 // @Param account_id query integer true "账号ID"
 // @Param path query string true "文件路径"
+// @Param force query integer false "是否强制直链播放，1为直链，0使用本地代理时会走代理"
 // @Success 302 {string} string "重定向到文件直链"
 // @Failure 200 {object} object
 // @Router /openlist/url [get]
@@ -23,6 +24,7 @@ func GetOpenListFileUrl(c *gin.Context) {
 	type fileUrlReq struct {
 		AccountId uint   `json:"account_id" form:"account_id"`
 		Path      string `json:"path" form:"path"`
+		Force     int    `json:"force" form:"force"`
 	}
 	var req fileUrlReq
 	if err := c.ShouldBind(&req); err != nil {
@@ -49,6 +51,12 @@ func GetOpenListFileUrl(c *gin.Context) {
 		c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: "文件详情中未找到直链", Data: nil})
 		return
 	}
+	if req.Force == 0 && models.SettingsGlobal.LocalProxy == 1 {
+		// 跳转到本地代理
+		helpers.AppLogger.Infof("通过本地代理访问OpenList下载链接: %s", fileDetail.RawURL)
+		c.Redirect(http.StatusFound, helpers.MakeProxyUrl(fileDetail.RawURL, helpers.ProxyFile{Source: string(models.SourceTypeOpenList), AccountId: account.ID, FileId: req.Path}))
+		return
+	}
 	// 302跳转到直链
 	c.Redirect(http.StatusFound, fileDetail.RawURL)
 }
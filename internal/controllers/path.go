@@ -10,6 +10,7 @@ import (
 	"os"
 	"path/filepath"
 	"runtime"
+	"strconv"
 	"strings"
 	"time"
 
@@ -59,10 +60,16 @@ func GetPathList(c *gin.Context) {
 		pathes, err = GetLocalPath(req.ParentId)
 	case models.SourceTypeOpenList:
 		pathes, err = GetOpenListPath(req.ParentId, req.AccountId)
+	case models.SourceTypeWebDAV:
+		pathes, err = GetWebDAVPath(req.ParentId, req.AccountId)
+	case models.SourceTypeS3:
+		pathes, err = GetS3Path(req.ParentId, req.AccountId)
 	case models.SourceType115:
 		pathes, err = Get115PathList(req.ParentId, req.AccountId)
 	case models.SourceTypeBaiduPan:
 		pathes, err = GetBaiduPanPathList(req.ParentId, req.AccountId)
+	case models.SourceType123:
+		pathes, err = Get123PathList(req.ParentId, req.ParentPath, req.AccountId)
 	default:
 		// 报错
 		c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: "未知的同步源类型", Data: nil})
@@ -188,6 +195,56 @@ func GetOpenListPath(parentPath string, accountId uint) ([]DirResp, error) {
 	return folders, nil
 }
 
+func GetWebDAVPath(parentPath string, accountId uint) ([]DirResp, error) {
+	account, err := models.GetAccountById(accountId)
+	if err != nil {
+		return nil, err
+	}
+	parentPath = strings.TrimSuffix(filepath.ToSlash(parentPath), "/")
+	helpers.AppLogger.Infof("开始获取WebDAV目录列表, 父目录路径: %s", parentPath)
+	files, err := account.GetWebDAVClient().ReadDir(context.Background(), parentPath)
+	if err != nil {
+		return nil, err
+	}
+	// 只返回文件夹列表
+	folders := make([]DirResp, 0)
+	for _, item := range files {
+		if item.IsDir {
+			folders = append(folders, DirResp{
+				Id:   parentPath + "/" + item.Name,
+				Name: item.Name,
+				Path: parentPath + "/" + item.Name,
+			})
+		}
+	}
+	return folders, nil
+}
+
+func GetS3Path(parentPath string, accountId uint) ([]DirResp, error) {
+	account, err := models.GetAccountById(accountId)
+	if err != nil {
+		return nil, err
+	}
+	parentPath = strings.TrimSuffix(filepath.ToSlash(parentPath), "/")
+	helpers.AppLogger.Infof("开始获取S3目录列表, 父目录路径: %s", parentPath)
+	files, err := account.GetS3Client().ReadDir(context.Background(), parentPath)
+	if err != nil {
+		return nil, err
+	}
+	// 只返回文件夹列表
+	folders := make([]DirResp, 0)
+	for _, item := range files {
+		if item.IsDir {
+			folders = append(folders, DirResp{
+				Id:   item.Path,
+				Name: item.Name,
+				Path: item.Path,
+			})
+		}
+	}
+	return folders, nil
+}
+
 func Get115PathList(parentId string, accountId uint) ([]DirResp, error) {
 	// 获取115目录列表
 	account, err := models.GetAccountById(accountId)
@@ -250,6 +307,43 @@ func GetBaiduPanPathList(parentId string, accountId uint) ([]DirResp, error) {
 	return items, nil
 }
 
+// 123云盘的目录ID是数字，根目录为0
+func parse123ParentId(parentId string) (int64, error) {
+	if parentId == "" {
+		return 0, nil
+	}
+	return strconv.ParseInt(parentId, 10, 64)
+}
+
+func Get123PathList(parentId string, parentPath string, accountId uint) ([]DirResp, error) {
+	account, err := models.GetAccountById(accountId)
+	if err != nil {
+		return nil, err
+	}
+	pid, err := parse123ParentId(parentId)
+	if err != nil {
+		return nil, fmt.Errorf("123云盘目录ID格式错误: %s", parentId)
+	}
+	client := account.Get123Client()
+	files, err := client.ListAllFiles(context.Background(), pid)
+	if err != nil {
+		helpers.AppLogger.Warnf("获取123云盘目录列表失败: 父目录：%s, 错误:%v", parentId, err)
+		return nil, err
+	}
+	folders := make([]DirResp, 0)
+	for _, item := range files {
+		if item.FileType != 1 || item.Trashed == 1 {
+			continue
+		}
+		folders = append(folders, DirResp{
+			Id:   strconv.FormatInt(item.FileID, 10),
+			Name: item.FileName,
+			Path: filepath.ToSlash(filepath.Join(parentPath, item.FileName)),
+		})
+	}
+	return folders, nil
+}
+
 type FileItem struct {
 	Id          string `json:"id"`
 	IsDirectory bool   `json:"is_directory"`
@@ -286,10 +380,16 @@ func GetNetFileList(c *gin.Context) {
 	switch account.SourceType {
 	case models.SourceTypeOpenList:
 		list, err = getOpenlistDirs(req.ParentId, account, req.Page, req.PageSize)
+	case models.SourceTypeWebDAV:
+		list, err = getWebDAVDirs(req.ParentId, account, req.Page, req.PageSize)
+	case models.SourceTypeS3:
+		list, err = getS3Dirs(req.ParentId, account, req.Page, req.PageSize)
 	case models.SourceType115:
 		list, err = get115Dirs(req.ParentId, account, req.Page, req.PageSize)
 	case models.SourceTypeBaiduPan:
 		list, err = getBaiduPanDirs(req.ParentId, account, req.Page, req.PageSize)
+	case models.SourceType123:
+		list, err = get123Dirs(req.ParentId, account, req.Page, req.PageSize)
 	default:
 		// 报错
 		c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: "未知的网盘类型", Data: nil})
@@ -333,6 +433,60 @@ func getOpenlistDirs(parentPath string, account *models.Account, page, pageSize
 	return items, nil
 }
 
+// WebDAV不支持分页，查询全部后按页返回
+func getWebDAVDirs(parentPath string, account *models.Account, page, pageSize int) ([]*FileItem, error) {
+	parentPath = strings.TrimSuffix(filepath.ToSlash(parentPath), "/")
+	helpers.AppLogger.Infof("开始获取WebDAV目录列表, 父目录路径: %s", parentPath)
+	files, err := account.GetWebDAVClient().ReadDir(context.Background(), parentPath)
+	if err != nil {
+		return nil, err
+	}
+	items := make([]*FileItem, 0)
+	start := (page - 1) * pageSize
+	for i := start; i < len(files) && i < start+pageSize; i++ {
+		item := files[i]
+		var mtime int64
+		if !item.Modified.IsZero() {
+			mtime = item.Modified.Unix()
+		}
+		items = append(items, &FileItem{
+			Id:          parentPath + "/" + item.Name,
+			IsDirectory: item.IsDir,
+			Name:        item.Name,
+			Size:        item.Size,
+			ModifiedAt:  mtime,
+		})
+	}
+	return items, nil
+}
+
+// S3按前缀列出全部对象后按页返回
+func getS3Dirs(parentPath string, account *models.Account, page, pageSize int) ([]*FileItem, error) {
+	parentPath = strings.TrimSuffix(filepath.ToSlash(parentPath), "/")
+	helpers.AppLogger.Infof("开始获取S3目录列表, 父目录路径: %s", parentPath)
+	files, err := account.GetS3Client().ReadDir(context.Background(), parentPath)
+	if err != nil {
+		return nil, err
+	}
+	items := make([]*FileItem, 0)
+	start := (page - 1) * pageSize
+	for i := start; i < len(files) && i < start+pageSize; i++ {
+		item := files[i]
+		var mtime int64
+		if !item.Modified.IsZero() {
+			mtime = item.Modified.Unix()
+		}
+		items = append(items, &FileItem{
+			Id:          item.Path,
+			IsDirectory: item.IsDir,
+			Name:        item.Name,
+			Size:        item.Size,
+			ModifiedAt:  mtime,
+		})
+	}
+	return items, nil
+}
+
 func get115Dirs(parentId string, account *models.Account, page, pageSize int) ([]*FileItem, error) {
 	client := account.Get115Client()
 	ctx := context.Background()
@@ -380,6 +534,35 @@ func getBaiduPanDirs(parentId string, account *models.Account, page, pageSize in
 	return items, nil
 }
 
+// 123云盘按lastFileId分页，不能跳页，查询全部后按页返回
+func get123Dirs(parentId string, account *models.Account, page, pageSize int) ([]*FileItem, error) {
+	pid, err := parse123ParentId(parentId)
+	if err != nil {
+		return nil, fmt.Errorf("123云盘目录ID格式错误: %s", parentId)
+	}
+	client := account.Get123Client()
+	files, err := client.ListAllFiles(context.Background(), pid)
+	if err != nil {
+		helpers.AppLogger.Warnf("获取123云盘目录列表失败: 父目录：%s, 错误:%v", parentId, err)
+		return nil, err
+	}
+	items := make([]*FileItem, 0)
+	for _, item := range files {
+		if item.Trashed == 1 {
+			continue
+		}
+		items = append(items, &FileItem{
+			Id:          strconv.FormatInt(item.FileID, 10),
+			IsDirectory: item.FileType == 1,
+			Name:        item.FileName,
+			Size:        item.FileSize,
+			ModifiedAt:  item.UpdateTime(),
+		})
+	}
+	start := min((page-1)*pageSize, len(items))
+	return items[start:min(start+pageSize, len(items))], nil
+}
+
 // 创建文件夹
 func CreateDir(c *gin.Context) {
 	type createDirReq struct {
@@ -415,10 +598,16 @@ func CreateDir(c *gin.Context) {
 		pathId, err = makeLocalPath(req.ParentId, req.Name)
 	case models.SourceTypeOpenList:
 		pathId, err = makeOpenListPath(req.ParentId, req.Name, req.AccountId)
+	case models.SourceTypeWebDAV:
+		pathId, err = makeWebDAVPath(req.ParentId, req.Name, req.AccountId)
+	case models.SourceTypeS3:
+		pathId, err = makeS3Path(req.ParentId, req.Name, req.AccountId)
 	case models.SourceType115:
 		pathId, err = make115PathList(req.ParentId, req.ParentPath, req.Name, req.AccountId)
 	case models.SourceTypeBaiduPan:
 		pathId, err = makeBaiduPanPathList(req.ParentId, req.Name, req.AccountId)
+	case models.SourceType123:
+		pathId, err = make123PathList(req.ParentId, req.Name, req.AccountId)
 	default:
 		// 报错
 		c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: "未知的同步源类型", Data: nil})
@@ -471,6 +660,36 @@ func makeOpenListPath(parentId string, folderName string, accountId uint) (strin
 	return newDir, nil
 }
 
+func makeWebDAVPath(parentId string, folderName string, accountId uint) (string, error) {
+	account, err := models.GetAccountById(accountId)
+	if err != nil {
+		return "", fmt.Errorf("获取账号失败: %v", err)
+	}
+	client := account.GetWebDAVClient()
+	// 检查父目录是否存在
+	if _, err := client.Stat(context.Background(), parentId); err != nil {
+		return "", fmt.Errorf("获取WebDAV目录详情失败，目录可能不存在: %v", err)
+	}
+	newDir := filepath.ToSlash(filepath.Join(parentId, folderName))
+	if err := client.Mkdir(newDir); err != nil {
+		return "", fmt.Errorf("创建WebDAV目录失败: %s, 错误: %v", newDir, err)
+	}
+	return newDir, nil
+}
+
+// S3没有真正的目录，创建以/结尾的目录标记对象
+func makeS3Path(parentId string, folderName string, accountId uint) (string, error) {
+	account, err := models.GetAccountById(accountId)
+	if err != nil {
+		return "", fmt.Errorf("获取账号失败: %v", err)
+	}
+	newDir := filepath.ToSlash(filepath.Join(parentId, folderName))
+	if err := account.GetS3Client().Mkdir(newDir); err != nil {
+		return "", fmt.Errorf("创建S3目录失败: %s, 错误: %v", newDir, err)
+	}
+	return newDir, nil
+}
+
 // 创建115目录
 func make115PathList(parentId, parentPath, folderName string, accountId uint) (string, error) {
 	// 检查父目录是否存在
@@ -510,3 +729,19 @@ func makeBaiduPanPathList(parentId string, folderName string, accountId uint) (s
 	}
 	return newDir, nil
 }
+
+func make123PathList(parentId string, folderName string, accountId uint) (string, error) {
+	account, err := models.GetAccountById(accountId)
+	if err != nil {
+		return "", fmt.Errorf("获取账号失败: %v", err)
+	}
+	pid, err := parse123ParentId(parentId)
+	if err != nil {
+		return "", fmt.Errorf("123云盘目录ID格式错误: %s", parentId)
+	}
+	resp, err := account.Get123Client().CreateFolder(context.Background(), folderName, pid)
+	if err != nil {
+		return "", fmt.Errorf("创建123云盘目录失败: %s, 错误: %v", folderName, err)
+	}
+	return strconv.FormatInt(resp.DirID, 10), nil
+}
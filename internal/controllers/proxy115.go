@@ -0,0 +1,291 @@
+package controllers
+
+import (
+	"Q115-STRM/internal/helpers"
+	"Q115-STRM/internal/linkcache"
+	"Q115-STRM/internal/models"
+	"Q115-STRM/internal/streamproxy"
+	"Q115-STRM/internal/v115open"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// proxyStats 本地反代的统计数据
+type proxyStats struct {
+	Requests atomic.Int64 // 反代的请求数
+	Rejected atomic.Int64 // 拒绝的请求数（签名、域名、并发限制）
+	Active   atomic.Int64 // 正在反代的连接数
+	Bytes    atomic.Int64 // 反代的字节数
+	hosts    sync.Map     // 每个域名反代的字节数，host -> *atomic.Int64
+}
+
+var proxy115Stats proxyStats
+
+// 每个客户端IP正在反代的连接数
+var (
+	proxyClientMu    sync.Mutex
+	proxyClientConns = make(map[string]int)
+)
+
+// acquireProxySlot 占用客户端的一个反代连接，超过限制返回false
+func acquireProxySlot(client string) bool {
+	limit := helpers.GlobalConfig.Proxy.MaxConnPerClient
+	proxyClientMu.Lock()
+	defer proxyClientMu.Unlock()
+	if limit > 0 && proxyClientConns[client] >= limit {
+		return false
+	}
+	proxyClientConns[client]++
+	return true
+}
+
+func releaseProxySlot(client string) {
+	proxyClientMu.Lock()
+	defer proxyClientMu.Unlock()
+	if proxyClientConns[client] <= 1 {
+		delete(proxyClientConns, client)
+		return
+	}
+	proxyClientConns[client]--
+}
+
+func addProxyHostBytes(host string, n int64) {
+	counter, _ := proxy115Stats.hosts.LoadOrStore(host, &atomic.Int64{})
+	counter.(*atomic.Int64).Add(n)
+}
+
+type proxyAllowHostsKey struct{}
+
+// proxyAllowHosts 反代允许的域名，OpenList的直链可能指向OpenList服务本身，S3的预签名地址指向endpoint
+// 同时允许OpenList和S3账号的域名，S3虚拟主机风格的存储桶子域名按子域名匹配
+func proxyAllowHosts(file helpers.ProxyFile) []string {
+	allowHosts := helpers.GlobalConfig.Proxy.AllowHosts
+	if (file.Source == string(models.SourceTypeOpenList) || file.Source == string(models.SourceTypeS3)) && file.AccountId != 0 {
+		if account, err := models.GetAccountById(file.AccountId); err == nil {
+			if u, err := url.Parse(account.BaseUrl); err == nil && u.Hostname() != "" {
+				allowHosts = append(slices.Clone(allowHosts), u.Hostname())
+			}
+		}
+	}
+	return allowHosts
+}
+
+// proxyHttpClient 反代使用的客户端，跳转的目标也必须在允许的域名列表中
+// 不设置总超时时间，否则长视频播放到一半会被断开，只限制等待响应头的时间
+var proxyHttpClient = &http.Client{
+	Transport: func() http.RoundTripper {
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.ResponseHeaderTimeout = 30 * time.Second
+		return transport
+	}(),
+	CheckRedirect: func(req *http.Request, via []*http.Request) error {
+		if len(via) >= 10 {
+			return errors.New("跳转次数过多")
+		}
+		allowHosts, ok := req.Context().Value(proxyAllowHostsKey{}).([]string)
+		if !ok {
+			allowHosts = helpers.GlobalConfig.Proxy.AllowHosts
+		}
+		if !helpers.IsProxyHostAllowed(req.URL.String(), allowHosts) {
+			return errors.New("跳转的目标域名不在反代允许列表中: " + req.URL.Host)
+		}
+		return nil
+	},
+}
+
+var (
+	streamProxyOnce sync.Once
+	streamProxy     *streamproxy.Proxy
+)
+
+// getStreamProxy 本地反代使用的流式反代，第一次使用时按配置文件创建
+func getStreamProxy() *streamproxy.Proxy {
+	streamProxyOnce.Do(func() {
+		config := helpers.GlobalConfig.Proxy
+		streamProxy = streamproxy.New(streamproxy.Options{
+			ChunkSize: int64(config.ChunkSize) << 20,
+			Parallel:  config.Parallel,
+			Client:    proxyHttpClient,
+			Cache:     streamproxy.NewChunkCache(filepath.Join(helpers.ConfigDir, "tmp", "stream-cache"), int64(config.CacheSize)<<20),
+		})
+	})
+	return streamProxy
+}
+
+// proxyFileUA 请求网盘直链使用的UA，和生成直链时使用的UA一致
+func proxyFileUA(file helpers.ProxyFile) string {
+	if file.Source == string(models.SourceTypeBaiduPan) {
+		return "pan.baidu.com"
+	}
+	return v115open.DEFAULTUA
+}
+
+// proxyResolver 播放过程中直链过期时重新获取直链，只支持115、百度网盘和OpenList
+func proxyResolver(file helpers.ProxyFile, allowHosts []string) streamproxy.Resolver {
+	if file.AccountId == 0 || file.FileId == "" {
+		return nil
+	}
+	var resolve func() (string, error)
+	switch models.SourceType(file.Source) {
+	case models.SourceType115:
+		resolve = func() (string, error) {
+			account, err := models.GetAccountById(file.AccountId)
+			if err != nil {
+				return "", err
+			}
+			ua := proxyFileUA(file)
+			link, servedBy := get115UrlWithFailover(account, nil, file.FileId, ua)
+			if link == "" {
+				return "", errors.New("获取115下载链接失败")
+			}
+			linkcache.Set(file.Source, file.FileId, ua, servedBy.ID, link, 2*time.Hour)
+			return link, nil
+		}
+	case models.SourceTypeBaiduPan:
+		resolve = func() (string, error) {
+			account, err := models.GetAccountById(file.AccountId)
+			if err != nil {
+				return "", err
+			}
+			// 缓存的链接按播放器的UA保存，这里不知道播放器的UA，删除后下次播放重新获取
+			linkcache.Evict(file.Source, file.FileId)
+			return getBaiduPanUrl(account, file.FileId)
+		}
+	case models.SourceTypeOpenList:
+		resolve = func() (string, error) {
+			account, err := models.GetAccountById(file.AccountId)
+			if err != nil {
+				return "", err
+			}
+			fileDetail, err := account.GetOpenListClient().FileDetail(file.FileId)
+			if err != nil {
+				return "", err
+			}
+			return fileDetail.RawURL, nil
+		}
+	default:
+		return nil
+	}
+	return func(ctx context.Context) (string, error) {
+		link, err := resolve()
+		if err != nil {
+			return "", err
+		}
+		if !helpers.IsProxyHostAllowed(link, allowHosts) {
+			return "", errors.New("新的直链域名不在反代允许列表中")
+		}
+		return link, nil
+	}
+}
+
+// proxyFileKey 分块缓存的键，同一个网盘文件的直链每次都不同，使用文件ID；没有文件ID时使用去掉参数的直链
+func proxyFileKey(target string, file helpers.ProxyFile) string {
+	if file.FileId != "" {
+		return fmt.Sprintf("%s:%d:%s", file.Source, file.AccountId, file.FileId)
+	}
+	u, err := url.Parse(target)
+	if err != nil {
+		return file.Source + ":" + target
+	}
+	return file.Source + ":" + u.Host + u.Path
+}
+
+// Proxy115 反代网盘下载链接，用于不能直接访问网盘CDN的播放器，支持115、百度网盘和OpenList等来源
+// 只接受QMS自己生成的带token的地址（helpers.MakeProxyUrl），目标域名必须在配置的允许列表中
+// 按分块并行下载并缓存最近播放的分块，播放过程中直链过期时自动重新获取
+func Proxy115(c *gin.Context) {
+	query := c.Request.URL.Query()
+	// 获取原始url参数
+	target := query.Get("url")
+	if target == "" {
+		c.JSON(http.StatusBadRequest, APIResponse[any]{Code: BadRequest, Message: "缺少url参数", Data: nil})
+		return
+	}
+	if err := helpers.VerifyProxyToken(query, time.Now()); err != nil {
+		proxy115Stats.Rejected.Add(1)
+		helpers.AppLogger.Warnf("拒绝反代请求，%v: %s, IP: %s", err, target, c.ClientIP())
+		c.JSON(http.StatusForbidden, APIResponse[any]{Code: BadRequest, Message: "反代地址无效: " + err.Error(), Data: nil})
+		return
+	}
+	file := helpers.ParseProxyFile(query)
+	allowHosts := proxyAllowHosts(file)
+	if !helpers.IsProxyHostAllowed(target, allowHosts) {
+		proxy115Stats.Rejected.Add(1)
+		helpers.AppLogger.Warnf("拒绝反代请求，目标域名不在允许列表中（配置文件proxy.allowHosts）: %s", target)
+		c.JSON(http.StatusForbidden, APIResponse[any]{Code: BadRequest, Message: "目标域名不在反代允许列表中", Data: nil})
+		return
+	}
+	client := c.ClientIP()
+	if !acquireProxySlot(client) {
+		proxy115Stats.Rejected.Add(1)
+		helpers.AppLogger.Warnf("拒绝反代请求，客户端 %s 的并发连接数超过限制 %d", client, helpers.GlobalConfig.Proxy.MaxConnPerClient)
+		c.JSON(http.StatusTooManyRequests, APIResponse[any]{Code: BadRequest, Message: "反代并发连接数超过限制", Data: nil})
+		return
+	}
+	defer releaseProxySlot(client)
+	proxy115Stats.Requests.Add(1)
+	proxy115Stats.Active.Add(1)
+	defer proxy115Stats.Active.Add(-1)
+
+	helpers.AppLogger.Infof("反代网盘下载链接: %s, Range: %s", target, c.GetHeader("Range"))
+	// 客户端断开后同时取消网盘请求，跳转时按这次请求允许的域名检查
+	c.Request = c.Request.WithContext(context.WithValue(c.Request.Context(), proxyAllowHostsKey{}, allowHosts))
+	n, err := getStreamProxy().Serve(c.Writer, c.Request, &streamproxy.File{
+		Key:     proxyFileKey(target, file),
+		Url:     target,
+		UA:      proxyFileUA(file),
+		Resolve: proxyResolver(file, allowHosts),
+	})
+	proxy115Stats.Bytes.Add(n)
+	if u, perr := url.Parse(target); perr == nil {
+		addProxyHostBytes(u.Hostname(), n)
+	}
+	if err != nil && c.Request.Context().Err() == nil {
+		helpers.AppLogger.Warnf("反代网盘下载链接中断，已发送%d字节: %v", n, err)
+	}
+}
+
+// GetProxyStats 查询本地反代的统计数据
+// @Summary 查询反代统计
+// @Description 查询本地反代（/proxy-115）的请求数、拒绝数、正在反代的连接数和反代的字节数，字节数按域名分别统计
+// @Tags 系统设置
+// @Accept json
+// @Produce json
+// @Success 200 {object} object
+// @Failure 200 {object} object
+// @Router /proxy/stats [get]
+// @Security JwtAuth
+// @Security ApiKeyAuth
+func GetProxyStats(c *gin.Context) {
+	hosts := make(map[string]int64)
+	proxy115Stats.hosts.Range(func(k, v any) bool {
+		hosts[k.(string)] = v.(*atomic.Int64).Load()
+		return true
+	})
+	proxyClientMu.Lock()
+	clients := make(map[string]int, len(proxyClientConns))
+	for client, conns := range proxyClientConns {
+		clients[client] = conns
+	}
+	proxyClientMu.Unlock()
+	c.JSON(http.StatusOK, APIResponse[any]{Code: Success, Message: "查询反代统计成功", Data: gin.H{
+		"requests":    proxy115Stats.Requests.Load(),
+		"rejected":    proxy115Stats.Rejected.Load(),
+		"active":      proxy115Stats.Active.Load(),
+		"bytes":       proxy115Stats.Bytes.Load(),
+		"host_bytes":  hosts,
+		"clients":     clients,
+		"allow_hosts": helpers.GlobalConfig.Proxy.AllowHosts,
+		"stream":      getStreamProxy().Stats(),
+	}})
+}
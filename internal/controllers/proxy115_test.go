@@ -0,0 +1,83 @@
+package controllers
+
+import (
+	"Q115-STRM/internal/helpers"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestProxy115(t *testing.T) {
+	helpers.AppLogger = &helpers.QLogger{Logger: log.New(io.Discard, "", 0)}
+	gin.SetMode(gin.TestMode)
+
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/lan" {
+			// 跳转到不允许的地址
+			http.Redirect(w, r, "http://localhost:1/admin", http.StatusFound)
+			return
+		}
+		io.WriteString(w, "video-bytes")
+	}))
+	defer upstream.Close()
+
+	oldProxy := helpers.GlobalConfig.Proxy
+	helpers.GlobalConfig.Proxy = helpers.ConfigProxy{AllowHosts: []string{"127.0.0.1"}, MaxConnPerClient: 1}
+	defer func() { helpers.GlobalConfig.Proxy = oldProxy }()
+
+	r := gin.New()
+	r.GET("/proxy-115", Proxy115)
+	get := func(target string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, target, nil))
+		return w
+	}
+
+	// 没有token的地址不反代
+	if w := get("/proxy-115?url=" + url.QueryEscape(upstream.URL+"/a.mkv")); w.Code != http.StatusForbidden {
+		t.Fatalf("unsigned request status = %d, want 403", w.Code)
+	}
+	// 篡改目标地址
+	signed := helpers.MakeProxyUrl(upstream.URL+"/a.mkv", helpers.ProxyFile{Source: "115"})
+	tampered, _ := url.Parse(signed)
+	query := tampered.Query()
+	query.Set("url", upstream.URL+"/other")
+	tampered.RawQuery = query.Encode()
+	if w := get(tampered.String()); w.Code != http.StatusForbidden {
+		t.Fatalf("tampered request status = %d, want 403", w.Code)
+	}
+	// 不在允许列表中的域名即使有token也不反代
+	if w := get(helpers.MakeProxyUrl("http://localhost:1/a.mkv", helpers.ProxyFile{Source: "115"})); w.Code != http.StatusForbidden {
+		t.Fatalf("disallowed host status = %d, want 403", w.Code)
+	}
+	if w := get(helpers.MakeProxyUrl(upstream.URL+"/lan", helpers.ProxyFile{Source: "115"})); w.Code != http.StatusBadGateway {
+		t.Fatalf("redirect to disallowed host status = %d, want 502", w.Code)
+	}
+
+	before := proxy115Stats.Bytes.Load()
+	w := get(signed)
+	if w.Code != http.StatusOK || w.Body.String() != "video-bytes" {
+		t.Fatalf("proxy response = %d %q", w.Code, w.Body.String())
+	}
+	if n := proxy115Stats.Bytes.Load() - before; n != int64(len("video-bytes")) {
+		t.Fatalf("proxied bytes = %d", n)
+	}
+
+	// 超过并发限制
+	if !acquireProxySlot("192.0.2.1") {
+		t.Fatal("first slot rejected")
+	}
+	if acquireProxySlot("192.0.2.1") {
+		t.Fatal("slot over limit acquired")
+	}
+	releaseProxySlot("192.0.2.1")
+	if !acquireProxySlot("192.0.2.1") {
+		t.Fatal("slot not released")
+	}
+	releaseProxySlot("192.0.2.1")
+}
@@ -0,0 +1,87 @@
+package controllers
+
+import (
+	"Q115-STRM/internal/db"
+	"Q115-STRM/internal/helpers"
+	"Q115-STRM/internal/models"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	s3PresignExpires = 2 * time.Hour // 预签名地址有效期
+	s3UrlCacheSecond = 3600          // 缓存时间比有效期短，保证跳转出去的地址至少还有1小时有效
+)
+
+// GetS3FileUrl 播放S3文件
+// @Summary 播放S3文件
+// @Description 为S3对象重新生成预签名地址并302重定向，地址有效期内使用缓存；开启本地播放代理时跳转到代理地址；只能访问同步过的对象
+// @Tags S3
+// @Accept json
+// @Produce octet-stream
+// @Param account_id query integer true "账号ID"
+// @Param path query string true "文件路径"
+// @Param force query integer false "为1时忽略缓存重新签名"
+// @Success 302 {string} string "重定向到预签名地址"
+// @Failure 200 {object} object
+// @Router /s3/url/{filename} [get]
+func GetS3FileUrl(c *gin.Context) {
+	type fileUrlReq struct {
+		AccountId uint   `json:"account_id" form:"account_id"`
+		Path      string `json:"path" form:"path"`
+		Force     int    `json:"force" form:"force"`
+	}
+	var req fileUrlReq
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, APIResponse[any]{Code: BadRequest, Message: "参数错误", Data: nil})
+		return
+	}
+	if req.Path == "" {
+		c.JSON(http.StatusBadRequest, APIResponse[any]{Code: BadRequest, Message: "path 参数不能为空", Data: nil})
+		return
+	}
+	account, err := models.GetAccountById(req.AccountId)
+	if err != nil || account.SourceType != models.SourceTypeS3 {
+		c.JSON(http.StatusBadRequest, APIResponse[any]{Code: BadRequest, Message: "账号ID不存在", Data: nil})
+		return
+	}
+	// 接口不需要登录，只给同步过的对象签名，带签名时必须是签名中的同步路径的文件
+	syncPathId, _ := helpers.GetStrmSignPathId(c.Request.URL.Query())
+	if models.GetFileByFileId(models.SourceTypeS3, account.ID, syncPathId, req.Path) == nil {
+		helpers.AppLogger.Warnf("拒绝为没有同步过的S3对象签名: %s, 账号: %d, IP: %s", req.Path, account.ID, c.ClientIP())
+		c.JSON(http.StatusForbidden, APIResponse[any]{Code: BadRequest, Message: "文件不存在", Data: nil})
+		return
+	}
+	cacheKey := fmt.Sprintf("s3url:%d:%s", account.ID, req.Path)
+	if keyLock.LockWithTimeout(cacheKey, 10*time.Second) {
+		defer keyLock.Unlock(cacheKey)
+		cachedUrl := ""
+		if req.Force == 0 {
+			cachedUrl = string(db.Cache.Get(cacheKey))
+		}
+		if cachedUrl == "" {
+			cachedUrl, err = account.GetS3Client().PresignGet(req.Path, s3PresignExpires)
+			if err != nil {
+				helpers.AppLogger.Errorf("生成S3预签名地址失败: %s %v", req.Path, err)
+				c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: "生成S3下载链接失败", Data: nil})
+				return
+			}
+			helpers.AppLogger.Infof("生成S3预签名地址: %s", req.Path)
+			db.Cache.Set(cacheKey, []byte(cachedUrl), s3UrlCacheSecond)
+		} else {
+			helpers.AppLogger.Infof("从缓存中查询到S3预签名地址: %s", req.Path)
+		}
+		// 检查是否开启了本地播放代理，如果开启则跳转到代理链接
+		if models.SettingsGlobal.LocalProxy == 1 {
+			proxyUrl := helpers.MakeProxyUrl(cachedUrl, helpers.ProxyFile{Source: string(models.SourceTypeS3), AccountId: account.ID, FileId: req.Path})
+			c.Redirect(http.StatusFound, proxyUrl)
+			return
+		}
+		c.Redirect(http.StatusFound, cachedUrl)
+		return
+	}
+	c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: "获取下载链接超时，请稍后重试", Data: nil})
+}
@@ -0,0 +1,113 @@
+package controllers
+
+import (
+	"Q115-STRM/internal/helpers"
+	"Q115-STRM/internal/models"
+	"Q115-STRM/internal/scheduler"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 有请求调度器的来源
+var scheduledSources = []models.SourceType{models.SourceType115, models.SourceTypeOpenList, models.SourceTypeBaiduPan, models.SourceType123}
+
+// GetSchedulerStats 获取网盘请求调度器的统计数据
+// @Summary 请求调度器统计
+// @Description 按账号返回115、OpenList、百度网盘、123云盘请求调度器的速率限制、限流状态和统计数据
+// @Tags 请求调度
+// @Produce json
+// @Param source query string true "来源类型：115、openlist、baidupan、123"
+// @Param time_window query integer false "统计时间窗口（秒），默认3600"
+// @Success 200 {object} object
+// @Failure 200 {object} object
+// @Router /scheduler/stats [get]
+// @Security JwtAuth
+// @Security ApiKeyAuth
+func GetSchedulerStats(c *gin.Context) {
+	source := models.SourceType(c.Query("source"))
+	if !slices.Contains(scheduledSources, source) {
+		c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: "source参数无效", Data: nil})
+		return
+	}
+	timeWindow, err := strconv.ParseInt(c.DefaultQuery("time_window", "3600"), 10, 64)
+	if err != nil || timeWindow <= 0 {
+		c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: "time_window参数无效", Data: nil})
+		return
+	}
+	duration := time.Duration(timeWindow) * time.Second
+
+	accounts := make([]gin.H, 0)
+	for _, executor := range scheduler.Executors(string(source)) {
+		stats := executor.GetStats(duration)
+		throttleStatus := executor.GetThrottleStatus()
+		accounts = append(accounts, gin.H{
+			"account_id":               executor.AccountId,
+			"limits":                   executor.Limits(),
+			"effective_limits":         executor.EffectiveLimits(),
+			"adaptive":                 executor.AdaptiveStatus(),
+			"total_requests":           stats.TotalRequests,
+			"qps_count":                stats.QPSCount,
+			"qpm_count":                stats.QPMCount,
+			"qph_count":                stats.QPHCount,
+			"throttled_count":          stats.ThrottledCount,
+			"avg_response_time_ms":     stats.AvgResponseTime,
+			"last_throttle_time":       stats.LastThrottleTime,
+			"is_throttled":             throttleStatus.IsThrottled,
+			"throttled_remaining_time": throttleStatus.RemainingTime.String(),
+		})
+	}
+	c.JSON(http.StatusOK, APIResponse[gin.H]{Code: Success, Message: "获取调度器统计数据成功", Data: gin.H{
+		"source":              source,
+		"default_limits":      scheduler.DefaultLimits(string(source)),
+		"time_window_seconds": timeWindow,
+		"accounts":            accounts,
+	}})
+}
+
+// SetAccountRateLimit 设置账号单独的速率限制
+// @Summary 设置账号速率限制
+// @Description 设置115、OpenList、百度网盘、123云盘账号单独的QPS/QPM/QPH，全部为0时使用默认值，某一项为0表示该项不限制
+// @Tags 请求调度
+// @Accept json
+// @Produce json
+// @Param account_id body integer true "账号ID"
+// @Param qps body integer false "每秒请求数"
+// @Param qpm body integer false "每分钟请求数"
+// @Param qph body integer false "每小时请求数"
+// @Success 200 {object} object
+// @Failure 200 {object} object
+// @Router /account/rate-limit [post]
+// @Security JwtAuth
+// @Security ApiKeyAuth
+func SetAccountRateLimit(c *gin.Context) {
+	var req struct {
+		AccountId uint `json:"account_id" form:"account_id" binding:"required"`
+		QPS       int  `json:"qps" form:"qps" binding:"min=0,max=1000"`
+		QPM       int  `json:"qpm" form:"qpm" binding:"min=0,max=100000"`
+		QPH       int  `json:"qph" form:"qph" binding:"min=0,max=1000000"`
+	}
+	if err := c.ShouldBind(&req); err != nil {
+		c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: "请求参数错误: " + err.Error(), Data: nil})
+		return
+	}
+	account, err := models.GetAccountById(req.AccountId)
+	if err != nil {
+		c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: "账号不存在", Data: nil})
+		return
+	}
+	if !slices.Contains(scheduledSources, account.SourceType) {
+		c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: "该账号类型不支持设置速率限制", Data: nil})
+		return
+	}
+	limits := scheduler.Limits{QPS: req.QPS, QPM: req.QPM, QPH: req.QPH}
+	if err := account.UpdateRateLimits(limits); err != nil {
+		c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: "保存速率限制失败: " + err.Error(), Data: nil})
+		return
+	}
+	helpers.AppLogger.Infof("账号 %d 的速率限制已更新: QPS=%d, QPM=%d, QPH=%d", account.ID, req.QPS, req.QPM, req.QPH)
+	c.JSON(http.StatusOK, APIResponse[any]{Code: Success, Message: "速率限制配置成功", Data: limits})
+}
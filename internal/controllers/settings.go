@@ -6,6 +6,7 @@ import (
 	"Q115-STRM/internal/models"
 	"Q115-STRM/internal/synccron"
 	"net/http"
+	"slices"
 
 	"github.com/gin-gonic/gin"
 )
@@ -336,6 +337,14 @@ func GetStrmConfig(c *gin.Context) {
 // @Param exclude_name body []string false "排除的文件名"
 // @Param download_meta body integer false "是否下载元数据，1下载 0不下载"
 // @Param add_path body integer false "是否添加路径，1添加 2不添加"
+// @Param strm_template body string false "STRM内容模板，为空使用默认格式"
+// @Param delete_max_count body integer false "删除保护：一次最多删除的文件数量，0不限制"
+// @Param delete_max_percent body integer false "删除保护：一次最多删除的文件百分比，0不限制，删除少于10个文件或者局部同步时不检查"
+// @Param trash_retention_days body integer false "回收站保留天数，0不使用回收站，直接删除"
+// @Param include_rule_arr body []string false "包含规则，glob或re:开头的正则，匹配相对路径"
+// @Param exclude_rule_arr body []string false "排除规则，glob或re:开头的正则，匹配相对路径"
+// @Param max_video_size body integer false "最大视频大小（MB），0不限制"
+// @Param max_age_days body integer false "只同步最近N天修改的文件，0不限制"
 // @Success 200 {object} object
 // @Failure 200 {object} object
 // @Router /setting/strm-config [post]
@@ -362,6 +371,30 @@ func UpdateStrmConfig(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, APIResponse[any]{Code: BadRequest, Message: "最小视频大小必须大于等于0", Data: nil})
 		return
 	}
+	if err := models.ValidateStrmTemplate(req.StrmTemplate); err != nil {
+		c.JSON(http.StatusBadRequest, APIResponse[any]{Code: BadRequest, Message: err.Error(), Data: nil})
+		return
+	}
+	if req.DeleteMaxCount < 0 || req.DeleteMaxPercent < 0 || req.DeleteMaxPercent > 100 {
+		c.JSON(http.StatusBadRequest, APIResponse[any]{Code: BadRequest, Message: "删除保护阈值不正确，数量必须大于等于0，百分比必须在0到100之间", Data: nil})
+		return
+	}
+	if req.TrashRetentionDays < 0 {
+		c.JSON(http.StatusBadRequest, APIResponse[any]{Code: BadRequest, Message: "回收站保留天数必须大于等于0", Data: nil})
+		return
+	}
+	if req.MaxVideoSize < 0 || req.MaxAgeDays < 0 {
+		c.JSON(http.StatusBadRequest, APIResponse[any]{Code: BadRequest, Message: "最大视频大小和最近天数必须大于等于0", Data: nil})
+		return
+	}
+	if req.MaxVideoSize > 0 && req.MaxVideoSize < req.MinVideoSize {
+		c.JSON(http.StatusBadRequest, APIResponse[any]{Code: BadRequest, Message: "最大视频大小不能小于最小视频大小", Data: nil})
+		return
+	}
+	if err := models.ValidateSyncPathRules(slices.Concat(req.IncludeRuleArr, req.ExcludeRuleArr)); err != nil {
+		c.JSON(http.StatusBadRequest, APIResponse[any]{Code: BadRequest, Message: err.Error(), Data: nil})
+		return
+	}
 	// 检查cron是否正确，是否符合要求的CRON表达式
 	runTimes := helpers.GetNextTimeByCronStr(req.Cron, 2)
 	if runTimes == nil {
@@ -0,0 +1,233 @@
+package controllers
+
+import (
+	"Q115-STRM/internal/helpers"
+	"Q115-STRM/internal/models"
+	"Q115-STRM/internal/syncstrm"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// strmRouteSourceTypes 直链接口对应的网盘类型
+var strmRouteSourceTypes = []struct {
+	prefix     string
+	sourceType models.SourceType
+}{
+	{"/115/", models.SourceType115},
+	{"/baidupan/", models.SourceTypeBaiduPan},
+	{"/123/", models.SourceType123},
+	{"/openlist/", models.SourceTypeOpenList},
+	{"/webdav/", models.SourceTypeWebDAV},
+	{"/s3/", models.SourceTypeS3},
+}
+
+// strmRequestSyncPathIds 没有签名的直链请求访问的文件所在的同步路径
+func strmRequestSyncPathIds(c *gin.Context) []uint {
+	query := c.Request.URL.Query()
+	for _, route := range strmRouteSourceTypes {
+		if !strings.HasPrefix(c.Request.URL.Path, route.prefix) {
+			continue
+		}
+		accountId, _ := strconv.ParseUint(query.Get("account_id"), 10, 64)
+		return models.GetFileSyncPathIds(route.sourceType, uint(accountId), query.Get("pickcode"), query.Get("path"))
+	}
+	return nil
+}
+
+// StrmSignAuth 校验直链接口的STRM签名
+// 带签名的请求必须校验通过；没有签名的请求，文件所在的同步路径开启了签名时拒绝，
+// 不属于任何同步路径的文件在设置了必须签名时拒绝，否则兼容旧的STRM文件直接放行
+func StrmSignAuth() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		query := c.Request.URL.Query()
+		syncPathId, signed := helpers.GetStrmSignPathId(query)
+		if !signed {
+			syncPathIds := strmRequestSyncPathIds(c)
+			required := len(syncPathIds) == 0 && models.SettingsGlobal.StrmSignRequired == 1
+			for _, id := range syncPathIds {
+				if syncPath := models.GetSyncPathById(id); syncPath != nil && syncPath.StrmSignEnabled {
+					required = true
+					break
+				}
+			}
+			if required {
+				helpers.AppLogger.Warnf("拒绝没有签名的直链请求: %s, IP: %s", c.Request.URL.Path, c.ClientIP())
+				c.AbortWithStatusJSON(http.StatusForbidden, APIResponse[any]{Code: BadRequest, Message: "STRM地址缺少签名", Data: nil})
+				return
+			}
+			c.Next()
+			return
+		}
+		var err error
+		syncPath := models.GetSyncPathById(syncPathId)
+		if syncPath == nil {
+			err = helpers.VerifyStrmSign(query, time.Now())
+		} else {
+			err = helpers.VerifyStrmSign(query, time.Now(), syncPath.StrmSignSecret, syncPath.StrmSignPrevSecret)
+		}
+		if err != nil {
+			helpers.AppLogger.Warnf("拒绝签名校验失败的直链请求: %s, 同步路径: %d, IP: %s, %v", c.Request.URL.Path, syncPathId, c.ClientIP(), err)
+			c.AbortWithStatusJSON(http.StatusForbidden, APIResponse[any]{Code: BadRequest, Message: "STRM地址签名校验失败: " + err.Error(), Data: nil})
+			return
+		}
+		c.Next()
+	}
+}
+
+// UpdateSyncPathStrmSign 修改同步路径的STRM签名设置
+// @Summary 修改STRM签名设置
+// @Description 启用或关闭同步路径的STRM地址签名，第一次启用时生成密钥，然后在后台重写已有的STRM文件
+// @Tags 同步管理
+// @Accept json
+// @Produce json
+// @Param id body integer true "同步路径ID"
+// @Param enabled body boolean true "是否启用签名"
+// @Param expire_days body integer false "签名有效天数，0表示不过期"
+// @Success 200 {object} object
+// @Failure 200 {object} object
+// @Router /sync/path/strm-sign [post]
+// @Security JwtAuth
+// @Security ApiKeyAuth
+func UpdateSyncPathStrmSign(c *gin.Context) {
+	type strmSignReq struct {
+		ID         uint `json:"id" form:"id"`
+		Enabled    bool `json:"enabled" form:"enabled"`
+		ExpireDays int  `json:"expire_days" form:"expire_days"`
+	}
+	var req strmSignReq
+	if err := c.ShouldBind(&req); err != nil || req.ID == 0 {
+		c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: "id 参数不能为空", Data: nil})
+		return
+	}
+	syncPath := models.GetSyncPathById(req.ID)
+	if syncPath == nil {
+		c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: "同步路径不存在", Data: nil})
+		return
+	}
+	if err := syncPath.UpdateStrmSign(req.Enabled, req.ExpireDays); err != nil {
+		c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: "修改STRM签名设置失败: " + err.Error(), Data: nil})
+		return
+	}
+	syncstrm.StartResignSyncPathStrm(syncPath)
+	c.JSON(http.StatusOK, APIResponse[any]{Code: Success, Message: "修改STRM签名设置成功，正在后台重写STRM文件", Data: nil})
+}
+
+// RotateSyncPathStrmSign 轮换同步路径的STRM签名密钥
+// @Summary 轮换STRM签名密钥
+// @Description 生成新的签名密钥并在后台用新密钥重写已有的STRM文件，重写完成前旧密钥签名的地址继续有效
+// @Tags 同步管理
+// @Accept json
+// @Produce json
+// @Param id body integer true "同步路径ID"
+// @Success 200 {object} object
+// @Failure 200 {object} object
+// @Router /sync/path/strm-sign/rotate [post]
+// @Security JwtAuth
+// @Security ApiKeyAuth
+func RotateSyncPathStrmSign(c *gin.Context) {
+	type rotateReq struct {
+		ID uint `json:"id" form:"id"`
+	}
+	var req rotateReq
+	if err := c.ShouldBind(&req); err != nil || req.ID == 0 {
+		c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: "id 参数不能为空", Data: nil})
+		return
+	}
+	syncPath := models.GetSyncPathById(req.ID)
+	if syncPath == nil {
+		c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: "同步路径不存在", Data: nil})
+		return
+	}
+	if !syncPath.StrmSignEnabled {
+		c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: "同步路径没有启用STRM签名", Data: nil})
+		return
+	}
+	if err := syncPath.RotateStrmSignSecret(); err != nil {
+		c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: "轮换STRM签名密钥失败: " + err.Error(), Data: nil})
+		return
+	}
+	helpers.AppLogger.Infof("同步路径 %d 的STRM签名密钥已轮换", syncPath.ID)
+	syncstrm.StartResignSyncPathStrm(syncPath)
+	c.JSON(http.StatusOK, APIResponse[any]{Code: Success, Message: "STRM签名密钥已轮换，正在后台重写STRM文件", Data: nil})
+}
+
+// ResignSyncPathStrm 重新签名同步路径的STRM文件
+// @Summary 重新签名STRM文件
+// @Description 按同步路径当前的签名设置重写已有的STRM文件，等待完成后返回重写的文件数量
+// @Tags 同步管理
+// @Accept json
+// @Produce json
+// @Param id body integer true "同步路径ID"
+// @Success 200 {object} object
+// @Failure 200 {object} object
+// @Router /sync/path/strm-sign/resign [post]
+// @Security JwtAuth
+// @Security ApiKeyAuth
+func ResignSyncPathStrm(c *gin.Context) {
+	type resignReq struct {
+		ID uint `json:"id" form:"id"`
+	}
+	var req resignReq
+	if err := c.ShouldBind(&req); err != nil || req.ID == 0 {
+		c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: "id 参数不能为空", Data: nil})
+		return
+	}
+	syncPath := models.GetSyncPathById(req.ID)
+	if syncPath == nil {
+		c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: "同步路径不存在", Data: nil})
+		return
+	}
+	count, err := syncstrm.ResignSyncPathStrm(syncPath)
+	if err != nil {
+		c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: "重新签名STRM文件失败: " + err.Error(), Data: gin.H{"count": count}})
+		return
+	}
+	c.JSON(http.StatusOK, APIResponse[any]{Code: Success, Message: "重新签名STRM文件成功", Data: gin.H{"count": count}})
+}
+
+// UpdateStrmSignRequired 修改直链接口是否必须签名
+// @Summary 修改STRM签名要求
+// @Description 设置为1后直链接口拒绝没有签名并且不属于任何同步路径的文件的请求，同步路径中的文件按同步路径的签名设置校验
+// @Tags 系统设置
+// @Accept json
+// @Produce json
+// @Param strm_sign_required body integer true "0表示未签名的也可以访问，1表示必须签名"
+// @Success 200 {object} object
+// @Failure 200 {object} object
+// @Router /setting/strm-sign [post]
+// @Security JwtAuth
+// @Security ApiKeyAuth
+func UpdateStrmSignRequired(c *gin.Context) {
+	type strmSignRequiredReq struct {
+		StrmSignRequired int `json:"strm_sign_required" form:"strm_sign_required"`
+	}
+	var req strmSignRequiredReq
+	if err := c.ShouldBind(&req); err != nil || (req.StrmSignRequired != 0 && req.StrmSignRequired != 1) {
+		c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: "strm_sign_required 只能是0或1", Data: nil})
+		return
+	}
+	if !models.SettingsGlobal.UpdateStrmSignRequired(req.StrmSignRequired) {
+		c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: "更新STRM签名设置失败", Data: nil})
+		return
+	}
+	c.JSON(http.StatusOK, APIResponse[any]{Code: Success, Message: "更新STRM签名设置成功", Data: nil})
+}
+
+// GetStrmSignRequired 获取直链接口是否必须签名
+// @Summary 获取STRM签名要求
+// @Description 获取直链接口是否拒绝没有签名的STRM地址
+// @Tags 系统设置
+// @Accept json
+// @Produce json
+// @Success 200 {object} object
+// @Failure 200 {object} object
+// @Router /setting/strm-sign [get]
+// @Security JwtAuth
+// @Security ApiKeyAuth
+func GetStrmSignRequired(c *gin.Context) {
+	c.JSON(http.StatusOK, APIResponse[any]{Code: Success, Message: "获取STRM签名设置成功", Data: gin.H{"strm_sign_required": models.SettingsGlobal.StrmSignRequired}})
+}
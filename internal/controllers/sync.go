@@ -7,6 +7,7 @@ import (
 	"net/http"
 	"path/filepath"
 	"runtime"
+	"slices"
 	"strings"
 
 	"github.com/gin-gonic/gin"
@@ -225,6 +226,16 @@ func AddSyncPath(c *gin.Context) {
 	// 	remotePath = strings.ReplaceAll(remotePath, "\\", "/")
 	// 	baseCid = strings.ReplaceAll(req.BaseCid, "\\", "/")
 	// }
+	if req.CustomConfig {
+		if err := models.ValidateStrmTemplate(req.StrmTemplate); err != nil {
+			c.JSON(http.StatusBadRequest, APIResponse[any]{Code: BadRequest, Message: err.Error(), Data: nil})
+			return
+		}
+		if err := models.ValidateSyncPathRules(slices.Concat(req.IncludeRuleArr, req.ExcludeRuleArr)); err != nil {
+			c.JSON(http.StatusBadRequest, APIResponse[any]{Code: BadRequest, Message: err.Error(), Data: nil})
+			return
+		}
+	}
 	// 创建同步路径
 	syncPath := models.CreateSyncPath(req.SourceType, req.AccountId, baseCid, localPath, remotePath, req.EnableCron, req.CustomConfig, req.SettingStrm)
 	if syncPath == nil {
@@ -295,16 +306,28 @@ func UpdateSyncPath(c *gin.Context) {
 			remotePath = "/" + remotePath
 		}
 	}
-	if req.SourceType == models.SourceTypeOpenList {
+	if req.SourceType == models.SourceTypeOpenList || req.SourceType == models.SourceTypeWebDAV || req.SourceType == models.SourceTypeS3 {
 		// 将remotepath中的\都替换为/
 		req.RemotePath = strings.ReplaceAll(req.RemotePath, "\\", "/")
 		req.BaseCid = strings.ReplaceAll(req.BaseCid, "\\", "/")
 	}
+	if req.CustomConfig {
+		if err := models.ValidateStrmTemplate(req.StrmTemplate); err != nil {
+			c.JSON(http.StatusBadRequest, APIResponse[any]{Code: BadRequest, Message: err.Error(), Data: nil})
+			return
+		}
+		if err := models.ValidateSyncPathRules(slices.Concat(req.IncludeRuleArr, req.ExcludeRuleArr)); err != nil {
+			c.JSON(http.StatusBadRequest, APIResponse[any]{Code: BadRequest, Message: err.Error(), Data: nil})
+			return
+		}
+	}
 	success := syncPath.Update(req.SourceType, req.AccountId, req.BaseCid, req.LocalPath, remotePath, req.EnableCron, req.CustomConfig, req.SettingStrm)
 	if !success {
 		c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: "更新同步路径失败", Data: nil})
 		return
 	}
+	// 路径可能变化，重新加载实时监控
+	synccron.RefreshLocalWatcher(syncPath.ID)
 	c.JSON(http.StatusOK, APIResponse[any]{Code: Success, Message: "更新同步路径成功", Data: syncPath})
 }
 
@@ -334,6 +357,8 @@ func DeleteSyncPath(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, APIResponse[any]{Code: BadRequest, Message: "id 参数不能为空", Data: nil})
 		return
 	}
+	// 停止实时监控
+	synccron.StopLocalWatcher(id)
 	// 删除同步路径
 	success := models.DeleteSyncPathById(id)
 	if !success {
@@ -536,6 +561,150 @@ func ToggleSyncByPath(c *gin.Context) {
 
 }
 
+// ToggleWatchByPath 切换同步路径的实时监控
+// @Summary 切换实时监控
+// @Description 开启或关闭本地同步目录的实时监控，文件变化后自动同步变化的目录
+// @Tags 同步管理
+// @Accept json
+// @Produce json
+// @Param id body integer true "同步路径ID"
+// @Success 200 {object} object
+// @Failure 200 {object} object
+// @Router /sync/path/toggle-watch [post]
+// @Security JwtAuth
+// @Security ApiKeyAuth
+func ToggleWatchByPath(c *gin.Context) {
+	type toggleWatchRequest struct {
+		ID uint `form:"id" json:"id" binding:"required"` // 同步路径ID
+	}
+	var req toggleWatchRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, APIResponse[any]{Code: BadRequest, Message: "请求参数错误", Data: nil})
+		return
+	}
+	syncPath := models.GetSyncPathById(req.ID)
+	if syncPath == nil {
+		c.JSON(http.StatusNotFound, APIResponse[any]{Code: BadRequest, Message: "同步路径不存在", Data: nil})
+		return
+	}
+	if syncPath.SourceType != models.SourceTypeLocal {
+		c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: "只有本地目录支持实时监控", Data: nil})
+		return
+	}
+	syncPath.ToggleWatch()
+	synccron.RefreshLocalWatcher(syncPath.ID)
+	if syncPath.EnableWatch {
+		c.JSON(http.StatusOK, APIResponse[any]{Code: Success, Message: "实时监控已开启", Data: nil})
+	} else {
+		c.JSON(http.StatusOK, APIResponse[any]{Code: Success, Message: "实时监控已关闭", Data: nil})
+	}
+}
+
+// PlanSyncByPath 预览同步路径
+// @Summary 预览同步
+// @Description 将预览同步任务加入队列，预览同步只记录将要执行的操作，不修改任何文件
+// @Tags 同步管理
+// @Accept json
+// @Produce json
+// @Param id body integer true "同步路径ID"
+// @Success 200 {object} object
+// @Failure 200 {object} object
+// @Router /sync/path/plan [post]
+// @Security JwtAuth
+// @Security ApiKeyAuth
+func PlanSyncByPath(c *gin.Context) {
+	type planSyncRequest struct {
+		ID uint `form:"id" json:"id" binding:"required"` // 同步路径ID
+	}
+	var req planSyncRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, APIResponse[any]{Code: BadRequest, Message: "请求参数错误", Data: nil})
+		return
+	}
+	syncPath := models.GetSyncPathById(req.ID)
+	if syncPath == nil {
+		c.JSON(http.StatusNotFound, APIResponse[any]{Code: BadRequest, Message: "同步路径不存在", Data: nil})
+		return
+	}
+	if err := synccron.AddNewSyncTask(syncPath.ID, synccron.SyncTaskTypeStrmPlan); err != nil {
+		c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: "添加预览同步任务失败: " + err.Error(), Data: nil})
+		return
+	}
+	c.JSON(http.StatusOK, APIResponse[any]{Code: Success, Message: "预览同步任务已添加到队列", Data: nil})
+}
+
+// GetSyncPathPlan 获取预览同步结果
+// @Summary 获取预览同步结果
+// @Description 分页获取预览同步将要执行的操作，不传sync_id时返回同步路径最近一次的预览
+// @Tags 同步管理
+// @Accept json
+// @Produce json
+// @Param id query integer false "同步路径ID"
+// @Param sync_id query integer false "预览同步记录ID"
+// @Param action query string false "操作类型：create_strm, update_strm, rename, download, upload, delete_local, skip"
+// @Param page query integer false "页码"
+// @Param page_size query integer false "每页数量"
+// @Success 200 {object} object
+// @Failure 200 {object} object
+// @Router /sync/path/plan [get]
+// @Security JwtAuth
+// @Security ApiKeyAuth
+func GetSyncPathPlan(c *gin.Context) {
+	type syncPlanRequest struct {
+		ID       uint   `form:"id" json:"id"`                                         // 同步路径ID
+		SyncID   uint   `form:"sync_id" json:"sync_id"`                               // 预览同步记录ID
+		Action   string `form:"action" json:"action"`                                 // 操作类型
+		Page     int    `form:"page" json:"page" binding:"omitempty,min=1"`           // 页码，默认1
+		PageSize int    `form:"page_size" json:"page_size" binding:"omitempty,min=1"` // 每页数量，默认50
+	}
+	var req syncPlanRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, APIResponse[any]{Code: BadRequest, Message: "请求参数错误", Data: nil})
+		return
+	}
+	if req.Page <= 0 {
+		req.Page = 1
+	}
+	if req.PageSize <= 0 {
+		req.PageSize = 50
+	}
+	var sync *models.Sync
+	if req.SyncID > 0 {
+		record, err := models.GetSyncByID(req.SyncID)
+		if err != nil || !record.IsPlan {
+			c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: "预览同步记录不存在", Data: nil})
+			return
+		}
+		sync = record
+	} else {
+		if req.ID == 0 {
+			c.JSON(http.StatusBadRequest, APIResponse[any]{Code: BadRequest, Message: "id 和 sync_id 不能同时为空", Data: nil})
+			return
+		}
+		sync = models.GetLastSyncPlanByPathId(req.ID)
+		if sync == nil {
+			c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: "该同步路径还没有预览同步记录", Data: nil})
+			return
+		}
+	}
+	summary, err := models.GetSyncPlanSummary(sync.ID)
+	if err != nil {
+		c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: "获取预览同步结果失败: " + err.Error(), Data: nil})
+		return
+	}
+	items, total, err := models.GetSyncPlanItems(sync.ID, models.SyncPlanAction(req.Action), req.Page, req.PageSize)
+	if err != nil {
+		c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: "获取预览同步结果失败: " + err.Error(), Data: nil})
+		return
+	}
+	c.JSON(http.StatusOK, APIResponse[any]{Code: Success, Message: "获取预览同步结果成功", Data: map[string]interface{}{
+		"sync":    sync,
+		"summary": summary,
+		"items":   items,
+		"total":   total,
+	}})
+}
+
 // FullStart115Sync 启动115全量同步
 // @Summary 启动115全量同步
 // @Description 删除本地缓存数据并触发115的全量同步
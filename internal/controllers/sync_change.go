@@ -0,0 +1,118 @@
+package controllers
+
+import (
+	"Q115-STRM/internal/models"
+	"encoding/csv"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetSyncTaskChanges 获取同步任务的变更记录
+// @Summary 获取同步任务的变更记录
+// @Description 分页获取同步过程中实际执行的文件操作，保留30天，同步记录过期后仍可按同步路径查询，format=csv时导出全部符合条件的记录
+// @Tags 同步管理
+// @Accept json
+// @Produce json
+// @Param sync_id query integer false "同步记录ID"
+// @Param sync_path_id query integer false "同步路径ID"
+// @Param action query string false "操作类型：create_strm, update_strm, rename, delete_local, download, upload"
+// @Param keyword query string false "按路径搜索"
+// @Param format query string false "导出格式，csv导出文件，不传返回JSON"
+// @Param page query integer false "页码"
+// @Param page_size query integer false "每页数量"
+// @Success 200 {object} object
+// @Failure 200 {object} object
+// @Router /sync/task/changes [get]
+// @Security JwtAuth
+// @Security ApiKeyAuth
+func GetSyncTaskChanges(c *gin.Context) {
+	type syncChangesRequest struct {
+		SyncId     uint   `form:"sync_id" json:"sync_id"`                               // 同步记录ID
+		SyncPathId uint   `form:"sync_path_id" json:"sync_path_id"`                     // 同步路径ID
+		Action     string `form:"action" json:"action"`                                 // 操作类型
+		Keyword    string `form:"keyword" json:"keyword"`                               // 按路径搜索
+		Format     string `form:"format" json:"format"`                                 // 导出格式
+		Page       int    `form:"page" json:"page" binding:"omitempty,min=1"`           // 页码，默认1
+		PageSize   int    `form:"page_size" json:"page_size" binding:"omitempty,min=1"` // 每页数量，默认50
+	}
+	var req syncChangesRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, APIResponse[any]{Code: BadRequest, Message: "请求参数错误", Data: nil})
+		return
+	}
+	if req.SyncId == 0 && req.SyncPathId == 0 {
+		c.JSON(http.StatusBadRequest, APIResponse[any]{Code: BadRequest, Message: "sync_id 和 sync_path_id 不能同时为空", Data: nil})
+		return
+	}
+	if req.Action != "" {
+		if _, ok := models.SyncChangeActionText[models.SyncChangeAction(req.Action)]; !ok {
+			c.JSON(http.StatusBadRequest, APIResponse[any]{Code: BadRequest, Message: "操作类型不正确", Data: nil})
+			return
+		}
+	}
+	if req.Page <= 0 {
+		req.Page = 1
+	}
+	if req.PageSize <= 0 {
+		req.PageSize = 50
+	}
+	query := &models.SyncChangeQuery{
+		SyncId:     req.SyncId,
+		SyncPathId: req.SyncPathId,
+		Action:     models.SyncChangeAction(req.Action),
+		Keyword:    req.Keyword,
+	}
+	if req.Format == "csv" {
+		exportSyncChangesCsv(c, query)
+		return
+	}
+	summary, err := models.GetSyncChangeSummary(query)
+	if err != nil {
+		c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: "获取同步变更记录失败: " + err.Error(), Data: nil})
+		return
+	}
+	changes, total, err := models.GetSyncChanges(query, req.Page, req.PageSize)
+	if err != nil {
+		c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: "获取同步变更记录失败: " + err.Error(), Data: nil})
+		return
+	}
+	c.JSON(http.StatusOK, APIResponse[any]{Code: Success, Message: "获取同步变更记录成功", Data: map[string]interface{}{
+		"summary": summary,
+		"list":    changes,
+		"total":   total,
+	}})
+}
+
+// 以CSV文件导出同步变更记录
+func exportSyncChangesCsv(c *gin.Context, query *models.SyncChangeQuery) {
+	fileName := fmt.Sprintf("sync_changes_%d.csv", query.SyncId)
+	if query.SyncId == 0 {
+		fileName = fmt.Sprintf("sync_path_%d_changes.csv", query.SyncPathId)
+	}
+	c.Header("Content-Disposition", "attachment; filename="+fileName)
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Status(http.StatusOK)
+	// 写入BOM，Excel打开时中文不乱码
+	c.Writer.Write([]byte("\xEF\xBB\xBF"))
+	w := csv.NewWriter(c.Writer)
+	w.Write([]string{"时间", "同步记录ID", "同步路径ID", "操作", "原路径", "新路径", "网盘路径", "原因"})
+	err := models.EachSyncChange(query, func(change *models.SyncChange) error {
+		return w.Write([]string{
+			time.Unix(change.CreatedAt, 0).Format("2006-01-02 15:04:05"),
+			fmt.Sprint(change.SyncId),
+			fmt.Sprint(change.SyncPathId),
+			models.SyncChangeActionText[change.Action],
+			change.OldPath,
+			change.NewPath,
+			change.RemotePath,
+			change.Reason,
+		})
+	})
+	w.Flush()
+	if err != nil {
+		c.Error(err)
+	}
+}
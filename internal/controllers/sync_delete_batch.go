@@ -0,0 +1,195 @@
+package controllers
+
+import (
+	"Q115-STRM/internal/models"
+	"Q115-STRM/internal/synccron"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 同步目录正在同步（包括预览）时不能处理删除批次
+func isSyncPathRunning(syncPathId uint) bool {
+	for _, taskType := range []synccron.SyncTaskType{synccron.SyncTaskTypeStrm, synccron.SyncTaskTypeStrmPartial, synccron.SyncTaskTypeStrmPlan} {
+		status := synccron.CheckNewTaskStatus(syncPathId, taskType)
+		if status == synccron.TaskStatusWaiting || status == synccron.TaskStatusRunning {
+			return true
+		}
+	}
+	return false
+}
+
+// 批准或拒绝删除批次，返回处理结果的提示
+func handleSyncDeleteBatch(id uint, approve bool) (string, error) {
+	batch := models.GetSyncDeleteBatchById(id)
+	if batch == nil {
+		return "", fmt.Errorf("删除批次不存在")
+	}
+	if !approve {
+		if err := batch.Reject(); err != nil {
+			return "", err
+		}
+		return "已拒绝删除，本地文件保留", nil
+	}
+	if isSyncPathRunning(batch.SyncPathId) {
+		return "", fmt.Errorf("同步目录正在同步，请等待同步完成后再批准")
+	}
+	deleted, err := batch.Approve()
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("已批准删除，共删除本地文件 %d 个", deleted), nil
+}
+
+// GetSyncDeleteBatches 获取删除批次列表
+// @Summary 获取删除批次列表
+// @Description 分页获取触发删除保护后等待审批或已处理的删除批次
+// @Tags 同步管理
+// @Accept json
+// @Produce json
+// @Param sync_path_id query integer false "同步路径ID"
+// @Param status query integer false "状态：0等待审批 1已删除 2已拒绝 3已失效，不传查询全部"
+// @Param page query integer false "页码"
+// @Param page_size query integer false "每页数量"
+// @Success 200 {object} object
+// @Failure 200 {object} object
+// @Router /sync/delete-batch/list [get]
+// @Security JwtAuth
+// @Security ApiKeyAuth
+func GetSyncDeleteBatches(c *gin.Context) {
+	type deleteBatchListRequest struct {
+		SyncPathId uint `form:"sync_path_id" json:"sync_path_id"`                     // 同步路径ID
+		Status     *int `form:"status" json:"status"`                                 // 状态
+		Page       int  `form:"page" json:"page" binding:"omitempty,min=1"`           // 页码，默认1
+		PageSize   int  `form:"page_size" json:"page_size" binding:"omitempty,min=1"` // 每页数量，默认20
+	}
+	var req deleteBatchListRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, APIResponse[any]{Code: BadRequest, Message: "请求参数错误", Data: nil})
+		return
+	}
+	if req.Page <= 0 {
+		req.Page = 1
+	}
+	if req.PageSize <= 0 {
+		req.PageSize = 20
+	}
+	status := -1
+	if req.Status != nil {
+		status = *req.Status
+	}
+	batches, total, err := models.GetSyncDeleteBatches(req.SyncPathId, status, req.Page, req.PageSize)
+	if err != nil {
+		c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: "获取删除批次失败: " + err.Error(), Data: nil})
+		return
+	}
+	c.JSON(http.StatusOK, APIResponse[any]{Code: Success, Message: "获取删除批次成功", Data: map[string]interface{}{
+		"list":  batches,
+		"total": total,
+	}})
+}
+
+// GetSyncDeleteBatchItems 获取删除批次中的文件
+// @Summary 获取删除批次中的文件
+// @Description 分页获取删除批次中等待删除的本地文件
+// @Tags 同步管理
+// @Accept json
+// @Produce json
+// @Param id query integer true "删除批次ID"
+// @Param page query integer false "页码"
+// @Param page_size query integer false "每页数量"
+// @Success 200 {object} object
+// @Failure 200 {object} object
+// @Router /sync/delete-batch/items [get]
+// @Security JwtAuth
+// @Security ApiKeyAuth
+func GetSyncDeleteBatchItems(c *gin.Context) {
+	type deleteBatchItemsRequest struct {
+		ID       uint `form:"id" json:"id" binding:"required"`                      // 删除批次ID
+		Page     int  `form:"page" json:"page" binding:"omitempty,min=1"`           // 页码，默认1
+		PageSize int  `form:"page_size" json:"page_size" binding:"omitempty,min=1"` // 每页数量，默认50
+	}
+	var req deleteBatchItemsRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, APIResponse[any]{Code: BadRequest, Message: "请求参数错误", Data: nil})
+		return
+	}
+	if req.Page <= 0 {
+		req.Page = 1
+	}
+	if req.PageSize <= 0 {
+		req.PageSize = 50
+	}
+	batch := models.GetSyncDeleteBatchById(req.ID)
+	if batch == nil {
+		c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: "删除批次不存在", Data: nil})
+		return
+	}
+	items, total, err := batch.GetItems(req.Page, req.PageSize)
+	if err != nil {
+		c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: "获取删除批次中的文件失败: " + err.Error(), Data: nil})
+		return
+	}
+	c.JSON(http.StatusOK, APIResponse[any]{Code: Success, Message: "获取删除批次中的文件成功", Data: map[string]interface{}{
+		"batch": batch,
+		"items": items,
+		"total": total,
+	}})
+}
+
+type deleteBatchRequest struct {
+	ID uint `form:"id" json:"id" binding:"required"` // 删除批次ID
+}
+
+// ApproveSyncDeleteBatch 批准删除批次
+// @Summary 批准删除批次
+// @Description 删除批次中仍然存在的本地文件，同步目录正在同步时不能批准
+// @Tags 同步管理
+// @Accept json
+// @Produce json
+// @Param id body integer true "删除批次ID"
+// @Success 200 {object} object
+// @Failure 200 {object} object
+// @Router /sync/delete-batch/approve [post]
+// @Security JwtAuth
+// @Security ApiKeyAuth
+func ApproveSyncDeleteBatch(c *gin.Context) {
+	var req deleteBatchRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, APIResponse[any]{Code: BadRequest, Message: "请求参数错误", Data: nil})
+		return
+	}
+	message, err := handleSyncDeleteBatch(req.ID, true)
+	if err != nil {
+		c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: err.Error(), Data: nil})
+		return
+	}
+	c.JSON(http.StatusOK, APIResponse[any]{Code: Success, Message: message, Data: nil})
+}
+
+// RejectSyncDeleteBatch 拒绝删除批次
+// @Summary 拒绝删除批次
+// @Description 保留删除批次中的本地文件，网盘仍然缺少这些文件时下次同步会再次触发删除保护
+// @Tags 同步管理
+// @Accept json
+// @Produce json
+// @Param id body integer true "删除批次ID"
+// @Success 200 {object} object
+// @Failure 200 {object} object
+// @Router /sync/delete-batch/reject [post]
+// @Security JwtAuth
+// @Security ApiKeyAuth
+func RejectSyncDeleteBatch(c *gin.Context) {
+	var req deleteBatchRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, APIResponse[any]{Code: BadRequest, Message: "请求参数错误", Data: nil})
+		return
+	}
+	message, err := handleSyncDeleteBatch(req.ID, false)
+	if err != nil {
+		c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: err.Error(), Data: nil})
+		return
+	}
+	c.JSON(http.StatusOK, APIResponse[any]{Code: Success, Message: message, Data: nil})
+}
@@ -0,0 +1,182 @@
+package controllers
+
+import (
+	"Q115-STRM/internal/models"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 新增和修改目标目录的请求参数，未传的设置使用同步目录的设置
+type syncPathTargetRequest struct {
+	ID           uint   `json:"id"`                              // 目标目录ID，修改时必填
+	SyncPathId   uint   `json:"sync_path_id" binding:"required"` // 同步目录ID
+	Name         string `json:"name"`                            // 名称
+	LocalPath    string `json:"local_path" binding:"required"`   // 本地路径
+	Enabled      *bool  `json:"enabled"`                         // 是否启用，默认启用
+	StrmBaseUrl  string `json:"strm_base_url"`                   // STRM的基础URL
+	StrmTemplate string `json:"strm_template"`                   // STRM内容模板
+	AddPath      *int   `json:"add_path"`                        // 是否添加路径
+	DownloadMeta *int   `json:"download_meta"`                   // 是否下载元数据
+	UploadMeta   *int   `json:"upload_meta"`                     // 网盘不存在的元数据
+	DeleteDir    *int   `json:"delete_dir"`                      // 是否删除空目录
+}
+
+func (req *syncPathTargetRequest) apply(target *models.SyncPathTarget) {
+	optional := func(v *int) int {
+		if v == nil {
+			return -1
+		}
+		return *v
+	}
+	target.Name = req.Name
+	target.LocalPath = req.LocalPath
+	target.Enabled = req.Enabled == nil || *req.Enabled
+	target.StrmBaseUrl = req.StrmBaseUrl
+	target.StrmTemplate = req.StrmTemplate
+	target.AddPath = optional(req.AddPath)
+	target.DownloadMeta = optional(req.DownloadMeta)
+	target.UploadMeta = optional(req.UploadMeta)
+	target.DeleteDir = optional(req.DeleteDir)
+}
+
+// GetSyncPathTargets 获取同步目录的目标目录
+// @Summary 获取同步目录的目标目录
+// @Description 获取同步目录除自己的本地路径外，额外生成STRM的所有目标目录
+// @Tags 同步管理
+// @Accept json
+// @Produce json
+// @Param sync_path_id query integer true "同步路径ID"
+// @Success 200 {object} object
+// @Failure 200 {object} object
+// @Router /sync/path/target/list [get]
+// @Security JwtAuth
+// @Security ApiKeyAuth
+func GetSyncPathTargets(c *gin.Context) {
+	type targetListRequest struct {
+		SyncPathId uint `form:"sync_path_id" json:"sync_path_id" binding:"required"` // 同步路径ID
+	}
+	var req targetListRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, APIResponse[any]{Code: BadRequest, Message: "请求参数错误", Data: nil})
+		return
+	}
+	c.JSON(http.StatusOK, APIResponse[any]{Code: Success, Message: "获取目标目录成功", Data: models.GetSyncPathTargets(req.SyncPathId)})
+}
+
+// AddSyncPathTarget 添加目标目录
+// @Summary 添加目标目录
+// @Description 给同步目录添加一个目标目录，同步时使用同一份网盘文件列表生成，本地路径不能和同步目录或其他目标目录重叠
+// @Tags 同步管理
+// @Accept json
+// @Produce json
+// @Param sync_path_id body integer true "同步路径ID"
+// @Param name body string false "名称"
+// @Param local_path body string true "本地路径"
+// @Param enabled body boolean false "是否启用，默认启用"
+// @Param strm_base_url body string false "STRM的基础URL，为空使用同步目录的设置"
+// @Param strm_template body string false "STRM内容模板，为空使用同步目录的设置"
+// @Param add_path body integer false "是否添加路径，-1或不传使用同步目录的设置"
+// @Param download_meta body integer false "是否下载元数据，-1或不传使用同步目录的设置"
+// @Param upload_meta body integer false "网盘不存在的元数据：0保留，2删除，-1或不传使用同步目录的设置"
+// @Param delete_dir body integer false "是否删除空目录，-1或不传使用同步目录的设置"
+// @Success 200 {object} object
+// @Failure 200 {object} object
+// @Router /sync/path/target/add [post]
+// @Security JwtAuth
+// @Security ApiKeyAuth
+func AddSyncPathTarget(c *gin.Context) {
+	var req syncPathTargetRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, APIResponse[any]{Code: BadRequest, Message: "请求参数错误", Data: nil})
+		return
+	}
+	saveSyncPathTarget(c, &req, &models.SyncPathTarget{})
+}
+
+// UpdateSyncPathTarget 修改目标目录
+// @Summary 修改目标目录
+// @Description 修改目标目录的设置，参数同添加目标目录，修改本地路径后旧路径下的文件不会删除
+// @Tags 同步管理
+// @Accept json
+// @Produce json
+// @Param id body integer true "目标目录ID"
+// @Param sync_path_id body integer true "同步路径ID"
+// @Param local_path body string true "本地路径"
+// @Success 200 {object} object
+// @Failure 200 {object} object
+// @Router /sync/path/target/update [post]
+// @Security JwtAuth
+// @Security ApiKeyAuth
+func UpdateSyncPathTarget(c *gin.Context) {
+	var req syncPathTargetRequest
+	if err := c.ShouldBindJSON(&req); err != nil || req.ID == 0 {
+		c.JSON(http.StatusBadRequest, APIResponse[any]{Code: BadRequest, Message: "请求参数错误", Data: nil})
+		return
+	}
+	target := models.GetSyncPathTargetById(req.ID)
+	if target == nil || target.SyncPathId != req.SyncPathId {
+		c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: "目标目录不存在", Data: nil})
+		return
+	}
+	saveSyncPathTarget(c, &req, target)
+}
+
+func saveSyncPathTarget(c *gin.Context, req *syncPathTargetRequest, target *models.SyncPathTarget) {
+	syncPath := models.GetSyncPathById(req.SyncPathId)
+	if syncPath == nil {
+		c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: "同步路径不存在", Data: nil})
+		return
+	}
+	if isSyncPathRunning(syncPath.ID) {
+		c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: "同步目录正在同步，请等待同步完成后再修改目标目录", Data: nil})
+		return
+	}
+	req.apply(target)
+	if err := target.Validate(syncPath); err != nil {
+		c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: err.Error(), Data: nil})
+		return
+	}
+	if err := target.Save(syncPath); err != nil {
+		c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: "保存目标目录失败: " + err.Error(), Data: nil})
+		return
+	}
+	c.JSON(http.StatusOK, APIResponse[any]{Code: Success, Message: "保存目标目录成功", Data: target})
+}
+
+// DeleteSyncPathTarget 删除目标目录
+// @Summary 删除目标目录
+// @Description 删除目标目录，已生成的本地文件不会删除
+// @Tags 同步管理
+// @Accept json
+// @Produce json
+// @Param id body integer true "目标目录ID"
+// @Success 200 {object} object
+// @Failure 200 {object} object
+// @Router /sync/path/target/delete [post]
+// @Security JwtAuth
+// @Security ApiKeyAuth
+func DeleteSyncPathTarget(c *gin.Context) {
+	type deleteTargetRequest struct {
+		ID uint `json:"id" binding:"required"` // 目标目录ID
+	}
+	var req deleteTargetRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, APIResponse[any]{Code: BadRequest, Message: "请求参数错误", Data: nil})
+		return
+	}
+	target := models.GetSyncPathTargetById(req.ID)
+	if target == nil {
+		c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: "目标目录不存在", Data: nil})
+		return
+	}
+	if isSyncPathRunning(target.SyncPathId) {
+		c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: "同步目录正在同步，请等待同步完成后再删除目标目录", Data: nil})
+		return
+	}
+	if err := target.Delete(); err != nil {
+		c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: "删除目标目录失败: " + err.Error(), Data: nil})
+		return
+	}
+	c.JSON(http.StatusOK, APIResponse[any]{Code: Success, Message: "删除目标目录成功", Data: nil})
+}
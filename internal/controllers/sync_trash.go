@@ -0,0 +1,114 @@
+package controllers
+
+import (
+	"Q115-STRM/internal/models"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetSyncTrashItems 获取回收站中的文件
+// @Summary 获取回收站中的文件
+// @Description 分页获取同步时删除并移动到回收站的本地文件
+// @Tags 同步管理
+// @Accept json
+// @Produce json
+// @Param sync_path_id query integer false "同步路径ID"
+// @Param keyword query string false "按原路径搜索"
+// @Param page query integer false "页码"
+// @Param page_size query integer false "每页数量"
+// @Success 200 {object} object
+// @Failure 200 {object} object
+// @Router /sync/trash/list [get]
+// @Security JwtAuth
+// @Security ApiKeyAuth
+func GetSyncTrashItems(c *gin.Context) {
+	type trashListRequest struct {
+		SyncPathId uint   `form:"sync_path_id" json:"sync_path_id"`                     // 同步路径ID
+		Keyword    string `form:"keyword" json:"keyword"`                               // 按原路径搜索
+		Page       int    `form:"page" json:"page" binding:"omitempty,min=1"`           // 页码，默认1
+		PageSize   int    `form:"page_size" json:"page_size" binding:"omitempty,min=1"` // 每页数量，默认50
+	}
+	var req trashListRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, APIResponse[any]{Code: BadRequest, Message: "请求参数错误", Data: nil})
+		return
+	}
+	if req.Page <= 0 {
+		req.Page = 1
+	}
+	if req.PageSize <= 0 {
+		req.PageSize = 50
+	}
+	items, total, err := models.GetSyncTrashItems(req.SyncPathId, req.Keyword, req.Page, req.PageSize)
+	if err != nil {
+		c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: "获取回收站文件失败: " + err.Error(), Data: nil})
+		return
+	}
+	c.JSON(http.StatusOK, APIResponse[any]{Code: Success, Message: "获取回收站文件成功", Data: map[string]interface{}{
+		"list":  items,
+		"total": total,
+	}})
+}
+
+// RestoreSyncTrashItems 还原回收站中的文件
+// @Summary 还原回收站中的文件
+// @Description 将回收站中的文件移回原来的位置，原位置已有文件时跳过，返回每个文件的还原结果
+// @Tags 同步管理
+// @Accept json
+// @Produce json
+// @Param ids body []integer true "回收站记录ID列表"
+// @Success 200 {object} object
+// @Failure 200 {object} object
+// @Router /sync/trash/restore [post]
+// @Security JwtAuth
+// @Security ApiKeyAuth
+func RestoreSyncTrashItems(c *gin.Context) {
+	type trashRestoreRequest struct {
+		IDs []uint `form:"ids" json:"ids" binding:"required"` // 回收站记录ID列表
+	}
+	type trashRestoreResult struct {
+		ID           uint   `json:"id"`
+		OriginalPath string `json:"original_path"`
+		Success      bool   `json:"success"`
+		Message      string `json:"message"`
+	}
+	var req trashRestoreRequest
+	if err := c.ShouldBindJSON(&req); err != nil || len(req.IDs) == 0 {
+		c.JSON(http.StatusBadRequest, APIResponse[any]{Code: BadRequest, Message: "请求参数错误", Data: nil})
+		return
+	}
+	items := models.GetSyncTrashItemsByIds(req.IDs)
+	found := make(map[uint]*models.SyncTrashItem, len(items))
+	for _, item := range items {
+		found[item.ID] = item
+	}
+	results := make([]trashRestoreResult, 0, len(req.IDs))
+	restored := 0
+	for _, id := range req.IDs {
+		item, ok := found[id]
+		if !ok {
+			results = append(results, trashRestoreResult{ID: id, Message: "回收站记录不存在"})
+			continue
+		}
+		if isSyncPathRunning(item.SyncPathId) {
+			results = append(results, trashRestoreResult{ID: id, OriginalPath: item.OriginalPath, Message: "同步目录正在同步，请等待同步完成后再还原"})
+			continue
+		}
+		if err := item.Restore(); err != nil {
+			results = append(results, trashRestoreResult{ID: id, OriginalPath: item.OriginalPath, Message: err.Error()})
+			continue
+		}
+		restored++
+		results = append(results, trashRestoreResult{ID: id, OriginalPath: item.OriginalPath, Success: true, Message: "还原成功"})
+	}
+	code := Success
+	if restored == 0 {
+		code = BadRequest
+	}
+	c.JSON(http.StatusOK, APIResponse[any]{Code: code, Message: "还原完成", Data: map[string]interface{}{
+		"restored": restored,
+		"failed":   len(req.IDs) - restored,
+		"results":  results,
+	}})
+}
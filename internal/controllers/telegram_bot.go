@@ -440,6 +440,33 @@ func StrmThenScrape(args []string) string {
 	return runStrmThenScrape(extractedIDs)
 }
 
+// runDeleteBatchCommand 批准或拒绝触发删除保护的删除批次
+// args: 参数格式为 #数字，代表删除批次ID
+func runDeleteBatchCommand(args []string, approve bool) string {
+	errMsg, batchID := checkAndExtractSingleParam(args)
+	if errMsg != "" {
+		return errMsg
+	}
+	if batchID == 0 {
+		return "❌ 请指定删除批次，格式: #序号"
+	}
+	message, err := handleSyncDeleteBatch(batchID, approve)
+	if err != nil {
+		return "❌ " + err.Error()
+	}
+	return "✅ " + message
+}
+
+// ApproveDeleteBatch 批准删除批次
+func ApproveDeleteBatch(args []string) string {
+	return runDeleteBatchCommand(args, true)
+}
+
+// RejectDeleteBatch 拒绝删除批次
+func RejectDeleteBatch(args []string) string {
+	return runDeleteBatchCommand(args, false)
+}
+
 func StartListenTelegramBot() {
 	mgr := notificationmanager.GlobalEnhancedNotificationManager
 
@@ -449,6 +476,9 @@ func StartListenTelegramBot() {
 		"scrape":      Scrape,
 		"scrape_strm": ScrapeThenStrm,
 		"strm_scrape": StrmThenScrape,
+
+		"strm_delete_approve": ApproveDeleteBatch,
+		"strm_delete_reject":  RejectDeleteBatch,
 	}
 
 	mgr.RegisterTelegramCommands(myCommands)
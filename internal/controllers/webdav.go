@@ -0,0 +1,64 @@
+package controllers
+
+import (
+	"Q115-STRM/internal/helpers"
+	"Q115-STRM/internal/models"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetWebDAVFileUrl 播放WebDAV文件
+// @Summary 播放WebDAV文件
+// @Description 使用账号密码访问WebDAV并转发文件内容，支持Range请求；只能访问同步过的文件，WebDAV地址包含账号密码，不会重定向过去
+// @Tags WebDAV
+// @Accept json
+// @Produce octet-stream
+// @Param account_id query integer true "账号ID"
+// @Param path query string true "文件路径"
+// @Success 200 {string} string "文件内容"
+// @Failure 200 {object} object
+// @Router /webdav/url/{filename} [get]
+func GetWebDAVFileUrl(c *gin.Context) {
+	type fileUrlReq struct {
+		AccountId uint   `json:"account_id" form:"account_id"`
+		Path      string `json:"path" form:"path"`
+	}
+	var req fileUrlReq
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, APIResponse[any]{Code: BadRequest, Message: "参数错误", Data: nil})
+		return
+	}
+	if req.Path == "" {
+		c.JSON(http.StatusBadRequest, APIResponse[any]{Code: BadRequest, Message: "path 参数不能为空", Data: nil})
+		return
+	}
+	account, err := models.GetAccountById(req.AccountId)
+	if err != nil || account.SourceType != models.SourceTypeWebDAV {
+		c.JSON(http.StatusBadRequest, APIResponse[any]{Code: BadRequest, Message: "账号ID不存在", Data: nil})
+		return
+	}
+	// 接口不需要登录，只允许访问同步过的文件，带签名时必须是签名中的同步路径的文件
+	syncPathId, _ := helpers.GetStrmSignPathId(c.Request.URL.Query())
+	if models.GetFileByFileId(models.SourceTypeWebDAV, account.ID, syncPathId, req.Path) == nil {
+		helpers.AppLogger.Warnf("[WebDAV] 拒绝访问没有同步过的文件: %s, 账号: %d, IP: %s", req.Path, account.ID, c.ClientIP())
+		c.JSON(http.StatusForbidden, APIResponse[any]{Code: BadRequest, Message: "文件不存在", Data: nil})
+		return
+	}
+	resp, err := account.GetWebDAVClient().Get(c.Request.Context(), req.Path, c.Request.Header)
+	if err != nil {
+		helpers.AppLogger.Errorf("[WebDAV] 读取文件 %s 失败: %v", req.Path, err)
+		c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: "读取文件失败", Data: nil})
+		return
+	}
+	defer resp.Body.Close()
+	for _, k := range []string{"Content-Type", "Content-Length", "Content-Range", "Accept-Ranges", "Last-Modified", "ETag"} {
+		if v := resp.Header.Get(k); v != "" {
+			c.Header(k, v)
+		}
+	}
+	c.Status(resp.StatusCode)
+	// 播放器断开连接时复制会出错，不需要记录
+	io.Copy(c.Writer, resp.Body)
+}
@@ -0,0 +1,309 @@
+package davserver
+
+import (
+	"Q115-STRM/internal/helpers"
+	"Q115-STRM/internal/models"
+	"Q115-STRM/internal/v115open"
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/webdav"
+	"golang.org/x/sync/singleflight"
+)
+
+const (
+	TREE_TTL  = 10 * time.Minute // 目录树的最长缓存时间，同步完成后也会重新生成
+	PATHS_TTL = 30 * time.Second // 同步路径列表的缓存时间，PROPFIND会对每个子项调用Stat
+)
+
+// 虚拟目录树的节点，实现了os.FileInfo
+type node struct {
+	name     string
+	isDir    bool
+	size     int64
+	modTime  time.Time
+	children map[string]*node
+	file     *models.SyncFile // 文件节点对应的同步记录
+}
+
+func newDirNode(name string) *node {
+	return &node{name: name, isDir: true, modTime: time.Now(), children: make(map[string]*node)}
+}
+
+func (n *node) Name() string       { return n.name }
+func (n *node) Size() int64        { return n.size }
+func (n *node) ModTime() time.Time { return n.modTime }
+func (n *node) IsDir() bool        { return n.isDir }
+func (n *node) Sys() any           { return nil }
+func (n *node) Mode() fs.FileMode {
+	if n.isDir {
+		return fs.ModeDir | 0555
+	}
+	return 0444
+}
+
+// ContentType 按扩展名返回类型，避免PROPFIND时读取文件内容
+func (n *node) ContentType(ctx context.Context) (string, error) {
+	if t := mime.TypeByExtension(path.Ext(n.name)); t != "" {
+		return t, nil
+	}
+	return "application/octet-stream", nil
+}
+
+// 子节点按名称排序，目录在前
+func (n *node) sortedChildren() []fs.FileInfo {
+	infos := make([]fs.FileInfo, 0, len(n.children))
+	for _, child := range n.children {
+		infos = append(infos, child)
+	}
+	sort.Slice(infos, func(i, j int) bool {
+		if infos[i].IsDir() != infos[j].IsDir() {
+			return infos[i].IsDir()
+		}
+		return infos[i].Name() < infos[j].Name()
+	})
+	return infos
+}
+
+// 用同步记录生成目录树，localRoot是同步路径的本地根目录
+// 视频文件使用网盘中的文件名（不是.strm），元数据使用本地文件名，目录由文件的本地路径推导
+func buildTree(name string, localRoot string, files []*models.SyncFile) *node {
+	root := newDirNode(name)
+	localRoot = filepath.Clean(localRoot)
+	for _, file := range files {
+		if file.FileType == v115open.TypeDir || (!file.IsVideo && !file.IsMeta) || file.LocalFilePath == "" {
+			continue
+		}
+		relDir, err := filepath.Rel(localRoot, filepath.Dir(filepath.FromSlash(file.LocalFilePath)))
+		if err != nil || relDir == ".." || strings.HasPrefix(relDir, ".."+string(filepath.Separator)) {
+			continue
+		}
+		dir := root
+		if relDir != "." {
+			for _, part := range strings.Split(filepath.ToSlash(relDir), "/") {
+				child, ok := dir.children[part]
+				if !ok {
+					child = newDirNode(part)
+					dir.children[part] = child
+				} else if !child.isDir {
+					break
+				}
+				dir = child
+			}
+		}
+		if !dir.isDir {
+			continue
+		}
+		fileName := file.FileName
+		if file.IsMeta {
+			fileName = filepath.Base(filepath.FromSlash(file.LocalFilePath))
+		}
+		if _, exists := dir.children[fileName]; exists {
+			continue
+		}
+		dir.children[fileName] = &node{
+			name:    fileName,
+			size:    file.FileSize,
+			modTime: time.Unix(file.MTime, 0),
+			file:    file,
+		}
+	}
+	return root
+}
+
+type libraryTree struct {
+	root    *node
+	builtAt time.Time
+}
+
+// FileSystem 只读的虚拟媒体库，根目录下每个同步路径是一个目录
+type FileSystem struct {
+	mu      sync.Mutex
+	group   singleflight.Group // 同一个同步路径同时只生成一次目录树，不同同步路径互不阻塞
+	trees   map[uint]*libraryTree
+	names   map[string]*models.SyncPath
+	namesAt time.Time
+}
+
+func NewFileSystem() *FileSystem {
+	return &FileSystem{trees: make(map[uint]*libraryTree)}
+}
+
+// 同步路径在根目录下显示的名称，重名时加上ID
+func syncPathNames(syncPaths []*models.SyncPath) map[string]*models.SyncPath {
+	names := make(map[string]*models.SyncPath, len(syncPaths))
+	for _, sp := range syncPaths {
+		name := filepath.Base(filepath.Clean(sp.GetFullLocalPath()))
+		if name == "." || name == string(filepath.Separator) || name == "" {
+			name = fmt.Sprintf("%d", sp.ID)
+		}
+		if _, exists := names[name]; exists {
+			name = fmt.Sprintf("%s-%d", name, sp.ID)
+		}
+		names[name] = sp
+	}
+	return names
+}
+
+// 获取根目录下的同步路径列表
+func (fsys *FileSystem) syncPaths() map[string]*models.SyncPath {
+	fsys.mu.Lock()
+	defer fsys.mu.Unlock()
+	if fsys.names == nil || time.Since(fsys.namesAt) >= PATHS_TTL {
+		fsys.names = syncPathNames(models.GetAllSyncPaths())
+		fsys.namesAt = time.Now()
+	}
+	return fsys.names
+}
+
+// 获取同步路径的目录树，过期或者同步过之后重新生成
+// 生成目录树需要查询所有同步记录，不持有fsys.mu，避免阻塞其他同步路径的访问
+func (fsys *FileSystem) tree(name string, sp *models.SyncPath) (*node, error) {
+	fsys.mu.Lock()
+	t, ok := fsys.trees[sp.ID]
+	fsys.mu.Unlock()
+	if ok && t.root.name == name && time.Since(t.builtAt) < TREE_TTL && sp.LastSyncAt < t.builtAt.Unix() {
+		return t.root, nil
+	}
+	root, err, _ := fsys.group.Do(fmt.Sprintf("%d/%s", sp.ID, name), func() (any, error) {
+		return fsys.buildSyncPathTree(name, sp)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return root.(*node), nil
+}
+
+func (fsys *FileSystem) buildSyncPathTree(name string, sp *models.SyncPath) (*node, error) {
+	builtAt := time.Now()
+	files := make([]*models.SyncFile, 0)
+	offset := 0
+	limit := 1000
+	for {
+		batch, err := models.GetFilesBySyncPathId(sp.ID, offset, limit)
+		if err != nil {
+			helpers.AppLogger.Errorf("[WebDAV服务] 查询同步路径 %d 的文件失败: %v", sp.ID, err)
+			return nil, err
+		}
+		files = append(files, batch...)
+		if len(batch) < limit {
+			break
+		}
+		offset += limit
+	}
+	root := buildTree(name, sp.GetFullLocalPath(), files)
+	// 使用开始查询的时间，查询期间完成的同步在下次访问时重新生成
+	fsys.mu.Lock()
+	fsys.trees[sp.ID] = &libraryTree{root: root, builtAt: builtAt}
+	fsys.mu.Unlock()
+	helpers.AppLogger.Infof("[WebDAV服务] 生成同步路径 %d 的目录树，共 %d 条记录", sp.ID, len(files))
+	return root, nil
+}
+
+// 查找路径对应的节点
+func (fsys *FileSystem) lookup(name string) (*node, error) {
+	name = strings.Trim(path.Clean("/"+name), "/")
+	names := fsys.syncPaths()
+	if name == "" {
+		root := newDirNode("/")
+		for spName := range names {
+			root.children[spName] = newDirNode(spName)
+		}
+		return root, nil
+	}
+	parts := strings.Split(name, "/")
+	sp, ok := names[parts[0]]
+	if !ok {
+		return nil, os.ErrNotExist
+	}
+	n, err := fsys.tree(parts[0], sp)
+	if err != nil {
+		return nil, err
+	}
+	for _, part := range parts[1:] {
+		if !n.isDir {
+			return nil, os.ErrNotExist
+		}
+		child, ok := n.children[part]
+		if !ok {
+			return nil, os.ErrNotExist
+		}
+		n = child
+	}
+	return n, nil
+}
+
+// FindVideo 如果路径是视频文件，返回对应的同步记录
+func (fsys *FileSystem) FindVideo(name string) *models.SyncFile {
+	n, err := fsys.lookup(name)
+	if err != nil || n.isDir || n.file == nil || !n.file.IsVideo {
+		return nil
+	}
+	return n.file
+}
+
+func (fsys *FileSystem) Mkdir(ctx context.Context, name string, perm os.FileMode) error {
+	return os.ErrPermission
+}
+
+func (fsys *FileSystem) RemoveAll(ctx context.Context, name string) error {
+	return os.ErrPermission
+}
+
+func (fsys *FileSystem) Rename(ctx context.Context, oldName, newName string) error {
+	return os.ErrPermission
+}
+
+func (fsys *FileSystem) Stat(ctx context.Context, name string) (os.FileInfo, error) {
+	return fsys.lookup(name)
+}
+
+// OpenFile 目录返回虚拟目录，元数据打开本地文件，视频文件只能查看属性，读取由调用方跳转到直链
+func (fsys *FileSystem) OpenFile(ctx context.Context, name string, flag int, perm os.FileMode) (webdav.File, error) {
+	if flag&(os.O_WRONLY|os.O_RDWR|os.O_CREATE|os.O_TRUNC|os.O_APPEND) != 0 {
+		return nil, os.ErrPermission
+	}
+	n, err := fsys.lookup(name)
+	if err != nil {
+		return nil, err
+	}
+	if !n.isDir && n.file != nil && n.file.IsMeta {
+		return os.Open(filepath.FromSlash(n.file.LocalFilePath))
+	}
+	return &virtualFile{node: n}, nil
+}
+
+// 目录和视频文件，不支持读写内容
+type virtualFile struct {
+	node *node
+	read bool
+}
+
+func (f *virtualFile) Close() error                   { return nil }
+func (f *virtualFile) Stat() (fs.FileInfo, error)     { return f.node, nil }
+func (f *virtualFile) Write(p []byte) (int, error)    { return 0, os.ErrPermission }
+func (f *virtualFile) Seek(int64, int) (int64, error) { return 0, nil }
+func (f *virtualFile) Read(p []byte) (int, error)     { return 0, io.EOF }
+func (f *virtualFile) Readdir(count int) ([]fs.FileInfo, error) {
+	if !f.node.isDir {
+		return nil, os.ErrInvalid
+	}
+	if f.read {
+		if count > 0 {
+			return nil, io.EOF
+		}
+		return nil, nil
+	}
+	f.read = true
+	return f.node.sortedChildren(), nil
+}
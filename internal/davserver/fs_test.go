@@ -0,0 +1,48 @@
+package davserver
+
+import (
+	"Q115-STRM/internal/models"
+	"Q115-STRM/internal/v115open"
+	"testing"
+)
+
+func TestBuildTree(t *testing.T) {
+	files := []*models.SyncFile{
+		{FileName: "阿凡达 (2009)", FileType: v115open.TypeDir, LocalFilePath: "/strm/电影/阿凡达 (2009)"},
+		{FileName: "阿凡达.mkv", FileType: v115open.TypeFile, IsVideo: true, FileSize: 100, MTime: 1700000000, LocalFilePath: "/strm/电影/阿凡达 (2009)/阿凡达.strm"},
+		{FileName: "阿凡达.nfo", FileType: v115open.TypeFile, IsMeta: true, LocalFilePath: "/strm/电影/阿凡达 (2009)/阿凡达.nfo"},
+		{FileName: "poster.jpg", FileType: v115open.TypeFile, IsMeta: true, LocalFilePath: "/strm/电影/poster.jpg"},
+		{FileName: "readme.txt", FileType: v115open.TypeFile, LocalFilePath: "/strm/电影/readme.txt"},
+		{FileName: "外部.mkv", FileType: v115open.TypeFile, IsVideo: true, LocalFilePath: "/other/外部.strm"},
+	}
+	root := buildTree("电影", "/strm/电影", files)
+	if !root.IsDir() || root.Name() != "电影" {
+		t.Fatalf("unexpected root %+v", root)
+	}
+	if len(root.children) != 2 {
+		t.Fatalf("root has %d children, want 2: %v", len(root.children), root.children)
+	}
+	movieDir, ok := root.children["阿凡达 (2009)"]
+	if !ok || !movieDir.IsDir() {
+		t.Fatalf("movie dir missing: %v", root.children)
+	}
+	video, ok := movieDir.children["阿凡达.mkv"]
+	if !ok || video.IsDir() || video.Size() != 100 || video.ModTime().Unix() != 1700000000 || video.file == nil {
+		t.Fatalf("unexpected video node %+v", video)
+	}
+	if _, ok := movieDir.children["阿凡达.strm"]; ok {
+		t.Errorf("strm file should not be listed")
+	}
+	if _, ok := movieDir.children["阿凡达.nfo"]; !ok {
+		t.Errorf("nfo file missing")
+	}
+
+	f := &virtualFile{node: root}
+	infos, err := f.Readdir(0)
+	if err != nil || len(infos) != 2 || !infos[0].IsDir() || infos[1].Name() != "poster.jpg" {
+		t.Errorf("Readdir = %v, %v", infos, err)
+	}
+	if infos, _ := f.Readdir(0); len(infos) != 0 {
+		t.Errorf("second Readdir should be empty, got %v", infos)
+	}
+}
@@ -60,12 +60,16 @@ type ConfigStrm struct {
 	MinVideoSize int64    `yaml:"minVideoSize"` // 最小视频大小，单位字节
 	MetaExt      []string `yaml:"metaExt"`
 	Cron         string   `yaml:"cron"` // 定时任务表达式
+	// 网盘文件总数超过该值时同步缓存使用磁盘，0使用默认值，-1表示始终使用内存
+	// 115使用同步前查询的文件总数，其他来源使用上次同步的文件数量
+	DiskCacheThreshold int64 `yaml:"diskCacheThreshold"`
 }
 
 type Config struct {
 	Log           ConfigLog  `yaml:"log"`
 	Db            ConfigDb   `yaml:"db"`
 	CacheSize     int        `yaml:"cacheSize"` // 数据库缓存大小，单位字节
+	LinkCache     string     `yaml:"linkCache"` // 直链缓存存储：db持久化到数据库（默认），memory只使用内存
 	JwtSecret     string     `yaml:"jwtSecret"`
 	HttpHost      string     `yaml:"httpHost"`  // HTTP主机地址
 	HttpsHost     string     `yaml:"httpsHost"` // HTTPS主机地址
@@ -74,6 +78,8 @@ type Config struct {
 	BaiDuPanAppId string     `yaml:"baiDuPanAppId"`
 	AdminUsername string     `yaml:"adminUsername"`
 	AdminPassword string     `yaml:"adminPassword"`
+
+	Proxy ConfigProxy `yaml:"proxy"`
 }
 
 var GlobalConfig Config
@@ -91,6 +97,22 @@ var DEFAULT_TMDB_API_KEY = ""
 var DEFAULT_SC_API_KEY = ""
 var ENCRYPTION_KEY = ""
 
+// 本地反代（/proxy-115）的限制
+type ConfigProxy struct {
+	AllowHosts       []string `yaml:"allowHosts"`       // 允许反代的域名，同时匹配子域名，默认允许115、百度网盘和123云盘的CDN
+	MaxConnPerClient int      `yaml:"maxConnPerClient"` // 每个客户端IP同时反代的连接数，0使用默认值，-1表示不限制
+	ChunkSize        int      `yaml:"chunkSize"`        // 分块大小，单位MB，按分块并行下载和缓存
+	Parallel         int      `yaml:"parallel"`         // 每个播放连接同时下载的分块数量
+	CacheSize        int      `yaml:"cacheSize"`        // 最近播放文件的分块缓存大小，单位MB，-1表示不缓存
+}
+
+// DefaultProxyAllowHosts 默认允许反代的域名，OpenList和S3账号的地址在反代时自动允许
+var DefaultProxyAllowHosts = []string{
+	"115cdn.net", "115cdn.com", "115.com",
+	"baidupcs.com", "pcs.baidu.com",
+	"123pan.com", "123pan.cn", "123295.com", "123952.com", "123624.com", "123684.com", "123865.com", "123912.com",
+}
+
 func InitConfig() error {
 	configPath := filepath.Join(ConfigDir, "config.yml")
 	// 从配置文件加载
@@ -110,6 +132,24 @@ func InitConfig() error {
 	// if GlobalConfig.Strm.Cron == "" {
 	GlobalConfig.Strm.Cron = "30 * * * *" // 每小时30分执行
 	// }
+	if GlobalConfig.Strm.DiskCacheThreshold == 0 {
+		GlobalConfig.Strm.DiskCacheThreshold = 300000
+	}
+	if len(GlobalConfig.Proxy.AllowHosts) == 0 {
+		GlobalConfig.Proxy.AllowHosts = DefaultProxyAllowHosts
+	}
+	if GlobalConfig.Proxy.MaxConnPerClient == 0 {
+		GlobalConfig.Proxy.MaxConnPerClient = 8
+	}
+	if GlobalConfig.Proxy.ChunkSize <= 0 {
+		GlobalConfig.Proxy.ChunkSize = 4
+	}
+	if GlobalConfig.Proxy.Parallel <= 0 {
+		GlobalConfig.Proxy.Parallel = 4
+	}
+	if GlobalConfig.Proxy.CacheSize == 0 {
+		GlobalConfig.Proxy.CacheSize = 2048
+	}
 	return nil
 }
 
@@ -225,6 +265,7 @@ func MakeDefaultConfig() *Config {
 			},
 		},
 		CacheSize:     20971520,
+		LinkCache:     "db",
 		JwtSecret:     "Q115-STRM-JWT-TOKEN-250706",
 		HttpHost:      ":12333",
 		HttpsHost:     ":12332",
@@ -238,5 +279,12 @@ func MakeDefaultConfig() *Config {
 			MinVideoSize: 100,          // 100MB
 			Cron:         "30 * * * *", // 每小时30分执行
 		},
+		Proxy: ConfigProxy{
+			AllowHosts:       DefaultProxyAllowHosts,
+			MaxConnPerClient: 8,
+			ChunkSize:        4,
+			Parallel:         4,
+			CacheSize:        2048,
+		},
 	}
 }
@@ -1,6 +1,7 @@
 package helpers
 
 import (
+	"errors"
 	"log"
 	"os"
 	"path/filepath"
@@ -19,32 +20,65 @@ type AdvancedFolderWatcher struct {
 	// 事件去重
 	eventCache map[string]time.Time
 	debounce   time.Duration
+	// 事件回调，为空时只打印日志
+	handler func(event fsnotify.Event)
 }
 
-func NewAdvancedFolderWatcher(path string) *AdvancedFolderWatcher {
+// 监控的根目录被删除或移走（比如挂载断开），监控已失效
+var ErrWatchRootRemoved = errors.New("监控的根目录已被删除或移动")
+
+func NewAdvancedFolderWatcher(path string) (*AdvancedFolderWatcher, error) {
 	watcher, err := fsnotify.NewWatcher()
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
 
 	return &AdvancedFolderWatcher{
 		watcher:    watcher,
-		watchPath:  path,
+		watchPath:  filepath.Clean(path),
 		extensions: []string{".txt", ".go", ".json", ".yaml", ".yml"},
 		ignoreDirs: []string{".git", ".vscode", "node_modules", "__pycache__"},
 		eventCache: make(map[string]time.Time),
 		debounce:   100 * time.Millisecond,
-	}
+	}, nil
 }
 
-// shouldIgnore 检查是否应该忽略该路径
-func (afw *AdvancedFolderWatcher) shouldIgnore(path string) bool {
-	// 检查是否在忽略目录中
+// SetExtensions 设置监控的文件扩展名，为空则监控所有文件
+func (afw *AdvancedFolderWatcher) SetExtensions(extensions []string) {
+	afw.extensions = extensions
+}
+
+// SetIgnoreDirs 设置忽略的目录名
+func (afw *AdvancedFolderWatcher) SetIgnoreDirs(ignoreDirs []string) {
+	afw.ignoreDirs = ignoreDirs
+}
+
+// SetDebounce 设置同一路径重复事件的去重间隔
+func (afw *AdvancedFolderWatcher) SetDebounce(debounce time.Duration) {
+	afw.debounce = debounce
+}
+
+// SetHandler 设置事件回调，在Start的协程中调用
+func (afw *AdvancedFolderWatcher) SetHandler(handler func(event fsnotify.Event)) {
+	afw.handler = handler
+}
+
+// isIgnoredDir 检查路径是否在忽略目录中
+func (afw *AdvancedFolderWatcher) isIgnoredDir(path string) bool {
 	for _, ignoreDir := range afw.ignoreDirs {
 		if strings.Contains(path, ignoreDir) {
 			return true
 		}
 	}
+	return false
+}
+
+// shouldIgnore 检查是否应该忽略该路径
+func (afw *AdvancedFolderWatcher) shouldIgnore(path string) bool {
+	// 检查是否在忽略目录中
+	if afw.isIgnoredDir(path) {
+		return true
+	}
 
 	// 检查文件扩展名
 	if !afw.isWatchedExtension(path) {
@@ -77,8 +111,8 @@ func (afw *AdvancedFolderWatcher) addWatchRecursive(path string) error {
 		}
 
 		if info.IsDir() {
-			// 跳过忽略的目录
-			if afw.shouldIgnore(walkPath) {
+			// 跳过忽略的目录，目录没有扩展名，不能按扩展名过滤
+			if afw.isIgnoredDir(walkPath) {
 				return filepath.SkipDir
 			}
 
@@ -92,17 +126,29 @@ func (afw *AdvancedFolderWatcher) addWatchRecursive(path string) error {
 }
 
 // processEvent 处理事件（带去重）
-func (afw *AdvancedFolderWatcher) processEvent(event fsnotify.Event) {
+func (afw *AdvancedFolderWatcher) processEvent(event fsnotify.Event) error {
+	// 根目录被删除或移走，监控失效
+	if filepath.Clean(event.Name) == afw.watchPath && event.Op&(fsnotify.Remove|fsnotify.Rename) != 0 {
+		return ErrWatchRootRemoved
+	}
+	// 新建的目录需要加入监控，不受扩展名过滤影响
+	if event.Op&fsnotify.Create == fsnotify.Create && !afw.isIgnoredDir(event.Name) {
+		if info, err := os.Stat(event.Name); err == nil && info.IsDir() {
+			afw.addWatchRecursive(event.Name)
+			afw.dispatch(event)
+			return nil
+		}
+	}
 	// 检查是否应该忽略
 	if afw.shouldIgnore(event.Name) {
-		return
+		return nil
 	}
 
 	// 事件去重
 	now := time.Now()
 	if lastTime, exists := afw.eventCache[event.Name]; exists {
 		if now.Sub(lastTime) < afw.debounce {
-			return // 忽略短时间内重复的事件
+			return nil // 忽略短时间内重复的事件
 		}
 	}
 	afw.eventCache[event.Name] = now
@@ -115,6 +161,16 @@ func (afw *AdvancedFolderWatcher) processEvent(event fsnotify.Event) {
 	}
 
 	// 处理事件
+	afw.dispatch(event)
+	return nil
+}
+
+// dispatch 有回调时交给回调，否则打印日志
+func (afw *AdvancedFolderWatcher) dispatch(event fsnotify.Event) {
+	if afw.handler != nil {
+		afw.handler(event)
+		return
+	}
 	afw.handleEvent(event)
 }
 
@@ -140,7 +196,6 @@ func (afw *AdvancedFolderWatcher) handleCreate(event fsnotify.Event) {
 
 	if info.IsDir() {
 		log.Printf("📁 新目录创建: %s", event.Name)
-		afw.addWatchRecursive(event.Name)
 	} else {
 		log.Printf("📄 新文件创建: %s", event.Name)
 	}
@@ -162,11 +217,12 @@ func (afw *AdvancedFolderWatcher) handleRename(event fsnotify.Event) {
 	log.Printf("📝 文件重命名: %s", event.Name)
 }
 
-// Start 开始监控
-func (afw *AdvancedFolderWatcher) Start() {
+// Start 开始监控，阻塞直到监控关闭或失效
+// 调用Close正常关闭时返回nil，监控出错或根目录被移除时返回错误
+func (afw *AdvancedFolderWatcher) Start() error {
 	// 初始添加监控
 	if err := afw.addWatchRecursive(afw.watchPath); err != nil {
-		log.Fatal(err)
+		return err
 	}
 
 	log.Printf("🚀 开始高级监控目录: %s", afw.watchPath)
@@ -177,15 +233,18 @@ func (afw *AdvancedFolderWatcher) Start() {
 		select {
 		case event, ok := <-afw.watcher.Events:
 			if !ok {
-				return
+				return nil
+			}
+			if err := afw.processEvent(event); err != nil {
+				return err
 			}
-			afw.processEvent(event)
 
 		case err, ok := <-afw.watcher.Errors:
 			if !ok {
-				return
+				return nil
 			}
 			log.Printf("❌ 监控错误: %v", err)
+			return err
 		}
 	}
 }
@@ -201,7 +260,10 @@ func (afw *AdvancedFolderWatcher) Close() {
 //         watchPath = os.Args[1]
 //     }
 
-//     watcher := NewAdvancedFolderWatcher(watchPath)
+//     watcher, err := NewAdvancedFolderWatcher(watchPath)
+//     if err != nil {
+//         log.Fatal(err)
+//     }
 //     defer watcher.Close()
 
 //     watcher.Start()
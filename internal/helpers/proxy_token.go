@@ -0,0 +1,90 @@
+package helpers
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ProxyTokenTTL 本地反代地址的有效期，网盘直链本身也会过期，不需要太长
+const ProxyTokenTTL = 12 * time.Hour
+
+// proxyTokenSecret 反代地址的签名密钥，每次启动重新生成，反代地址都是播放时临时生成的
+var proxyTokenSecret = GenerateStrmSignSecret()
+
+// ProxyFile 反代的网盘文件，播放过程中直链过期时用来重新获取直链
+type ProxyFile struct {
+	Source    string // 来源：115、baidupan、openlist、123、s3，决定请求网盘使用的UA和重新获取直链的方式
+	AccountId uint   // 网盘账号ID
+	FileId    string // 115、百度网盘、123云盘是PickCode，OpenList和S3是文件路径，为空时直链过期后不能重新获取
+}
+
+func proxyToken(target string, file ProxyFile, expireAt string) string {
+	mac := hmac.New(sha256.New, []byte(proxyTokenSecret))
+	mac.Write([]byte(strings.Join([]string{target, file.Source, strconv.FormatUint(uint64(file.AccountId), 10), file.FileId, expireAt}, "\n")))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// MakeProxyUrl 生成本地反代地址，只有QMS自己生成的地址才能通过反代访问
+func MakeProxyUrl(target string, file ProxyFile) string {
+	exp := strconv.FormatInt(time.Now().Add(ProxyTokenTTL).Unix(), 10)
+	query := url.Values{}
+	query.Set("url", target)
+	query.Set("source", file.Source)
+	if file.AccountId != 0 {
+		query.Set("aid", strconv.FormatUint(uint64(file.AccountId), 10))
+	}
+	if file.FileId != "" {
+		query.Set("fid", file.FileId)
+	}
+	query.Set("exp", exp)
+	query.Set("token", proxyToken(target, file, exp))
+	return "/proxy-115?" + query.Encode()
+}
+
+// ParseProxyFile 从反代地址中解析网盘文件
+func ParseProxyFile(query url.Values) ProxyFile {
+	accountId, _ := strconv.ParseUint(query.Get("aid"), 10, 64)
+	return ProxyFile{Source: query.Get("source"), AccountId: uint(accountId), FileId: query.Get("fid")}
+}
+
+// VerifyProxyToken 校验反代地址的签名
+func VerifyProxyToken(query url.Values, now time.Time) error {
+	token := query.Get("token")
+	if token == "" {
+		return fmt.Errorf("缺少token参数")
+	}
+	exp := query.Get("exp")
+	expireAt, err := strconv.ParseInt(exp, 10, 64)
+	if err != nil {
+		return fmt.Errorf("exp参数无效")
+	}
+	if !hmac.Equal([]byte(token), []byte(proxyToken(query.Get("url"), ParseProxyFile(query), exp))) {
+		return fmt.Errorf("token无效")
+	}
+	if now.Unix() > expireAt {
+		return fmt.Errorf("反代地址已过期")
+	}
+	return nil
+}
+
+// IsProxyHostAllowed 反代的目标地址是否在允许的域名列表中，允许列表中的域名同时匹配所有子域名
+func IsProxyHostAllowed(target string, allowHosts []string) bool {
+	u, err := url.Parse(target)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return false
+	}
+	host := strings.ToLower(u.Hostname())
+	for _, allow := range allowHosts {
+		allow = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(allow), "."))
+		if allow != "" && (host == allow || strings.HasSuffix(host, "."+allow)) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,182 @@
+package helpers
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// STRM签名参数，sp是同步路径ID，exp是过期时间戳（0不过期），sig是签名
+const (
+	StrmSignPathParam   = "sp"
+	StrmSignExpireParam = "exp"
+	StrmSignParam       = "sig"
+)
+
+// strmSignPaths 需要签名的QMS直链接口，这些接口不需要登录
+var strmSignPaths = []string{"/115/url/", "/115/newurl", "/baidupan/url/", "/123/url/", "/openlist/url", "/webdav/url/", "/s3/url/"}
+
+// strmSignFields 参与签名的参数，播放器追加的其他参数（比如force=1）不影响签名
+var strmSignFields = []string{"pickcode", "userid", "account_id", "path"}
+
+// GenerateStrmSignSecret 生成STRM签名密钥
+func GenerateStrmSignSecret() string {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return RandStr(64)
+	}
+	return hex.EncodeToString(b)
+}
+
+// IsSignableStrmUrl 是否是需要签名的QMS直链地址
+func IsSignableStrmUrl(rawUrl string) bool {
+	u, err := url.Parse(strings.TrimSpace(rawUrl))
+	if err != nil || u.Host == "" {
+		return false
+	}
+	for _, p := range strmSignPaths {
+		if strings.Contains(u.Path+"/", p) {
+			return true
+		}
+	}
+	return false
+}
+
+func strmSign(query url.Values, syncPathId, expireAt, secret string) string {
+	var sb strings.Builder
+	sb.WriteString(StrmSignPathParam + "=" + syncPathId + "\n")
+	sb.WriteString(StrmSignExpireParam + "=" + expireAt + "\n")
+	for _, field := range strmSignFields {
+		sb.WriteString(field + "=" + query.Get(field) + "\n")
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(sb.String()))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// StripStrmSign 去掉地址中的签名参数，其他参数保持原样（path参数有自己的编码方式，不能重新编码）
+func StripStrmSign(rawUrl string) string {
+	rawUrl = strings.TrimSpace(rawUrl)
+	base, rawQuery, found := strings.Cut(rawUrl, "?")
+	if !found {
+		return rawUrl
+	}
+	params := make([]string, 0)
+	for _, param := range strings.Split(rawQuery, "&") {
+		key, _, _ := strings.Cut(param, "=")
+		if key == StrmSignPathParam || key == StrmSignExpireParam || key == StrmSignParam {
+			continue
+		}
+		params = append(params, param)
+	}
+	if len(params) == 0 {
+		return base
+	}
+	return base + "?" + strings.Join(params, "&")
+}
+
+// SignStrmUrl 给STRM地址签名，expireAt为0表示不过期，不是QMS直链地址的原样返回
+func SignStrmUrl(rawUrl string, syncPathId uint, secret string, expireAt int64) string {
+	if secret == "" || !IsSignableStrmUrl(rawUrl) {
+		return rawUrl
+	}
+	rawUrl = StripStrmSign(rawUrl)
+	u, err := url.Parse(rawUrl)
+	if err != nil {
+		return rawUrl
+	}
+	id := strconv.FormatUint(uint64(syncPathId), 10)
+	exp := strconv.FormatInt(expireAt, 10)
+	sep := "&"
+	if u.RawQuery == "" {
+		sep = "?"
+	}
+	return fmt.Sprintf("%s%s%s=%s&%s=%s&%s=%s", rawUrl, sep, StrmSignPathParam, id, StrmSignExpireParam, exp, StrmSignParam, strmSign(u.Query(), id, exp, secret))
+}
+
+// SignStrmQuery 给QMS直链地址的参数签名，用于QMS自己生成的跳转地址
+func SignStrmQuery(query url.Values, syncPathId uint, secret string, expireAt int64) {
+	if secret == "" {
+		return
+	}
+	id := strconv.FormatUint(uint64(syncPathId), 10)
+	exp := strconv.FormatInt(expireAt, 10)
+	query.Set(StrmSignPathParam, id)
+	query.Set(StrmSignExpireParam, exp)
+	query.Set(StrmSignParam, strmSign(query, id, exp, secret))
+}
+
+// GetStrmSignPathId 获取签名地址中的同步路径ID，没有签名返回false
+func GetStrmSignPathId(query url.Values) (uint, bool) {
+	if query.Get(StrmSignParam) == "" {
+		return 0, false
+	}
+	id, err := strconv.ParseUint(query.Get(StrmSignPathParam), 10, 64)
+	if err != nil {
+		return 0, true
+	}
+	return uint(id), true
+}
+
+// VerifyStrmSign 校验STRM地址的签名，任意一个密钥校验通过即可（密钥轮换期间新旧密钥都有效）
+func VerifyStrmSign(query url.Values, now time.Time, secrets ...string) error {
+	sig := query.Get(StrmSignParam)
+	if sig == "" {
+		return fmt.Errorf("缺少签名")
+	}
+	exp := query.Get(StrmSignExpireParam)
+	expireAt, err := strconv.ParseInt(exp, 10, 64)
+	if err != nil {
+		return fmt.Errorf("签名过期时间无效")
+	}
+	for _, secret := range secrets {
+		if secret != "" && hmac.Equal([]byte(sig), []byte(strmSign(query, query.Get(StrmSignPathParam), exp, secret))) {
+			if expireAt > 0 && now.Unix() > expireAt {
+				return fmt.Errorf("签名已过期")
+			}
+			return nil
+		}
+	}
+	return fmt.Errorf("签名无效")
+}
+
+// StrmSignExpireAt 按有效期天数计算签名的过期时间，expireDays<=0表示不过期，返回0
+func StrmSignExpireAt(expireDays int, now time.Time) int64 {
+	if expireDays <= 0 {
+		return 0
+	}
+	return now.AddDate(0, 0, expireDays).Unix()
+}
+
+// StrmSignNeedsRefresh 判断STRM地址是否需要重新签名：没有签名、签名无效、有效期设置变化或者剩余有效期不足一半
+func StrmSignNeedsRefresh(rawUrl string, secret string, expireDays int, now time.Time) bool {
+	u, err := url.Parse(strings.TrimSpace(rawUrl))
+	if err != nil {
+		return false
+	}
+	query := u.Query()
+	if secret == "" {
+		// 关闭了签名，有签名的需要去掉
+		return query.Get(StrmSignParam) != ""
+	}
+	if !IsSignableStrmUrl(rawUrl) {
+		return false
+	}
+	if VerifyStrmSign(query, now, secret) != nil {
+		return true
+	}
+	expireAt, _ := strconv.ParseInt(query.Get(StrmSignExpireParam), 10, 64)
+	if expireDays <= 0 {
+		return expireAt != 0
+	}
+	if expireAt == 0 {
+		return true
+	}
+	return expireAt-now.Unix() < int64(expireDays)*86400/2
+}
@@ -0,0 +1,60 @@
+package helpers
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStrmSign(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	raw := "http://qms:12333/115/url/video.mkv?pickcode=abc&userid=1&path=%E7%94%B5%E5%BD%B1%2Fa%20b.mkv"
+	signed := SignStrmUrl(raw, 3, "secret", now.Add(48*time.Hour).Unix())
+	if !strings.HasPrefix(signed, raw+"&sp=3&exp=") {
+		t.Fatalf("signed url = %q, want original params kept", signed)
+	}
+	if StripStrmSign(signed) != raw {
+		t.Fatalf("StripStrmSign = %q, want %q", StripStrmSign(signed), raw)
+	}
+
+	query := func(u string) url.Values {
+		parsed, _ := url.Parse(u)
+		return parsed.Query()
+	}
+	// 播放器追加的参数不影响签名，轮换期间旧密钥继续有效
+	if err := VerifyStrmSign(query(signed+"&force=1"), now, "new", "secret"); err != nil {
+		t.Fatalf("VerifyStrmSign = %v", err)
+	}
+	if err := VerifyStrmSign(query(strings.Replace(signed, "pickcode=abc", "pickcode=abd", 1)), now, "secret"); err == nil {
+		t.Fatal("tampered pickcode passed verification")
+	}
+	if err := VerifyStrmSign(query(signed), now, "other"); err == nil {
+		t.Fatal("wrong secret passed verification")
+	}
+	if err := VerifyStrmSign(query(signed), now.Add(72*time.Hour), "secret"); err == nil {
+		t.Fatal("expired signature passed verification")
+	}
+
+	// 直接签名参数的结果和签名地址相同
+	params := url.Values{"pickcode": {"abc"}, "userid": {"1"}}
+	SignStrmQuery(params, 3, "secret", 0)
+	if err := VerifyStrmSign(params, now, "secret"); err != nil || params.Get(StrmSignPathParam) != "3" {
+		t.Fatalf("SignStrmQuery = %v, %v", params, err)
+	}
+
+	// 非QMS直链接口的地址不签名
+	if local := "/mnt/media/a.mkv"; SignStrmUrl(local, 3, "secret", 0) != local {
+		t.Fatal("local path was signed")
+	}
+
+	if StrmSignNeedsRefresh(signed, "secret", 2, now) {
+		t.Fatal("fresh signature needs refresh")
+	}
+	if !StrmSignNeedsRefresh(signed, "secret", 2, now.Add(30*time.Hour)) {
+		t.Fatal("signature past half its life does not need refresh")
+	}
+	if !StrmSignNeedsRefresh(signed, "new", 2, now) || !StrmSignNeedsRefresh(raw, "secret", 0, now) || !StrmSignNeedsRefresh(signed, "", 0, now) {
+		t.Fatal("rotated, unsigned or disabled signature does not need refresh")
+	}
+}
@@ -363,6 +363,10 @@ func (bot *TelegramBot) StartListening(ctx context.Context, handleCommand map[st
 					• 格式: /scrape_strm #刮削目录序号 #同步目录序号
 					• 格式: /strm_scrape #同步目录序号 #刮削目录序号
 					• 若参数为 #0，则对所有目录执行任务
+
+					🛡️ <b>删除保护命令：</b>  
+					• 格式: /strm_delete_approve #删除批次序号 批准删除
+					• 格式: /strm_delete_reject #删除批次序号 拒绝删除
 					`
 			case "status":
 				responseText = "📊 <b>系统状态</b>\n运行中: OK\n时间: " + time.Now().Format("2006-01-02 15:04:05")
@@ -0,0 +1,62 @@
+package linkcache
+
+import (
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 解析unix时间戳，只接受合理范围内的秒级时间戳
+func parseUnix(value string) (time.Time, bool) {
+	ts, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || ts < 1e9 || ts > 1e11 {
+		return time.Time{}, false
+	}
+	return time.Unix(ts, 0), true
+}
+
+// ParseExpiry 从签名链接中解析过期时间，支持：
+//   - 115：t=过期时间戳
+//   - 百度网盘：expires=8h，从time或dstime开始计算
+//   - 123云盘：auth_key=过期时间戳-随机数-uid-签名
+//   - S3：X-Amz-Date + X-Amz-Expires
+//   - 其他：expires=过期时间戳
+func ParseExpiry(rawUrl string, now time.Time) (time.Time, bool) {
+	u, err := url.Parse(rawUrl)
+	if err != nil {
+		return time.Time{}, false
+	}
+	query := u.Query()
+	if t, ok := parseUnix(query.Get("t")); ok {
+		return t, true
+	}
+	expires := query.Get("expires")
+	if expires == "" {
+		expires = query.Get("Expires")
+	}
+	if d, err := time.ParseDuration(expires); err == nil && d > 0 {
+		for _, name := range []string{"time", "dstime"} {
+			if start, ok := parseUnix(query.Get(name)); ok {
+				return start.Add(d), true
+			}
+		}
+		return now.Add(d), true
+	}
+	if authKey := query.Get("auth_key"); authKey != "" {
+		if t, ok := parseUnix(strings.SplitN(authKey, "-", 2)[0]); ok {
+			return t, true
+		}
+	}
+	if amzDate := query.Get("X-Amz-Date"); amzDate != "" {
+		start, err := time.Parse("20060102T150405Z", amzDate)
+		seconds, serr := strconv.Atoi(query.Get("X-Amz-Expires"))
+		if err == nil && serr == nil && seconds > 0 {
+			return start.Add(time.Duration(seconds) * time.Second), true
+		}
+	}
+	if t, ok := parseUnix(expires); ok {
+		return t, true
+	}
+	return time.Time{}, false
+}
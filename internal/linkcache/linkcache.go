@@ -0,0 +1,194 @@
+package linkcache
+
+import (
+	"Q115-STRM/internal/helpers"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// 链接在过期前这么久就不再使用，避免播放器拿到马上失效的链接
+const ExpiryMargin = 5 * time.Minute
+
+// Entry 一条缓存的直链
+type Entry struct {
+	Source    string    `json:"source"`    // 来源：115、baidupan、123
+	PickCode  string    `json:"pick_code"` // 文件PickCode（百度网盘为fs_id，123为文件ID）
+	UA        string    `json:"ua"`        // 获取链接时使用的UA，链接和UA绑定
+	AccountId uint      `json:"account_id"`
+	Url       string    `json:"url"`
+	ExpiresAt time.Time `json:"expires_at"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Key 缓存键，同一个文件不同UA分开缓存
+func Key(source, pickCode, ua string) string {
+	return fmt.Sprintf("%s:%s, ua=%s", source, pickCode, ua)
+}
+
+func (e *Entry) Key() string {
+	return Key(e.Source, e.PickCode, e.UA)
+}
+
+// 是否可以使用（没有过期且不在过期前的保留时间内）
+func (e *Entry) usable(now time.Time) bool {
+	return now.Add(ExpiryMargin).Before(e.ExpiresAt)
+}
+
+// Store 直链缓存的存储后端
+type Store interface {
+	// Get 查询一条记录，不存在时返回nil
+	Get(key string) (*Entry, error)
+	// Set 写入记录，已存在时覆盖
+	Set(entry *Entry) error
+	// DeleteByPickCode 删除文件的所有记录（所有UA），source为空时不限来源
+	DeleteByPickCode(source, pickCode string) (int64, error)
+	// List 分页查询记录，source、pickCode为空时不过滤
+	List(source, pickCode string, page, pageSize int) ([]*Entry, int64, error)
+	// DeleteExpired 删除已过期的记录
+	DeleteExpired(now time.Time) (int64, error)
+	// DeleteAll 删除所有记录，source为空时不限来源
+	DeleteAll(source string) (int64, error)
+}
+
+// Cache 内存缓存在前，持久化存储在后，持久化存储为nil时只使用内存
+type Cache struct {
+	sync.RWMutex
+	memory *MemoryStore
+	store  Store
+}
+
+// NewCache 创建直链缓存
+func NewCache(store Store) *Cache {
+	return &Cache{memory: NewMemoryStore(), store: store}
+}
+
+var defaultCache = NewCache(nil)
+
+// SetStore 设置持久化存储后端，nil只使用内存
+func SetStore(store Store) {
+	defaultCache.Lock()
+	defer defaultCache.Unlock()
+	defaultCache.store = store
+}
+
+func (c *Cache) backend() Store {
+	c.RLock()
+	defer c.RUnlock()
+	return c.store
+}
+
+// Get 查询可用的直链，没有或即将过期时返回nil
+func (c *Cache) Get(source, pickCode, ua string) *Entry {
+	key := Key(source, pickCode, ua)
+	now := time.Now()
+	if entry, _ := c.memory.Get(key); entry != nil && entry.usable(now) {
+		return entry
+	}
+	store := c.backend()
+	if store == nil {
+		return nil
+	}
+	entry, err := store.Get(key)
+	if err != nil {
+		helpers.AppLogger.Warnf("查询直链缓存失败: %s %v", key, err)
+		return nil
+	}
+	if entry == nil || !entry.usable(now) {
+		return nil
+	}
+	// 重启后第一次命中持久化记录，放入内存
+	c.memory.Set(entry)
+	return entry
+}
+
+// Set 缓存直链，过期时间从签名链接中解析，解析不到或超过maxTTL时使用maxTTL
+func (c *Cache) Set(source, pickCode, ua string, accountId uint, url string, maxTTL time.Duration) *Entry {
+	now := time.Now()
+	expiresAt := now.Add(maxTTL)
+	if parsed, ok := ParseExpiry(url, now); ok && parsed.Before(expiresAt) {
+		expiresAt = parsed
+	}
+	entry := &Entry{
+		Source:    source,
+		PickCode:  pickCode,
+		UA:        ua,
+		AccountId: accountId,
+		Url:       url,
+		ExpiresAt: expiresAt,
+		CreatedAt: now,
+	}
+	c.memory.Set(entry)
+	if store := c.backend(); store != nil {
+		if err := store.Set(entry); err != nil {
+			helpers.AppLogger.Warnf("写入直链缓存失败: %s %v", entry.Key(), err)
+		}
+	}
+	return entry
+}
+
+// Evict 删除文件的所有缓存直链，source为空时不限来源
+func (c *Cache) Evict(source, pickCode string) (int64, error) {
+	count, _ := c.memory.DeleteByPickCode(source, pickCode)
+	if store := c.backend(); store != nil {
+		return store.DeleteByPickCode(source, pickCode)
+	}
+	return count, nil
+}
+
+// List 分页查询缓存的直链，有持久化存储时查询持久化存储
+func (c *Cache) List(source, pickCode string, page, pageSize int) ([]*Entry, int64, error) {
+	if store := c.backend(); store != nil {
+		return store.List(source, pickCode, page, pageSize)
+	}
+	return c.memory.List(source, pickCode, page, pageSize)
+}
+
+// Purge 清空缓存，source为空时清空所有来源
+func (c *Cache) Purge(source string) (int64, error) {
+	count, _ := c.memory.DeleteAll(source)
+	if store := c.backend(); store != nil {
+		return store.DeleteAll(source)
+	}
+	return count, nil
+}
+
+// PurgeExpired 清理过期的直链
+func (c *Cache) PurgeExpired() (int64, error) {
+	now := time.Now()
+	count, _ := c.memory.DeleteExpired(now)
+	if store := c.backend(); store != nil {
+		return store.DeleteExpired(now)
+	}
+	return count, nil
+}
+
+// Get 从默认缓存查询直链
+func Get(source, pickCode, ua string) *Entry {
+	return defaultCache.Get(source, pickCode, ua)
+}
+
+// Set 写入默认缓存
+func Set(source, pickCode, ua string, accountId uint, url string, maxTTL time.Duration) *Entry {
+	return defaultCache.Set(source, pickCode, ua, accountId, url, maxTTL)
+}
+
+// Evict 从默认缓存删除文件的所有直链
+func Evict(source, pickCode string) (int64, error) {
+	return defaultCache.Evict(source, pickCode)
+}
+
+// List 分页查询默认缓存
+func List(source, pickCode string, page, pageSize int) ([]*Entry, int64, error) {
+	return defaultCache.List(source, pickCode, page, pageSize)
+}
+
+// Purge 清空默认缓存
+func Purge(source string) (int64, error) {
+	return defaultCache.Purge(source)
+}
+
+// PurgeExpired 清理默认缓存中过期的直链
+func PurgeExpired() (int64, error) {
+	return defaultCache.PurgeExpired()
+}
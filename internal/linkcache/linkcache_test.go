@@ -0,0 +1,83 @@
+package linkcache
+
+import (
+	"Q115-STRM/internal/helpers"
+	"io"
+	"log"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func init() {
+	helpers.AppLogger = &helpers.QLogger{Logger: log.New(io.Discard, "", 0)}
+}
+
+func TestParseExpiry(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	cases := []struct {
+		url    string
+		expiry int64
+		ok     bool
+	}{
+		{"https://cdnfhnfile.115cdn.net/a.mkv?t=1700007200&u=1&s=2", 1700007200, true},
+		{"https://d.pcs.baidu.com/file/x?fid=1&expires=8h&time=1700000100&sign=x", 1700000100 + 8*3600, true},
+		{"https://d.pcs.baidu.com/file/x?fid=1&expires=8h&dstime=1700000200", 1700000200 + 8*3600, true},
+		{"https://d.pcs.baidu.com/file/x?expires=1h", 1700000000 + 3600, true},
+		{"https://download-cdn.123295.com/x?auth_key=1700001800-123-0-abcdef", 1700001800, true},
+		{"https://bucket.s3.amazonaws.com/x?X-Amz-Date=20231114T221320Z&X-Amz-Expires=600", 1700000000 + 600, true},
+		{"https://oss.example.com/x?Expires=1700003600&Signature=x", 1700003600, true},
+		{"https://example.com/x?t=abc", 0, false},
+		{"https://example.com/x", 0, false},
+		{"://bad", 0, false},
+	}
+	for _, c := range cases {
+		got, ok := ParseExpiry(c.url, now)
+		if ok != c.ok || (ok && got.Unix() != c.expiry) {
+			t.Errorf("ParseExpiry(%q) = %d, %v, want %d, %v", c.url, got.Unix(), ok, c.expiry, c.ok)
+		}
+	}
+}
+
+func TestCachePersistence(t *testing.T) {
+	store := NewMemoryStore()
+	cache := NewCache(store)
+	expires := time.Now().Add(time.Hour).Unix()
+	link := "https://cdn.115.com/a.mkv?t=" + strconv.FormatInt(expires, 10)
+	entry := cache.Set("115", "pc1", "ua1", 7, link, 2*time.Hour)
+	if entry.ExpiresAt.Unix() != expires {
+		t.Fatalf("expiry = %v, want parsed from url", entry.ExpiresAt)
+	}
+	cache.Set("115", "pc1", "ua2", 7, link, 2*time.Hour)
+	cache.Set("baidupan", "pc1", "ua1", 8, "https://d.pcs.baidu.com/x", time.Hour)
+
+	// 模拟重启：新的内存缓存，同一个持久化存储
+	restarted := NewCache(store)
+	if got := restarted.Get("115", "pc1", "ua1"); got == nil || got.Url != link || got.AccountId != 7 {
+		t.Fatalf("Get after restart = %+v", got)
+	}
+	if got := restarted.Get("115", "pc1", "other"); got != nil {
+		t.Fatalf("Get with other ua = %+v", got)
+	}
+
+	// 即将过期的链接不再使用
+	soon := "https://cdn.115.com/b.mkv?t=" + strconv.FormatInt(time.Now().Add(time.Minute).Unix(), 10)
+	restarted.Set("115", "pc2", "ua1", 7, soon, 2*time.Hour)
+	if got := restarted.Get("115", "pc2", "ua1"); got != nil {
+		t.Fatalf("Get returned a link about to expire: %+v", got)
+	}
+
+	if n, err := restarted.Evict("115", "pc1"); err != nil || n != 2 {
+		t.Fatalf("Evict = %d, %v, want 2", n, err)
+	}
+	if got := NewCache(store).Get("115", "pc1", "ua1"); got != nil {
+		t.Fatal("evicted entry still in persistent store")
+	}
+	entries, total, _ := restarted.List("", "", 1, 10)
+	if total != 2 || len(entries) != 2 {
+		t.Fatalf("List = %d entries, total %d", len(entries), total)
+	}
+	if n, _ := restarted.Purge("baidupan"); n != 1 {
+		t.Fatalf("Purge(baidupan) = %d, want 1", n)
+	}
+}
@@ -0,0 +1,76 @@
+package linkcache
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// MemoryStore 内存存储，重启后丢失
+type MemoryStore struct {
+	sync.RWMutex
+	entries map[string]*Entry
+}
+
+// NewMemoryStore 创建内存存储
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{entries: make(map[string]*Entry)}
+}
+
+func (m *MemoryStore) Get(key string) (*Entry, error) {
+	m.RLock()
+	defer m.RUnlock()
+	return m.entries[key], nil
+}
+
+func (m *MemoryStore) Set(entry *Entry) error {
+	m.Lock()
+	defer m.Unlock()
+	m.entries[entry.Key()] = entry
+	return nil
+}
+
+// 删除满足条件的记录
+func (m *MemoryStore) deleteWhere(match func(*Entry) bool) int64 {
+	m.Lock()
+	defer m.Unlock()
+	var count int64
+	for key, entry := range m.entries {
+		if match(entry) {
+			delete(m.entries, key)
+			count++
+		}
+	}
+	return count
+}
+
+func (m *MemoryStore) DeleteByPickCode(source, pickCode string) (int64, error) {
+	return m.deleteWhere(func(e *Entry) bool {
+		return e.PickCode == pickCode && (source == "" || e.Source == source)
+	}), nil
+}
+
+func (m *MemoryStore) DeleteExpired(now time.Time) (int64, error) {
+	return m.deleteWhere(func(e *Entry) bool { return !e.ExpiresAt.After(now) }), nil
+}
+
+func (m *MemoryStore) DeleteAll(source string) (int64, error) {
+	return m.deleteWhere(func(e *Entry) bool { return source == "" || e.Source == source }), nil
+}
+
+func (m *MemoryStore) List(source, pickCode string, page, pageSize int) ([]*Entry, int64, error) {
+	m.RLock()
+	entries := make([]*Entry, 0, len(m.entries))
+	for _, entry := range m.entries {
+		if (source == "" || entry.Source == source) && (pickCode == "" || entry.PickCode == pickCode) {
+			entries = append(entries, entry)
+		}
+	}
+	m.RUnlock()
+	// 最新的在前
+	sort.Slice(entries, func(i, j int) bool { return entries[i].CreatedAt.After(entries[j].CreatedAt) })
+	total := int64(len(entries))
+	start := min(max(page-1, 0)*pageSize, len(entries))
+	end := min(start+pageSize, len(entries))
+	return entries[start:end], total, nil
+}
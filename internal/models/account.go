@@ -5,26 +5,44 @@ import (
 	"Q115-STRM/internal/db"
 	"Q115-STRM/internal/helpers"
 	"Q115-STRM/internal/notificationmanager"
+	"Q115-STRM/internal/open123"
 	"Q115-STRM/internal/openlist"
+	"Q115-STRM/internal/s3"
+	"Q115-STRM/internal/scheduler"
 	"Q115-STRM/internal/v115open"
+	"Q115-STRM/internal/webdav"
 	"context"
 	"fmt"
 	"time"
+
+	"gorm.io/gorm"
 )
 
 type Account struct {
 	BaseModel
 	Name              string     `json:"name"` // 账号备注，仅供用户自己识别账号使用，唯一
 	SourceType        SourceType `json:"source_type"`
-	AppId             string     `json:"app_id"`
+	AppId             string     `json:"app_id"`                        // 123开放平台的clientID或者S3的AccessKey
+	AppSecret         string     `json:"-" gorm:"type:string;size:256"` // 123开放平台的clientSecret或者S3的SecretKey
 	Token             string     `json:"token" gorm:"type:string;size:512"`
 	RefreshToken      string     `json:"refresh_token" gorm:"type:string;size:512"`
 	TokenExpiriesTime int64      `json:"token_expiries_time"`
 	UserId            string     `json:"user_id"`                                         // 账号对应的用户id，唯一
 	Username          string     `json:"username" gorm:"type:string;size:32"`             // 网盘对应的用户名或者openlist的登录用户名
 	Password          string     `json:"password" gorm:"type:string;size:256"`            // openlist的用户密码
-	BaseUrl           string     `json:"base_url" gorm:"type:string;size:1024"`           // openlist的访问地址http[s]://ip:port
+	BaseUrl           string     `json:"base_url" gorm:"type:string;size:1024"`           // openlist的访问地址http[s]://ip:port，S3的endpoint
 	TokenFailedReason string     `json:"token_failed_reason" gorm:"type:string;size:256"` // 刷新token失败的原因
+	DirectLink        bool       `json:"direct_link"`                                     // WebDAV的STRM直接使用WebDAV地址，只支持没有账号密码的WebDAV，否则通过QMS代理播放
+	Bucket            string     `json:"bucket" gorm:"type:string;size:256"`              // S3的存储桶
+	Region            string     `json:"region" gorm:"type:string;size:64"`               // S3的区域，为空时使用us-east-1
+	PathStyle         bool       `json:"path_style"`                                      // S3使用路径风格访问（endpoint/bucket/key），MinIO一般需要开启
+	QPS               int        `json:"qps"`                                             // 账号单独的接口速率限制，全部为0时使用默认值
+	QPM               int        `json:"qpm"`
+	QPH               int        `json:"qph"`
+	LearnedQPS        int        `json:"learned_qps"` // 自适应速率控制学习到的安全速率，重启后恢复
+	LearnedQPM        int        `json:"learned_qpm"`
+	LearnedQPH        int        `json:"learned_qph"`
+	FailoverOrder     int        `json:"failover_order"` // 115直链故障转移时使用其他账号的顺序，数值越小越优先，0表示未设置（排在最后）
 }
 
 func (account *Account) TableName() string {
@@ -70,17 +88,102 @@ func (account *Account) UpdateUser(userId string, username string) bool {
 
 // 如果是normal模式，创建一个新的客户端，不启用限速器
 func (account *Account) Get115Client() *v115open.OpenClient {
+	account.applyRateLimits()
 	return v115open.GetClient(account.ID, account.AppId, account.Token, account.RefreshToken)
 }
 
+// 把账号单独配置的速率限制应用到调度器
+func (account *Account) applyRateLimits() {
+	scheduler.SetAccountLimits(string(account.SourceType), account.ID, account.RateLimits())
+	if account.SourceType == SourceType115 {
+		// 115账号的Worker数量跟随速率限制
+		v115open.GetExecutor(account.ID).RefreshWorkers()
+	}
+}
+
+// RateLimits 账号单独配置的速率限制
+func (account *Account) RateLimits() scheduler.Limits {
+	return scheduler.Limits{QPS: account.QPS, QPM: account.QPM, QPH: account.QPH}
+}
+
+// LearnedLimits 自适应速率控制学习到的速率
+func (account *Account) LearnedLimits() scheduler.Limits {
+	return scheduler.Limits{QPS: account.LearnedQPS, QPM: account.LearnedQPM, QPH: account.LearnedQPH}
+}
+
+// SaveLearnedLimits 保存调度器学习到的速率
+func SaveLearnedLimits(source string, accountId uint, learned scheduler.Limits) {
+	updateData := map[string]any{"learned_qps": learned.QPS, "learned_qpm": learned.QPM, "learned_qph": learned.QPH}
+	if err := db.Db.Model(&Account{}).Where("id = ? AND source_type = ?", accountId, source).Updates(updateData).Error; err != nil {
+		helpers.AppLogger.Errorf("保存账号 %d 学习到的速率失败: %v", accountId, err)
+	}
+}
+
+// LoadLearnedLimits 读取账号保存的学习速率，创建调度器时恢复
+func LoadLearnedLimits(source string, accountId uint) scheduler.Limits {
+	account := &Account{}
+	if err := db.Db.Select("learned_qps", "learned_qpm", "learned_qph").Where("id = ? AND source_type = ?", accountId, source).First(account).Error; err != nil {
+		return scheduler.Limits{}
+	}
+	return account.LearnedLimits()
+}
+
+// SetFailoverOrder 设置115直链故障转移的账号顺序，没有列出的115账号排在最后
+func SetFailoverOrder(accountIds []uint) error {
+	return db.Db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&Account{}).Where("source_type = ?", SourceType115).Update("failover_order", 0).Error; err != nil {
+			return err
+		}
+		for i, id := range accountIds {
+			result := tx.Model(&Account{}).Where("id = ? AND source_type = ?", id, SourceType115).Update("failover_order", i+1)
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected == 0 {
+				return fmt.Errorf("115账号 %d 不存在", id)
+			}
+		}
+		return nil
+	})
+}
+
+// UpdateRateLimits 更新账号单独的速率限制，全部为0时使用默认值
+func (account *Account) UpdateRateLimits(limits scheduler.Limits) error {
+	account.QPS = limits.QPS
+	account.QPM = limits.QPM
+	account.QPH = limits.QPH
+	updateData := map[string]any{"qps": limits.QPS, "qpm": limits.QPM, "qph": limits.QPH}
+	if err := db.Db.Model(account).Where("id = ?", account.ID).Updates(updateData).Error; err != nil {
+		helpers.AppLogger.Errorf("更新账号 %d 的速率限制失败: %v", account.ID, err)
+		return err
+	}
+	account.applyRateLimits()
+	return nil
+}
+
 func (account *Account) GetOpenListClient() *openlist.Client {
+	account.applyRateLimits()
 	return openlist.NewClient(account.ID, account.BaseUrl, account.Username, account.Password, account.Token)
 }
 
 func (account *Account) GetBaiDuPanClient() *baidupan.Client {
+	account.applyRateLimits()
 	return baidupan.NewBaiDuPanClient(account.ID, account.Token)
 }
 
+func (account *Account) Get123Client() *open123.Client {
+	account.applyRateLimits()
+	return open123.GetClient(account.ID, account.AppId, account.AppSecret, account.Token, account.TokenExpiriesTime)
+}
+
+func (account *Account) GetWebDAVClient() *webdav.Client {
+	return webdav.NewClient(account.ID, account.BaseUrl, account.Username, account.Password)
+}
+
+func (account *Account) GetS3Client() *s3.Client {
+	return s3.NewClient(account.ID, account.BaseUrl, account.Region, account.Bucket, account.AppId, account.AppSecret, account.PathStyle)
+}
+
 func (account *Account) Delete() error {
 	// 检查是否有关联的同步目录没有删除
 	syncPaths := GetAllSyncPathByAccountId(account.ID)
@@ -159,6 +262,90 @@ func (account *Account) UpdateOpenList(baseUrl string, username string, password
 	return nil
 }
 
+// 创建或更新WebDAV账号，保存前先验证地址和账号密码
+// id为0时创建，否则更新
+func SaveWebDAVAccount(id uint, name string, baseUrl string, username string, password string, directLink bool) (*Account, error) {
+	account := &Account{}
+	if id != 0 {
+		var err error
+		if account, err = GetAccountById(id); err != nil {
+			return nil, err
+		}
+		if account.SourceType != SourceTypeWebDAV {
+			return nil, fmt.Errorf("账号 %d 不是WebDAV账号", id)
+		}
+	}
+	// 使用临时客户端验证，避免验证失败时修改缓存的客户端
+	client := webdav.NewClient(0, baseUrl, username, password)
+	if _, err := client.Stat(context.Background(), "/"); err != nil {
+		helpers.AppLogger.Errorf("验证WebDAV账号失败: %v", err)
+		return nil, err
+	}
+	if name == "" {
+		name = username
+	}
+	account.Name = name
+	account.SourceType = SourceTypeWebDAV
+	account.BaseUrl = baseUrl
+	account.Username = username
+	account.Password = password
+	account.UserId = username
+	// 直链会写入STRM，有账号密码时只能通过QMS代理播放
+	account.DirectLink = directLink && username == "" && password == ""
+	if err := db.Db.Save(account).Error; err != nil {
+		helpers.AppLogger.Errorf("保存WebDAV账号失败: %v", err)
+		return nil, err
+	}
+	// 更新缓存的客户端
+	account.GetWebDAVClient()
+	helpers.AppLogger.Infof("保存WebDAV账号成功，地址：%s，用户名：%s", account.BaseUrl, account.Username)
+	return account, nil
+}
+
+// 创建或更新S3账号，保存前先验证存储桶和密钥
+// id为0时创建，否则更新；更新时secretKey为空表示不修改
+func SaveS3Account(id uint, name string, endpoint string, region string, bucket string, accessKey string, secretKey string, pathStyle bool) (*Account, error) {
+	account := &Account{}
+	if id != 0 {
+		var err error
+		if account, err = GetAccountById(id); err != nil {
+			return nil, err
+		}
+		if account.SourceType != SourceTypeS3 {
+			return nil, fmt.Errorf("账号 %d 不是S3账号", id)
+		}
+		if secretKey == "" {
+			secretKey = account.AppSecret
+		}
+	}
+	// 使用临时客户端验证，避免验证失败时修改缓存的客户端
+	client := s3.NewClient(0, endpoint, region, bucket, accessKey, secretKey, pathStyle)
+	if _, err := client.Stat(context.Background(), "/"); err != nil {
+		helpers.AppLogger.Errorf("验证S3账号失败: %v", err)
+		return nil, err
+	}
+	if name == "" {
+		name = bucket
+	}
+	account.Name = name
+	account.SourceType = SourceTypeS3
+	account.BaseUrl = client.Endpoint
+	account.Region = client.Region
+	account.Bucket = bucket
+	account.AppId = accessKey
+	account.AppSecret = secretKey
+	account.PathStyle = pathStyle
+	account.UserId = fmt.Sprintf("%s/%s", client.Endpoint, bucket)
+	if err := db.Db.Save(account).Error; err != nil {
+		helpers.AppLogger.Errorf("保存S3账号失败: %v", err)
+		return nil, err
+	}
+	// 更新缓存的客户端
+	account.GetS3Client()
+	helpers.AppLogger.Infof("保存S3账号成功，地址：%s，存储桶：%s", account.BaseUrl, account.Bucket)
+	return account, nil
+}
+
 // 使用name创建一个临时账号，用户后续授权绑定
 // name: 账号备注
 func CreateAccountByName(name string, srouceType SourceType, appId string) (*Account, error) {
@@ -236,6 +423,48 @@ func CreateOpenListAccount(baseUrl string, username string, password string, tok
 	return account, nil
 }
 
+// 创建123云盘账号，如果userId已经存在，则更新
+// clientId: 123开放平台的clientID
+// clientSecret: 123开放平台的clientSecret
+func Create123Account(name string, clientId string, clientSecret string) (*Account, error) {
+	// 先用临时客户端验证clientID和clientSecret
+	client := open123.NewClient(clientId, clientSecret)
+	defer client.Close()
+	if err := client.RefreshAccessToken(); err != nil {
+		helpers.AppLogger.Errorf("验证123云盘开放平台凭据失败: %v", err)
+		return nil, err
+	}
+	userInfo, err := client.GetUserInfo(context.Background())
+	if err != nil {
+		helpers.AppLogger.Errorf("获取123云盘用户信息失败: %v", err)
+		return nil, err
+	}
+	userId := fmt.Sprintf("%d", userInfo.UID)
+	account, err := GetAccountByUserId(userId)
+	if err != nil {
+		account = &Account{}
+	}
+	if name == "" {
+		name = userInfo.Nickname
+	}
+	account.Name = name
+	account.SourceType = SourceType123
+	account.AppId = clientId
+	account.AppSecret = clientSecret
+	account.Token = client.GetAccessToken()
+	account.RefreshToken = ""
+	account.TokenExpiriesTime = client.GetExpiredAt().Unix()
+	account.TokenFailedReason = ""
+	account.UserId = userId
+	account.Username = userInfo.Nickname
+	if err := db.Db.Save(account).Error; err != nil {
+		helpers.AppLogger.Errorf("保存123云盘账号失败: %v", err)
+		return nil, err
+	}
+	helpers.AppLogger.Infof("保存123云盘账号成功，用户ID：%s，用户名：%s", account.UserId, account.Username)
+	return account, nil
+}
+
 // 创建115账号，如果userId已经存在，则更新
 // token: 115账号的token
 // refreshToken: 115账号的refreshToken
@@ -290,6 +519,17 @@ func GetAccountByUserId(userId string) (*Account, error) {
 	return account, nil
 }
 
+// 通过来源和用户ID查询账号，不同网盘的用户ID可能重复
+func GetAccountBySourceUserId(sourceType SourceType, userId string) (*Account, error) {
+	account := &Account{}
+	err := db.Db.Where("source_type = ? AND user_id = ?", sourceType, userId).First(account).Error
+	if err != nil {
+		helpers.AppLogger.Errorf("查询开放平台账号失败: %v", err)
+		return nil, err
+	}
+	return account, nil
+}
+
 // 通过ID查询开放平台账号
 func GetAccountById(id uint) (*Account, error) {
 	account := &Account{}
@@ -405,3 +645,33 @@ func HandleOpenListTokenSaveSync(event helpers.Event) helpers.EventResult {
 		}
 	}
 }
+
+// 处理123云盘访问凭证保存事件（同步版本）
+func Handle123TokenSaveSync(event helpers.Event) helpers.EventResult {
+	eventData := event.Data.(map[string]any)
+	account, err := GetAccountById(eventData["account_id"].(uint))
+	if err != nil {
+		helpers.AppLogger.Errorf("查询123云盘账号失败: %v", err)
+		return helpers.EventResult{
+			Success: false,
+			Error:   err,
+			Data:    nil,
+		}
+	}
+	// 123返回的是过期时间点，转换成剩余秒数
+	expiresTime := eventData["expired_at"].(int64) - time.Now().Unix()
+	if !account.UpdateToken(eventData["token"].(string), "", expiresTime) {
+		helpers.AppLogger.Warn("123云盘访问凭证保存失败")
+		return helpers.EventResult{
+			Success: false,
+			Error:   fmt.Errorf("123云盘访问凭证保存失败"),
+			Data:    nil,
+		}
+	}
+	helpers.AppLogger.Infof("123云盘访问凭证保存成功")
+	return helpers.EventResult{
+		Success: true,
+		Error:   nil,
+		Data:    nil,
+	}
+}
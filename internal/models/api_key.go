@@ -146,6 +146,7 @@ func DeleteAPIKey(id uint, userID uint) error {
 	if result.RowsAffected == 0 {
 		return fmt.Errorf("API Key不存在或无权限删除")
 	}
+	credentialVersion.Add(1)
 	helpers.AppLogger.Infof("用户 %d 删除了API Key ID: %d", userID, id)
 	return nil
 }
@@ -161,6 +162,7 @@ func UpdateAPIKeyStatus(id uint, userID uint, isActive bool) error {
 	if result.RowsAffected == 0 {
 		return fmt.Errorf("API Key不存在或无权限更新")
 	}
+	credentialVersion.Add(1)
 	statusText := "禁用"
 	if isActive {
 		statusText = "启用"
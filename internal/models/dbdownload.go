@@ -3,12 +3,15 @@ package models
 import (
 	"Q115-STRM/internal/db"
 	"Q115-STRM/internal/helpers"
+	"Q115-STRM/internal/open123"
 	"Q115-STRM/internal/v115open"
+	"Q115-STRM/internal/webdav"
 	"context"
 	"errors"
 	"fmt"
 	"net/http"
 	"os"
+	"strconv"
 	"time"
 )
 
@@ -130,6 +133,11 @@ func (task *DbDownloadTask) Download() {
 		case SourceTypeBaiduPan:
 			task.DownloadBaiduPanFile()
 		case SourceType123:
+			task.Download123File()
+		case SourceTypeWebDAV:
+			task.DownloadWebDAVFile()
+		case SourceTypeS3:
+			task.DownloadS3File()
 		}
 	case DownloadSourceEmbyMedia:
 		// emby媒体信息提取，从emby下载
@@ -187,7 +195,7 @@ func (task *DbDownloadTask) Download115File() {
 	// 查询下载链接
 	v115Client := account.Get115Client()
 	// 首先获取到下载链接
-	url := v115Client.GetDownloadUrl(context.Background(), task.RemoteFileId, v115open.DEFAULTUA, false)
+	url := v115Client.GetDownloadUrl(v115open.WithPriority(context.Background(), v115open.PRIORITY_SYNC), task.RemoteFileId, v115open.DEFAULTUA, false)
 	if url == "" {
 		helpers.AppLogger.Warnf("[下载] 获取下载链接失败: %s", task.RemoteFileId)
 		task.Fail(fmt.Errorf("获取 %s => %s 的下载链接失败", task.RemoteFileId, task.FileName))
@@ -246,6 +254,55 @@ func (task *DbDownloadTask) DownloadOpenListFile() {
 	task.Complete()
 }
 
+// 下载WebDAV的文件，RemoteFileId是文件的完整路径
+func (task *DbDownloadTask) DownloadWebDAVFile() {
+	account := task.GetAccount()
+	if account == nil {
+		task.Fail(fmt.Errorf("账户不存在，无法下载文件%s", task.LocalFullPath))
+		return
+	}
+	// 标记为下载中
+	task.Downloading()
+	// 使用包含账号密码的地址下载
+	downloadErr := helpers.DownloadFile(account.GetWebDAVClient().DirectUrl(task.RemoteFileId), task.LocalFullPath, webdav.DEFAULTUA)
+	if downloadErr != nil {
+		helpers.AppLogger.Warnf("[下载] 下载WebDAV文件失败: %s", downloadErr.Error())
+		task.Fail(downloadErr)
+		return
+	}
+	// 设置文件修改时间
+	task.SetMTime()
+	// 下载完成
+	task.Complete()
+}
+
+// 下载S3的文件，RemoteFileId是文件的完整路径
+func (task *DbDownloadTask) DownloadS3File() {
+	account := task.GetAccount()
+	if account == nil {
+		task.Fail(fmt.Errorf("账户不存在，无法下载文件%s", task.LocalFullPath))
+		return
+	}
+	// 标记为下载中
+	task.Downloading()
+	// 使用预签名地址下载
+	signedUrl, err := account.GetS3Client().PresignGet(task.RemoteFileId, time.Hour)
+	if err != nil {
+		task.Fail(fmt.Errorf("生成S3下载地址失败: %v", err))
+		return
+	}
+	downloadErr := helpers.DownloadFile(signedUrl, task.LocalFullPath, v115open.DEFAULTUA)
+	if downloadErr != nil {
+		helpers.AppLogger.Warnf("[下载] 下载S3文件失败: %s", downloadErr.Error())
+		task.Fail(downloadErr)
+		return
+	}
+	// 设置文件修改时间
+	task.SetMTime()
+	// 下载完成
+	task.Complete()
+}
+
 // 下载百度网盘的文件
 func (task *DbDownloadTask) DownloadBaiduPanFile() {
 	account := task.GetAccount()
@@ -282,6 +339,40 @@ func (task *DbDownloadTask) DownloadBaiduPanFile() {
 	task.Complete()
 }
 
+func (task *DbDownloadTask) Download123File() {
+	account := task.GetAccount()
+	if account == nil {
+		task.Fail(fmt.Errorf("账户不存在，无法下载文件%s", task.LocalFullPath))
+		return
+	}
+	fileId, err := strconv.ParseInt(task.RemoteFileId, 10, 64)
+	if err != nil {
+		task.Fail(fmt.Errorf("123云盘文件ID %s 格式错误: %v", task.RemoteFileId, err))
+		return
+	}
+	// 标记为下载中
+	task.Downloading()
+	// 查询下载链接
+	client := account.Get123Client()
+	url, err := client.GetDirectLink(context.Background(), fileId)
+	if err != nil || url == "" {
+		helpers.AppLogger.Warnf("[下载] 获取123云盘下载链接失败: %s %v", task.RemoteFileId, err)
+		task.Fail(fmt.Errorf("获取 %s => %s 的下载链接失败", task.RemoteFileId, task.FileName))
+		return
+	}
+	// 下载文件到指定位置
+	downloadErr := helpers.DownloadFile(url, task.LocalFullPath, open123.DEFAULTUA)
+	if downloadErr != nil {
+		helpers.AppLogger.Warnf("[下载] 下载文件失败: %s", downloadErr.Error())
+		task.Fail(downloadErr)
+		return
+	}
+	// 设置文件修改时间
+	task.SetMTime()
+	// 下载完成
+	task.Complete()
+}
+
 // 访问Emby下载链接
 func (task *DbDownloadTask) DownloadEmbyMedia() {
 	// 标记为下载中
@@ -323,10 +414,22 @@ func CheckDownloadTaskExist(source DownloadSource, remoteFileId string) *DbDownl
 	return task
 }
 
+// 检查下载到某个本地路径的任务是否存在，同一个网盘文件可以下载到多个目标目录
+func CheckDownloadTaskExistByLocalPath(source DownloadSource, remoteFileId, localFullPath string) *DbDownloadTask {
+	var task *DbDownloadTask
+	err := db.Db.Model(&DbDownloadTask{}).
+		Where("source = ? AND remote_file_id = ? AND local_full_path = ?", source, remoteFileId, localFullPath).
+		First(&task).Error
+	if err != nil {
+		return nil
+	}
+	return task
+}
+
 // 添加任务
 func AddDownloadTaskFromSyncFile(file *SyncFile) error {
 	// 先检查是否存在
-	if task := CheckDownloadTaskExist(DownloadSourceStrm, file.PickCode); task != nil {
+	if task := CheckDownloadTaskExistByLocalPath(DownloadSourceStrm, file.PickCode, file.LocalFilePath); task != nil {
 		if task.Status == DownloadStatusPending {
 			return errors.New("任务已存在，状态为待下载")
 		}
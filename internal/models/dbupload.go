@@ -3,11 +3,13 @@ package models
 import (
 	"Q115-STRM/internal/db"
 	"Q115-STRM/internal/helpers"
+	"Q115-STRM/internal/v115open"
 	"context"
 	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"strconv"
 	"time"
 )
 
@@ -147,6 +149,18 @@ func (task *DbUploadTask) Upload() {
 		if !task.UploadBaiduPanFile() {
 			return
 		}
+	case SourceType123:
+		if !task.Upload123File() {
+			return
+		}
+	case SourceTypeWebDAV:
+		if !task.UploadWebDAVFile() {
+			return
+		}
+	case SourceTypeS3:
+		if !task.UploadS3File() {
+			return
+		}
 	default:
 		task.Fail(fmt.Errorf("未知的上传来源类型 %s", task.SourceType))
 		return
@@ -179,6 +193,8 @@ func (task *DbUploadTask) Upload115File() bool {
 		return false
 	}
 	task.Uploading()
+	// 上传属于后台任务，115请求进入同步队列
+	ctx := v115open.WithPriority(context.Background(), v115open.PRIORITY_SYNC)
 	// var file *SyncFile
 	// if task.Source == UploadSourceStrm {
 	// 	file = GetSyncFileById(task.SyncFileId)
@@ -188,7 +204,7 @@ func (task *DbUploadTask) Upload115File() bool {
 	// 	}
 	// }
 	// 检查远程文件是否存在
-	detail, existsErr := client.GetFsDetailByPath(context.Background(), task.RemoteFileId)
+	detail, existsErr := client.GetFsDetailByPath(ctx, task.RemoteFileId)
 
 	if existsErr == nil && detail.FileId != "" {
 		if task.Source == UploadSourceStrm {
@@ -208,7 +224,7 @@ func (task *DbUploadTask) Upload115File() bool {
 		}
 	}
 	// 检查父目录是否存在
-	detail, existsErr = client.GetFsDetailByCid(context.Background(), task.RemotePathId)
+	detail, existsErr = client.GetFsDetailByCid(ctx, task.RemotePathId)
 	if existsErr != nil {
 		task.Fail(fmt.Errorf("115检查父目录 %s 失败: %s", task.RemotePathId, existsErr.Error()))
 		return false
@@ -219,7 +235,7 @@ func (task *DbUploadTask) Upload115File() bool {
 	}
 	helpers.AppLogger.Infof("准备将文件 %s 上传到115目录 %s", task.LocalFullPath, task.RemotePathId)
 	// 上传文件
-	fileId, err := client.Upload(context.Background(), task.LocalFullPath, task.RemotePathId, "", "")
+	fileId, err := client.Upload(ctx, task.LocalFullPath, task.RemotePathId, "", "")
 	if err != nil {
 		task.Fail(fmt.Errorf("调用115上传API失败: %v", err))
 		return false
@@ -231,7 +247,7 @@ func (task *DbUploadTask) Upload115File() bool {
 	helpers.AppLogger.Infof("115上传文件 %s 成功, 新的文件ID: %s", task.LocalFullPath, fileId)
 	if task.Source == UploadSourceStrm {
 		// 查询文件详情，然后更新本地文件的修改时间
-		detail, err = client.GetFsDetailByCid(context.Background(), fileId)
+		detail, err = client.GetFsDetailByCid(ctx, fileId)
 		if err != nil {
 			task.Fail(fmt.Errorf("115查询文件详情 %s 失败: %s", fileId, err.Error()))
 			return false
@@ -284,6 +300,29 @@ func (task *DbUploadTask) UploadBaiduPanFile() bool {
 	return true
 }
 
+func (task *DbUploadTask) Upload123File() bool {
+	// 检查账户是否存在
+	account := task.GetAccount()
+	if account == nil {
+		task.Fail(fmt.Errorf("账户 %d 不存在", task.AccountId))
+		return false
+	}
+	parentId, err := strconv.ParseInt(task.RemotePathId, 10, 64)
+	if err != nil {
+		task.Fail(fmt.Errorf("123云盘目录ID %s 格式错误: %v", task.RemotePathId, err))
+		return false
+	}
+	task.Uploading()
+	// 调用上传方法
+	client := account.Get123Client()
+	_, err = client.UploadFile(context.Background(), task.LocalFullPath, parentId)
+	if err != nil {
+		task.Fail(fmt.Errorf("123云盘上传文件 %s 失败: %v", task.FileName, err))
+		return false
+	}
+	return true
+}
+
 func (task *DbUploadTask) UploadOpenListFile() bool {
 	// 检查账户是否存在
 	account := task.GetAccount()
@@ -326,6 +365,67 @@ func (task *DbUploadTask) UploadOpenListFile() bool {
 	return true
 }
 
+func (task *DbUploadTask) UploadWebDAVFile() bool {
+	// 检查账户是否存在
+	account := task.GetAccount()
+	if account == nil {
+		task.Fail(fmt.Errorf("账户 %d 不存在", task.AccountId))
+		return false
+	}
+	client := account.GetWebDAVClient()
+	task.Uploading()
+	if err := client.MkdirAll(filepath.ToSlash(filepath.Dir(task.RemoteFileId))); err != nil {
+		task.Fail(fmt.Errorf("WebDAV创建目录 %s 失败: %v", filepath.Dir(task.RemoteFileId), err))
+		return false
+	}
+	if err := client.Upload(task.LocalFullPath, task.RemoteFileId); err != nil {
+		task.Fail(fmt.Errorf("WebDAV上传文件 %s 失败: %v", task.FileName, err))
+		return false
+	}
+	if task.Source == UploadSourceStrm {
+		// 使用网盘的修改时间更新本地文件，避免下次同步时认为本地比网盘新
+		info, err := client.Stat(context.Background(), task.RemoteFileId)
+		if err != nil || info.Modified.IsZero() {
+			helpers.AppLogger.Warnf("WebDAV查询文件 %s 修改时间失败: %v", task.RemoteFileId, err)
+			return true
+		}
+		if err := os.Chtimes(task.LocalFullPath, info.Modified, info.Modified); err != nil {
+			task.Fail(fmt.Errorf("更新本地文件 %s 修改时间失败: %v", task.LocalFullPath, err))
+			return false
+		}
+	}
+	return true
+}
+
+// S3没有目录，直接上传到完整路径
+func (task *DbUploadTask) UploadS3File() bool {
+	// 检查账户是否存在
+	account := task.GetAccount()
+	if account == nil {
+		task.Fail(fmt.Errorf("账户 %d 不存在", task.AccountId))
+		return false
+	}
+	client := account.GetS3Client()
+	task.Uploading()
+	if err := client.Upload(task.LocalFullPath, task.RemoteFileId); err != nil {
+		task.Fail(fmt.Errorf("S3上传文件 %s 失败: %v", task.FileName, err))
+		return false
+	}
+	if task.Source == UploadSourceStrm {
+		// 使用网盘的修改时间更新本地文件，避免下次同步时认为本地比网盘新
+		info, err := client.Stat(context.Background(), task.RemoteFileId)
+		if err != nil || info.Modified.IsZero() {
+			helpers.AppLogger.Warnf("S3查询文件 %s 修改时间失败: %v", task.RemoteFileId, err)
+			return true
+		}
+		if err := os.Chtimes(task.LocalFullPath, info.Modified, info.Modified); err != nil {
+			task.Fail(fmt.Errorf("更新本地文件 %s 修改时间失败: %v", task.LocalFullPath, err))
+			return false
+		}
+	}
+	return true
+}
+
 func (task *DbUploadTask) UploadLocalFile() bool {
 	task.Uploading()
 	err := helpers.CopyFile(task.LocalFullPath, task.RemoteFileId)
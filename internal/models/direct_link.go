@@ -0,0 +1,97 @@
+package models
+
+import (
+	"Q115-STRM/internal/db"
+	"Q115-STRM/internal/helpers"
+	"Q115-STRM/internal/linkcache"
+	"time"
+
+	"gorm.io/gorm/clause"
+)
+
+// DirectLink 持久化的网盘直链缓存，重启后继续使用没有过期的链接
+type DirectLink struct {
+	BaseModel
+	CacheKey  string `json:"cache_key" gorm:"type:varchar(32);uniqueIndex"` // 缓存键的MD5，UA可能很长
+	Source    string `json:"source" gorm:"type:varchar(32);index:idx_direct_link_pick_code"`
+	PickCode  string `json:"pick_code" gorm:"type:varchar(128);index:idx_direct_link_pick_code"`
+	UA        string `json:"ua" gorm:"type:text"`
+	AccountId uint   `json:"account_id"`
+	Url       string `json:"url" gorm:"type:text"`
+	ExpiresAt int64  `json:"expires_at" gorm:"index"` // 过期时间戳（秒）
+}
+
+func (*DirectLink) TableName() string {
+	return "direct_links"
+}
+
+func (l *DirectLink) toEntry() *linkcache.Entry {
+	return &linkcache.Entry{
+		Source:    l.Source,
+		PickCode:  l.PickCode,
+		UA:        l.UA,
+		AccountId: l.AccountId,
+		Url:       l.Url,
+		ExpiresAt: time.Unix(l.ExpiresAt, 0),
+		CreatedAt: time.Unix(l.CreatedAt, 0),
+	}
+}
+
+// DirectLinkStore 直链缓存的数据库存储
+type DirectLinkStore struct{}
+
+func (DirectLinkStore) Get(key string) (*linkcache.Entry, error) {
+	var links []*DirectLink
+	if err := db.Db.Where("cache_key = ?", helpers.MD5Hash(key)).Limit(1).Find(&links).Error; err != nil || len(links) == 0 {
+		return nil, err
+	}
+	return links[0].toEntry(), nil
+}
+
+func (DirectLinkStore) Set(entry *linkcache.Entry) error {
+	link := &DirectLink{
+		CacheKey:  helpers.MD5Hash(entry.Key()),
+		Source:    entry.Source,
+		PickCode:  entry.PickCode,
+		UA:        entry.UA,
+		AccountId: entry.AccountId,
+		Url:       entry.Url,
+		ExpiresAt: entry.ExpiresAt.Unix(),
+	}
+	return db.Db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "cache_key"}},
+		DoUpdates: clause.AssignmentColumns([]string{"account_id", "url", "expires_at", "created_at", "updated_at"}),
+	}).Create(link).Error
+}
+
+func (DirectLinkStore) DeleteByPickCode(source, pickCode string) (int64, error) {
+	result := db.Db.Where("(? = '' OR source = ?) AND pick_code = ?", source, source, pickCode).Delete(&DirectLink{})
+	return result.RowsAffected, result.Error
+}
+
+func (DirectLinkStore) List(source, pickCode string, page, pageSize int) ([]*linkcache.Entry, int64, error) {
+	query := db.Db.Model(&DirectLink{}).Where("(? = '' OR source = ?) AND (? = '' OR pick_code = ?)", source, source, pickCode, pickCode)
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	var links []*DirectLink
+	if err := query.Order("created_at DESC").Offset(max(page-1, 0) * pageSize).Limit(pageSize).Find(&links).Error; err != nil {
+		return nil, 0, err
+	}
+	entries := make([]*linkcache.Entry, 0, len(links))
+	for _, link := range links {
+		entries = append(entries, link.toEntry())
+	}
+	return entries, total, nil
+}
+
+func (DirectLinkStore) DeleteExpired(now time.Time) (int64, error) {
+	result := db.Db.Where("expires_at <= ?", now.Unix()).Delete(&DirectLink{})
+	return result.RowsAffected, result.Error
+}
+
+func (DirectLinkStore) DeleteAll(source string) (int64, error) {
+	result := db.Db.Where("? = '' OR source = ?", source, source).Delete(&DirectLink{})
+	return result.RowsAffected, result.Error
+}
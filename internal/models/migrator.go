@@ -26,7 +26,7 @@ func (*Migrator) TableName() string {
 // 如果已有数据库则从数据库中获取版本，根据版本执行变更
 func Migrate() {
 	// sqliteDb := db.InitSqlite3(dbFile)
-	maxVersion := 25
+	maxVersion := 42
 	// 先初始化所有表和基础数据
 	if !InitDB(maxVersion) {
 		// 初始化数据库版本表
@@ -334,6 +334,98 @@ func Migrate() {
 		db.Db.AutoMigrate(SyncPath{})
 		migrator.UpdateVersionCode(db.Db)
 	}
+	if migrator.VersionCode == 26 {
+		// 给Account表添加AppSecret字段，用于123云盘
+		db.Db.AutoMigrate(Account{})
+		migrator.UpdateVersionCode(db.Db)
+	}
+	if migrator.VersionCode == 27 {
+		// 给SyncPath表添加EnableWatch字段，用于本地目录实时监控
+		db.Db.AutoMigrate(SyncPath{})
+		migrator.UpdateVersionCode(db.Db)
+	}
+	if migrator.VersionCode == 28 {
+		// 预览同步：Sync表添加IsPlan字段，新增预览操作表
+		db.Db.AutoMigrate(Sync{}, SyncPlanItem{})
+		migrator.UpdateVersionCode(db.Db)
+	}
+	if migrator.VersionCode == 29 {
+		// 添加STRM内容模板字段
+		db.Db.AutoMigrate(Settings{}, SyncPath{})
+		migrator.UpdateVersionCode(db.Db)
+	}
+	if migrator.VersionCode == 30 {
+		// 添加115同步断点表
+		db.Db.AutoMigrate(SyncCheckpoint{}, SyncCheckpointItem{})
+		migrator.UpdateVersionCode(db.Db)
+	}
+	if migrator.VersionCode == 31 {
+		// 添加删除保护阈值和待审批的删除批次
+		db.Db.AutoMigrate(Settings{}, SyncPath{}, SyncDeleteBatch{}, SyncDeleteBatchItem{})
+		// 已有的同步目录都使用STRM设置，自定义配置的同步目录也不能变成不限制
+		db.Db.Model(&SyncPath{}).Where("id > ?", 0).Updates(map[string]any{"delete_max_count": -1, "delete_max_percent": -1})
+		migrator.UpdateVersionCode(db.Db)
+	}
+	if migrator.VersionCode == 32 {
+		// 添加本地回收站
+		db.Db.AutoMigrate(Settings{}, SyncPath{}, SyncTrashItem{})
+		// 已有的同步目录都使用STRM设置的回收站，0表示直接删除
+		db.Db.Model(&SyncPath{}).Where("id > ?", 0).Update("trash_retention_days", -1)
+		migrator.UpdateVersionCode(db.Db)
+	}
+	if migrator.VersionCode == 33 {
+		// 添加同步变更记录
+		db.Db.AutoMigrate(SyncChange{})
+		migrator.UpdateVersionCode(db.Db)
+	}
+	if migrator.VersionCode == 34 {
+		// 添加包含、排除规则和大小、时间过滤
+		db.Db.AutoMigrate(Settings{}, SyncPath{})
+		// 已有的同步目录都使用STRM设置的过滤条件
+		db.Db.Model(&SyncPath{}).Where("id > ?", 0).Updates(map[string]any{"max_video_size": -1, "max_age_days": -1})
+		migrator.UpdateVersionCode(db.Db)
+	}
+	if migrator.VersionCode == 35 {
+		// 添加同步目录的目标目录，删除批次区分目标目录
+		db.Db.AutoMigrate(SyncPathTarget{}, SyncDeleteBatch{})
+		migrator.UpdateVersionCode(db.Db)
+	}
+	if migrator.VersionCode == 36 {
+		// 添加WebDAV账号的STRM直链设置
+		db.Db.AutoMigrate(Account{})
+		migrator.UpdateVersionCode(db.Db)
+	}
+	if migrator.VersionCode == 37 {
+		// 添加S3账号的存储桶、区域和路径风格设置
+		db.Db.AutoMigrate(Account{})
+		migrator.UpdateVersionCode(db.Db)
+	}
+	if migrator.VersionCode == 38 {
+		// 账号单独的速率限制，请求统计区分来源
+		db.Db.AutoMigrate(Account{}, RequestStat{})
+		db.Db.Model(&RequestStat{}).Where("source = ? OR source IS NULL", "").Update("source", string(SourceType115))
+		migrator.UpdateVersionCode(db.Db)
+	}
+	if migrator.VersionCode == 39 {
+		// 添加账号自适应速率控制学习到的速率
+		db.Db.AutoMigrate(Account{})
+		migrator.UpdateVersionCode(db.Db)
+	}
+	if migrator.VersionCode == 40 {
+		// 添加115直链故障转移的账号顺序，同步文件的SHA1索引
+		db.Db.AutoMigrate(Account{}, SyncFile{})
+		migrator.UpdateVersionCode(db.Db)
+	}
+	if migrator.VersionCode == 41 {
+		// 添加持久化的直链缓存表
+		db.Db.AutoMigrate(DirectLink{})
+		migrator.UpdateVersionCode(db.Db)
+	}
+	if migrator.VersionCode == 42 {
+		// 添加STRM地址签名设置
+		db.Db.AutoMigrate(SyncPath{}, Settings{})
+		migrator.UpdateVersionCode(db.Db)
+	}
 	helpers.AppLogger.Infof("当前数据库版本 %d", migrator.VersionCode)
 }
 
@@ -343,11 +435,13 @@ func BatchCreateTable() {
 	db.Db.AutoMigrate(Migrator{})
 	// 配置、用户、同步目录表
 	db.Db.AutoMigrate(Settings{}, Sync{}, User{}, SyncPath{}, Account{})
-	db.Db.AutoMigrate(SyncFile{})
+	db.Db.AutoMigrate(SyncFile{}, SyncPlanItem{}, SyncCheckpoint{}, SyncCheckpointItem{}, SyncDeleteBatch{}, SyncDeleteBatchItem{}, SyncTrashItem{}, SyncChange{}, SyncPathTarget{})
 	// 刮削相关表
 	db.Db.AutoMigrate(ScrapeSettings{}, ScrapePath{}, MovieCategory{}, TvShowCategory{}, ScrapePathCategory{}, ScrapeMediaFile{}, Media{}, MediaSeason{}, MediaEpisode{})
 	// 115请求统计表
 	db.Db.AutoMigrate(&RequestStat{})
+	// 直链缓存表
+	db.Db.AutoMigrate(DirectLink{})
 	// Emby 同步相关表
 	db.Db.AutoMigrate(EmbyConfig{}, EmbyMediaItem{}, EmbyMediaSyncFile{}, EmbyLibrary{}, EmbyLibrarySyncPath{})
 	// 下载队列
@@ -14,6 +14,7 @@ type RequestStat struct {
 	Duration    int64  `json:"duration"`     // 响应时间（毫秒）
 	IsThrottled bool   `json:"is_throttled"` // 是否限流
 	AccountID   uint   `json:"account_id" gorm:"index;default:0"`
+	Source      string `json:"source" gorm:"type:varchar(32);index;default:'115'"` // 请求来源：115、openlist、baidupan、123
 }
 
 func (*RequestStat) TableName() string {
@@ -25,35 +26,38 @@ func CreateRequestStat(stat *RequestStat) error {
 	return db.Db.Create(stat).Error
 }
 
-// GetRequestStatsByDateRange 获取指定日期范围内的请求统计
-func GetRequestStatsByDateRange(startTime, endTime int64) ([]RequestStat, error) {
+// 按来源和账号过滤，accountId为0时不过滤账号
+const requestStatFilter = "source = ? AND (? = 0 OR account_id = ?) AND request_time >= ? AND request_time <= ?"
+
+// GetRequestStatsByDateRange 获取指定来源（账号）在指定日期范围内的请求统计，accountId为0时查询所有账号
+func GetRequestStatsByDateRange(source SourceType, accountId uint, startTime, endTime int64) ([]RequestStat, error) {
 	var stats []RequestStat
-	err := db.Db.Where("request_time >= ? AND request_time <= ?", startTime, endTime).
+	err := db.Db.Where(requestStatFilter, source, accountId, accountId, startTime, endTime).
 		Order("request_time ASC").
 		Find(&stats).Error
 	return stats, err
 }
 
-// GetRequestStatsCount 获取指定时间范围内的请求总数
-func GetRequestStatsCount(startTime, endTime int64) (int64, error) {
+// GetRequestStatsCount 获取指定来源（账号）在指定时间范围内的请求总数
+func GetRequestStatsCount(source SourceType, accountId uint, startTime, endTime int64) (int64, error) {
 	var count int64
 	err := db.Db.Model(&RequestStat{}).
-		Where("request_time >= ? AND request_time <= ?", startTime, endTime).
+		Where(requestStatFilter, source, accountId, accountId, startTime, endTime).
 		Count(&count).Error
 	return count, err
 }
 
-// GetThrottledRequestsCount 获取指定时间范围内的限流请求数
-func GetThrottledRequestsCount(startTime, endTime int64) (int64, error) {
+// GetThrottledRequestsCount 获取指定来源（账号）在指定时间范围内的限流请求数
+func GetThrottledRequestsCount(source SourceType, accountId uint, startTime, endTime int64) (int64, error) {
 	var count int64
 	err := db.Db.Model(&RequestStat{}).
-		Where("request_time >= ? AND request_time <= ? AND is_throttled = ?", startTime, endTime, true).
+		Where(requestStatFilter+" AND is_throttled = ?", source, accountId, accountId, startTime, endTime, true).
 		Count(&count).Error
 	return count, err
 }
 
-// GetHourlyRequestStats 获取按小时分组的请求统计
-func GetHourlyRequestStats(startTime, endTime int64) ([]map[string]interface{}, error) {
+// GetHourlyRequestStats 获取指定来源（账号）按小时分组的请求统计
+func GetHourlyRequestStats(source SourceType, accountId uint, startTime, endTime int64) ([]map[string]interface{}, error) {
 	var results []map[string]interface{}
 
 	// SQLite 使用整除取整，PostgreSQL 使用 date_trunc
@@ -66,7 +70,7 @@ func GetHourlyRequestStats(startTime, endTime int64) ([]map[string]interface{},
 				SUM(CASE WHEN is_throttled THEN 1 ELSE 0 END) as throttled_requests,
 				AVG(duration) as avg_duration
 			FROM request_stats
-			WHERE request_time >= ? AND request_time <= ?
+			WHERE source = ? AND (? = 0 OR account_id = ?) AND request_time >= ? AND request_time <= ?
 			GROUP BY date_trunc('hour', to_timestamp(request_time))
 			ORDER BY hour_ts ASC
 		`
@@ -78,13 +82,13 @@ func GetHourlyRequestStats(startTime, endTime int64) ([]map[string]interface{},
 				SUM(CASE WHEN is_throttled THEN 1 ELSE 0 END) as throttled_requests,
 				AVG(duration) as avg_duration
 			FROM request_stats
-			WHERE request_time >= ? AND request_time <= ?
+			WHERE source = ? AND (? = 0 OR account_id = ?) AND request_time >= ? AND request_time <= ?
 			GROUP BY CAST(request_time / 3600 AS INTEGER)
 			ORDER BY hour_ts ASC
 		`
 	}
 
-	err := db.Db.Raw(query, startTime, endTime).Scan(&results).Error
+	err := db.Db.Raw(query, source, accountId, accountId, startTime, endTime).Scan(&results).Error
 	if err != nil {
 		return results, err
 	}
@@ -95,8 +99,8 @@ func GetHourlyRequestStats(startTime, endTime int64) ([]map[string]interface{},
 	return results, nil
 }
 
-// GetDailyRequestStats 获取按天分组的请求统计
-func GetDailyRequestStats(startTime, endTime int64) ([]map[string]interface{}, error) {
+// GetDailyRequestStats 获取指定来源（账号）按天分组的请求统计
+func GetDailyRequestStats(source SourceType, accountId uint, startTime, endTime int64) ([]map[string]interface{}, error) {
 	var results []map[string]interface{}
 
 	var query string
@@ -108,7 +112,7 @@ func GetDailyRequestStats(startTime, endTime int64) ([]map[string]interface{}, e
 				SUM(CASE WHEN is_throttled THEN 1 ELSE 0 END) as throttled_requests,
 				AVG(duration) as avg_duration
 			FROM request_stats
-			WHERE request_time >= ? AND request_time <= ?
+			WHERE source = ? AND (? = 0 OR account_id = ?) AND request_time >= ? AND request_time <= ?
 			GROUP BY to_char(to_timestamp(request_time), 'YYYY-MM-DD')
 			ORDER BY date ASC
 		`
@@ -120,13 +124,13 @@ func GetDailyRequestStats(startTime, endTime int64) ([]map[string]interface{}, e
 				SUM(CASE WHEN is_throttled THEN 1 ELSE 0 END) as throttled_requests,
 				AVG(duration) as avg_duration
 			FROM request_stats
-			WHERE request_time >= ? AND request_time <= ?
+			WHERE source = ? AND (? = 0 OR account_id = ?) AND request_time >= ? AND request_time <= ?
 			GROUP BY strftime('%Y-%m-%d', datetime(request_time, 'unixepoch'))
 			ORDER BY date ASC
 		`
 	}
 
-	err := db.Db.Raw(query, startTime, endTime).Scan(&results).Error
+	err := db.Db.Raw(query, source, accountId, accountId, startTime, endTime).Scan(&results).Error
 	if err != nil {
 		return results, err
 	}
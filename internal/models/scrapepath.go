@@ -5,7 +5,9 @@ import (
 	"Q115-STRM/internal/helpers"
 	"Q115-STRM/internal/openai"
 	"Q115-STRM/internal/openlist"
+	"Q115-STRM/internal/s3"
 	"Q115-STRM/internal/v115open"
+	"Q115-STRM/internal/webdav"
 	"context"
 	"encoding/json"
 	"fmt"
@@ -14,6 +16,7 @@ import (
 	"slices"
 	"strings"
 	"sync"
+	"time"
 )
 
 type MediaType string
@@ -92,6 +95,8 @@ type ScrapePath struct {
 	MaxThreads            int                          `json:"max_threads" form:"max_threads"`                           // 刮削最大线程数，默认值为5
 	V115Client            *v115open.OpenClient         `json:"-" gorm:"-"`                                               // 115客户端
 	OpenListClient        *openlist.Client             `json:"-" gorm:"-"`                                               // openlist客户端
+	WebDAVClient          *webdav.Client               `json:"-" gorm:"-"`                                               // WebDAV客户端
+	S3Client              *s3.Client                   `json:"-" gorm:"-"`                                               // S3客户端
 	ExistsFiles           map[string]bool              `json:"-" gorm:"-"`                                               // 已存在的文件，key为文件路径，value为是否存在
 	ScrapeRootPath        string                       `json:"-" gorm:"-"`                                               // 刮削根路径
 	Category              ScrapePathCategoryCollection `json:"-" gorm:"-"`
@@ -258,6 +263,10 @@ func (sp *ScrapePath) Init() bool {
 				return false
 			}
 			// helpers.AppLogger.Infof("获取OpenList客户端成功")
+		case SourceTypeWebDAV:
+			sp.WebDAVClient = account.GetWebDAVClient()
+		case SourceTypeS3:
+			sp.S3Client = account.GetS3Client()
 		}
 	}
 	// 创建临时目录
@@ -355,9 +364,17 @@ func (sp *ScrapePath) GetDownloadUrl(videoPathOrUrl string) string {
 	}
 	switch sp.SourceType {
 	case SourceType115:
-		videoPathOrUrl = sp.V115Client.GetDownloadUrl(context.Background(), videoPathOrUrl, v115open.DEFAULTUA, false)
+		videoPathOrUrl = sp.V115Client.GetDownloadUrl(v115open.WithPriority(context.Background(), v115open.PRIORITY_SCRAPE), videoPathOrUrl, v115open.DEFAULTUA, false)
 	case SourceTypeOpenList:
 		videoPathOrUrl = sp.OpenListClient.GetRawUrl(videoPathOrUrl)
+	case SourceTypeWebDAV:
+		videoPathOrUrl = sp.WebDAVClient.DirectUrl(videoPathOrUrl)
+	case SourceTypeS3:
+		signedUrl, err := sp.S3Client.PresignGet(videoPathOrUrl, time.Hour)
+		if err != nil {
+			helpers.AppLogger.Errorf("生成S3下载地址失败: %v", err)
+		}
+		videoPathOrUrl = signedUrl
 	case SourceType123:
 	}
 	return videoPathOrUrl
@@ -478,12 +495,13 @@ func (sp *ScrapePath) GenerateCategory() {
 		case SourceType115:
 			// 先查询是否存在
 			categoryPath := filepath.Join(sp.DestPath, category.Name)
-			detail, detailErr := sp.V115Client.GetFsDetailByPath(context.Background(), categoryPath)
+			ctx := v115open.WithPriority(context.Background(), v115open.PRIORITY_SCRAPE)
+			detail, detailErr := sp.V115Client.GetFsDetailByPath(ctx, categoryPath)
 			if detail != nil && detailErr == nil && detail.FileId != "" {
 				helpers.AppLogger.Infof("目录 %s 已存在, 目录ID=%s, 返回值:%+v", categoryPath, detail.FileId, detail)
 				fileId = detail.FileId
 			} else {
-				fileId, err = sp.V115Client.MkDir(context.Background(), sp.DestPathId, category.Name)
+				fileId, err = sp.V115Client.MkDir(ctx, sp.DestPathId, category.Name)
 				if err != nil {
 					helpers.AppLogger.Errorf("创建115目录失败: %v", err)
 					continue
@@ -496,6 +514,20 @@ func (sp *ScrapePath) GenerateCategory() {
 				helpers.AppLogger.Errorf("创建OpenList目录失败: %v", err)
 				continue
 			}
+		case SourceTypeWebDAV:
+			fileId = sp.DestPathId + "/" + category.Name
+			err = sp.WebDAVClient.Mkdir(fileId)
+			if err != nil {
+				helpers.AppLogger.Errorf("创建WebDAV目录失败: %v", err)
+				continue
+			}
+		case SourceTypeS3:
+			fileId = sp.DestPathId + "/" + category.Name
+			err = sp.S3Client.Mkdir(fileId)
+			if err != nil {
+				helpers.AppLogger.Errorf("创建S3目录失败: %v", err)
+				continue
+			}
 		case SourceTypeLocal:
 			fileId = filepath.Join(sp.DestPathId, category.Name)
 			os.MkdirAll(fileId, 0777)
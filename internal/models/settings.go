@@ -4,7 +4,11 @@ import (
 	"Q115-STRM/internal/db"
 	"Q115-STRM/internal/helpers"
 	"Q115-STRM/internal/notificationmanager"
+	"Q115-STRM/internal/scheduler"
 	"encoding/json"
+	"fmt"
+	"regexp"
+	"slices"
 	"strings"
 )
 
@@ -34,6 +38,50 @@ type SettingStrm struct {
 	DeleteDir      int      `form:"delete_dir" json:"delete_dir" gorm:"default: 1"`            // 是否删除目录，-1表示使用STRM设置，0表示不删除，1表示删除
 	AddPath        int      `form:"add_path" json:"add_path" gorm:"default: 2"`                // 是否添加路径，默认-1(使用settings的值), 1- 表示添加路径， 2-表示不添加路径
 	CheckMetaMtime int      `form:"check_meta_mtime" json:"check_meta_mtime" gorm:"default:0"` // 是否检查元数据文件修改时间，默认-1(使用settings的值), 0表示不检查，1表示检查
+	StrmTemplate   string   `form:"strm_template" json:"strm_template"`                        // STRM内容模板，为空则使用默认格式，变量见StrmTemplateVars
+	// 删除保护，一次同步要删除的本地文件超过阈值时暂停删除，等待审批
+	DeleteMaxCount   int `form:"delete_max_count" json:"delete_max_count" gorm:"default:1000"`   // 最多删除的文件数量，-1表示使用STRM设置，0表示不限制
+	DeleteMaxPercent int `form:"delete_max_percent" json:"delete_max_percent" gorm:"default:30"` // 最多删除的文件占本地文件的百分比，-1表示使用STRM设置，0表示不限制
+	// 回收站，同步删除的本地文件移动到同步目录下的.qms-trash目录
+	TrashRetentionDays int `form:"trash_retention_days" json:"trash_retention_days" gorm:"default:30"` // 回收站保留天数，-1表示使用STRM设置，0表示不使用回收站，直接删除
+	// 过滤规则，规则格式见SyncPathRule，不在范围内的网盘文件按不存在处理
+	IncludeRule    string   `json:"-"`                                                     // 包含规则，JSON格式，为空表示包含全部
+	IncludeRuleArr []string `form:"include_rule_arr" json:"include_rule_arr" gorm:"-"`     // 包含规则数组，不参与数据库操作，仅供前端使用
+	ExcludeRule    string   `json:"-"`                                                     // 排除规则，JSON格式
+	ExcludeRuleArr []string `form:"exclude_rule_arr" json:"exclude_rule_arr" gorm:"-"`     // 排除规则数组，不参与数据库操作，仅供前端使用
+	MaxVideoSize   int64    `form:"max_video_size" json:"max_video_size" gorm:"default:0"` // 最大视频大小，单位MB，-1表示使用STRM设置，0表示不限制
+	MaxAgeDays     int      `form:"max_age_days" json:"max_age_days" gorm:"default:0"`     // 只同步最近N天修改的文件，-1表示使用STRM设置，0表示不限制
+}
+
+// STRM内容模板支持的变量，使用{变量名}引用
+var StrmTemplateVars = []string{
+	"base_url",       // STRM基础URL，末尾没有/
+	"pickcode",       // 文件提取码，百度网盘为fsid，123云盘为文件ID
+	"fileid",         // 文件ID，OpenList和本地为文件完整路径，在查询参数中时自动编码
+	"userid",         // 网盘用户ID
+	"path",           // 文件在网盘中的完整路径，在查询参数中时自动编码
+	"path_urlencode", // URL编码后的完整路径
+	"ext",            // 文件扩展名，含.
+	"sign",           // OpenList签名
+}
+
+var strmTemplateVarRe = regexp.MustCompile(`\{([a-z_]+)\}`)
+
+// 检查STRM内容模板中的变量是否都支持
+func ValidateStrmTemplate(tpl string) error {
+	if tpl == "" {
+		return nil
+	}
+	matches := strmTemplateVarRe.FindAllStringSubmatch(tpl, -1)
+	if len(matches) == 0 {
+		return fmt.Errorf("STRM内容模板中没有任何变量")
+	}
+	for _, match := range matches {
+		if !slices.Contains(StrmTemplateVars, match[1]) {
+			return fmt.Errorf("STRM内容模板中的变量 {%s} 不支持，支持的变量：%s", match[1], strings.Join(StrmTemplateVars, ", "))
+		}
+	}
+	return nil
 }
 
 type Settings struct {
@@ -48,6 +96,8 @@ type Settings struct {
 	EmbyApiKey       string `json:"emby_api_key"`                 // @deprecated 已迁移到EmbyConfig Emby的API Key
 	HttpProxy        string `json:"http_proxy"`                   // HTTP代理地址
 	LocalProxy       int    `json:"local_proxy" gorm:"default:0"` // 是否启用本地代理，0表示不启用，1表示启用
+
+	StrmSignRequired int `json:"strm_sign_required" gorm:"default:0"` // 不属于任何同步路径的文件是否必须签名，0表示未签名的也可以访问，1表示必须签名；同步路径中的文件按同步路径的设置校验
 }
 
 func (t SettingThreads) ToMap() map[string]int {
@@ -62,15 +112,21 @@ func (t SettingThreads) ToMap() map[string]int {
 
 func (s SettingStrm) ToMap(isDb bool) map[string]any {
 	dataMap := map[string]any{
-		"cron":             s.Cron,
-		"min_video_size":   s.MinVideoSize,
-		"delete_dir":       s.DeleteDir,
-		"upload_meta":      s.UploadMeta,
-		"download_meta":    s.DownloadMeta,
-		"strm_base_url":    s.StrmBaseUrl,
-		"add_path":         s.AddPath,
-		"check_meta_mtime": s.CheckMetaMtime,
-		"local_proxy":      s.LocalProxy,
+		"cron":                 s.Cron,
+		"min_video_size":       s.MinVideoSize,
+		"delete_dir":           s.DeleteDir,
+		"upload_meta":          s.UploadMeta,
+		"download_meta":        s.DownloadMeta,
+		"strm_base_url":        s.StrmBaseUrl,
+		"add_path":             s.AddPath,
+		"check_meta_mtime":     s.CheckMetaMtime,
+		"local_proxy":          s.LocalProxy,
+		"strm_template":        strings.TrimSpace(s.StrmTemplate),
+		"delete_max_count":     s.DeleteMaxCount,
+		"delete_max_percent":   s.DeleteMaxPercent,
+		"trash_retention_days": s.TrashRetentionDays,
+		"max_video_size":       s.MaxVideoSize,
+		"max_age_days":         s.MaxAgeDays,
 	}
 	if s.Cron == "" {
 		dataMap["cron"] = helpers.GlobalConfig.Strm.Cron // 使用默认配置
@@ -100,8 +156,18 @@ func (s SettingStrm) ToMap(isDb bool) map[string]any {
 			// 从config.yml中读取默认的排除的文件名
 			dataMap["exclude_name_arr"] = []string{}
 		}
+		dataMap["include_rule_arr"] = s.IncludeRuleArr
+		dataMap["exclude_rule_arr"] = s.ExcludeRuleArr
+		if s.IncludeRule == "" {
+			dataMap["include_rule_arr"] = []string{}
+		}
+		if s.ExcludeRule == "" {
+			dataMap["exclude_rule_arr"] = []string{}
+		}
 	} else {
 		// 数据库
+		dataMap["include_rule"] = s.IncludeRule
+		dataMap["exclude_rule"] = s.ExcludeRule
 		dataMap["exclude_name"] = s.ExcludeName
 		dataMap["meta_ext"] = s.MetaExt
 		dataMap["video_ext"] = s.VideoExt
@@ -139,6 +205,25 @@ func (s SettingStrm) EncodeArr() *SettingStrm {
 		return nil
 	}
 	s.ExcludeName = string(excludeNameStr)
+	// 过滤规则区分大小写保存，匹配时不区分
+	s.IncludeRule = ""
+	if rules := trimRules(s.IncludeRuleArr); len(rules) > 0 {
+		includeRuleStr, err := json.Marshal(rules)
+		if err != nil {
+			helpers.AppLogger.Errorf("将包含规则转换为JSON字符串失败: %v", err)
+			return nil
+		}
+		s.IncludeRule = string(includeRuleStr)
+	}
+	s.ExcludeRule = ""
+	if rules := trimRules(s.ExcludeRuleArr); len(rules) > 0 {
+		excludeRuleStr, err := json.Marshal(rules)
+		if err != nil {
+			helpers.AppLogger.Errorf("将排除规则转换为JSON字符串失败: %v", err)
+			return nil
+		}
+		s.ExcludeRule = string(excludeRuleStr)
+	}
 	s.VideoExt = string(videoExtStr)
 	s.MetaExt = string(metaExtStr)
 	return &s
@@ -172,12 +257,41 @@ func (s SettingStrm) DecodeArr() *SettingStrm {
 	if len(s.ExcludeNameArr) == 0 {
 		s.ExcludeNameArr = []string{}
 	}
+	if s.IncludeRule != "" {
+		if err := json.Unmarshal([]byte(s.IncludeRule), &s.IncludeRuleArr); err != nil {
+			helpers.AppLogger.Errorf("将包含规则转换为数组失败: %v", err)
+			return nil
+		}
+	}
+	if len(s.IncludeRuleArr) == 0 {
+		s.IncludeRuleArr = []string{}
+	}
+	if s.ExcludeRule != "" {
+		if err := json.Unmarshal([]byte(s.ExcludeRule), &s.ExcludeRuleArr); err != nil {
+			helpers.AppLogger.Errorf("将排除规则转换为数组失败: %v", err)
+			return nil
+		}
+	}
+	if len(s.ExcludeRuleArr) == 0 {
+		s.ExcludeRuleArr = []string{}
+	}
 	if s.Cron == "" {
 		s.Cron = helpers.GlobalConfig.Strm.Cron
 	}
 	return &s
 }
 
+// 去掉空规则和首尾空格
+func trimRules(rules []string) []string {
+	trimmed := make([]string, 0, len(rules))
+	for _, rule := range rules {
+		if rule = strings.TrimSpace(rule); rule != "" {
+			trimmed = append(trimmed, rule)
+		}
+	}
+	return trimmed
+}
+
 var SettingsGlobal = &Settings{}
 
 func (settings *Settings) UpdateThreads(req SettingThreads) bool {
@@ -192,6 +306,8 @@ func (settings *Settings) UpdateThreads(req SettingThreads) bool {
 	}
 	// 重新初始化下载队列
 	InitDQ()
+	// OpenList的默认QPS
+	scheduler.SetDefaultLimits(scheduler.SOURCE_OPENLIST, scheduler.Limits{QPS: req.OpenlistQPS})
 	return true
 }
 
@@ -229,6 +345,16 @@ func (settings *Settings) UpdateHttpProxy(httpProxy string) bool {
 	return true
 }
 
+func (settings *Settings) UpdateStrmSignRequired(required int) bool {
+	settings.StrmSignRequired = required
+	err := db.Db.Model(settings).Where("id = ?", settings.ID).Update("strm_sign_required", required).Error
+	if err != nil {
+		helpers.AppLogger.Errorf("更新STRM签名设置失败: %v", err)
+		return false
+	}
+	return true
+}
+
 func (settings *Settings) UpdateStrm(req SettingStrm) bool {
 	strm := req.EncodeArr()
 	if strm == nil {
@@ -73,6 +73,7 @@ type Sync struct {
 	BaseCid           string           `json:"base_cid"`                    // 基础CID，用于标识同步的根目录
 	FailReason        string           `json:"fail_reason"`                 // 失败原因
 	IsFullSync        bool             `json:"is_full_sync"`                // 是否全量同步
+	IsPlan            bool             `json:"is_plan"`                     // 是否是预览同步，预览同步不修改任何文件，只记录将要执行的操作
 	SyncPath          *SyncPath        `gorm:"-" json:"-"`                  // 同步路径实例
 	Logger            *helpers.QLogger `gorm:"-" json:"-"`                  // 日志句柄，不参与数据读写
 }
@@ -89,7 +90,7 @@ func (s *Sync) Complete(sourceType SourceType) bool {
 	}
 	// s.SyncPath.SetIsFullSync(false) // 改回默认值，下次非全量同步
 	s.Logger.Infof("同步任务已完成: %d", s.ID)
-	if s.NewUpload > 0 || s.NewMeta > 0 || s.NewStrm > 0 {
+	if !s.IsPlan && (s.NewUpload > 0 || s.NewMeta > 0 || s.NewStrm > 0) {
 		ctx := context.Background()
 
 		notif := &Notification{
@@ -115,6 +116,10 @@ func (s *Sync) Failed(reason string) {
 	s.FinishAt = time.Now().Unix()
 	s.LocalFileFinishAt = s.FinishAt
 	s.UpdateStatus(SyncStatusFailed)
+	if s.IsPlan {
+		// 预览同步失败不发送通知
+		return
+	}
 	ctx := context.Background()
 	notif := &Notification{
 		Type:      SyncError,
@@ -269,6 +274,11 @@ func FailAllRunningSyncTasks() {
 	}
 	syncPathId := make([]uint, 0)
 	for _, sync := range runningSyncs {
+		// 有断点的同步目录保持全量同步状态，下次同步从断点继续
+		if GetSyncCheckpointByPathId(sync.SyncPathId) != nil {
+			helpers.AppLogger.Infof("同步目录 %d 有未完成的同步断点，下次同步将从断点继续", sync.SyncPathId)
+			continue
+		}
 		syncPathId = append(syncPathId, sync.SyncPathId)
 	}
 	// 批量更新同步路径的IsFullSync为false
@@ -283,11 +293,26 @@ func FailAllRunningSyncTasks() {
 
 // 使用ID删除同步记录和相关文件
 func DeleteSyncRecordById(id uint) error {
+	return deleteSyncRecord(id, false)
+}
+
+// keepChanges为true时保留同步变更记录，变更记录按自己的保留天数清理
+func deleteSyncRecord(id uint, keepChanges bool) error {
 	sync := &Sync{BaseModel: BaseModel{ID: id}}
 	if err := db.Db.Delete(sync).Error; err != nil {
 		helpers.AppLogger.Errorf("删除同步记录失败: %v", err)
 		return err
 	}
+	// 删除预览同步记录的操作
+	if err := DeleteSyncPlanItemsBySyncId(id); err != nil {
+		helpers.AppLogger.Errorf("删除预览同步操作失败: %v", err)
+	}
+	// 删除同步变更记录
+	if !keepChanges {
+		if err := DeleteSyncChangesBySyncId(id); err != nil {
+			helpers.AppLogger.Errorf("删除同步变更记录失败: %v", err)
+		}
+	}
 	// 删除同步结果文件
 	logFile := filepath.Join(helpers.ConfigDir, "logs", "libs", fmt.Sprintf("sync_%d.log", id))
 	// 删除相关的日志和同步结果文件
@@ -298,6 +323,7 @@ func DeleteSyncRecordById(id uint) error {
 }
 
 // 清除过期的同步记录和相关文件，默认保留最近7天的记录
+// 同步变更记录用于追溯文件的变化，不随同步记录删除，由ClearExpiredSyncChanges清理
 func ClearExpiredSyncRecords(days int) {
 	cutoff := time.Now().AddDate(0, 0, -days).Unix()
 	var expiredSyncs []Sync
@@ -310,7 +336,7 @@ func ClearExpiredSyncRecords(days int) {
 		return
 	}
 	for _, sync := range expiredSyncs {
-		if err := DeleteSyncRecordById(sync.ID); err != nil {
+		if err := deleteSyncRecord(sync.ID, true); err != nil {
 			helpers.AppLogger.Errorf("删除过期的同步记录失败: %v", err)
 		} else {
 			helpers.AppLogger.Infof("删除过期的同步记录成功: %d", sync.ID)
@@ -347,7 +373,7 @@ func GetTodayFirstSyncByPathId(syncPathId uint) *Sync {
 	var sync Sync
 	// 计算今天0点的时间戳
 	today := time.Now().Truncate(24 * time.Hour).Unix()
-	if err := db.Db.Where("sync_path_id = ? AND created_at >= ?", syncPathId, today).First(&sync).Error; err != nil {
+	if err := db.Db.Where("sync_path_id = ? AND created_at >= ? AND is_plan = ?", syncPathId, today, false).First(&sync).Error; err != nil {
 		return nil
 	}
 	return &sync
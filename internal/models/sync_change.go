@@ -0,0 +1,144 @@
+package models
+
+import (
+	"Q115-STRM/internal/db"
+	"Q115-STRM/internal/helpers"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type SyncChangeAction string
+
+// 同步变更记录的保留天数，同步记录只保留1天，变更记录需要保留更久用于追溯
+const SyncChangeRetentionDays = 30
+
+const (
+	SyncChangeActionCreateStrm  SyncChangeAction = "create_strm"  // 新建STRM文件
+	SyncChangeActionUpdateStrm  SyncChangeAction = "update_strm"  // 重写STRM文件
+	SyncChangeActionRename      SyncChangeAction = "rename"       // 重命名本地文件
+	SyncChangeActionDeleteLocal SyncChangeAction = "delete_local" // 删除本地文件（或移动到回收站）
+	SyncChangeActionDownload    SyncChangeAction = "download"     // 添加元数据下载任务
+	SyncChangeActionUpload      SyncChangeAction = "upload"       // 添加元数据上传任务
+)
+
+var SyncChangeActionText map[SyncChangeAction]string = map[SyncChangeAction]string{
+	SyncChangeActionCreateStrm:  "新建STRM",
+	SyncChangeActionUpdateStrm:  "重写STRM",
+	SyncChangeActionRename:      "重命名",
+	SyncChangeActionDeleteLocal: "删除本地文件",
+	SyncChangeActionDownload:    "添加下载任务",
+	SyncChangeActionUpload:      "添加上传任务",
+}
+
+// SyncChange 同步过程中实际执行的每一个文件操作
+type SyncChange struct {
+	BaseModel
+	SyncId     uint             `json:"sync_id" gorm:"index"` // 所属的同步记录
+	SyncPathId uint             `json:"sync_path_id" gorm:"index"`
+	Action     SyncChangeAction `json:"action" gorm:"index"`
+	OldPath    string           `json:"old_path"`    // 操作前的本地路径，新建时为空
+	NewPath    string           `json:"new_path"`    // 操作后的路径，删除时为空，上传时为网盘路径
+	RemotePath string           `json:"remote_path"` // 对应的网盘路径
+	Reason     string           `json:"reason"`      // 执行该操作的原因
+}
+
+func (*SyncChange) TableName() string {
+	return "sync_changes"
+}
+
+// 查询同步变更的条件
+type SyncChangeQuery struct {
+	SyncId     uint
+	SyncPathId uint
+	Action     SyncChangeAction
+	Keyword    string // 匹配新旧路径和网盘路径
+}
+
+func (q *SyncChangeQuery) build() *gorm.DB {
+	query := db.Db.Model(&SyncChange{})
+	if q.SyncId > 0 {
+		query = query.Where("sync_id = ?", q.SyncId)
+	}
+	if q.SyncPathId > 0 {
+		query = query.Where("sync_path_id = ?", q.SyncPathId)
+	}
+	if q.Action != "" {
+		query = query.Where("action = ?", q.Action)
+	}
+	if q.Keyword != "" {
+		like := "%" + q.Keyword + "%"
+		query = query.Where("(old_path LIKE ? OR new_path LIKE ? OR remote_path LIKE ?)", like, like, like)
+	}
+	return query
+}
+
+// 批量保存同步变更
+func CreateSyncChanges(changes []*SyncChange) error {
+	if len(changes) == 0 {
+		return nil
+	}
+	return db.Db.CreateInBatches(changes, 500).Error
+}
+
+// 分页查询同步变更
+func GetSyncChanges(q *SyncChangeQuery, page, pageSize int) ([]*SyncChange, int64, error) {
+	var changes []*SyncChange
+	var total int64
+	query := q.build()
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	if err := query.Offset((page - 1) * pageSize).Limit(pageSize).Order("id ASC").Find(&changes).Error; err != nil {
+		return nil, 0, err
+	}
+	return changes, total, nil
+}
+
+// 分批遍历符合条件的所有同步变更，用于导出
+func EachSyncChange(q *SyncChangeQuery, fn func(change *SyncChange) error) error {
+	var changes []*SyncChange
+	return q.build().FindInBatches(&changes, 1000, func(tx *gorm.DB, batch int) error {
+		for _, change := range changes {
+			if err := fn(change); err != nil {
+				return err
+			}
+		}
+		return nil
+	}).Error
+}
+
+// 按操作类型统计数量
+func GetSyncChangeSummary(q *SyncChangeQuery) (map[SyncChangeAction]int64, error) {
+	type actionCount struct {
+		Action SyncChangeAction
+		Count  int64
+	}
+	var counts []actionCount
+	if err := q.build().Select("action, COUNT(*) as count").Group("action").Scan(&counts).Error; err != nil {
+		return nil, err
+	}
+	summary := make(map[SyncChangeAction]int64, len(counts))
+	for _, c := range counts {
+		summary[c.Action] = c.Count
+	}
+	return summary, nil
+}
+
+// 删除同步记录的所有变更
+func DeleteSyncChangesBySyncId(syncId uint) error {
+	return db.Db.Where("sync_id = ?", syncId).Delete(&SyncChange{}).Error
+}
+
+// 清除超过保留天数的同步变更记录
+func ClearExpiredSyncChanges(days int) {
+	cutoff := time.Now().AddDate(0, 0, -days).Unix()
+	result := db.Db.Where("created_at < ?", cutoff).Delete(&SyncChange{})
+	if result.Error != nil {
+		helpers.AppLogger.Errorf("清理过期的同步变更记录失败: %v", result.Error)
+		return
+	}
+	if result.RowsAffected > 0 {
+		helpers.AppLogger.Infof("清理过期的同步变更记录 %d 条", result.RowsAffected)
+	}
+}
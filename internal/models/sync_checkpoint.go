@@ -0,0 +1,121 @@
+package models
+
+import (
+	"Q115-STRM/internal/db"
+	"Q115-STRM/internal/helpers"
+
+	"gorm.io/gorm"
+)
+
+type SyncCheckpointKind string
+
+const (
+	SyncCheckpointKindDirs SyncCheckpointKind = "dirs" // 预取或加载完成后的目录缓存
+	SyncCheckpointKindPage SyncCheckpointKind = "page" // 已处理完成的文件页
+	SyncCheckpointKindPath SyncCheckpointKind = "path" // 已补全的目录路径
+)
+
+// SyncCheckpoint 115同步的断点，同步中断后下一次同步从断点继续，不再重新查询全部文件列表
+// 每个同步目录只保留一个断点，同步成功后删除
+type SyncCheckpoint struct {
+	BaseModel
+	SyncPathId    uint   `json:"sync_path_id" gorm:"uniqueIndex"`
+	SyncId        uint   `json:"sync_id"` // 最后写入断点的同步记录
+	SourcePathId  string `json:"source_path_id"`
+	Total         int64  `json:"total"`       // 网盘文件总数，变化后断点失效
+	FullSync      bool   `json:"full_sync"`   // 是否是全量同步
+	ConfigHash    string `json:"config_hash"` // 同步配置的摘要，配置变化后断点失效
+	DirsLoaded    bool   `json:"dirs_loaded"` // 目录缓存是否已保存
+	DonePages     int    `json:"done_pages"`  // 已完成的文件页数
+	ResolvedPaths int    `json:"resolved_paths"`
+}
+
+func (*SyncCheckpoint) TableName() string {
+	return "sync_checkpoints"
+}
+
+// SyncCheckpointItem 断点中保存的数据，Data为JSON
+type SyncCheckpointItem struct {
+	BaseModel
+	CheckpointId uint               `json:"checkpoint_id" gorm:"index"`
+	Kind         SyncCheckpointKind `json:"kind"`
+	Page         int                `json:"page"` // 文件页序号，只有page类型有效
+	Data         string             `json:"data" gorm:"type:text"`
+}
+
+func (*SyncCheckpointItem) TableName() string {
+	return "sync_checkpoint_items"
+}
+
+// 获取同步目录的断点，不存在返回nil
+func GetSyncCheckpointByPathId(syncPathId uint) *SyncCheckpoint {
+	var checkpoint SyncCheckpoint
+	if err := db.Db.Where("sync_path_id = ?", syncPathId).First(&checkpoint).Error; err != nil {
+		return nil
+	}
+	return &checkpoint
+}
+
+// 创建新的断点，会先删除同步目录已有的断点
+func CreateSyncCheckpoint(checkpoint *SyncCheckpoint) error {
+	if err := DeleteSyncCheckpointByPathId(checkpoint.SyncPathId); err != nil {
+		return err
+	}
+	if err := db.Db.Create(checkpoint).Error; err != nil {
+		helpers.AppLogger.Errorf("创建同步断点失败: %v", err)
+		return err
+	}
+	return nil
+}
+
+// 保存断点数据并更新进度
+func (c *SyncCheckpoint) Save(items []*SyncCheckpointItem) error {
+	return db.Db.Transaction(func(tx *gorm.DB) error {
+		if len(items) > 0 {
+			for _, item := range items {
+				item.CheckpointId = c.ID
+			}
+			if err := tx.CreateInBatches(items, 100).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Model(c).Updates(map[string]any{
+			"sync_id":        c.SyncId,
+			"dirs_loaded":    c.DirsLoaded,
+			"done_pages":     c.DonePages,
+			"resolved_paths": c.ResolvedPaths,
+		}).Error
+	})
+}
+
+// 按写入顺序遍历某个类型的断点数据
+func (c *SyncCheckpoint) EachItem(kind SyncCheckpointKind, fn func(item *SyncCheckpointItem) error) error {
+	var items []*SyncCheckpointItem
+	return db.Db.Where("checkpoint_id = ? AND kind = ?", c.ID, kind).FindInBatches(&items, 100, func(tx *gorm.DB, batch int) error {
+		for _, item := range items {
+			if err := fn(item); err != nil {
+				return err
+			}
+		}
+		return nil
+	}).Error
+}
+
+// 删除同步目录的断点和断点数据
+func DeleteSyncCheckpointByPathId(syncPathId uint) error {
+	checkpoint := GetSyncCheckpointByPathId(syncPathId)
+	if checkpoint == nil {
+		return nil
+	}
+	return db.Db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("checkpoint_id = ?", checkpoint.ID).Delete(&SyncCheckpointItem{}).Error; err != nil {
+			helpers.AppLogger.Errorf("删除同步断点数据失败: %v", err)
+			return err
+		}
+		if err := tx.Delete(checkpoint).Error; err != nil {
+			helpers.AppLogger.Errorf("删除同步断点失败: %v", err)
+			return err
+		}
+		return nil
+	})
+}
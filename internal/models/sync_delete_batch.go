@@ -0,0 +1,275 @@
+package models
+
+import (
+	"Q115-STRM/internal/db"
+	"Q115-STRM/internal/helpers"
+	"Q115-STRM/internal/notificationmanager"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type SyncDeleteBatchStatus int
+
+const (
+	SyncDeleteBatchStatusPending    SyncDeleteBatchStatus = iota // 等待审批
+	SyncDeleteBatchStatusApproved                                // 已批准并删除
+	SyncDeleteBatchStatusRejected                                // 已拒绝，不删除
+	SyncDeleteBatchStatusSuperseded                              // 已失效，之后的同步没有触发删除保护
+)
+
+var SyncDeleteBatchStatusText map[SyncDeleteBatchStatus]string = map[SyncDeleteBatchStatus]string{
+	SyncDeleteBatchStatusPending:    "等待审批",
+	SyncDeleteBatchStatusApproved:   "已删除",
+	SyncDeleteBatchStatusRejected:   "已拒绝",
+	SyncDeleteBatchStatusSuperseded: "已失效",
+}
+
+// SyncDeleteBatch 同步时要删除的本地文件超过删除保护阈值，暂停删除，等待审批
+// 每个同步目录（的每个目标目录）最多只有一个等待审批的批次，再次触发时替换其中的文件
+type SyncDeleteBatch struct {
+	BaseModel
+	SyncPathId  uint                  `json:"sync_path_id" gorm:"index"`
+	TargetId    uint                  `json:"target_id"` // 目标目录ID，0为同步目录自己的本地路径
+	SyncId      uint                  `json:"sync_id"`   // 触发删除保护的同步记录
+	Status      SyncDeleteBatchStatus `json:"status"`
+	DeleteCount int                   `json:"delete_count"` // 要删除的文件数量
+	LocalTotal  int                   `json:"local_total"`  // 同步时本地的STRM和元数据文件总数
+	Reason      string                `json:"reason"`       // 触发了哪个阈值
+	HandledAt   int64                 `json:"handled_at"`   // 审批时间
+}
+
+func (*SyncDeleteBatch) TableName() string {
+	return "sync_delete_batches"
+}
+
+// SyncDeleteBatchItem 删除批次中的本地文件
+type SyncDeleteBatchItem struct {
+	BaseModel
+	BatchId   uint   `json:"batch_id" gorm:"index"`
+	LocalPath string `json:"local_path"`
+}
+
+func (*SyncDeleteBatchItem) TableName() string {
+	return "sync_delete_batch_items"
+}
+
+// 保存等待审批的删除批次，已有等待审批的批次时替换其中的文件
+// 返回的bool表示是否是新建的批次，新建时才需要发送通知
+func CreateSyncDeleteBatch(syncPathId, targetId, syncId uint, localPaths []string, localTotal int, reason string) (*SyncDeleteBatch, bool, error) {
+	batch := &SyncDeleteBatch{}
+	isNew := false
+	err := db.Db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Where("sync_path_id = ? AND target_id = ? AND status = ?", syncPathId, targetId, SyncDeleteBatchStatusPending).First(batch).Error
+		if err != nil {
+			isNew = true
+			batch = &SyncDeleteBatch{SyncPathId: syncPathId, TargetId: targetId, Status: SyncDeleteBatchStatusPending}
+		}
+		batch.SyncId = syncId
+		batch.DeleteCount = len(localPaths)
+		batch.LocalTotal = localTotal
+		batch.Reason = reason
+		if err := tx.Save(batch).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("batch_id = ?", batch.ID).Delete(&SyncDeleteBatchItem{}).Error; err != nil {
+			return err
+		}
+		items := make([]*SyncDeleteBatchItem, 0, len(localPaths))
+		for _, localPath := range localPaths {
+			items = append(items, &SyncDeleteBatchItem{BatchId: batch.ID, LocalPath: localPath})
+		}
+		return tx.CreateInBatches(items, 500).Error
+	})
+	if err != nil {
+		helpers.AppLogger.Errorf("保存删除批次失败: %v", err)
+		return nil, false, err
+	}
+	return batch, isNew, nil
+}
+
+func GetSyncDeleteBatchById(id uint) *SyncDeleteBatch {
+	var batch SyncDeleteBatch
+	if err := db.Db.First(&batch, id).Error; err != nil {
+		return nil
+	}
+	return &batch
+}
+
+// 分页查询删除批次，syncPathId为0时查询全部，status小于0时查询全部状态
+func GetSyncDeleteBatches(syncPathId uint, status int, page, pageSize int) ([]*SyncDeleteBatch, int64, error) {
+	var batches []*SyncDeleteBatch
+	var total int64
+	query := db.Db.Model(&SyncDeleteBatch{})
+	if syncPathId > 0 {
+		query = query.Where("sync_path_id = ?", syncPathId)
+	}
+	if status >= 0 {
+		query = query.Where("status = ?", status)
+	}
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	if err := query.Offset((page - 1) * pageSize).Limit(pageSize).Order("id DESC").Find(&batches).Error; err != nil {
+		return nil, 0, err
+	}
+	return batches, total, nil
+}
+
+// 分页查询删除批次中的文件
+func (b *SyncDeleteBatch) GetItems(page, pageSize int) ([]*SyncDeleteBatchItem, int64, error) {
+	var items []*SyncDeleteBatchItem
+	var total int64
+	query := db.Db.Model(&SyncDeleteBatchItem{}).Where("batch_id = ?", b.ID)
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	if err := query.Offset((page - 1) * pageSize).Limit(pageSize).Order("id ASC").Find(&items).Error; err != nil {
+		return nil, 0, err
+	}
+	return items, total, nil
+}
+
+// 批准删除，删除批次中仍然存在的本地文件，返回删除的数量
+func (b *SyncDeleteBatch) Approve() (int, error) {
+	if b.Status != SyncDeleteBatchStatusPending {
+		return 0, fmt.Errorf("删除批次已处理，状态：%s", SyncDeleteBatchStatusText[b.Status])
+	}
+	delEmptyDir := SettingsGlobal.DeleteDir == 1
+	syncPath := GetSyncPathById(b.SyncPathId)
+	trashBaseDir := ""
+	// 目标目录和同步目录的文件结构相同，按相对路径换算成同步目录中的路径，检查是否重新同步过
+	syncedPathOf := func(localPath string) string { return localPath }
+	if syncPath != nil {
+		delEmptyDir = syncPath.GetDeleteDir() == 1
+		trashBaseDir = syncPath.GetFullLocalPath()
+		if b.TargetId > 0 {
+			// 目标目录的文件移动到目标目录下的回收站
+			if target := GetSyncPathTargetById(b.TargetId); target != nil {
+				delEmptyDir = target.GetDeleteDir(syncPath) == 1
+				trashBaseDir = target.GetFullLocalPath(syncPath)
+				syncPathDir, targetDir := syncPath.GetFullLocalPath(), trashBaseDir
+				syncedPathOf = func(localPath string) string {
+					rel, err := filepath.Rel(targetDir, localPath)
+					if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+						return ""
+					}
+					return filepath.Join(syncPathDir, rel)
+				}
+			}
+		}
+	}
+	deleted := 0
+	skipped := 0
+	var items []*SyncDeleteBatchItem
+	err := db.Db.Where("batch_id = ?", b.ID).FindInBatches(&items, 500, func(tx *gorm.DB, batch int) error {
+		changes := make([]*SyncChange, 0, len(items))
+		// 变更记录到触发删除保护的同步记录
+		defer func() {
+			if err := CreateSyncChanges(changes); err != nil {
+				helpers.AppLogger.Errorf("保存同步变更记录失败: %v", err)
+			}
+		}()
+		for _, item := range items {
+			if syncedPath := syncedPathOf(item.LocalPath); syncedPath != "" && isSyncedLocalFile(b.SyncPathId, syncedPath) {
+				// 之后的同步重新生成了这个文件，网盘中已经存在，不能删除
+				skipped++
+				continue
+			}
+			var err error
+			reason := fmt.Sprintf("批准删除批次 %d", b.ID)
+			if syncPath != nil && syncPath.GetTrashRetentionDays() > 0 {
+				// 开启了回收站，移动到回收站
+				_, err = MoveToSyncTrash(syncPath.ID, trashBaseDir, item.LocalPath)
+				reason += "，已移动到回收站"
+			} else {
+				err = os.Remove(item.LocalPath)
+			}
+			if err != nil {
+				if !os.IsNotExist(err) {
+					helpers.AppLogger.Warnf("删除本地文件失败: %s %v", item.LocalPath, err)
+				}
+				continue
+			}
+			deleted++
+			changes = append(changes, &SyncChange{SyncId: b.SyncId, SyncPathId: b.SyncPathId, Action: SyncChangeActionDeleteLocal, OldPath: item.LocalPath, Reason: reason})
+			if !delEmptyDir {
+				continue
+			}
+			dir := filepath.Dir(item.LocalPath)
+			if entries, err := os.ReadDir(dir); err == nil && len(entries) == 0 {
+				os.Remove(dir)
+			}
+		}
+		return nil
+	}).Error
+	if err != nil {
+		helpers.AppLogger.Errorf("查询删除批次中的文件失败: %v", err)
+		return deleted, err
+	}
+	helpers.AppLogger.Infof("删除批次 %d 已批准，删除本地文件 %d 个，跳过网盘中已存在的文件 %d 个", b.ID, deleted, skipped)
+	return deleted, b.updateStatus(SyncDeleteBatchStatusApproved)
+}
+
+// 本地文件是否对应同步过的网盘文件
+func isSyncedLocalFile(syncPathId uint, localPath string) bool {
+	var count int64
+	db.Db.Model(&SyncFile{}).Where("sync_path_id = ? AND local_file_path = ?", syncPathId, localPath).Limit(1).Count(&count)
+	return count > 0
+}
+
+// 拒绝删除，本地文件保留
+func (b *SyncDeleteBatch) Reject() error {
+	if b.Status != SyncDeleteBatchStatusPending {
+		return fmt.Errorf("删除批次已处理，状态：%s", SyncDeleteBatchStatusText[b.Status])
+	}
+	helpers.AppLogger.Infof("删除批次 %d 已拒绝，保留本地文件", b.ID)
+	return b.updateStatus(SyncDeleteBatchStatusRejected)
+}
+
+func (b *SyncDeleteBatch) updateStatus(status SyncDeleteBatchStatus) error {
+	b.Status = status
+	b.HandledAt = time.Now().Unix()
+	return db.Db.Model(b).Updates(map[string]any{"status": b.Status, "handled_at": b.HandledAt}).Error
+}
+
+// 同步完成并且没有触发删除保护时，之前等待审批的批次已经过时（比如网盘恢复正常后重新生成了STRM），标记为失效
+func SupersedeSyncDeleteBatches(syncPathId, targetId uint) (int64, error) {
+	result := db.Db.Model(&SyncDeleteBatch{}).
+		Where("sync_path_id = ? AND target_id = ? AND status = ?", syncPathId, targetId, SyncDeleteBatchStatusPending).
+		Updates(map[string]any{"status": SyncDeleteBatchStatusSuperseded, "handled_at": time.Now().Unix()})
+	return result.RowsAffected, result.Error
+}
+
+// 删除同步目录的所有删除批次
+func DeleteSyncDeleteBatchesByPathId(syncPathId uint) error {
+	return db.Db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("batch_id IN (?)", tx.Model(&SyncDeleteBatch{}).Select("id").Where("sync_path_id = ?", syncPathId)).Delete(&SyncDeleteBatchItem{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("sync_path_id = ?", syncPathId).Delete(&SyncDeleteBatch{}).Error
+	})
+}
+
+// 发送等待审批的通知
+func (b *SyncDeleteBatch) Notify(remotePath string) {
+	ctx := context.Background()
+	notif := &Notification{
+		Type:  SystemAlert,
+		Title: "⚠️ 同步删除保护已触发",
+		Content: fmt.Sprintf("📁 目录: %s\n🗑️ 待删除: %d / %d 个本地文件\n🔍 原因: %s\n请在同步记录中确认，或发送 /strm_delete_approve #%d 批准删除，/strm_delete_reject #%d 拒绝删除\n⏰ 时间: %s",
+			remotePath, b.DeleteCount, b.LocalTotal, b.Reason, b.ID, b.ID, time.Now().Format("2006-01-02 15:04:05")),
+		Timestamp: time.Now(),
+		Priority:  HighPriority,
+	}
+	if notificationmanager.GlobalEnhancedNotificationManager != nil {
+		if err := notificationmanager.GlobalEnhancedNotificationManager.SendNotification(ctx, notif); err != nil {
+			helpers.AppLogger.Errorf("发送删除保护通知失败: %v", err)
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package models
+
+import (
+	"Q115-STRM/internal/db"
+)
+
+type SyncPlanAction string
+
+const (
+	SyncPlanActionCreateStrm  SyncPlanAction = "create_strm"  // 新建STRM文件
+	SyncPlanActionUpdateStrm  SyncPlanAction = "update_strm"  // 重写STRM文件
+	SyncPlanActionRename      SyncPlanAction = "rename"       // 重命名本地文件
+	SyncPlanActionDownload    SyncPlanAction = "download"     // 添加元数据下载任务
+	SyncPlanActionUpload      SyncPlanAction = "upload"       // 添加元数据上传任务
+	SyncPlanActionDeleteLocal SyncPlanAction = "delete_local" // 删除本地文件或空目录
+	SyncPlanActionSkip        SyncPlanAction = "skip"         // 被过滤规则排除，不处理
+)
+
+// SyncPlanItem 预览同步（不修改任何文件）时记录的每一个将要执行的操作
+type SyncPlanItem struct {
+	BaseModel
+	SyncId       uint           `json:"sync_id" gorm:"index"` // 所属的预览同步记录
+	SyncPathId   uint           `json:"sync_path_id"`
+	Action       SyncPlanAction `json:"action" gorm:"index"`
+	LocalPath    string         `json:"local_path"`     // 操作的本地路径
+	OldLocalPath string         `json:"old_local_path"` // 重命名前的本地路径
+	RemotePath   string         `json:"remote_path"`    // 对应的网盘路径
+	Content      string         `json:"content"`        // 新的STRM内容
+	OldContent   string         `json:"old_content"`    // 原有的STRM内容
+	Reason       string         `json:"reason"`         // 执行该操作的原因
+}
+
+func (*SyncPlanItem) TableName() string {
+	return "sync_plan_items"
+}
+
+// 批量保存预览同步的操作
+func CreateSyncPlanItems(items []*SyncPlanItem) error {
+	if len(items) == 0 {
+		return nil
+	}
+	return db.Db.CreateInBatches(items, 500).Error
+}
+
+// 分页查询预览同步的操作，action为空时查询全部
+func GetSyncPlanItems(syncId uint, action SyncPlanAction, page, pageSize int) ([]*SyncPlanItem, int64, error) {
+	var items []*SyncPlanItem
+	var total int64
+	query := db.Db.Model(&SyncPlanItem{}).Where("sync_id = ?", syncId)
+	if action != "" {
+		query = query.Where("action = ?", action)
+	}
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	if err := query.Offset((page - 1) * pageSize).Limit(pageSize).Order("id ASC").Find(&items).Error; err != nil {
+		return nil, 0, err
+	}
+	return items, total, nil
+}
+
+// 按操作类型统计数量
+func GetSyncPlanSummary(syncId uint) (map[SyncPlanAction]int64, error) {
+	type actionCount struct {
+		Action SyncPlanAction
+		Count  int64
+	}
+	var counts []actionCount
+	err := db.Db.Model(&SyncPlanItem{}).Select("action, COUNT(*) as count").Where("sync_id = ?", syncId).Group("action").Scan(&counts).Error
+	if err != nil {
+		return nil, err
+	}
+	summary := make(map[SyncPlanAction]int64, len(counts))
+	for _, c := range counts {
+		summary[c.Action] = c.Count
+	}
+	return summary, nil
+}
+
+// 删除预览同步的所有操作
+func DeleteSyncPlanItemsBySyncId(syncId uint) error {
+	return db.Db.Where("sync_id = ?", syncId).Delete(&SyncPlanItem{}).Error
+}
+
+// 获取同步目录最近一次的预览同步记录
+func GetLastSyncPlanByPathId(syncPathId uint) *Sync {
+	var sync Sync
+	if err := db.Db.Where("sync_path_id = ? AND is_plan = ?", syncPathId, true).Order("id DESC").First(&sync).Error; err != nil {
+		return nil
+	}
+	return &sync
+}
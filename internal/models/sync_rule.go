@@ -0,0 +1,97 @@
+package models
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// 同步路径的包含和排除规则，匹配相对于同步源路径的完整路径，不区分大小写
+// re:开头的是正则表达式，其他的是glob：* 匹配除/外的任意字符，? 匹配单个字符，** 匹配任意层级目录
+// 不含/的glob只匹配名称，例如 *.sample.mkv；含/的glob匹配完整相对路径，例如 电影/**/花絮/*
+const syncRuleRegexPrefix = "re:"
+
+type SyncPathRule struct {
+	Raw      string
+	re       *regexp.Regexp
+	nameOnly bool // 只匹配名称
+}
+
+// 编译单条规则
+func CompileSyncPathRule(rule string) (*SyncPathRule, error) {
+	rule = strings.TrimSpace(rule)
+	if rule == "" {
+		return nil, fmt.Errorf("规则不能为空")
+	}
+	if expr, ok := strings.CutPrefix(rule, syncRuleRegexPrefix); ok {
+		re, err := regexp.Compile("(?i)" + expr)
+		if err != nil {
+			return nil, fmt.Errorf("正则表达式 %s 不正确: %v", expr, err)
+		}
+		return &SyncPathRule{Raw: rule, re: re}, nil
+	}
+	pattern := strings.Trim(rule, "/")
+	re, err := regexp.Compile("(?i)^" + globToRegexp(pattern) + "$")
+	if err != nil {
+		return nil, fmt.Errorf("规则 %s 不正确: %v", rule, err)
+	}
+	return &SyncPathRule{Raw: rule, re: re, nameOnly: !strings.Contains(pattern, "/")}, nil
+}
+
+// 编译多条规则，忽略空行
+func CompileSyncPathRules(rules []string) ([]*SyncPathRule, error) {
+	compiled := make([]*SyncPathRule, 0, len(rules))
+	for _, rule := range rules {
+		if strings.TrimSpace(rule) == "" {
+			continue
+		}
+		r, err := CompileSyncPathRule(rule)
+		if err != nil {
+			return nil, err
+		}
+		compiled = append(compiled, r)
+	}
+	return compiled, nil
+}
+
+// 检查规则是否都正确
+func ValidateSyncPathRules(rules []string) error {
+	_, err := CompileSyncPathRules(rules)
+	return err
+}
+
+// 是否匹配相对路径
+func (r *SyncPathRule) Match(relPath string) bool {
+	if r.nameOnly {
+		return r.re.MatchString(path.Base(relPath))
+	}
+	return r.re.MatchString(relPath)
+}
+
+func globToRegexp(glob string) string {
+	var sb strings.Builder
+	for i := 0; i < len(glob); i++ {
+		c := glob[i]
+		switch c {
+		case '*':
+			if i+1 < len(glob) && glob[i+1] == '*' {
+				i++
+				if i+1 < len(glob) && glob[i+1] == '/' {
+					// **/ 匹配0或多层目录
+					i++
+					sb.WriteString("(.*/)?")
+				} else {
+					sb.WriteString(".*")
+				}
+			} else {
+				sb.WriteString("[^/]*")
+			}
+		case '?':
+			sb.WriteString("[^/]")
+		default:
+			sb.WriteString(regexp.QuoteMeta(glob[i : i+1]))
+		}
+	}
+	return sb.String()
+}
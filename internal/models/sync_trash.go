@@ -0,0 +1,165 @@
+package models
+
+import (
+	"Q115-STRM/internal/db"
+	"Q115-STRM/internal/helpers"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// 同步删除的本地文件移动到同步目录下的回收站，保留原来的相对路径
+const SyncTrashDirName = ".qms-trash"
+
+// SyncTrashItem 回收站中的文件
+type SyncTrashItem struct {
+	BaseModel
+	SyncPathId   uint   `json:"sync_path_id" gorm:"index"`
+	OriginalPath string `json:"original_path"`           // 删除前的本地路径
+	TrashPath    string `json:"trash_path" gorm:"index"` // 回收站中的路径
+	FileSize     int64  `json:"file_size"`               // 文件大小
+	DeletedAt    int64  `json:"deleted_at" gorm:"index"` // 移入回收站的时间，保留天数从这个时间开始计算
+}
+
+func (*SyncTrashItem) TableName() string {
+	return "sync_trash_items"
+}
+
+// 回收站目录，baseDir是同步目录的本地根目录
+func GetSyncTrashDir(baseDir string) string {
+	return filepath.ToSlash(filepath.Join(baseDir, SyncTrashDirName))
+}
+
+// 将本地文件移动到回收站，回收站中已有同一路径的文件时覆盖
+func MoveToSyncTrash(syncPathId uint, baseDir, filePath string) (*SyncTrashItem, error) {
+	relPath, err := filepath.Rel(baseDir, filePath)
+	if err != nil || relPath == "." || strings.HasPrefix(relPath, "..") {
+		return nil, fmt.Errorf("文件 %s 不在同步目录 %s 下", filePath, baseDir)
+	}
+	info, err := os.Stat(filePath)
+	if err != nil {
+		return nil, err
+	}
+	trashPath := filepath.ToSlash(filepath.Join(GetSyncTrashDir(baseDir), relPath))
+	if err := os.MkdirAll(filepath.Dir(trashPath), 0777); err != nil {
+		return nil, fmt.Errorf("创建回收站目录失败: %w", err)
+	}
+	if err := os.Rename(filePath, trashPath); err != nil {
+		return nil, fmt.Errorf("移动文件到回收站失败: %w", err)
+	}
+	item := &SyncTrashItem{}
+	if err := db.Db.Where("trash_path = ?", trashPath).First(item).Error; err != nil {
+		item = &SyncTrashItem{SyncPathId: syncPathId, TrashPath: trashPath}
+	}
+	item.OriginalPath = filepath.ToSlash(filePath)
+	item.FileSize = info.Size()
+	item.DeletedAt = time.Now().Unix()
+	if err := db.Db.Save(item).Error; err != nil {
+		helpers.AppLogger.Errorf("保存回收站记录失败: %v", err)
+		return nil, err
+	}
+	return item, nil
+}
+
+// 分页查询回收站中的文件，syncPathId为0时查询全部，keyword匹配原路径
+func GetSyncTrashItems(syncPathId uint, keyword string, page, pageSize int) ([]*SyncTrashItem, int64, error) {
+	var items []*SyncTrashItem
+	var total int64
+	query := db.Db.Model(&SyncTrashItem{})
+	if syncPathId > 0 {
+		query = query.Where("sync_path_id = ?", syncPathId)
+	}
+	if keyword != "" {
+		query = query.Where("original_path LIKE ?", "%"+keyword+"%")
+	}
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	if err := query.Offset((page - 1) * pageSize).Limit(pageSize).Order("id DESC").Find(&items).Error; err != nil {
+		return nil, 0, err
+	}
+	return items, total, nil
+}
+
+func GetSyncTrashItemsByIds(ids []uint) []*SyncTrashItem {
+	var items []*SyncTrashItem
+	if err := db.Db.Where("id IN ?", ids).Find(&items).Error; err != nil {
+		helpers.AppLogger.Errorf("查询回收站记录失败: %v", err)
+		return nil
+	}
+	return items
+}
+
+// 将文件还原到原来的位置，原位置已有文件时不还原
+func (t *SyncTrashItem) Restore() error {
+	if helpers.PathExists(t.OriginalPath) {
+		return fmt.Errorf("原位置已存在文件: %s", t.OriginalPath)
+	}
+	if err := os.MkdirAll(filepath.Dir(t.OriginalPath), 0777); err != nil {
+		return fmt.Errorf("创建目录失败: %w", err)
+	}
+	if err := os.Rename(t.TrashPath, t.OriginalPath); err != nil {
+		return fmt.Errorf("还原文件失败: %w", err)
+	}
+	removeEmptyTrashDirs(t.TrashPath)
+	return db.Db.Delete(t).Error
+}
+
+// 彻底删除回收站中的文件
+func (t *SyncTrashItem) Purge() error {
+	if err := os.Remove(t.TrashPath); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	removeEmptyTrashDirs(t.TrashPath)
+	return db.Db.Delete(t).Error
+}
+
+// 从文件所在目录向上删除回收站中的空目录，回收站根目录保留
+func removeEmptyTrashDirs(trashPath string) {
+	dir := filepath.Dir(trashPath)
+	for filepath.Base(dir) != SyncTrashDirName && dir != filepath.Dir(dir) {
+		if entries, err := os.ReadDir(dir); err != nil || len(entries) > 0 {
+			return
+		}
+		if err := os.Remove(dir); err != nil {
+			return
+		}
+		dir = filepath.Dir(dir)
+	}
+}
+
+// 清理超过保留天数的回收站文件
+func PurgeExpiredSyncTrash() {
+	syncPaths, _ := GetSyncPathList(1, 10000000, false)
+	for _, syncPath := range syncPaths {
+		days := syncPath.GetTrashRetentionDays()
+		if days <= 0 {
+			// 不使用回收站时，清理之前留下的文件
+			days = 0
+		}
+		cutoff := time.Now().AddDate(0, 0, -days).Unix()
+		var items []*SyncTrashItem
+		if err := db.Db.Where("sync_path_id = ? AND deleted_at < ?", syncPath.ID, cutoff).Find(&items).Error; err != nil {
+			helpers.AppLogger.Errorf("查询过期的回收站文件失败: %v", err)
+			continue
+		}
+		purged := 0
+		for _, item := range items {
+			if err := item.Purge(); err != nil {
+				helpers.AppLogger.Warnf("清理回收站文件失败: %s %v", item.TrashPath, err)
+				continue
+			}
+			purged++
+		}
+		if purged > 0 {
+			helpers.AppLogger.Infof("同步目录 %s 回收站清理了 %d 个超过 %d 天的文件", syncPath.RemotePath, purged, days)
+		}
+	}
+}
+
+// 删除同步目录的回收站记录，回收站中的文件保留在磁盘上
+func DeleteSyncTrashItemsByPathId(syncPathId uint) error {
+	return db.Db.Where("sync_path_id = ?", syncPathId).Delete(&SyncTrashItem{}).Error
+}
@@ -3,6 +3,9 @@ package models
 import (
 	"Q115-STRM/internal/db"
 	"Q115-STRM/internal/v115open"
+	"math"
+	"slices"
+	"sort"
 )
 
 type SyncTreeItemMetaAction int
@@ -24,7 +27,7 @@ type SyncFile struct {
 	FileSize      int64             `json:"file_size"`
 	FileType      v115open.FileType `json:"file_type"`
 	PickCode      string            `json:"pick_code" gorm:"index:pick_code"`
-	Sha1          string            `json:"sha1"`
+	Sha1          string            `json:"sha1" gorm:"index:sha1"`
 	MTime         int64             `json:"mtime"`                                        // 最后修改时间
 	LocalFilePath string            `json:"local_file_path" gorm:"index:local_file_path"` // 本地文件路径，包含文件名
 	Path          string            `json:"path"`                                         // 绝对路径，不包含FileName
@@ -74,6 +77,102 @@ func GetFileByPickCode(pickCode string) *SyncFile {
 	return db115File
 }
 
+// GetFileBySourcePickCode 按来源和PickCode查询同步过的文件，123云盘和百度网盘的PickCode是数字，不同来源可能重复
+func GetFileBySourcePickCode(sourceType SourceType, pickCode string) *SyncFile {
+	if pickCode == "" {
+		return nil
+	}
+	var syncFile *SyncFile
+	err := db.Db.Model(&SyncFile{}).Where("source_type = ? AND pick_code = ?", sourceType, pickCode).First(&syncFile).Error
+	if err != nil {
+		return nil
+	}
+	return syncFile
+}
+
+// GetFileByFileId 按来源、账号和文件ID查询同步过的文件，OpenList、WebDAV和S3的文件ID是完整路径
+// syncPathId不为0时只查询这个同步路径的文件
+func GetFileByFileId(sourceType SourceType, accountId, syncPathId uint, fileId string) *SyncFile {
+	if fileId == "" {
+		return nil
+	}
+	var syncFile *SyncFile
+	query := db.Db.Model(&SyncFile{}).Where("source_type = ? AND account_id = ? AND file_id = ?", sourceType, accountId, fileId)
+	if syncPathId != 0 {
+		query = query.Where("sync_path_id = ?", syncPathId)
+	}
+	err := query.First(&syncFile).Error
+	if err != nil {
+		return nil
+	}
+	return syncFile
+}
+
+// GetFileSyncPathIds 查询文件所在的同步路径，同一个文件可能在多个同步路径中
+// 115、百度网盘和123云盘按pickCode查询，其他按账号和文件ID查询
+func GetFileSyncPathIds(sourceType SourceType, accountId uint, pickCode, fileId string) []uint {
+	query := db.Db.Model(&SyncFile{}).Where("source_type = ?", sourceType)
+	switch {
+	case pickCode != "":
+		query = query.Where("pick_code = ?", pickCode)
+	case fileId != "":
+		query = query.Where("account_id = ? AND file_id = ?", accountId, fileId)
+	default:
+		return nil
+	}
+	var ids []uint
+	if err := query.Distinct("sync_path_id").Pluck("sync_path_id", &ids).Error; err != nil {
+		return nil
+	}
+	return ids
+}
+
+// GetFailoverFiles 查找其他115账号中SHA1相同的文件，用于直链故障转移
+// 每个账号只返回一个文件，按账号的故障转移顺序排序，返回的文件已经关联账号
+func GetFailoverFiles(sha1 string, excludeAccountId uint) []*SyncFile {
+	if sha1 == "" {
+		return nil
+	}
+	var files []*SyncFile
+	err := db.Db.Model(&SyncFile{}).
+		Where("sha1 = ? AND source_type = ? AND account_id <> ? AND pick_code <> ?", sha1, SourceType115, excludeAccountId, "").
+		Order("id ASC").Find(&files).Error
+	if err != nil {
+		return nil
+	}
+	result := make([]*SyncFile, 0)
+	for _, file := range files {
+		if slices.ContainsFunc(result, func(f *SyncFile) bool { return f.AccountId == file.AccountId }) {
+			continue
+		}
+		account, err := GetAccountById(file.AccountId)
+		if err != nil || account.SourceType != SourceType115 {
+			continue
+		}
+		file.Account = account
+		result = append(result, file)
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		return failoverRank(result[i].Account) < failoverRank(result[j].Account)
+	})
+	return result
+}
+
+// 没有设置顺序的账号排在最后
+func failoverRank(account *Account) int {
+	if account.FailoverOrder <= 0 {
+		return math.MaxInt
+	}
+	return account.FailoverOrder
+}
+
+// CountFilesBySyncPathId 同步路径上次同步保存的文件数量
+func CountFilesBySyncPathId(syncPathId uint) int64 {
+	var count int64
+	db.Db.Model(&SyncFile{}).Where("sync_path_id = ?", syncPathId).Count(&count)
+	return count
+}
+
 func GetFilesBySyncPathId(syncPathId uint, offset, limit int) ([]*SyncFile, error) {
 	var syncFiles []*SyncFile
 	err := db.Db.Model(&SyncFile{}).Where("sync_path_id = ?", syncPathId).Offset(offset).Limit(limit).Find(&syncFiles).Error
@@ -20,6 +20,8 @@ const (
 	SourceType123       SourceType = "123"
 	SourceTypeOpenList  SourceType = "openlist"
 	SourceTypeBaiduPan  SourceType = "baidupan"
+	SourceTypeWebDAV    SourceType = "webdav"
+	SourceTypeS3        SourceType = "s3"
 	SourceTypeEmbyMedia SourceType = "emby媒体信息提取" // emby媒体信息提取专用
 )
 
@@ -35,6 +37,10 @@ func (s SourceType) String() string {
 		return "开放列表"
 	case SourceTypeBaiduPan:
 		return "百度网盘"
+	case SourceTypeWebDAV:
+		return "WebDAV"
+	case SourceTypeS3:
+		return "S3"
 	case SourceTypeEmbyMedia:
 		return "Emby媒体信息提取"
 	default:
@@ -56,24 +62,37 @@ type SyncPath struct {
 	AccountName  string     `json:"account_name" gorm:"-"`  // 115账号名或者123账号名，不参与数据库操作，仅供前端使用
 	IsFullSync   bool       `json:"is_full_sync"`           // 是否全量同步，默认false
 	IsRunning    int        `json:"is_running" gorm:"-"`    // 是否正在运行 0-未运行，1-已在队列，2-正在运行
+	EnableWatch  bool       `json:"enable_watch"`           // 是否启用实时监控，仅本地来源可用
+
+	StrmSignEnabled    bool   `json:"strm_sign_enabled"`     // 是否给STRM地址签名，防止知道PickCode就能获取直链
+	StrmSignExpireDays int    `json:"strm_sign_expire_days"` // 签名有效天数，0表示不过期，过期前会自动重新签名
+	StrmSignSecret     string `json:"-"`                     // 签名密钥
+	StrmSignPrevSecret string `json:"-"`                     // 轮换前的密钥，重新签名完成前继续有效
 }
 
 func GetStrmSettingDefault() SettingStrm {
 	return SettingStrm{
-		StrmBaseUrl:    "",
-		Cron:           "",
-		MinVideoSize:   -1,
-		AddPath:        -1,
-		CheckMetaMtime: -1,
-		UploadMeta:     -1,
-		DownloadMeta:   -1,
-		DeleteDir:      -1,
-		VideoExtArr:    []string{},
-		MetaExtArr:     []string{},
-		ExcludeNameArr: []string{},
-		VideoExt:       "",
-		MetaExt:        "",
-		ExcludeName:    "",
+		StrmBaseUrl:        "",
+		Cron:               "",
+		MinVideoSize:       -1,
+		AddPath:            -1,
+		CheckMetaMtime:     -1,
+		UploadMeta:         -1,
+		DownloadMeta:       -1,
+		DeleteDir:          -1,
+		VideoExtArr:        []string{},
+		MetaExtArr:         []string{},
+		ExcludeNameArr:     []string{},
+		VideoExt:           "",
+		MetaExt:            "",
+		ExcludeName:        "",
+		DeleteMaxCount:     -1,
+		DeleteMaxPercent:   -1,
+		TrashRetentionDays: -1,
+		IncludeRuleArr:     []string{},
+		ExcludeRuleArr:     []string{},
+		MaxVideoSize:       -1,
+		MaxAgeDays:         -1,
 	}
 }
 
@@ -126,6 +145,34 @@ func (sp *SyncPath) GetExcludeNameArr() []string {
 	return sp.ExcludeNameArr
 }
 
+func (sp *SyncPath) GetIncludeRuleArr() []string {
+	if len(sp.IncludeRuleArr) == 0 {
+		return SettingsGlobal.IncludeRuleArr
+	}
+	return sp.IncludeRuleArr
+}
+
+func (sp *SyncPath) GetExcludeRuleArr() []string {
+	if len(sp.ExcludeRuleArr) == 0 {
+		return SettingsGlobal.ExcludeRuleArr
+	}
+	return sp.ExcludeRuleArr
+}
+
+func (sp *SyncPath) GetMaxVideoSize() int64 {
+	if sp.MaxVideoSize == -1 {
+		return SettingsGlobal.MaxVideoSize
+	}
+	return sp.MaxVideoSize
+}
+
+func (sp *SyncPath) GetMaxAgeDays() int {
+	if sp.MaxAgeDays == -1 {
+		return SettingsGlobal.MaxAgeDays
+	}
+	return sp.MaxAgeDays
+}
+
 func (sp *SyncPath) GetAddPath() int {
 	if sp.AddPath != -1 {
 		return sp.AddPath
@@ -154,6 +201,34 @@ func (sp *SyncPath) GetStrmBaseUrl() string {
 	return sp.StrmBaseUrl
 }
 
+func (sp *SyncPath) GetStrmTemplate() string {
+	if sp.StrmTemplate == "" {
+		return SettingsGlobal.StrmTemplate
+	}
+	return sp.StrmTemplate
+}
+
+func (sp *SyncPath) GetDeleteMaxCount() int {
+	if sp.DeleteMaxCount == -1 {
+		return SettingsGlobal.DeleteMaxCount
+	}
+	return sp.DeleteMaxCount
+}
+
+func (sp *SyncPath) GetDeleteMaxPercent() int {
+	if sp.DeleteMaxPercent == -1 {
+		return SettingsGlobal.DeleteMaxPercent
+	}
+	return sp.DeleteMaxPercent
+}
+
+func (sp *SyncPath) GetTrashRetentionDays() int {
+	if sp.TrashRetentionDays == -1 {
+		return SettingsGlobal.TrashRetentionDays
+	}
+	return sp.TrashRetentionDays
+}
+
 // 修改同步路径
 func (sp *SyncPath) Update(sourceType SourceType, accountId uint, baseCid, localPath, remotePath string, enableCron bool, customConfig bool, syncPathSetting SettingStrm) bool {
 	if runtime.GOOS != "windows" {
@@ -255,6 +330,54 @@ func (sp *SyncPath) ToggleCron() {
 	db.Db.Save(sp)
 }
 
+func (sp *SyncPath) ToggleWatch() {
+	sp.EnableWatch = !sp.EnableWatch
+	db.Db.Save(sp)
+}
+
+// GetStrmSignSecret 获取当前的签名密钥，没有启用签名返回空
+func (sp *SyncPath) GetStrmSignSecret() string {
+	if !sp.StrmSignEnabled {
+		return ""
+	}
+	return sp.StrmSignSecret
+}
+
+// GetStrmSignExpireAt 新签名的过期时间戳，0表示不过期
+func (sp *SyncPath) GetStrmSignExpireAt(now time.Time) int64 {
+	return helpers.StrmSignExpireAt(sp.StrmSignExpireDays, now)
+}
+
+// UpdateStrmSign 修改签名设置，第一次启用时生成密钥
+func (sp *SyncPath) UpdateStrmSign(enabled bool, expireDays int) error {
+	sp.StrmSignEnabled = enabled
+	sp.StrmSignExpireDays = max(expireDays, 0)
+	if sp.StrmSignSecret == "" {
+		sp.StrmSignSecret = helpers.GenerateStrmSignSecret()
+	}
+	return db.Db.Model(sp).Updates(map[string]any{
+		"strm_sign_enabled":     sp.StrmSignEnabled,
+		"strm_sign_expire_days": sp.StrmSignExpireDays,
+		"strm_sign_secret":      sp.StrmSignSecret,
+	}).Error
+}
+
+// RotateStrmSignSecret 轮换签名密钥，旧密钥在重新签名完成前继续有效
+func (sp *SyncPath) RotateStrmSignSecret() error {
+	sp.StrmSignPrevSecret = sp.StrmSignSecret
+	sp.StrmSignSecret = helpers.GenerateStrmSignSecret()
+	return db.Db.Model(sp).Updates(map[string]any{
+		"strm_sign_secret":      sp.StrmSignSecret,
+		"strm_sign_prev_secret": sp.StrmSignPrevSecret,
+	}).Error
+}
+
+// ClearStrmSignPrevSecret 重新签名完成后让旧密钥失效
+func (sp *SyncPath) ClearStrmSignPrevSecret() error {
+	sp.StrmSignPrevSecret = ""
+	return db.Db.Model(sp).Update("strm_sign_prev_secret", "").Error
+}
+
 func (sp *SyncPath) IsValidVideoExt(name string) bool {
 	ext := filepath.Ext(name)
 	ext = strings.ToLower(ext)
@@ -285,7 +408,7 @@ func (sp *SyncPath) MakeFullLocalPath(pid, name string) string {
 	switch sp.SourceType {
 	case SourceType115:
 		return filepath.Join(sp.LocalPath, sp.RemotePath, pid, name)
-	case SourceTypeOpenList:
+	case SourceTypeOpenList, SourceTypeWebDAV, SourceTypeS3:
 		return filepath.Join(sp.LocalPath, pid, name)
 	case SourceTypeLocal:
 		return filepath.Join(sp.LocalPath, pid, name)
@@ -366,6 +489,10 @@ func DeleteSyncPathById(id uint) bool {
 	tx.Delete(EmbyLibrarySyncPath{}, "sync_path_id = ?", syncPath.ID)
 	tx.Delete(EmbyMediaSyncFile{}, "sync_path_id = ?", syncPath.ID)
 	tx.Commit()
+	DeleteSyncCheckpointByPathId(syncPath.ID)
+	DeleteSyncDeleteBatchesByPathId(syncPath.ID)
+	DeleteSyncTrashItemsByPathId(syncPath.ID)
+	DeleteSyncPathTargetsByPathId(syncPath.ID)
 	// 其他类型删除localpath/remotePath
 	fullPath := filepath.Join(syncPath.LocalPath, syncPath.RemotePath)
 	if syncPath.SourceType == SourceTypeLocal {
@@ -444,9 +571,26 @@ func GetSyncPathList(page, pageSize int, enableCron bool) ([]*SyncPath, int64) {
 	return syncPaths, total
 }
 
+// 获取所有同步路径，按ID升序
+func GetAllSyncPaths() []*SyncPath {
+	var syncPaths []*SyncPath
+	db.Db.Order("id ASC").Find(&syncPaths)
+	return syncPaths
+}
+
 // 根据账号ID获取同步路径列表
 func GetAllSyncPathByAccountId(accountId uint) []SyncPath {
 	var syncPaths []SyncPath
 	db.Db.Where("account_id = ?", accountId).Find(&syncPaths)
 	return syncPaths
 }
+
+// 获取所有启用了实时监控的本地同步路径
+func GetWatchedLocalSyncPaths() []*SyncPath {
+	var syncPaths []*SyncPath
+	db.Db.Where("source_type = ? AND enable_watch = ?", SourceTypeLocal, true).Find(&syncPaths)
+	for _, syncPath := range syncPaths {
+		syncPath.ParseVideoAndMetaExt()
+	}
+	return syncPaths
+}
@@ -0,0 +1,190 @@
+package models
+
+import (
+	"Q115-STRM/internal/db"
+	"Q115-STRM/internal/helpers"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// SyncPathTarget 同步目录的额外目标目录
+// 同步时只查询一次网盘文件列表，然后分别生成到同步目录自己的本地路径和所有目标目录
+// 每个目标目录可以使用不同的STRM地址、模板和元数据设置，其他设置（扩展名、过滤规则、删除保护等）和同步目录相同
+type SyncPathTarget struct {
+	BaseModel
+	SyncPathId   uint   `json:"sync_path_id" form:"sync_path_id" gorm:"index"`
+	Name         string `json:"name" form:"name"`                                     // 名称，例如Kodi
+	LocalPath    string `json:"local_path" form:"local_path"`                         // 存放strm文件和元数据文件的本地路径，和同步目录的LocalPath含义相同
+	Enabled      bool   `json:"enabled" form:"enabled" gorm:"default:true"`           // 是否启用
+	StrmBaseUrl  string `json:"strm_base_url" form:"strm_base_url"`                   // STRM的基础URL，为空使用同步目录的设置
+	StrmTemplate string `json:"strm_template" form:"strm_template"`                   // STRM内容模板，为空使用同步目录的设置
+	AddPath      int    `json:"add_path" form:"add_path" gorm:"default:-1"`           // 是否添加路径，-1使用同步目录的设置，1添加，2不添加
+	DownloadMeta int    `json:"download_meta" form:"download_meta" gorm:"default:-1"` // 是否下载元数据，-1使用同步目录的设置，0不下载，1下载
+	UploadMeta   int    `json:"upload_meta" form:"upload_meta" gorm:"default:-1"`     // 网盘不存在的元数据，-1使用同步目录的设置，0保留，2删除，目标目录不支持上传
+	DeleteDir    int    `json:"delete_dir" form:"delete_dir" gorm:"default:-1"`       // 是否删除空目录，-1使用同步目录的设置，0不删除，1删除
+}
+
+func (*SyncPathTarget) TableName() string {
+	return "sync_path_targets"
+}
+
+func (t *SyncPathTarget) GetStrmBaseUrl(sp *SyncPath) string {
+	if t.StrmBaseUrl == "" {
+		return sp.GetStrmBaseUrl()
+	}
+	return t.StrmBaseUrl
+}
+
+func (t *SyncPathTarget) GetStrmTemplate(sp *SyncPath) string {
+	if t.StrmTemplate == "" {
+		return sp.GetStrmTemplate()
+	}
+	return t.StrmTemplate
+}
+
+func (t *SyncPathTarget) GetAddPath(sp *SyncPath) int {
+	if t.AddPath == -1 {
+		return sp.GetAddPath()
+	}
+	return t.AddPath
+}
+
+func (t *SyncPathTarget) GetDownloadMeta(sp *SyncPath) int {
+	if t.DownloadMeta == -1 {
+		return sp.GetDownloadMeta()
+	}
+	return t.DownloadMeta
+}
+
+// 目标目录不上传元数据，同步目录设置为上传时，目标目录保留本地文件
+func (t *SyncPathTarget) GetUploadMeta(sp *SyncPath) int {
+	uploadMeta := t.UploadMeta
+	if uploadMeta == -1 {
+		uploadMeta = sp.GetUploadMeta()
+	}
+	if uploadMeta == int(SyncTreeItemMetaActionUpload) {
+		return int(SyncTreeItemMetaActionKeep)
+	}
+	return uploadMeta
+}
+
+func (t *SyncPathTarget) GetDeleteDir(sp *SyncPath) int {
+	if t.DeleteDir == -1 {
+		return sp.GetDeleteDir()
+	}
+	return t.DeleteDir
+}
+
+// 获取完整的本地路径
+func (t *SyncPathTarget) GetFullLocalPath(sp *SyncPath) string {
+	if sp.SourceType == SourceTypeLocal {
+		return t.LocalPath
+	}
+	return filepath.Join(t.LocalPath, sp.RemotePath)
+}
+
+// 检查目标目录的设置，本地路径不能和同步目录或其他目标目录重叠，否则对比本地文件时会互相删除
+func (t *SyncPathTarget) Validate(sp *SyncPath) error {
+	if strings.TrimSpace(t.LocalPath) == "" {
+		return fmt.Errorf("目标目录的本地路径不能为空")
+	}
+	if t.UploadMeta == int(SyncTreeItemMetaActionUpload) {
+		return fmt.Errorf("目标目录不支持上传元数据")
+	}
+	if err := ValidateStrmTemplate(t.StrmTemplate); err != nil {
+		return err
+	}
+	fullPath := t.GetFullLocalPath(sp)
+	if isPathOverlap(fullPath, sp.GetFullLocalPath()) {
+		return fmt.Errorf("目标目录 %s 和同步目录的本地路径 %s 重叠", fullPath, sp.GetFullLocalPath())
+	}
+	for _, other := range GetSyncPathTargets(sp.ID) {
+		if other.ID == t.ID {
+			continue
+		}
+		if otherPath := other.GetFullLocalPath(sp); isPathOverlap(fullPath, otherPath) {
+			return fmt.Errorf("目标目录 %s 和目标目录 %s 的本地路径 %s 重叠", fullPath, other.Name, otherPath)
+		}
+	}
+	return nil
+}
+
+// 两个路径相同或者一个是另一个的上级目录
+func isPathOverlap(a, b string) bool {
+	a = filepath.ToSlash(filepath.Clean(a))
+	b = filepath.ToSlash(filepath.Clean(b))
+	return a == b || strings.HasPrefix(a, strings.TrimSuffix(b, "/")+"/") || strings.HasPrefix(b, strings.TrimSuffix(a, "/")+"/")
+}
+
+func GetSyncPathTargetById(id uint) *SyncPathTarget {
+	var target SyncPathTarget
+	if err := db.Db.First(&target, id).Error; err != nil {
+		return nil
+	}
+	return &target
+}
+
+// 获取同步目录的所有目标目录
+func GetSyncPathTargets(syncPathId uint) []*SyncPathTarget {
+	var targets []*SyncPathTarget
+	if err := db.Db.Where("sync_path_id = ?", syncPathId).Order("id ASC").Find(&targets).Error; err != nil {
+		helpers.AppLogger.Errorf("查询同步目录的目标目录失败: %v", err)
+		return nil
+	}
+	return targets
+}
+
+// 获取同步目录启用的目标目录
+func GetEnabledSyncPathTargets(syncPathId uint) []*SyncPathTarget {
+	var targets []*SyncPathTarget
+	if err := db.Db.Where("sync_path_id = ? AND enabled = ?", syncPathId, true).Order("id ASC").Find(&targets).Error; err != nil {
+		helpers.AppLogger.Errorf("查询同步目录的目标目录失败: %v", err)
+		return nil
+	}
+	return targets
+}
+
+// 保存目标目录，新建时创建本地目录
+func (t *SyncPathTarget) Save(sp *SyncPath) error {
+	t.SyncPathId = sp.ID
+	t.LocalPath = strings.TrimRight(filepath.ToSlash(t.LocalPath), "/")
+	// 使用map保存，避免0值不更新
+	data := map[string]any{
+		"sync_path_id":  t.SyncPathId,
+		"name":          t.Name,
+		"local_path":    t.LocalPath,
+		"enabled":       t.Enabled,
+		"strm_base_url": strings.TrimSuffix(t.StrmBaseUrl, "/"),
+		"strm_template": strings.TrimSpace(t.StrmTemplate),
+		"add_path":      t.AddPath,
+		"download_meta": t.DownloadMeta,
+		"upload_meta":   t.UploadMeta,
+		"delete_dir":    t.DeleteDir,
+	}
+	var err error
+	if t.ID == 0 {
+		if err = db.Db.Create(t).Error; err == nil {
+			err = db.Db.Model(t).Updates(data).Error
+		}
+	} else {
+		err = db.Db.Model(t).Updates(data).Error
+	}
+	if err != nil {
+		helpers.AppLogger.Errorf("保存目标目录失败: %v", err)
+		return err
+	}
+	os.MkdirAll(t.GetFullLocalPath(sp), 0777)
+	return nil
+}
+
+// 删除目标目录，本地文件保留
+func (t *SyncPathTarget) Delete() error {
+	return db.Db.Delete(t).Error
+}
+
+// 删除同步目录的所有目标目录
+func DeleteSyncPathTargetsByPathId(syncPathId uint) error {
+	return db.Db.Where("sync_path_id = ?", syncPathId).Delete(&SyncPathTarget{}).Error
+}
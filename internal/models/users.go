@@ -3,6 +3,7 @@ package models
 import (
 	"Q115-STRM/internal/db"
 	"Q115-STRM/internal/helpers"
+	"sync/atomic"
 
 	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
@@ -19,6 +20,14 @@ func (User) TableName() string {
 	return "users"
 }
 
+// 用户名密码或API Key变化时加1，缓存的认证结果带上版本号，变化后立即失效
+var credentialVersion atomic.Int64
+
+// CredentialVersion 当前的认证信息版本号
+func CredentialVersion() int64 {
+	return credentialVersion.Load()
+}
+
 // 修改用户密码
 // 传入用户ID和新密码，更新用户的密码
 func (user *User) ChangeUsernameAndPassword(username, newPassword string) (bool, error) {
@@ -40,6 +49,7 @@ func (user *User) ChangeUsernameAndPassword(username, newPassword string) (bool,
 		helpers.AppLogger.Errorf("修改用户名和密码失败: %v", err)
 		return false, err
 	}
+	credentialVersion.Add(1)
 	return isChange, nil
 }
 
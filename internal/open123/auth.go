@@ -1,6 +1,8 @@
 package open123
 
 import (
+	"Q115-STRM/internal/helpers"
+	"context"
 	"encoding/json"
 	"fmt"
 	"time"
@@ -70,6 +72,15 @@ func (c *Client) performTokenRefresh() error {
 	c.expiredAt = expiredAt
 	c.tokenMu.Unlock()
 
+	// 通知models保存token到数据库
+	if c.AccountId > 0 {
+		helpers.PublishSync(helpers.Save123TokenEvent, map[string]any{
+			"account_id": c.AccountId,
+			"token":      result.Data.AccessToken,
+			"expired_at": expiredAt.Unix(),
+		})
+	}
+
 	return nil
 }
 
@@ -84,3 +95,41 @@ func (c *Client) GetExpiredAt() time.Time {
 	defer c.tokenMu.RUnlock()
 	return c.expiredAt
 }
+
+type UserInfoResponse struct {
+	UID            int64  `json:"uid"`
+	Nickname       string `json:"nickname"`
+	HeadImage      string `json:"headImage"`
+	Passport       string `json:"passport"`
+	Mail           string `json:"mail"`
+	SpaceUsed      int64  `json:"spaceUsed"`
+	SpacePermanent int64  `json:"spacePermanent"`
+	SpaceTemp      int64  `json:"spaceTemp"`
+	Vip            bool   `json:"vip"`
+}
+
+// 获取当前开发者对应的用户信息
+func (c *Client) GetUserInfo(ctx context.Context) (*UserInfoResponse, error) {
+	url := fmt.Sprintf("%s/api/v1/user/info", c.baseURL)
+
+	resp, err := c.doRequest(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if !resp.IsSuccess() {
+		return nil, fmt.Errorf("get user info failed with status: %s", resp.Status())
+	}
+
+	result := &RespBase[UserInfoResponse]{}
+	if err := json.Unmarshal(resp.Bytes(), result); err != nil {
+		return nil, fmt.Errorf("unmarshal user info response failed: %w", err)
+	}
+
+	if result.Code != 0 {
+		return nil, fmt.Errorf("get user info failed: code=%d, message=%s", result.Code, result.Message)
+	}
+
+	return &result.Data, nil
+}
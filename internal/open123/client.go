@@ -1,8 +1,10 @@
 package open123
 
 import (
+	"Q115-STRM/internal/scheduler"
 	"context"
 	"fmt"
+	"net/http"
 	"net/url"
 	"sync"
 	"time"
@@ -12,6 +14,7 @@ import (
 )
 
 type Client struct {
+	AccountId    uint
 	clientID     string
 	clientSecret string
 	accessToken  string
@@ -44,6 +47,51 @@ func NewClient(clientID, clientSecret string) *Client {
 	}
 }
 
+var cachedClients map[uint]*Client = make(map[uint]*Client, 0)
+var cachedClientsMutex sync.Mutex
+
+// 获取账号对应的客户端，同一个账号复用同一个客户端（共享限速器）
+// expiredAt: token的过期时间戳（秒）
+func GetClient(accountId uint, clientID, clientSecret, accessToken string, expiredAt int64) *Client {
+	cachedClientsMutex.Lock()
+	defer cachedClientsMutex.Unlock()
+	if client, exists := cachedClients[accountId]; exists {
+		client.clientID = clientID
+		client.clientSecret = clientSecret
+		client.SetAccessToken(accessToken, expiredAt)
+		return client
+	}
+	client := NewClient(clientID, clientSecret)
+	client.AccountId = accountId
+	// 所有请求经过账号的调度器限速
+	client.client.SetTransport(scheduler.NewTransport(client.client.Transport(), scheduler.Get(scheduler.SOURCE_123, accountId), isThrottled))
+	client.initDefaultRateLimits()
+	client.SetAccessToken(accessToken, expiredAt)
+	cachedClients[accountId] = client
+	return client
+}
+
+// 设置访问凭证，token由数据库保存，启动时需要恢复
+func (c *Client) SetAccessToken(accessToken string, expiredAt int64) {
+	c.tokenMu.Lock()
+	defer c.tokenMu.Unlock()
+	c.accessToken = accessToken
+	if accessToken == "" || expiredAt <= 0 {
+		c.expiredAt = time.Time{}
+		return
+	}
+	c.expiredAt = time.Unix(expiredAt, 0)
+}
+
+// 123云盘返回429表示触发了限流
+func isThrottled(resp *http.Response, body []byte) bool {
+	if resp.StatusCode == http.StatusTooManyRequests {
+		return true
+	}
+	code, ok := scheduler.ResponseCode(body, "code")
+	return ok && code == ErrCodeRateLimit
+}
+
 func (c *Client) initDefaultRateLimits() {
 	c.SetRateLimit("/api/v1/", 10)
 	c.SetRateLimit("/upload/v2/", 5)
@@ -127,34 +175,17 @@ func (c *Client) isTokenExpiredLocked() bool {
 }
 
 func (c *Client) ensureValidAccessToken(ctx context.Context) error {
-	var refreshErr error
-	c.refreshOnce.Do(func() {
-		refreshErr = c.refreshAccessToken()
-		if refreshErr != nil {
-			c.refreshOnce = sync.Once{}
-		} else {
-			close(c.refreshTokenChan)
-		}
-	})
-
-	if refreshErr != nil {
-		return refreshErr
-	}
-
-	select {
-	case <-c.refreshTokenChan:
-		return nil
-	case <-ctx.Done():
-		return ctx.Err()
-	case <-time.After(30 * time.Second):
-		return fmt.Errorf("token refresh timeout")
+	if err := ctx.Err(); err != nil {
+		return err
 	}
+	return c.refreshAccessToken()
 }
 
 func (c *Client) refreshAccessToken() error {
 	c.isRefreshing.Lock()
 	defer c.isRefreshing.Unlock()
 
+	// 其他请求已经刷新过了
 	c.tokenMu.RLock()
 	if !c.isTokenExpiredLocked() {
 		c.tokenMu.RUnlock()
@@ -162,5 +193,12 @@ func (c *Client) refreshAccessToken() error {
 	}
 	c.tokenMu.RUnlock()
 
-	return nil
+	return c.performTokenRefresh()
+}
+
+// 强制使用clientID和clientSecret重新获取访问凭证
+func (c *Client) RefreshAccessToken() error {
+	c.isRefreshing.Lock()
+	defer c.isRefreshing.Unlock()
+	return c.performTokenRefresh()
 }
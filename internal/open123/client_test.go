@@ -1,6 +1,10 @@
 package open123
 
 import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"sync"
 	"testing"
 	"time"
@@ -212,3 +216,72 @@ func TestFileUploadCreateRequest(t *testing.T) {
 		t.Errorf("Expected Size 44321, got %d", req.Size)
 	}
 }
+
+func TestGetClientCached(t *testing.T) {
+	expiredAt := time.Now().Add(1 * time.Hour).Unix()
+	client := GetClient(9999, "test_id", "test_secret", "token_1", expiredAt)
+	if client.AccountId != 9999 {
+		t.Errorf("Expected AccountId 9999, got %d", client.AccountId)
+	}
+	if client.limiters["/api/v1/"] == nil {
+		t.Error("Expected default rate limiters to be initialized")
+	}
+
+	cached := GetClient(9999, "test_id", "test_secret", "token_2", expiredAt)
+	if cached != client {
+		t.Error("Expected cached client to be reused")
+	}
+	if cached.GetAccessToken() != "token_2" {
+		t.Errorf("Expected token to be updated to token_2, got %s", cached.GetAccessToken())
+	}
+	if cached.isTokenExpired() {
+		t.Error("Expected token not to be expired")
+	}
+
+	// 没有token时视为过期
+	GetClient(9999, "test_id", "test_secret", "", 0)
+	if !client.isTokenExpired() {
+		t.Error("Expected empty token to be expired")
+	}
+}
+
+func TestListAllFiles(t *testing.T) {
+	repeat := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v2/file/list" || r.URL.Query().Get("limit") != "100" {
+			t.Errorf("unexpected request %s", r.URL.String())
+		}
+		switch last := r.URL.Query().Get("lastFileId"); {
+		case last == "":
+			fmt.Fprint(w, `{"code":0,"data":{"lastFileId":2,"fileList":[{"fileId":1,"filename":"电影","type":1,"size":0},{"fileId":2,"filename":"a.mkv","type":0,"size":1048576,"etag":"e","updateAt":"2025-02-24 17:56:45"}]}}`)
+		case repeat:
+			fmt.Fprint(w, `{"code":0,"data":{"lastFileId":2,"fileList":[{"fileId":2,"filename":"a.mkv","type":0,"size":1048576}]}}`)
+		default:
+			fmt.Fprint(w, `{"code":0,"data":{"lastFileId":-1,"fileList":[{"fileId":3,"filename":"b.mkv","type":0,"size":2048,"trashed":1}]}}`)
+		}
+	}))
+	defer server.Close()
+
+	client := NewClient("test_id", "test_secret")
+	client.baseURL = server.URL
+	client.SetAccessToken("token", time.Now().Add(time.Hour).Unix())
+	files, err := client.ListAllFiles(context.Background(), 0)
+	if err != nil {
+		t.Fatalf("ListAllFiles: %v", err)
+	}
+	if len(files) != 3 {
+		t.Fatalf("got %d files, want 3", len(files))
+	}
+	if files[0].FileType != 1 || files[1].FileType != 0 || files[1].FileSize != 1048576 || files[1].FileName != "a.mkv" || files[2].Trashed != 1 {
+		t.Fatalf("decoded files = %+v", files)
+	}
+	if got := files[1].UpdateTime(); got != time.Date(2025, 2, 24, 9, 56, 45, 0, time.UTC).Unix() {
+		t.Fatalf("UpdateTime = %d", got)
+	}
+
+	// 接口重复返回同一页时报错，不会一直请求
+	repeat = true
+	if _, err := client.ListAllFiles(context.Background(), 0); err == nil {
+		t.Fatal("repeated lastFileId did not fail")
+	}
+}
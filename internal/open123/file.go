@@ -6,8 +6,15 @@ import (
 	"fmt"
 )
 
-func (c *Client) ListFiles(ctx context.Context, parentFileID int64, page, pageSize int) (*FileListResponse, error) {
-	url := fmt.Sprintf("%s/api/v2/file/list?driveID=0&parentFileID=%d&page=%d&pageSize=%d", c.baseURL, parentFileID, page, pageSize)
+// ListFilesLimit 文件列表每页的最大数量
+const ListFilesLimit = 100
+
+// ListFiles 获取目录下的一页文件，lastFileID为0时从第一页开始
+func (c *Client) ListFiles(ctx context.Context, parentFileID int64, limit int, lastFileID int64) (*FileListResponse, error) {
+	url := fmt.Sprintf("%s/api/v2/file/list?parentFileId=%d&limit=%d", c.baseURL, parentFileID, limit)
+	if lastFileID > 0 {
+		url += fmt.Sprintf("&lastFileId=%d", lastFileID)
+	}
 
 	resp, err := c.doRequest(ctx, "GET", url, nil)
 	if err != nil {
@@ -31,6 +38,32 @@ func (c *Client) ListFiles(ctx context.Context, parentFileID int64, page, pageSi
 	return &result.Data, nil
 }
 
+// ListAllFiles 按lastFileId分页取出目录下的所有文件，包括回收站中的文件
+// 接口重复返回同一个lastFileId时报错，避免一直请求同一页
+func (c *Client) ListAllFiles(ctx context.Context, parentFileID int64) ([]FileInfo, error) {
+	files := make([]FileInfo, 0)
+	seen := make(map[int64]bool)
+	var lastFileID int64
+	for {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		resp, err := c.ListFiles(ctx, parentFileID, ListFilesLimit, lastFileID)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, resp.FileList...)
+		if resp.LastFileID == -1 || len(resp.FileList) == 0 {
+			return files, nil
+		}
+		if seen[resp.LastFileID] {
+			return nil, fmt.Errorf("list files failed: repeated lastFileId %d", resp.LastFileID)
+		}
+		seen[resp.LastFileID] = true
+		lastFileID = resp.LastFileID
+	}
+}
+
 func (c *Client) CreateFolder(ctx context.Context, name string, parentFileID int64) (*CreateFolderResponse, error) {
 	url := fmt.Sprintf("%s/api/v1/dir/create", c.baseURL)
 
@@ -1,5 +1,7 @@
 package open123
 
+import "time"
+
 type RespBase[T any] struct {
 	XTraceID string `json:"x-traceID"`
 	Code     int    `json:"code"`
@@ -25,27 +27,34 @@ type UploadDomainResponse struct {
 	Domains []string `json:"data"`
 }
 
+// FileInfo /api/v2/file/list 返回的文件
 type FileInfo struct {
-	FileID     int64  `json:"fileID"`
-	FileName   string `json:"fileName"`
-	FileSize   int64  `json:"fileSize"`
-	CreateTime int64  `json:"createTime"`
-	UpdateTime int64  `json:"updateTime"`
-	FileType   int    `json:"fileType"`
-	Etag       string `json:"etag"`
+	FileID       int64  `json:"fileId"`
+	FileName     string `json:"filename"`
+	FileSize     int64  `json:"size"`
+	FileType     int    `json:"type"` // 0文件 1文件夹
+	Etag         string `json:"etag"`
+	Status       int    `json:"status"` // 审核状态，大于100是审核驳回的文件
+	ParentFileID int64  `json:"parentFileId"`
+	Category     int    `json:"category"` // 0未知 1音频 2视频 3图片
+	Trashed      int    `json:"trashed"`  // 是否在回收站
+	CreateAt     string `json:"createAt"`
+	UpdateAt     string `json:"updateAt"`
 }
 
-type FileListRequest struct {
-	DriveID      int64 `json:"driveID"`
-	ParentFileID int64 `json:"parentFileID"`
-	Page         int   `json:"page"`
-	PageSize     int   `json:"pageSize"`
+// UpdateTime 修改时间戳，123云盘返回的是北京时间的字符串
+func (f *FileInfo) UpdateTime() int64 {
+	t, err := time.ParseInLocation("2006-01-02 15:04:05", f.UpdateAt, time.FixedZone("CST", 8*3600))
+	if err != nil {
+		return 0
+	}
+	return t.Unix()
 }
 
+// FileListResponse 文件列表的一页，LastFileID为-1表示最后一页，否则作为下一页的lastFileId参数
 type FileListResponse struct {
-	Total    int64      `json:"total"`
-	NextPage int        `json:"nextPage"`
-	FileList []FileInfo `json:"fileList"`
+	LastFileID int64      `json:"lastFileId"`
+	FileList   []FileInfo `json:"fileList"`
 }
 
 type CreateFolderRequest struct {
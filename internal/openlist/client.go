@@ -2,6 +2,7 @@ package openlist
 
 import (
 	"Q115-STRM/internal/helpers"
+	"Q115-STRM/internal/scheduler"
 	"encoding/json"
 	"fmt"
 	"net/http"
@@ -40,6 +41,8 @@ func NewClient(accountId uint, url, username, password, accessToken string) *Cli
 	}
 	restyClient := resty.New()
 	restyClient.SetTimeout(time.Duration(DEFAULT_TIMEOUT) * time.Second).SetBaseURL(url)
+	// 所有请求经过账号的调度器限速
+	restyClient.SetTransport(scheduler.NewTransport(restyClient.Transport(), scheduler.Get(scheduler.SOURCE_OPENLIST, accountId), isThrottled))
 	// 设置代理
 	// restyClient.SetProxy("http://127.0.0.1:10808")
 
@@ -55,6 +58,15 @@ func NewClient(accountId uint, url, username, password, accessToken string) *Cli
 	return client
 }
 
+// OpenList返回429表示触发了限流
+func isThrottled(resp *http.Response, body []byte) bool {
+	if resp.StatusCode == http.StatusTooManyRequests {
+		return true
+	}
+	code, ok := scheduler.ResponseCode(body, "code")
+	return ok && code == http.StatusTooManyRequests
+}
+
 func (c *Client) SetAuthToken(accessToken string) {
 	c.AccessToken = accessToken
 }
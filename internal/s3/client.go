@@ -0,0 +1,669 @@
+package s3
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// 列表、删除等请求的超时时间，下载和上传不限制
+	DEFAULT_TIMEOUT = 60 // 秒
+	DEFAULT_REGION  = "us-east-1"
+
+	// 复制和合并分片在服务端完成，大文件需要的时间比较长
+	copyTimeout = 10 * time.Minute
+
+	unsignedPayload = "UNSIGNED-PAYLOAD"
+	emptyPayload    = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855" // 空内容的sha256
+)
+
+// 单次复制的最大文件大小，超过时使用分片复制，测试时修改
+var (
+	maxCopySize  int64 = 5 << 30
+	copyPartSize int64 = 1 << 30
+)
+
+// Client S3兼容对象存储客户端，使用AWS Signature V4签名
+// 所有的path参数都是以/开头的路径，对应的对象key去掉开头的/，目录就是以/结尾的key前缀
+type Client struct {
+	AccountId uint
+	Endpoint  string // 服务地址，例如 http://127.0.0.1:9000 或 https://s3.amazonaws.com
+	Region    string
+	Bucket    string
+	AccessKey string
+	SecretKey string
+	PathStyle bool // true使用 endpoint/bucket/key，false使用 bucket.endpoint/key（MinIO一般使用true）
+	client    *http.Client
+	now       func() time.Time
+}
+
+// Object 文件或目录信息
+type Object struct {
+	Path     string    `json:"path"` // 以/开头的完整路径
+	Name     string    `json:"name"`
+	Size     int64     `json:"size"`
+	IsDir    bool      `json:"is_dir"`
+	Modified time.Time `json:"modified"`
+	ETag     string    `json:"etag"`
+}
+
+var cachedClients map[uint]*Client = make(map[uint]*Client, 0)
+var cachedClientsMutex sync.Mutex
+
+// NewClient 创建客户端，同一个账号复用同一个客户端
+func NewClient(accountId uint, endpoint, region, bucket, accessKey, secretKey string, pathStyle bool) *Client {
+	cachedClientsMutex.Lock()
+	defer cachedClientsMutex.Unlock()
+	endpoint = strings.TrimSuffix(endpoint, "/")
+	if region == "" {
+		region = DEFAULT_REGION
+	}
+	client, exists := cachedClients[accountId]
+	if !exists || accountId == 0 {
+		client = &Client{AccountId: accountId, client: &http.Client{}, now: time.Now}
+		if accountId != 0 {
+			cachedClients[accountId] = client
+		}
+	}
+	client.Endpoint = endpoint
+	client.Region = region
+	client.Bucket = bucket
+	client.AccessKey = accessKey
+	client.SecretKey = secretKey
+	client.PathStyle = pathStyle
+	return client
+}
+
+// 路径转成对象key，去掉开头的/
+func pathToKey(p string) string {
+	p = path.Clean("/" + strings.ReplaceAll(p, "\\", "/"))
+	return strings.TrimPrefix(p, "/")
+}
+
+// 路径转成目录前缀，根目录是空字符串，其他以/结尾
+func pathToPrefix(p string) string {
+	key := pathToKey(p)
+	if key == "" {
+		return ""
+	}
+	return key + "/"
+}
+
+// 按S3的规则编码，只保留非保留字符，encodeSlash为false时保留/
+func awsEscape(s string, encodeSlash bool) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if (c >= 'A' && c <= 'Z') || (c >= 'a' && c <= 'z') || (c >= '0' && c <= '9') || c == '-' || c == '_' || c == '.' || c == '~' || (c == '/' && !encodeSlash) {
+			b.WriteByte(c)
+			continue
+		}
+		fmt.Fprintf(&b, "%%%02X", c)
+	}
+	return b.String()
+}
+
+// 生成对象的访问地址，key为空时是存储桶地址
+func (c *Client) objectUrl(key string, query url.Values) (*url.URL, error) {
+	u, err := url.Parse(c.Endpoint)
+	if err != nil {
+		return nil, err
+	}
+	basePath := strings.TrimSuffix(u.Path, "/")
+	if c.PathStyle {
+		basePath += "/" + c.Bucket
+	} else {
+		u.Host = c.Bucket + "." + u.Host
+	}
+	u.Path = basePath + "/" + key
+	u.RawPath = awsEscape(u.Path, false)
+	u.RawQuery = canonicalQuery(query)
+	return u, nil
+}
+
+func canonicalQuery(query url.Values) string {
+	keys := make([]string, 0, len(query))
+	for k := range query {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	parts := make([]string, 0, len(keys))
+	for _, k := range keys {
+		values := append([]string{}, query[k]...)
+		sort.Strings(values)
+		for _, v := range values {
+			parts = append(parts, awsEscape(k, true)+"="+awsEscape(v, true))
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+func sha256Hex(data string) string {
+	h := sha256.Sum256([]byte(data))
+	return hex.EncodeToString(h[:])
+}
+
+func (c *Client) scope(date string) string {
+	return fmt.Sprintf("%s/%s/s3/aws4_request", date, c.Region)
+}
+
+// 计算签名，signedHeaders已经排序
+func (c *Client) signature(method string, u *url.URL, headers map[string]string, signedHeaders []string, payloadHash string, amzDate string) string {
+	var canonicalHeaders strings.Builder
+	for _, h := range signedHeaders {
+		canonicalHeaders.WriteString(h + ":" + strings.TrimSpace(headers[h]) + "\n")
+	}
+	canonicalRequest := strings.Join([]string{
+		method,
+		u.EscapedPath(),
+		u.RawQuery,
+		canonicalHeaders.String(),
+		strings.Join(signedHeaders, ";"),
+		payloadHash,
+	}, "\n")
+	date := amzDate[:8]
+	stringToSign := strings.Join([]string{"AWS4-HMAC-SHA256", amzDate, c.scope(date), sha256Hex(canonicalRequest)}, "\n")
+	key := hmacSHA256([]byte("AWS4"+c.SecretKey), date)
+	key = hmacSHA256(key, c.Region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	return hex.EncodeToString(hmacSHA256(key, stringToSign))
+}
+
+// 给请求添加Authorization头，签名host、range和所有x-amz-*请求头
+func (c *Client) signRequest(req *http.Request, payloadHash string) {
+	amzDate := c.now().UTC().Format("20060102T150405Z")
+	req.Header.Set("x-amz-date", amzDate)
+	req.Header.Set("x-amz-content-sha256", payloadHash)
+	headers := map[string]string{"host": req.URL.Host}
+	for k := range req.Header {
+		lk := strings.ToLower(k)
+		if strings.HasPrefix(lk, "x-amz-") || lk == "range" {
+			headers[lk] = req.Header.Get(k)
+		}
+	}
+	signedHeaders := make([]string, 0, len(headers))
+	for k := range headers {
+		signedHeaders = append(signedHeaders, k)
+	}
+	sort.Strings(signedHeaders)
+	signature := c.signature(req.Method, req.URL, headers, signedHeaders, payloadHash, amzDate)
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s,SignedHeaders=%s,Signature=%s",
+		c.AccessKey, c.scope(amzDate[:8]), strings.Join(signedHeaders, ";"), signature))
+}
+
+// PresignGet 生成预签名的下载地址，有效期最长7天
+func (c *Client) PresignGet(p string, expires time.Duration) (string, error) {
+	if expires > 7*24*time.Hour {
+		expires = 7 * 24 * time.Hour
+	}
+	amzDate := c.now().UTC().Format("20060102T150405Z")
+	query := url.Values{}
+	query.Set("X-Amz-Algorithm", "AWS4-HMAC-SHA256")
+	query.Set("X-Amz-Credential", c.AccessKey+"/"+c.scope(amzDate[:8]))
+	query.Set("X-Amz-Date", amzDate)
+	query.Set("X-Amz-Expires", strconv.Itoa(int(expires.Seconds())))
+	query.Set("X-Amz-SignedHeaders", "host")
+	u, err := c.objectUrl(pathToKey(p), query)
+	if err != nil {
+		return "", err
+	}
+	signature := c.signature(http.MethodGet, u, map[string]string{"host": u.Host}, []string{"host"}, unsignedPayload, amzDate)
+	u.RawQuery += "&X-Amz-Signature=" + signature
+	return u.String(), nil
+}
+
+type errorResponse struct {
+	Code    string `xml:"Code"`
+	Message string `xml:"Message"`
+}
+
+// 执行请求，返回的响应状态码是2xx，调用方负责关闭Body
+func (c *Client) do(ctx context.Context, method, key string, query url.Values, header http.Header, body io.Reader, contentLength int64, payloadHash string) (*http.Response, error) {
+	u, err := c.objectUrl(key, query)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), body)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	if body != nil {
+		req.ContentLength = contentLength
+	}
+	c.signRequest(req, payloadHash)
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%s %s 请求失败: %w", method, key, err)
+	}
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return resp, nil
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, fmt.Errorf("%s %s 失败: %w", method, key, os.ErrNotExist)
+	}
+	var e errorResponse
+	data, _ := io.ReadAll(resp.Body)
+	if xml.Unmarshal(data, &e) == nil && e.Code != "" {
+		return nil, fmt.Errorf("%s %s 失败: 状态码 %d %s %s", method, key, resp.StatusCode, e.Code, e.Message)
+	}
+	return nil, fmt.Errorf("%s %s 失败: 状态码 %d", method, key, resp.StatusCode)
+}
+
+// 执行不需要响应内容的请求
+func (c *Client) doSimple(method, key string, header http.Header) error {
+	ctx, cancel := context.WithTimeout(context.Background(), DEFAULT_TIMEOUT*time.Second)
+	defer cancel()
+	resp, err := c.do(ctx, method, key, nil, header, nil, 0, emptyPayload)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+	return nil
+}
+
+type listBucketResult struct {
+	IsTruncated           bool   `xml:"IsTruncated"`
+	NextContinuationToken string `xml:"NextContinuationToken"`
+	KeyCount              int    `xml:"KeyCount"`
+	Contents              []struct {
+		Key          string `xml:"Key"`
+		LastModified string `xml:"LastModified"`
+		ETag         string `xml:"ETag"`
+		Size         int64  `xml:"Size"`
+	} `xml:"Contents"`
+	CommonPrefixes []struct {
+		Prefix string `xml:"Prefix"`
+	} `xml:"CommonPrefixes"`
+}
+
+// ListObjectsV2，delimiter为空时返回前缀下的所有对象
+func (c *Client) list(ctx context.Context, prefix, delimiter, token string, maxKeys int) (*listBucketResult, error) {
+	ctx, cancel := context.WithTimeout(ctx, DEFAULT_TIMEOUT*time.Second)
+	defer cancel()
+	query := url.Values{}
+	query.Set("list-type", "2")
+	query.Set("prefix", prefix)
+	if delimiter != "" {
+		query.Set("delimiter", delimiter)
+	}
+	if token != "" {
+		query.Set("continuation-token", token)
+	}
+	if maxKeys > 0 {
+		query.Set("max-keys", strconv.Itoa(maxKeys))
+	}
+	resp, err := c.do(ctx, http.MethodGet, "", query, nil, nil, 0, emptyPayload)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	var result listBucketResult
+	if err := xml.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("解析对象列表失败: %w", err)
+	}
+	return &result, nil
+}
+
+// ReadDir 列出目录下的文件和子目录，子目录没有修改时间
+func (c *Client) ReadDir(ctx context.Context, p string) ([]*Object, error) {
+	prefix := pathToPrefix(p)
+	objects := make([]*Object, 0)
+	token := ""
+	for {
+		result, err := c.list(ctx, prefix, "/", token, 0)
+		if err != nil {
+			return nil, err
+		}
+		for _, cp := range result.CommonPrefixes {
+			dirKey := strings.TrimSuffix(cp.Prefix, "/")
+			objects = append(objects, &Object{Path: "/" + dirKey, Name: path.Base(dirKey), IsDir: true})
+		}
+		for _, content := range result.Contents {
+			// 目录标记对象（key以/结尾）不返回
+			if content.Key == prefix || strings.HasSuffix(content.Key, "/") {
+				continue
+			}
+			obj := &Object{
+				Path: "/" + content.Key,
+				Name: path.Base(content.Key),
+				Size: content.Size,
+				ETag: strings.Trim(content.ETag, `"`),
+			}
+			obj.Modified, _ = time.Parse(time.RFC3339, content.LastModified)
+			objects = append(objects, obj)
+		}
+		if !result.IsTruncated || result.NextContinuationToken == "" {
+			break
+		}
+		token = result.NextContinuationToken
+	}
+	return objects, nil
+}
+
+// Stat 查询文件或目录，不存在时返回的错误可以用errors.Is(err, os.ErrNotExist)判断
+// 根目录用来检查存储桶和密钥是否正确
+func (c *Client) Stat(ctx context.Context, p string) (*Object, error) {
+	key := pathToKey(p)
+	if key != "" {
+		headCtx, cancel := context.WithTimeout(ctx, DEFAULT_TIMEOUT*time.Second)
+		defer cancel()
+		resp, err := c.do(headCtx, http.MethodHead, key, nil, nil, nil, 0, emptyPayload)
+		if err == nil {
+			resp.Body.Close()
+			obj := &Object{Path: "/" + key, Name: path.Base(key), Size: resp.ContentLength, ETag: strings.Trim(resp.Header.Get("ETag"), `"`)}
+			obj.Modified, _ = http.ParseTime(resp.Header.Get("Last-Modified"))
+			return obj, nil
+		}
+		if !errors.Is(err, os.ErrNotExist) {
+			return nil, err
+		}
+	}
+	// 不是文件时检查是否有该前缀的对象
+	result, err := c.list(ctx, pathToPrefix(p), "", "", 1)
+	if err != nil {
+		return nil, err
+	}
+	if key != "" && len(result.Contents) == 0 && len(result.CommonPrefixes) == 0 {
+		return nil, fmt.Errorf("%s 不存在: %w", p, os.ErrNotExist)
+	}
+	return &Object{Path: "/" + key, Name: path.Base("/" + key), IsDir: true}, nil
+}
+
+// Mkdir 创建目录标记对象，S3没有真正的目录，不需要逐级创建
+func (c *Client) Mkdir(p string) error {
+	prefix := pathToPrefix(p)
+	if prefix == "" {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), DEFAULT_TIMEOUT*time.Second)
+	defer cancel()
+	resp, err := c.do(ctx, http.MethodPut, prefix, nil, nil, strings.NewReader(""), 0, emptyPayload)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// Remove 删除文件，目录会删除前缀下的所有对象
+func (c *Client) Remove(p string) error {
+	key := pathToKey(p)
+	if key == "" {
+		return fmt.Errorf("不能删除存储桶根目录")
+	}
+	info, err := c.Stat(context.Background(), p)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+	if !info.IsDir {
+		return c.doSimple(http.MethodDelete, key, nil)
+	}
+	return c.walk(key+"/", func(objectKey string, _ int64) error {
+		return c.doSimple(http.MethodDelete, objectKey, nil)
+	})
+}
+
+// 遍历前缀下的所有对象（包括子目录中的）
+func (c *Client) walk(prefix string, fn func(key string, size int64) error) error {
+	token := ""
+	for {
+		result, err := c.list(context.Background(), prefix, "", token, 0)
+		if err != nil {
+			return err
+		}
+		for _, content := range result.Contents {
+			if err := fn(content.Key, content.Size); err != nil {
+				return err
+			}
+		}
+		if !result.IsTruncated || result.NextContinuationToken == "" {
+			return nil
+		}
+		token = result.NextContinuationToken
+	}
+}
+
+// Copy 复制文件，newPath是新的完整路径，超过5GB的文件使用分片复制
+func (c *Client) Copy(oldPath, newPath string) error {
+	info, err := c.Stat(context.Background(), oldPath)
+	if err != nil {
+		return err
+	}
+	if info.IsDir {
+		return fmt.Errorf("%s 是目录，不能复制", oldPath)
+	}
+	return c.copyKey(pathToKey(oldPath), pathToKey(newPath), info.Size)
+}
+
+// 按原始key复制，目录标记对象的key以/结尾，size超过单次复制的上限时使用分片复制
+func (c *Client) copyKey(srcKey, dstKey string, size int64) error {
+	if size > maxCopySize {
+		return c.multipartCopy(srcKey, dstKey, size)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), copyTimeout)
+	defer cancel()
+	resp, err := c.do(ctx, http.MethodPut, dstKey, nil, c.copySourceHeader(srcKey), nil, 0, emptyPayload)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	// 复制失败时也可能返回200，错误信息在响应内容中
+	return readCopyError(http.MethodPut, dstKey, resp.Body)
+}
+
+func (c *Client) copySourceHeader(srcKey string) http.Header {
+	header := http.Header{}
+	header.Set("x-amz-copy-source", awsEscape("/"+c.Bucket+"/"+srcKey, false))
+	return header
+}
+
+// 读取复制请求的响应，响应中是Error时返回错误
+func readCopyError(method, key string, body io.Reader) error {
+	data, _ := io.ReadAll(body)
+	var e struct {
+		XMLName xml.Name
+		errorResponse
+	}
+	if xml.Unmarshal(data, &e) == nil && e.XMLName.Local == "Error" {
+		return fmt.Errorf("%s %s 失败: %s %s", method, key, e.Code, e.Message)
+	}
+	return nil
+}
+
+type initiateMultipartUploadResult struct {
+	UploadId string `xml:"UploadId"`
+}
+
+type copyPartResult struct {
+	ETag string `xml:"ETag"`
+}
+
+type completedPart struct {
+	PartNumber int    `xml:"PartNumber"`
+	ETag       string `xml:"ETag"`
+}
+
+type completeMultipartUpload struct {
+	XMLName xml.Name        `xml:"CompleteMultipartUpload"`
+	Parts   []completedPart `xml:"Part"`
+}
+
+// 分片复制，S3单次复制最大5GB，大文件按copyPartSize分片调用UploadPartCopy，失败时取消分片上传
+func (c *Client) multipartCopy(srcKey, dstKey string, size int64) error {
+	uploadId, err := c.initiateMultipartUpload(dstKey)
+	if err != nil {
+		return err
+	}
+	// 分片数量最多10000个
+	partSize := max(copyPartSize, (size+9999)/10000)
+	parts := make([]completedPart, 0, (size+partSize-1)/partSize)
+	for start, partNumber := int64(0), 1; start < size; start, partNumber = start+partSize, partNumber+1 {
+		end := min(start+partSize, size) - 1
+		etag, err := c.uploadPartCopy(srcKey, dstKey, uploadId, partNumber, start, end)
+		if err != nil {
+			c.abortMultipartUpload(dstKey, uploadId)
+			return err
+		}
+		parts = append(parts, completedPart{PartNumber: partNumber, ETag: etag})
+	}
+	if err := c.completeMultipartUpload(dstKey, uploadId, parts); err != nil {
+		c.abortMultipartUpload(dstKey, uploadId)
+		return err
+	}
+	return nil
+}
+
+func (c *Client) initiateMultipartUpload(key string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), DEFAULT_TIMEOUT*time.Second)
+	defer cancel()
+	query := url.Values{}
+	query.Set("uploads", "")
+	resp, err := c.do(ctx, http.MethodPost, key, query, nil, strings.NewReader(""), 0, emptyPayload)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	var result initiateMultipartUploadResult
+	if err := xml.NewDecoder(resp.Body).Decode(&result); err != nil || result.UploadId == "" {
+		return "", fmt.Errorf("创建分片上传 %s 失败: %v", key, err)
+	}
+	return result.UploadId, nil
+}
+
+func (c *Client) uploadPartCopy(srcKey, dstKey, uploadId string, partNumber int, start, end int64) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), copyTimeout)
+	defer cancel()
+	query := url.Values{}
+	query.Set("partNumber", strconv.Itoa(partNumber))
+	query.Set("uploadId", uploadId)
+	header := c.copySourceHeader(srcKey)
+	header.Set("x-amz-copy-source-range", fmt.Sprintf("bytes=%d-%d", start, end))
+	resp, err := c.do(ctx, http.MethodPut, dstKey, query, header, nil, 0, emptyPayload)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	data, _ := io.ReadAll(resp.Body)
+	var result copyPartResult
+	if err := xml.Unmarshal(data, &result); err != nil || result.ETag == "" {
+		if err := readCopyError(http.MethodPut, dstKey, strings.NewReader(string(data))); err != nil {
+			return "", err
+		}
+		return "", fmt.Errorf("复制分片 %s #%d 失败: %v", dstKey, partNumber, err)
+	}
+	return result.ETag, nil
+}
+
+func (c *Client) completeMultipartUpload(key, uploadId string, parts []completedPart) error {
+	body, err := xml.Marshal(completeMultipartUpload{Parts: parts})
+	if err != nil {
+		return err
+	}
+	// 合并大文件的分片可能需要几分钟
+	ctx, cancel := context.WithTimeout(context.Background(), copyTimeout)
+	defer cancel()
+	query := url.Values{}
+	query.Set("uploadId", uploadId)
+	resp, err := c.do(ctx, http.MethodPost, key, query, nil, strings.NewReader(string(body)), int64(len(body)), sha256Hex(string(body)))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	return readCopyError(http.MethodPost, key, resp.Body)
+}
+
+func (c *Client) abortMultipartUpload(key, uploadId string) {
+	ctx, cancel := context.WithTimeout(context.Background(), DEFAULT_TIMEOUT*time.Second)
+	defer cancel()
+	query := url.Values{}
+	query.Set("uploadId", uploadId)
+	resp, err := c.do(ctx, http.MethodDelete, key, query, nil, nil, 0, emptyPayload)
+	if err != nil {
+		return
+	}
+	resp.Body.Close()
+}
+
+// Move 移动或重命名文件或目录，S3不支持移动，逐个对象先复制再删除
+func (c *Client) Move(oldPath, newPath string) error {
+	info, err := c.Stat(context.Background(), oldPath)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir {
+		if err := c.copyKey(pathToKey(oldPath), pathToKey(newPath), info.Size); err != nil {
+			return err
+		}
+		return c.doSimple(http.MethodDelete, pathToKey(oldPath), nil)
+	}
+	oldPrefix := pathToPrefix(oldPath)
+	newPrefix := pathToPrefix(newPath)
+	if oldPrefix == "" || strings.HasPrefix(newPrefix, oldPrefix) {
+		return fmt.Errorf("不能把目录 %s 移动到 %s", oldPath, newPath)
+	}
+	return c.walk(oldPrefix, func(key string, size int64) error {
+		newKey := newPrefix + strings.TrimPrefix(key, oldPrefix)
+		if err := c.copyKey(key, newKey, size); err != nil {
+			return err
+		}
+		return c.doSimple(http.MethodDelete, key, nil)
+	})
+}
+
+// Upload 上传本地文件，已存在时覆盖
+func (c *Client) Upload(localFile string, remotePath string) error {
+	f, err := os.Open(localFile)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	stat, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	resp, err := c.do(context.Background(), http.MethodPut, pathToKey(remotePath), nil, nil, f, stat.Size(), unsignedPayload)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// Get 下载文件，header中的Range请求头会转发给服务器，调用方负责关闭Body
+func (c *Client) Get(ctx context.Context, p string, header http.Header) (*http.Response, error) {
+	reqHeader := http.Header{}
+	if v := header.Get("Range"); v != "" {
+		reqHeader.Set("Range", v)
+	}
+	return c.do(ctx, http.MethodGet, pathToKey(p), nil, reqHeader, nil, 0, emptyPayload)
+}
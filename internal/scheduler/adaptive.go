@@ -0,0 +1,169 @@
+package scheduler
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// AdaptiveConfig AIMD速率控制参数
+type AdaptiveConfig struct {
+	DecreaseFactor float64       // 触发限流时速率乘以这个系数
+	IncreaseStep   float64       // 每次试探增加的速率（占上限的比例）
+	ProbeInterval  time.Duration // 持续多久没有限流才试探增加一次
+	MinFactor      float64       // 速率最低为上限的多少
+	// 一次限流会有多个并发请求同时返回限流，冷却时间内只减小一次
+	DecreaseCooldown time.Duration
+}
+
+// DefaultAdaptiveConfig 默认参数：限流时减半，每5分钟没有限流增加上限的10%
+var DefaultAdaptiveConfig = AdaptiveConfig{
+	DecreaseFactor: 0.5,
+	IncreaseStep:   0.1,
+	ProbeInterval:  5 * time.Minute,
+	MinFactor:      0.05,
+	// 和限流暂停时长一致
+	DecreaseCooldown: time.Minute,
+}
+
+// Adaptive AIMD速率控制器，配置的速率限制作为上限，
+// 触发限流时乘性减小，持续一段时间没有限流时缓慢增加，学习账号安全的请求速率
+type Adaptive struct {
+	sync.Mutex
+	config  AdaptiveConfig
+	ceiling Limits
+	// 当前速率占上限的比例，(0, 1]
+	factor float64
+	// 最后一次调整的时间，试探增加从这个时间开始计算
+	lastChange       time.Time
+	lastThrottleTime time.Time
+	lastDecreaseTime time.Time
+	lastIncreaseTime time.Time
+	decreaseCount    int64
+	increaseCount    int64
+}
+
+// AdaptiveStatus AIMD控制器状态
+type AdaptiveStatus struct {
+	Ceiling          Limits     `json:"ceiling"` // 配置的上限
+	Learned          Limits     `json:"learned"` // 学习到的当前速率
+	Factor           float64    `json:"factor"`  // 当前速率占上限的比例
+	DecreaseCount    int64      `json:"decrease_count"`
+	IncreaseCount    int64      `json:"increase_count"`
+	LastThrottleTime *time.Time `json:"last_throttle_time"`
+	LastIncreaseTime *time.Time `json:"last_increase_time"`
+	NextProbeTime    *time.Time `json:"next_probe_time"` // 没有达到上限时，下一次试探增加的时间
+}
+
+// NewAdaptive 创建AIMD控制器，初始速率为上限
+func NewAdaptive(ceiling Limits, config AdaptiveConfig) *Adaptive {
+	return &Adaptive{
+		config:     config,
+		ceiling:    ceiling,
+		factor:     1,
+		lastChange: time.Now(),
+	}
+}
+
+// 按比例缩小速率限制，不限制的项保持不限制，最小为1
+func scaleLimits(limits Limits, factor float64) Limits {
+	scale := func(n int) int {
+		if n <= 0 {
+			return n
+		}
+		return max(1, int(math.Floor(float64(n)*factor)))
+	}
+	return Limits{QPS: scale(limits.QPS), QPM: scale(limits.QPM), QPH: scale(limits.QPH)}
+}
+
+// Learned 学习到的当前速率
+func (a *Adaptive) Learned() Limits {
+	a.Lock()
+	defer a.Unlock()
+	return scaleLimits(a.ceiling, a.factor)
+}
+
+// SetCeiling 修改上限，学习到的比例保持不变
+func (a *Adaptive) SetCeiling(ceiling Limits) {
+	a.Lock()
+	defer a.Unlock()
+	a.ceiling = ceiling
+}
+
+// Restore 从保存的速率恢复学习到的比例
+func (a *Adaptive) Restore(learned Limits) {
+	a.Lock()
+	defer a.Unlock()
+	factor := 1.0
+	switch {
+	case learned.QPS > 0 && a.ceiling.QPS > 0:
+		factor = float64(learned.QPS) / float64(a.ceiling.QPS)
+	case learned.QPM > 0 && a.ceiling.QPM > 0:
+		factor = float64(learned.QPM) / float64(a.ceiling.QPM)
+	case learned.QPH > 0 && a.ceiling.QPH > 0:
+		factor = float64(learned.QPH) / float64(a.ceiling.QPH)
+	}
+	a.factor = min(1, max(a.config.MinFactor, factor))
+	a.lastChange = time.Now()
+}
+
+// OnThrottle 触发限流时乘性减小，返回是否有变化
+func (a *Adaptive) OnThrottle() bool {
+	a.Lock()
+	defer a.Unlock()
+	now := time.Now()
+	a.lastThrottleTime = now
+	a.lastChange = now
+	if !a.lastDecreaseTime.IsZero() && now.Sub(a.lastDecreaseTime) < a.config.DecreaseCooldown {
+		return false
+	}
+	factor := max(a.config.MinFactor, a.factor*a.config.DecreaseFactor)
+	if factor == a.factor {
+		return false
+	}
+	a.factor = factor
+	a.lastDecreaseTime = now
+	a.decreaseCount++
+	return true
+}
+
+// OnSuccess 请求正常时，如果已经持续一段时间没有限流就加性增加，返回是否有变化
+func (a *Adaptive) OnSuccess() bool {
+	a.Lock()
+	defer a.Unlock()
+	if a.factor >= 1 || time.Since(a.lastChange) < a.config.ProbeInterval {
+		return false
+	}
+	now := time.Now()
+	a.factor = min(1, a.factor+a.config.IncreaseStep)
+	a.lastChange = now
+	a.lastIncreaseTime = now
+	a.increaseCount++
+	return true
+}
+
+// Status 获取控制器状态
+func (a *Adaptive) Status() AdaptiveStatus {
+	a.Lock()
+	defer a.Unlock()
+	status := AdaptiveStatus{
+		Ceiling:       a.ceiling,
+		Learned:       scaleLimits(a.ceiling, a.factor),
+		Factor:        math.Round(a.factor*100) / 100,
+		DecreaseCount: a.decreaseCount,
+		IncreaseCount: a.increaseCount,
+	}
+	if !a.lastThrottleTime.IsZero() {
+		t := a.lastThrottleTime
+		status.LastThrottleTime = &t
+	}
+	if !a.lastIncreaseTime.IsZero() {
+		t := a.lastIncreaseTime
+		status.LastIncreaseTime = &t
+	}
+	if a.factor < 1 {
+		t := a.lastChange.Add(a.config.ProbeInterval)
+		status.NextProbeTime = &t
+	}
+	return status
+}
@@ -0,0 +1,381 @@
+package scheduler
+
+import (
+	"Q115-STRM/internal/helpers"
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// 来源类型，和models.SourceType的取值一致
+const (
+	SOURCE_115      = "115"
+	SOURCE_OPENLIST = "openlist"
+	SOURCE_BAIDUPAN = "baidupan"
+	SOURCE_123      = "123"
+)
+
+// Limits 速率限制配置，0表示不限制
+type Limits struct {
+	QPS int `json:"qps"` // 每秒请求数
+	QPM int `json:"qpm"` // 每分钟请求数
+	QPH int `json:"qph"` // 每小时请求数
+}
+
+// IsZero 是否没有配置任何限制
+func (l Limits) IsZero() bool {
+	return l.QPS <= 0 && l.QPM <= 0 && l.QPH <= 0
+}
+
+// Call 一次需要调度的请求
+type Call struct {
+	URL    string
+	Method string
+	// 是否绕过速率限制（已经调用过Wait等），仍然会等待限流恢复
+	BypassRateLimit bool
+	// 是否使用预留容量（播放请求等），不会排在其他请求后面等待速率限制
+	Reserved bool
+	// 执行请求，返回是否触发了网盘限流
+	Do func() (bool, error)
+}
+
+// Result 请求调度结果
+type Result struct {
+	// 响应耗时（毫秒），包含排队等待的时间
+	Duration int64
+	// 是否是限流响应
+	IsThrottled bool
+	Error       error
+}
+
+// Executor 一个来源（账号）的请求调度器，负责速率限制、限流恢复和统计
+type Executor struct {
+	sync.RWMutex
+	Source    string
+	AccountId uint
+	limits    Limits
+	// 为Reserved请求预留的每秒请求数，从QPS中扣除
+	reservedQPS int
+	// 速率限制器（支持qps/qpm/qph）
+	qpsLimiter      *rate.Limiter // 每秒请求数限制
+	qpmLimiter      *rate.Limiter // 每分钟请求数限制
+	qphLimiter      *rate.Limiter // 每小时请求数限制
+	reservedLimiter *rate.Limiter // 预留容量的每秒请求数限制，没有预留时为nil
+	// AIMD速率控制器，启用后limits作为上限
+	adaptive *Adaptive
+	// 上级调度器，多个账号共用的全局上限，只使用它的速率限制和汇总统计
+	parent *Executor
+	// 限流管理器
+	throttleManager *ThrottleManager
+	// 统计数据
+	stats *RequestStats
+}
+
+// NewExecutor 创建新的调度器，一般使用Get获取，保证同一个账号共享一个调度器
+func NewExecutor(source string, accountId uint, limits Limits) *Executor {
+	e := &Executor{
+		Source:          source,
+		AccountId:       accountId,
+		throttleManager: NewThrottleManager(source),
+		stats:           NewRequestStats(10000),
+	}
+	e.SetLimits(limits)
+	return e
+}
+
+// 每个周期最多n个请求，n<=0表示不限制
+func newLimiter(n int, per time.Duration) *rate.Limiter {
+	if n <= 0 {
+		return rate.NewLimiter(rate.Inf, 0)
+	}
+	return rate.NewLimiter(rate.Every(per/time.Duration(n)), n)
+}
+
+// 修改已有的限制器，保留已经消耗的令牌，limiter为空时新建
+func updateLimiter(limiter *rate.Limiter, n int, per time.Duration) *rate.Limiter {
+	if limiter == nil {
+		return newLimiter(n, per)
+	}
+	if n <= 0 {
+		limiter.SetLimit(rate.Inf)
+		limiter.SetBurst(0)
+		return limiter
+	}
+	limiter.SetLimit(rate.Every(per / time.Duration(n)))
+	limiter.SetBurst(n)
+	return limiter
+}
+
+// SetLimits 设置速率限制
+func (e *Executor) SetLimits(limits Limits) {
+	e.Lock()
+	defer e.Unlock()
+	if e.qpsLimiter != nil && e.limits == limits {
+		return
+	}
+	e.limits = limits
+	if e.adaptive != nil {
+		e.adaptive.SetCeiling(limits)
+	}
+	e.buildLimiters()
+}
+
+// SetReservedQPS 设置预留给Reserved请求的每秒请求数，普通请求只能使用剩下的部分
+func (e *Executor) SetReservedQPS(n int) {
+	e.Lock()
+	defer e.Unlock()
+	if e.reservedQPS == n {
+		return
+	}
+	e.reservedQPS = n
+	e.buildLimiters()
+}
+
+// 需要持有锁，QPS不足以预留时不预留
+func (e *Executor) buildLimiters() {
+	limits := e.effectiveLimits()
+	qps := limits.QPS
+	if e.reservedQPS > 0 && qps > e.reservedQPS {
+		qps -= e.reservedQPS
+		e.reservedLimiter = updateLimiter(e.reservedLimiter, e.reservedQPS, time.Second)
+	} else {
+		e.reservedLimiter = nil
+	}
+	e.qpsLimiter = updateLimiter(e.qpsLimiter, qps, time.Second)
+	e.qpmLimiter = updateLimiter(e.qpmLimiter, limits.QPM, time.Minute)
+	e.qphLimiter = updateLimiter(e.qphLimiter, limits.QPH, time.Hour)
+}
+
+// 需要持有锁，启用AIMD时使用学习到的速率
+func (e *Executor) effectiveLimits() Limits {
+	if e.adaptive != nil {
+		return e.adaptive.Learned()
+	}
+	return e.limits
+}
+
+// Limits 配置的速率限制，启用AIMD时是上限
+func (e *Executor) Limits() Limits {
+	e.RLock()
+	defer e.RUnlock()
+	return e.limits
+}
+
+// EffectiveLimits 当前实际使用的速率限制
+func (e *Executor) EffectiveLimits() Limits {
+	e.RLock()
+	defer e.RUnlock()
+	return e.effectiveLimits()
+}
+
+// EnableAdaptive 启用AIMD速率控制，配置的速率限制作为上限，已经启用时不做任何事
+func (e *Executor) EnableAdaptive(config AdaptiveConfig) {
+	e.Lock()
+	defer e.Unlock()
+	if e.adaptive != nil {
+		return
+	}
+	e.adaptive = NewAdaptive(e.limits, config)
+	e.buildLimiters()
+}
+
+// RestoreAdaptive 恢复保存的学习速率，没有启用AIMD时不做任何事
+func (e *Executor) RestoreAdaptive(learned Limits) {
+	e.Lock()
+	defer e.Unlock()
+	if e.adaptive == nil || learned.IsZero() {
+		return
+	}
+	e.adaptive.Restore(learned)
+	e.buildLimiters()
+}
+
+// AdaptiveStatus AIMD控制器状态，没有启用时返回nil
+func (e *Executor) AdaptiveStatus() *AdaptiveStatus {
+	e.RLock()
+	adaptive := e.adaptive
+	e.RUnlock()
+	if adaptive == nil {
+		return nil
+	}
+	status := adaptive.Status()
+	return &status
+}
+
+// 根据请求结果调整学习到的速率，有变化时更新限制器并保存
+func (e *Executor) adapt(isThrottled bool) {
+	e.RLock()
+	adaptive := e.adaptive
+	e.RUnlock()
+	if adaptive == nil {
+		return
+	}
+	var changed bool
+	if isThrottled {
+		changed = adaptive.OnThrottle()
+	} else {
+		changed = adaptive.OnSuccess()
+	}
+	if !changed {
+		return
+	}
+	e.Lock()
+	e.buildLimiters()
+	learned := e.effectiveLimits()
+	e.Unlock()
+	if isThrottled {
+		logger(e.Source).Warnf("[%s] 账号 %d 触发限流，速率降低为 QPS: %d, QPM: %d, QPH: %d", e.Source, e.AccountId, learned.QPS, learned.QPM, learned.QPH)
+	} else {
+		logger(e.Source).Infof("[%s] 账号 %d 持续没有限流，速率提高为 QPS: %d, QPM: %d, QPH: %d", e.Source, e.AccountId, learned.QPS, learned.QPM, learned.QPH)
+	}
+	if saver := getLimitSaver(); saver != nil {
+		go saver(e.Source, e.AccountId, learned)
+	}
+}
+
+// SetParent 设置上级调度器，请求需要同时满足自己和上级的速率限制
+func (e *Executor) SetParent(parent *Executor) {
+	e.Lock()
+	defer e.Unlock()
+	e.parent = parent
+}
+
+// Wait 等待普通请求的速率限制（qps/qpm/qph），有上级调度器时还要等待上级的限制
+func (e *Executor) Wait(ctx context.Context) error {
+	e.RLock()
+	limiters := []*rate.Limiter{e.qpsLimiter, e.qpmLimiter, e.qphLimiter}
+	parent := e.parent
+	e.RUnlock()
+	for i, limiter := range limiters {
+		if err := limiter.Wait(ctx); err != nil {
+			return fmt.Errorf("%s限制错误: %w", []string{"QPS", "QPM", "QPH"}[i], err)
+		}
+	}
+	if parent != nil {
+		if err := parent.Wait(ctx); err != nil {
+			return fmt.Errorf("全局%w", err)
+		}
+	}
+	return nil
+}
+
+// 预留容量：优先使用预留的令牌，其次借用普通请求的空闲令牌，都没有时只等待预留容量
+// qpm/qph的令牌有就扣除，不等待
+func (e *Executor) waitReserved(ctx context.Context) error {
+	e.RLock()
+	reserved, qps, qpm, qph, parent := e.reservedLimiter, e.qpsLimiter, e.qpmLimiter, e.qphLimiter, e.parent
+	e.RUnlock()
+	qpm.Allow()
+	qph.Allow()
+	if !(reserved != nil && reserved.Allow()) && !qps.Allow() {
+		if reserved == nil {
+			reserved = qps
+		}
+		if err := reserved.Wait(ctx); err != nil {
+			return fmt.Errorf("预留QPS限制错误: %w", err)
+		}
+	}
+	if parent != nil {
+		return parent.waitReserved(ctx)
+	}
+	return nil
+}
+
+// Execute 等待限流恢复和速率限制后执行请求，并记录统计数据
+func (e *Executor) Execute(ctx context.Context, call Call) Result {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	startTime := time.Now()
+
+	// 检查限流状态
+	if e.throttleManager.IsThrottled() {
+		logger(e.Source).Debugf("[%s] 账号 %d 处于限流状态，等待恢复...", e.Source, e.AccountId)
+		e.throttleManager.WaitThrottleRecovery(ctx)
+	}
+
+	// 如果不绕过速率限制，则检查三层限制
+	var err error
+	switch {
+	case call.BypassRateLimit:
+	case call.Reserved:
+		err = e.waitReserved(ctx)
+	default:
+		err = e.Wait(ctx)
+	}
+	if err != nil {
+		return Result{Error: err, Duration: time.Since(startTime).Milliseconds()}
+	}
+
+	isThrottled, err := call.Do()
+	duration := time.Since(startTime).Milliseconds()
+	if isThrottled {
+		e.throttleManager.MarkThrottled(e.stats)
+	}
+	if err == nil || isThrottled {
+		e.adapt(isThrottled)
+	}
+
+	// 记录请求，上级调度器汇总所有账号的请求
+	entry := RequestLogEntry{
+		Timestamp:   time.Now(),
+		Duration:    duration,
+		IsThrottled: isThrottled,
+		URL:         call.URL,
+		Method:      call.Method,
+	}
+	e.stats.RecordRequest(entry)
+	e.RLock()
+	parent := e.parent
+	e.RUnlock()
+	if parent != nil {
+		parent.stats.RecordRequest(entry)
+	}
+
+	// 异步写入数据库（如果设置了回调函数）
+	if saver := getStatSaver(); saver != nil {
+		go saver(e.Source, e.AccountId, time.Now().Unix(), call.URL, call.Method, duration, isThrottled)
+	}
+
+	return Result{Duration: duration, IsThrottled: isThrottled, Error: err}
+}
+
+// GetStats 获取统计数据
+func (e *Executor) GetStats(duration time.Duration) *StatsSnapshot {
+	return e.stats.GetStats(duration)
+}
+
+// GetThrottleStatus 获取限流状态
+func (e *Executor) GetThrottleStatus() ThrottleStatus {
+	return e.throttleManager.GetThrottleStatus()
+}
+
+// MarkThrottled 手动标记为限流状态
+func (e *Executor) MarkThrottled() {
+	e.throttleManager.MarkThrottled(e.stats)
+}
+
+// ClearThrottled 清除限流状态
+func (e *Executor) ClearThrottled() {
+	e.throttleManager.ClearThrottled()
+}
+
+// 每个来源使用自己的日志文件
+func logger(source string) *helpers.QLogger {
+	var l *helpers.QLogger
+	switch source {
+	case SOURCE_115:
+		l = helpers.V115Log
+	case SOURCE_OPENLIST:
+		l = helpers.OpenListLog
+	case SOURCE_BAIDUPAN:
+		l = helpers.BaiduPanLog
+	}
+	if l == nil {
+		return helpers.AppLogger
+	}
+	return l
+}
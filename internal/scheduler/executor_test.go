@@ -0,0 +1,244 @@
+package scheduler
+
+import (
+	"Q115-STRM/internal/helpers"
+	"context"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func init() {
+	helpers.AppLogger = &helpers.QLogger{Logger: log.New(io.Discard, "", 0)}
+}
+
+func TestRegistryLimits(t *testing.T) {
+	const source = "test-registry"
+	SetDefaultLimits(source, Limits{QPS: 5})
+	e := Get(source, 1)
+	if Get(source, 1) != e {
+		t.Fatal("Get returned a different executor for the same account")
+	}
+	if got := e.Limits(); got != (Limits{QPS: 5}) {
+		t.Fatalf("default limits = %+v", got)
+	}
+
+	SetAccountLimits(source, 1, Limits{QPS: 1, QPM: 30})
+	SetDefaultLimits(source, Limits{QPS: 8})
+	if got := e.Limits(); got != (Limits{QPS: 1, QPM: 30}) {
+		t.Fatalf("account limits = %+v, want override to survive default change", got)
+	}
+	if got := Get(source, 2).Limits(); got != (Limits{QPS: 8}) {
+		t.Fatalf("other account limits = %+v", got)
+	}
+
+	// 零值恢复默认
+	SetAccountLimits(source, 1, Limits{})
+	if got := e.Limits(); got != (Limits{QPS: 8}) {
+		t.Fatalf("reset limits = %+v", got)
+	}
+	if n := len(Executors(source)); n != 2 {
+		t.Fatalf("Executors = %d, want 2", n)
+	}
+}
+
+func TestTransportThrottle(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path == "/limited" {
+			w.Write([]byte(`{"code":429,"message":"too many requests"}`))
+			return
+		}
+		w.Write([]byte(`{"code":200}`))
+	}))
+	defer server.Close()
+
+	e := NewExecutor("test-transport", 1, Limits{})
+	client := &http.Client{Transport: NewTransport(nil, e, func(resp *http.Response, body []byte) bool {
+		code, ok := ResponseCode(body, "code")
+		return resp.StatusCode == http.StatusTooManyRequests || (ok && code == 429)
+	})}
+
+	resp, err := client.Get(server.URL + "/ok?token=secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if string(body) != `{"code":200}` {
+		t.Fatalf("body = %q, want it restored after peeking", body)
+	}
+	if e.GetThrottleStatus().IsThrottled {
+		t.Fatal("executor throttled after a normal response")
+	}
+
+	resp, err = client.Get(server.URL + "/limited")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if !e.GetThrottleStatus().IsThrottled {
+		t.Fatal("executor not throttled after a 429 response")
+	}
+	e.ClearThrottled()
+
+	stats := e.GetStats(time.Minute)
+	if stats.TotalRequests != 2 || stats.ThrottledCount != 1 {
+		t.Fatalf("stats = %d requests, %d throttled", stats.TotalRequests, stats.ThrottledCount)
+	}
+	for _, entry := range e.stats.RequestLog {
+		if entry.URL != server.URL+"/ok" && entry.URL != server.URL+"/limited" {
+			t.Errorf("recorded url %q", entry.URL)
+		}
+	}
+}
+
+func TestResponseCode(t *testing.T) {
+	cases := []struct {
+		body  string
+		field string
+		code  int64
+		ok    bool
+	}{
+		{`{"errno":31034}`, "errno", 31034, true},
+		{`{"code":0,"errno":1}`, "code", 0, true},
+		{`{"errno":"31034"}`, "errno", 31034, true},
+		{`{"errno":"abc"}`, "errno", 0, false},
+		{`{"code":1.5}`, "code", 0, false},
+		{`{"code":429}`, "errno", 0, false},
+		{`not json`, "code", 0, false},
+		{``, "code", 0, false},
+	}
+	for _, c := range cases {
+		code, ok := ResponseCode([]byte(c.body), c.field)
+		if code != c.code || ok != c.ok {
+			t.Errorf("ResponseCode(%q, %q) = %d, %v, want %d, %v", c.body, c.field, code, ok, c.code, c.ok)
+		}
+	}
+}
+
+func TestReservedCapacity(t *testing.T) {
+	e := NewExecutor("test-reserved", 1, Limits{QPS: 2})
+	e.SetReservedQPS(1)
+
+	// 普通请求只能使用剩下的1个令牌
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if err := e.Wait(ctx); err != nil {
+		t.Fatalf("first Wait: %v", err)
+	}
+	if err := e.Wait(ctx); err == nil {
+		t.Fatal("second Wait succeeded, want the reserved token to stay unused")
+	}
+
+	// 预留请求不排在普通请求后面
+	start := time.Now()
+	result := e.Execute(context.Background(), Call{Reserved: true, Do: func() (bool, error) { return false, nil }})
+	if result.Error != nil {
+		t.Fatal(result.Error)
+	}
+	if waited := time.Since(start); waited > 50*time.Millisecond {
+		t.Fatalf("reserved call waited %v", waited)
+	}
+
+	// QPS不足以预留时不预留
+	e.SetLimits(Limits{QPS: 1})
+	if e.reservedLimiter != nil {
+		t.Fatal("reserved limiter kept although QPS is too low")
+	}
+}
+
+func TestAdaptive(t *testing.T) {
+	config := AdaptiveConfig{DecreaseFactor: 0.5, IncreaseStep: 0.25, ProbeInterval: time.Hour, MinFactor: 0.1, DecreaseCooldown: time.Hour}
+	a := NewAdaptive(Limits{QPS: 8, QPM: 400}, config)
+
+	if !a.OnThrottle() {
+		t.Fatal("first throttle did not decrease")
+	}
+	if got := a.Learned(); got != (Limits{QPS: 4, QPM: 200}) {
+		t.Fatalf("learned after throttle = %+v", got)
+	}
+	// 同一次限流的其他请求不重复减小
+	if a.OnThrottle() {
+		t.Fatal("throttle within cooldown decreased again")
+	}
+	// 没有到试探时间不增加
+	if a.OnSuccess() {
+		t.Fatal("increased before probe interval")
+	}
+	a.lastChange = time.Now().Add(-2 * time.Hour)
+	if !a.OnSuccess() {
+		t.Fatal("did not increase after a clean probe interval")
+	}
+	if got := a.Learned(); got != (Limits{QPS: 6, QPM: 300}) {
+		t.Fatalf("learned after probe = %+v", got)
+	}
+
+	// 恢复保存的速率，上限变化后按比例计算
+	a.Restore(Limits{QPS: 2, QPM: 100})
+	a.SetCeiling(Limits{QPS: 16})
+	if got := a.Learned(); got != (Limits{QPS: 4}) {
+		t.Fatalf("learned after restore = %+v", got)
+	}
+	status := a.Status()
+	if status.Factor != 0.25 || status.NextProbeTime == nil || status.DecreaseCount != 1 || status.IncreaseCount != 1 {
+		t.Errorf("status = %+v", status)
+	}
+}
+
+func TestExecutorAdaptive(t *testing.T) {
+	saved := make(chan Limits, 1)
+	SetLimitSaver(func(source string, accountId uint, learned Limits) {
+		if source == "test-adaptive" {
+			saved <- learned
+		}
+	})
+	defer SetLimitSaver(nil)
+
+	e := NewExecutor("test-adaptive", 1, Limits{QPS: 10, QPM: 600})
+	e.EnableAdaptive(DefaultAdaptiveConfig)
+	e.Execute(context.Background(), Call{Do: func() (bool, error) { return true, nil }})
+	e.ClearThrottled()
+
+	want := Limits{QPS: 5, QPM: 300}
+	if got := e.EffectiveLimits(); got != want {
+		t.Fatalf("effective limits = %+v, want %+v", got, want)
+	}
+	if got := e.Limits(); got != (Limits{QPS: 10, QPM: 600}) {
+		t.Fatalf("configured limits changed to %+v", got)
+	}
+	select {
+	case got := <-saved:
+		if got != want {
+			t.Errorf("saved limits = %+v, want %+v", got, want)
+		}
+	case <-time.After(time.Second):
+		t.Error("learned limits were not saved")
+	}
+}
+
+func TestParentCeiling(t *testing.T) {
+	parent := NewExecutor("test-parent", 0, Limits{QPS: 1})
+	first := NewExecutor("test-parent", 1, Limits{QPS: 5})
+	second := NewExecutor("test-parent", 2, Limits{QPS: 5})
+	first.SetParent(parent)
+	second.SetParent(parent)
+
+	first.Execute(context.Background(), Call{Do: func() (bool, error) { return false, nil }})
+	// 全局上限的令牌已经用完，另一个账号也要等待
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if err := second.Wait(ctx); err == nil {
+		t.Fatal("second account ignored the parent ceiling")
+	}
+
+	if got := parent.GetStats(time.Minute).TotalRequests; got != 1 {
+		t.Errorf("parent recorded %d requests, want 1", got)
+	}
+	if got := second.GetStats(time.Minute).TotalRequests; got != 0 {
+		t.Errorf("second account recorded %d requests, want 0", got)
+	}
+}
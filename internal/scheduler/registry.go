@@ -0,0 +1,151 @@
+package scheduler
+
+import (
+	"sort"
+	"sync"
+)
+
+// StatSaver 请求统计保存回调函数类型
+type StatSaver func(source string, accountId uint, requestTime int64, url, method string, duration int64, isThrottled bool)
+
+// LimitSaver 学习到的速率保存回调函数类型
+type LimitSaver func(source string, accountId uint, learned Limits)
+
+// LimitLoader 读取保存的学习速率回调函数类型
+type LimitLoader func(source string, accountId uint) Limits
+
+type executorKey struct {
+	source    string
+	accountId uint
+}
+
+var (
+	registryMu sync.Mutex
+	executors  = make(map[executorKey]*Executor)
+	// 账号单独配置的速率限制，没有配置的账号使用来源的默认值
+	accountLimits = make(map[executorKey]Limits)
+	// 各来源的默认速率限制
+	defaultLimits = map[string]Limits{
+		SOURCE_115:      {QPS: 3, QPM: 200, QPH: 12000},
+		SOURCE_OPENLIST: {QPS: 3},
+		SOURCE_BAIDUPAN: {QPS: 2},
+		SOURCE_123:      {QPS: 10},
+	}
+	statSaver   StatSaver
+	limitSaver  LimitSaver
+	limitLoader LimitLoader
+	statSaverMu sync.RWMutex
+)
+
+// Get 获取账号的调度器，同一个账号共享同一个调度器
+func Get(source string, accountId uint) *Executor {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	key := executorKey{source, accountId}
+	if e, ok := executors[key]; ok {
+		return e
+	}
+	e := NewExecutor(source, accountId, effectiveLimits(key))
+	executors[key] = e
+	return e
+}
+
+// 需要持有registryMu
+func effectiveLimits(key executorKey) Limits {
+	if l, ok := accountLimits[key]; ok {
+		return l
+	}
+	return defaultLimits[key.source]
+}
+
+// DefaultLimits 获取来源的默认速率限制
+func DefaultLimits(source string) Limits {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	return defaultLimits[source]
+}
+
+// SetDefaultLimits 设置来源的默认速率限制，没有单独配置的账号立即生效
+func SetDefaultLimits(source string, limits Limits) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	defaultLimits[source] = limits
+	for key, e := range executors {
+		if key.source == source {
+			e.SetLimits(effectiveLimits(key))
+		}
+	}
+}
+
+// SetAccountLimits 设置账号单独的速率限制，传入零值表示使用来源的默认值
+func SetAccountLimits(source string, accountId uint, limits Limits) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	key := executorKey{source, accountId}
+	if limits.IsZero() {
+		delete(accountLimits, key)
+	} else {
+		accountLimits[key] = limits
+	}
+	if e, ok := executors[key]; ok {
+		e.SetLimits(effectiveLimits(key))
+	}
+}
+
+// Executors 获取来源下所有已创建的调度器，按账号ID排序
+func Executors(source string) []*Executor {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	list := make([]*Executor, 0)
+	for key, e := range executors {
+		if key.source == source {
+			list = append(list, e)
+		}
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].AccountId < list[j].AccountId })
+	return list
+}
+
+// SetStatSaver 设置统计保存回调函数，所有来源共用
+func SetStatSaver(saver StatSaver) {
+	statSaverMu.Lock()
+	defer statSaverMu.Unlock()
+	statSaver = saver
+}
+
+func getStatSaver() StatSaver {
+	statSaverMu.RLock()
+	defer statSaverMu.RUnlock()
+	return statSaver
+}
+
+// SetLimitSaver 设置学习速率的保存回调函数，所有来源共用
+func SetLimitSaver(saver LimitSaver) {
+	statSaverMu.Lock()
+	defer statSaverMu.Unlock()
+	limitSaver = saver
+}
+
+func getLimitSaver() LimitSaver {
+	statSaverMu.RLock()
+	defer statSaverMu.RUnlock()
+	return limitSaver
+}
+
+// SetLimitLoader 设置读取学习速率的回调函数，所有来源共用
+func SetLimitLoader(loader LimitLoader) {
+	statSaverMu.Lock()
+	defer statSaverMu.Unlock()
+	limitLoader = loader
+}
+
+// LoadLearnedLimits 读取账号保存的学习速率，没有设置回调函数或者没有保存时返回零值
+func LoadLearnedLimits(source string, accountId uint) Limits {
+	statSaverMu.RLock()
+	loader := limitLoader
+	statSaverMu.RUnlock()
+	if loader == nil {
+		return Limits{}
+	}
+	return loader(source, accountId)
+}
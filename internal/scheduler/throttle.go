@@ -1,15 +1,16 @@
-package v115open
+package scheduler
 
 import (
-	"Q115-STRM/internal/helpers"
 	"context"
 	"sync"
 	"time"
 )
 
-// ThrottleManager 全局限流管理器，用于管理API访问频率限制
+// ThrottleManager 限流管理器，用于管理API访问频率限制
 type ThrottleManager struct {
 	sync.RWMutex
+	// 所属来源，用于选择日志
+	source string
 	// 是否处于限流状态
 	isThrottled bool
 	// 限流开始时间
@@ -21,8 +22,9 @@ type ThrottleManager struct {
 }
 
 // NewThrottleManager 创建新的限流管理器
-func NewThrottleManager() *ThrottleManager {
+func NewThrottleManager(source string) *ThrottleManager {
 	return &ThrottleManager{
+		source:           source,
 		isThrottled:      false,
 		throttleNotify:   make(chan struct{}),
 		throttleDuration: 1 * time.Minute,
@@ -60,7 +62,7 @@ func (tm *ThrottleManager) MarkThrottled(stats *RequestStats) {
 	tm.isThrottled = true
 	tm.throttleStartTime = time.Now()
 
-	helpers.V115Log.Warnf("检测到限流，将在 %v 秒后恢复", tm.throttleDuration.Seconds())
+	logger(tm.source).Warnf("[%s] 检测到限流，将在 %v 秒后恢复", tm.source, tm.throttleDuration.Seconds())
 
 	// 记录限流事件
 	if stats != nil {
@@ -79,7 +81,7 @@ func (tm *ThrottleManager) startRecoveryTimer() {
 	defer tm.Unlock()
 
 	tm.isThrottled = false
-	helpers.V115Log.Infof("限流已恢复，继续处理请求")
+	logger(tm.source).Infof("[%s] 限流已恢复，继续处理请求", tm.source)
 
 	// 发送恢复通知
 	select {
@@ -0,0 +1,96 @@
+package scheduler
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// 只检查较小的JSON响应体，避免读取文件内容
+const maxPeekSize = 1 << 20
+
+// ThrottleDetector 根据网盘的响应判断是否触发了限流，body是JSON响应体（非JSON时为空）
+type ThrottleDetector func(resp *http.Response, body []byte) bool
+
+// Transport 让HTTP客户端的请求经过调度器
+type Transport struct {
+	Base      http.RoundTripper
+	Executor  *Executor
+	Throttled ThrottleDetector
+}
+
+// NewTransport 创建经过调度器的Transport，base为空时使用http.DefaultTransport
+func NewTransport(base http.RoundTripper, executor *Executor, throttled ThrottleDetector) *Transport {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &Transport{Base: base, Executor: executor, Throttled: throttled}
+}
+
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var resp *http.Response
+	result := t.Executor.Execute(req.Context(), Call{
+		// 不记录查询参数，里面可能有访问凭证
+		URL:    req.URL.Scheme + "://" + req.URL.Host + req.URL.Path,
+		Method: req.Method,
+		Do: func() (bool, error) {
+			var err error
+			resp, err = t.Base.RoundTrip(req)
+			if err != nil {
+				return false, err
+			}
+			if t.Throttled == nil {
+				return false, nil
+			}
+			return t.Throttled(resp, peekBody(resp)), nil
+		},
+	})
+	if result.Error != nil {
+		if resp != nil {
+			resp.Body.Close()
+		}
+		return nil, result.Error
+	}
+	return resp, nil
+}
+
+type peekedBody struct {
+	io.Reader
+	io.Closer
+}
+
+// 读取JSON响应体，然后放回去给调用方继续读取
+func peekBody(resp *http.Response) []byte {
+	if resp.Body == nil || !strings.Contains(resp.Header.Get("Content-Type"), "json") || resp.ContentLength > maxPeekSize {
+		return nil
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxPeekSize))
+	resp.Body = peekedBody{Reader: io.MultiReader(bytes.NewReader(body), resp.Body), Closer: resp.Body}
+	if err != nil {
+		return nil
+	}
+	return body
+}
+
+// ResponseCode 读取JSON响应体中的数字错误码，比如code、errno
+func ResponseCode(body []byte, field string) (int64, bool) {
+	if len(body) == 0 {
+		return 0, false
+	}
+	var data map[string]json.RawMessage
+	if err := json.Unmarshal(body, &data); err != nil {
+		return 0, false
+	}
+	raw, ok := data[field]
+	if !ok {
+		return 0, false
+	}
+	var code json.Number
+	if err := json.Unmarshal(raw, &code); err != nil {
+		return 0, false
+	}
+	n, err := code.Int64()
+	return n, err == nil
+}
@@ -0,0 +1,380 @@
+package rename
+
+import (
+	"Q115-STRM/internal/helpers"
+	"Q115-STRM/internal/models"
+	"Q115-STRM/internal/s3"
+	"Q115-STRM/internal/webdav"
+	"context"
+	"io"
+	"net/http"
+	"path"
+	"strings"
+)
+
+// pathClient 按路径操作文件的网盘，文件ID和目录ID都是完整路径
+type pathClient interface {
+	// 返回文件名，不存在时返回错误
+	Stat(ctx context.Context, p string) (string, error)
+	// 返回目录下的文件名
+	ReadDir(ctx context.Context, p string) ([]string, error)
+	Copy(oldPath, newPath string) error
+	Move(oldPath, newPath string) error
+	MkdirAll(p string) error
+	Remove(p string) error
+	Get(ctx context.Context, p string) (*http.Response, error)
+}
+
+type webdavPathClient struct{ *webdav.Client }
+
+func (c webdavPathClient) Stat(ctx context.Context, p string) (string, error) {
+	info, err := c.Client.Stat(ctx, p)
+	if err != nil {
+		return "", err
+	}
+	return info.Name, nil
+}
+
+func (c webdavPathClient) ReadDir(ctx context.Context, p string) ([]string, error) {
+	files, err := c.Client.ReadDir(ctx, p)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(files))
+	for _, file := range files {
+		names = append(names, file.Name)
+	}
+	return names, nil
+}
+
+func (c webdavPathClient) Copy(oldPath, newPath string) error {
+	return c.Client.Copy(oldPath, newPath, false)
+}
+
+func (c webdavPathClient) Move(oldPath, newPath string) error {
+	return c.Client.Move(oldPath, newPath, false)
+}
+
+func (c webdavPathClient) Get(ctx context.Context, p string) (*http.Response, error) {
+	return c.Client.Get(ctx, p, nil)
+}
+
+// S3没有真正的目录，移动和改名是先复制再删除
+type s3PathClient struct{ *s3.Client }
+
+func (c s3PathClient) Stat(ctx context.Context, p string) (string, error) {
+	info, err := c.Client.Stat(ctx, p)
+	if err != nil {
+		return "", err
+	}
+	return info.Name, nil
+}
+
+func (c s3PathClient) ReadDir(ctx context.Context, p string) ([]string, error) {
+	files, err := c.Client.ReadDir(ctx, p)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(files))
+	for _, file := range files {
+		names = append(names, file.Name)
+	}
+	return names, nil
+}
+
+func (c s3PathClient) MkdirAll(p string) error {
+	return c.Client.Mkdir(p)
+}
+
+func (c s3PathClient) Get(ctx context.Context, p string) (*http.Response, error) {
+	return c.Client.Get(ctx, p, nil)
+}
+
+// RenamePath WebDAV和S3共用的整理实现，name是日志中显示的来源名称
+type RenamePath struct {
+	RenameBase
+	client pathClient
+	name   string
+}
+
+func NewRenameWebDAV(ctx context.Context, scrapePath *models.ScrapePath, client *webdav.Client) *RenamePath {
+	return newRenamePath(ctx, scrapePath, webdavPathClient{client}, "WebDAV")
+}
+
+func NewRenameS3(ctx context.Context, scrapePath *models.ScrapePath, client *s3.Client) *RenamePath {
+	return newRenamePath(ctx, scrapePath, s3PathClient{client}, "S3")
+}
+
+func newRenamePath(ctx context.Context, scrapePath *models.ScrapePath, client pathClient, name string) *RenamePath {
+	return &RenamePath{
+		RenameBase: RenameBase{
+			scrapePath: scrapePath,
+			ctx:        ctx,
+		},
+		client: client,
+		name:   name,
+	}
+}
+
+func (r *RenamePath) exists(p string) bool {
+	_, err := r.client.Stat(r.ctx, p)
+	return err == nil
+}
+
+// 将oldDir下的文件移动或复制到newDir，文件名不变
+func (r *RenamePath) transfer(oldDir, newDir string, names []string, isCopy bool) error {
+	for _, name := range names {
+		src := path.Join(oldDir, name)
+		dst := path.Join(newDir, name)
+		var err error
+		if isCopy {
+			err = r.client.Copy(src, dst)
+		} else {
+			err = r.client.Move(src, dst)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *RenamePath) RenameAndMove(mediaFile *models.ScrapeMediaFile, destPath, destPathId, newName string) error {
+	oldPath := mediaFile.PathId
+	if oldPath == "" && mediaFile.MediaType == models.MediaTypeTvShow {
+		oldPath = mediaFile.TvshowPathId
+	}
+	destName := path.Join(destPath, newName)
+	if r.exists(destName) {
+		helpers.AppLogger.Infof("文件 %s 已存在，无需移动", destName)
+		return nil
+	}
+	if mediaFile.VideoFilename != newName {
+		// 改名
+		if err := r.Rename(path.Join(oldPath, mediaFile.VideoFilename), newName); err != nil {
+			helpers.AppLogger.Errorf("%s改名文件失败: %v", r.name, err)
+			return err
+		}
+		helpers.AppLogger.Infof("文件 %s 重命名成功：%s", oldPath+"/"+mediaFile.VideoFilename, newName)
+	}
+	// 先改名，后移动或复制
+	switch mediaFile.RenameType {
+	case models.RenameTypeMove:
+		return r.transferMediaFiles(mediaFile, newName, destPathId, oldPath, false)
+	case models.RenameTypeCopy:
+		return r.transferMediaFiles(mediaFile, newName, destPathId, oldPath, true)
+	}
+	return nil
+}
+
+// 把视频文件和字幕文件移动或复制到新目录，其他类型仅整理时图片和nfo也一起转移
+func (r *RenamePath) transferMediaFiles(mediaFile *models.ScrapeMediaFile, newName, newPathId, oldPath string, isCopy bool) error {
+	action := "移动"
+	if isCopy {
+		action = "复制"
+	}
+	destFullPath := path.Join(newPathId, newName)
+	if r.exists(destFullPath) {
+		helpers.AppLogger.Infof("文件 %s 已存在，无需%s", destFullPath, action)
+	} else {
+		if err := r.transfer(oldPath, newPathId, []string{newName}, isCopy); err != nil {
+			helpers.AppLogger.Errorf("%s%s文件失败: %v", r.name, action, err)
+			return err
+		}
+		helpers.AppLogger.Infof("%s 文件 %s 成功从 %s %s到新文件夹 %s", r.name, newName, oldPath, action, newPathId)
+	}
+	if !isCopy {
+		mediaFile.Media.VideoFileId = destFullPath
+	}
+	oldBaseName := strings.TrimSuffix(mediaFile.VideoFilename, mediaFile.VideoExt)
+	if mediaFile.SubtitleFileJson != "" {
+		subtitleFiles := make([]*models.MediaMetaFiles, 0, len(mediaFile.SubtitleFiles))
+		for _, sub := range mediaFile.SubtitleFiles {
+			if err := r.transfer(oldPath, newPathId, []string{sub.FileName}, isCopy); err != nil {
+				helpers.AppLogger.Errorf("%s%s字幕文件 %s 失败: %v", r.name, action, sub.FileName, err)
+			}
+			name := sub.FileName
+			newSubName := strings.Replace(sub.FileName, oldBaseName, mediaFile.NewVideoBaseName, 1)
+			if newSubName != sub.FileName {
+				if err := r.Rename(path.Join(newPathId, sub.FileName), newSubName); err != nil {
+					helpers.AppLogger.Errorf("%s改名字幕文件失败: %v", r.name, err)
+				} else {
+					helpers.AppLogger.Infof("字幕文件 %s 重命名成功：%s", newPathId+"/"+sub.FileName, newSubName)
+					name = newSubName
+				}
+			}
+			subtitleFiles = append(subtitleFiles, &models.MediaMetaFiles{
+				FileName: name,
+				FileId:   path.Join(newPathId, name),
+				PickCode: path.Join(newPathId, name),
+			})
+		}
+		if mediaFile.MediaType != models.MediaTypeTvShow {
+			mediaFile.Media.SubtitleFiles = subtitleFiles
+		} else {
+			mediaFile.MediaEpisode.SubtitleFiles = subtitleFiles
+		}
+	}
+	if mediaFile.MediaType != models.MediaTypeTvShow {
+		mediaFile.Media.Save()
+	} else {
+		mediaFile.MediaEpisode.Save()
+	}
+	if mediaFile.ScrapeType != models.ScrapeTypeOnlyRename || mediaFile.MediaType != models.MediaTypeOther {
+		return nil
+	}
+	// 其他类型仅整理要把图片和nfo也转移过去
+	for _, imageFile := range mediaFile.ImageFiles {
+		if err := r.transfer(oldPath, newPathId, []string{imageFile.FileName}, isCopy); err != nil {
+			helpers.AppLogger.Errorf("%s%s图片文件失败: %v", r.name, action, err)
+			return err
+		}
+		newImageName := strings.Replace(imageFile.FileName, oldBaseName, mediaFile.NewVideoBaseName, 1)
+		if newImageName != imageFile.FileName {
+			if err := r.Rename(path.Join(newPathId, imageFile.FileName), newImageName); err != nil {
+				helpers.AppLogger.Errorf("%s改图片文件名失败: %v", r.name, err)
+				return err
+			}
+			helpers.AppLogger.Infof("图片文件 %s 重命名成功：%s", newPathId+"/"+imageFile.FileName, newImageName)
+		}
+	}
+	if mediaFile.NfoFileId != "" {
+		if err := r.transfer(oldPath, newPathId, []string{mediaFile.NfoFileName}, isCopy); err != nil {
+			helpers.AppLogger.Errorf("%s%snfo文件 %s 失败: %v", r.name, action, mediaFile.NfoFileName, err)
+		}
+		newNfoName := strings.Replace(mediaFile.NfoFileName, oldBaseName, mediaFile.NewVideoBaseName, 1)
+		if newNfoName != mediaFile.NfoFileName {
+			if err := r.Rename(path.Join(newPathId, mediaFile.NfoFileName), newNfoName); err != nil {
+				helpers.AppLogger.Errorf("%s改名nfo文件 %s 失败: %v", r.name, mediaFile.NfoFileName, err)
+			} else {
+				helpers.AppLogger.Infof("nfo文件 %s 成功重命名为 %s", mediaFile.NfoFileName, newNfoName)
+			}
+		}
+	}
+	return nil
+}
+
+func (r *RenamePath) CheckAndMkDir(destFullPath string, rootPath, rootPathId string) (string, error) {
+	if err := r.client.MkdirAll(destFullPath); err != nil {
+		helpers.AppLogger.Errorf("创建文件夹失败: %s 错误：%v", destFullPath, err)
+		return destFullPath, err
+	}
+	return destFullPath, nil
+}
+
+func (r *RenamePath) RemoveMediaSourcePath(mediaFile *models.ScrapeMediaFile, sp *models.ScrapePath) error {
+	sourcePath := mediaFile.PathId
+	if sourcePath == "" && mediaFile.MediaType == models.MediaTypeTvShow {
+		sourcePath = mediaFile.TvshowPathId
+	}
+	files, err := r.client.ReadDir(r.ctx, sourcePath)
+	if err != nil {
+		helpers.AppLogger.Errorf("获取%s文件列表失败: %s %v", r.name, sourcePath, err)
+		return err
+	}
+	for _, name := range files {
+		if sp.IsVideoFile(name) {
+			helpers.AppLogger.Infof("目录 %s 下有其他视频文件，不删除", sourcePath)
+			return nil
+		}
+	}
+	if len(files) == 0 || sp.ForceDeleteSourcePath {
+		if sourcePath == sp.SourcePath {
+			helpers.AppLogger.Info("视频文件的父目录是来源根路径，不删除")
+			return nil
+		}
+		if err := r.client.Remove(sourcePath); err != nil {
+			helpers.AppLogger.Errorf("删除%s文件夹失败: %s %v", r.name, sourcePath, err)
+			return err
+		}
+		helpers.AppLogger.Infof("刮削完成，尝试删除%s文件夹成功, 路径：%s", r.name, sourcePath)
+	}
+	// 再删除电视剧文件夹
+	tvshowPath := mediaFile.TvshowPathId
+	if mediaFile.MediaType != models.MediaTypeTvShow || mediaFile.PathId == "" || tvshowPath == "" || tvshowPath == sourcePath {
+		return nil
+	}
+	if tvshowPath == sp.SourcePath {
+		helpers.AppLogger.Info("电视剧的父目录是来源根路径，不删除")
+		return nil
+	}
+	files, err = r.client.ReadDir(r.ctx, tvshowPath)
+	if err != nil {
+		helpers.AppLogger.Errorf("获取%s文件列表失败: %s %v", r.name, tvshowPath, err)
+		return err
+	}
+	if len(files) == 0 || sp.ForceDeleteSourcePath {
+		if err := r.client.Remove(tvshowPath); err != nil {
+			helpers.AppLogger.Errorf("删除%s文件夹失败: %s %v", r.name, tvshowPath, err)
+			return err
+		}
+		helpers.AppLogger.Infof("刮削完成，尝试删除%s中的电视剧文件夹成功, 路径：%s", r.name, tvshowPath)
+	}
+	return nil
+}
+
+func (r *RenamePath) ReadFileContent(fileId string) ([]byte, error) {
+	resp, err := r.client.Get(r.ctx, fileId)
+	if err != nil {
+		helpers.AppLogger.Errorf("%s读取文件内容失败: fileId=%s, %v", r.name, fileId, err)
+		return nil, err
+	}
+	defer resp.Body.Close()
+	return io.ReadAll(resp.Body)
+}
+
+func (r *RenamePath) CheckAndDeleteFiles(mediaFile *models.ScrapeMediaFile, files []models.WillDeleteFile) error {
+	for _, f := range files {
+		if !r.exists(f.FullFilePath) {
+			helpers.AppLogger.Infof("%s文件不存在，无需删除: 路径：%s", r.name, f.FullFilePath)
+			continue
+		}
+		if err := r.client.Remove(f.FullFilePath); err != nil {
+			helpers.AppLogger.Errorf("删除%s文件失败: 路径：%s %v", r.name, f.FullFilePath, err)
+			continue
+		}
+		helpers.AppLogger.Infof("删除%s文件成功, 路径：%s", r.name, f.FullFilePath)
+	}
+	return nil
+}
+
+func (r *RenamePath) MoveFiles(f models.MoveNewFileToSourceFile) error {
+	newFileId := path.Join(f.PathId, path.Base(f.FileId))
+	if r.exists(newFileId) {
+		helpers.AppLogger.Infof("%s文件存在，无需移动: 路径：%s", r.name, newFileId)
+		return nil
+	}
+	if err := r.client.Move(f.FileId, newFileId); err != nil {
+		helpers.AppLogger.Errorf("移动%s文件失败: %s => %s 错误：%v", r.name, f.FileId, newFileId, err)
+		return err
+	}
+	helpers.AppLogger.Infof("移动%s文件成功: %s => %s", r.name, f.FileId, newFileId)
+	return nil
+}
+
+func (r *RenamePath) DeleteDir(dirPath, pathId string) error {
+	return r.client.Remove(pathId)
+}
+
+func (r *RenamePath) Rename(fileId, newName string) error {
+	return r.client.Move(fileId, path.Join(path.Dir(fileId), newName))
+}
+
+// 检查是否存在，存在就改名字，然后返回新的fileId
+func (r *RenamePath) ExistsAndRename(fileId, newName string) (string, error) {
+	name, err := r.client.Stat(r.ctx, fileId)
+	if err != nil {
+		helpers.AppLogger.Infof("%s文件不存在，无需重命名: 文件ID：%s", r.name, fileId)
+		return "", nil
+	}
+	if name == newName {
+		helpers.AppLogger.Infof("%s文件名字没变，无需重命名: 文件ID：%s", r.name, fileId)
+		return fileId, nil
+	}
+	if err := r.Rename(fileId, newName); err != nil {
+		helpers.AppLogger.Errorf("重命名%s文件失败：%s => %s 错误：%v", r.name, fileId, newName, err)
+		return "", err
+	}
+	helpers.AppLogger.Infof("重命名%s文件成功, %s => %s", r.name, fileId, newName)
+	return path.Join(path.Dir(fileId), newName), nil
+}
@@ -4,8 +4,10 @@ import (
 	"Q115-STRM/internal/helpers"
 	"Q115-STRM/internal/models"
 	"Q115-STRM/internal/openlist"
+	"Q115-STRM/internal/s3"
 	"Q115-STRM/internal/scrape/rename"
 	"Q115-STRM/internal/v115open"
+	"Q115-STRM/internal/webdav"
 	"context"
 	"errors"
 	"fmt"
@@ -18,13 +20,17 @@ type renameMovieImpl struct {
 	renameImpl renameImpl
 }
 
-func NewRenameMovieImpl(scrapePath *models.ScrapePath, ctx context.Context, v115Client *v115open.OpenClient, openlistClient *openlist.Client) renameImpl {
+func NewRenameMovieImpl(scrapePath *models.ScrapePath, ctx context.Context, v115Client *v115open.OpenClient, openlistClient *openlist.Client, webdavClient *webdav.Client, s3Client *s3.Client) renameImpl {
 	var ri renameImpl
 	switch scrapePath.SourceType {
 	case models.SourceType115:
 		ri = rename.NewRename115(ctx, scrapePath, v115Client)
 	case models.SourceTypeOpenList:
 		ri = rename.NewRenameOpenList(ctx, scrapePath, openlistClient)
+	case models.SourceTypeWebDAV:
+		ri = rename.NewRenameWebDAV(ctx, scrapePath, webdavClient)
+	case models.SourceTypeS3:
+		ri = rename.NewRenameS3(ctx, scrapePath, s3Client)
 	default:
 		ri = rename.NewRenameLocal(ctx, scrapePath)
 	}
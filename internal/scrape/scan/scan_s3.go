@@ -0,0 +1,139 @@
+package scan
+
+import (
+	"Q115-STRM/internal/helpers"
+	"Q115-STRM/internal/models"
+	"Q115-STRM/internal/s3"
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"slices"
+	"sync"
+	"time"
+)
+
+// 从S3扫描需要刮削的文件入库，文件ID和PickCode都是文件的完整路径
+type ScanS3Impl struct {
+	scanBaseImpl
+	client *s3.Client
+}
+
+func NewS3ScanImpl(scrapePath *models.ScrapePath, client *s3.Client, ctx context.Context) *ScanS3Impl {
+	return &ScanS3Impl{scanBaseImpl: scanBaseImpl{ctx: ctx, scrapePath: scrapePath}, client: client}
+}
+
+// 检查来源目录和目标目录是否存在
+func (s *ScanS3Impl) CheckPathExists() error {
+	if _, err := s.client.Stat(s.ctx, s.scrapePath.SourcePathId); err != nil {
+		return fmt.Errorf("刮削来源目录 %s 疑似不存在，请检查或编辑重新选择来源目录: %v", s.scrapePath.SourcePathId, err)
+	}
+	if s.scrapePath.ScrapeType != models.ScrapeTypeOnly {
+		if _, err := s.client.Stat(s.ctx, s.scrapePath.DestPathId); err != nil {
+			return fmt.Errorf("刮削目标目录 %s 疑似不存在，请检查或编辑重新选择目标目录: %v", s.scrapePath.DestPathId, err)
+		}
+	}
+	return nil
+}
+
+// 递归扫描来源目录下的所有文件
+func (s *ScanS3Impl) GetNetFileFiles() error {
+	// 批次号
+	s.BatchNo = time.Now().Format("20060102150405000")
+	// 检查是否停止任务
+	if !s.CheckIsRunning() {
+		return errors.New("任务已停止")
+	}
+	if _, err := s.client.Stat(s.ctx, s.scrapePath.SourcePathId); err != nil {
+		return fmt.Errorf("源目录 %s 不存在或者其他错误：%v", s.scrapePath.SourcePathId, err)
+	}
+	// 初始化路径队列，容量为接口线程数
+	s.pathTasks = make(chan string, models.SettingsGlobal.FileDetailThreads)
+	bufferCtx, cancelBuffer := context.WithCancel(context.Background())
+	go s.bufferMonitor(bufferCtx)
+	// 加入根目录
+	s.wg = sync.WaitGroup{}
+	s.addPathToTasks(s.scrapePath.SourcePathId)
+	helpers.AppLogger.Infof("开始处理目录 %s, 开启 %d 个任务", s.scrapePath.SourcePath, models.SettingsGlobal.FileDetailThreads)
+	for i := 0; i < models.SettingsGlobal.FileDetailThreads; i++ {
+		go s.startPathWork(i)
+	}
+	go func() {
+		<-s.ctx.Done()
+		for range s.pathTasks {
+			s.wg.Done()
+		}
+	}()
+	s.wg.Wait()        // 等待最后一个目录处理完
+	close(s.pathTasks) // 关闭pathTasks，释放资源
+	cancelBuffer()     // 让bufferMonitor退出
+	return nil
+}
+
+func (s *ScanS3Impl) startPathWork(workerID int) {
+	for {
+		select {
+		case <-s.ctx.Done():
+			return
+		case pathId, ok := <-s.pathTasks:
+			if !ok {
+				return
+			}
+			if !s.CheckIsRunning() {
+				s.wg.Done()
+				return
+			}
+			helpers.AppLogger.Infof("worker %d 开始处理目录 %s", workerID, pathId)
+			files, err := s.client.ReadDir(s.ctx, pathId)
+			if err != nil {
+				// 列出目录失败时跳过该目录
+				helpers.AppLogger.Errorf("worker %d 处理目录 %s 失败: %v", workerID, pathId, err)
+				s.wg.Done()
+				continue
+			}
+			// 记录下图片、nfo、字幕文件，用来寻找视频文件对应的元数据
+			picFiles := make([]*localFile, 0)
+			nfoFiles := make([]*localFile, 0)
+			subFiles := make([]*localFile, 0)
+			videoFiles := make([]*localFile, 0)
+			for _, file := range files {
+				fullFilePathName := path.Join(pathId, file.Name)
+				if file.IsDir {
+					s.addPathToTasks(fullFilePathName)
+					continue
+				}
+				if !s.scrapePath.CheckFileIsAllowed(file.Name, file.Size) {
+					continue
+				}
+				// 已在数据库中的文件直接跳过
+				if models.CheckExistsFileIdAndName(fullFilePathName, s.scrapePath.ID) {
+					helpers.AppLogger.Infof("文件 %s 已在数据库中，跳过", fullFilePathName)
+					continue
+				}
+				lf := &localFile{
+					Id:       fullFilePathName,
+					PickCode: fullFilePathName,
+					Name:     file.Name,
+					Size:     file.Size,
+					Path:     fullFilePathName,
+				}
+				ext := path.Ext(file.Name)
+				switch {
+				case slices.Contains(models.SubtitleExtArr, ext):
+					subFiles = append(subFiles, lf)
+				case slices.Contains(models.ImageExtArr, ext):
+					picFiles = append(picFiles, lf)
+				case ext == ".nfo":
+					nfoFiles = append(nfoFiles, lf)
+				case s.scrapePath.IsVideoFile(file.Name):
+					videoFiles = append(videoFiles, lf)
+				}
+			}
+			verr := s.processVideoFile(pathId, pathId, videoFiles, picFiles, nfoFiles, subFiles)
+			s.wg.Done()
+			if verr != nil {
+				return
+			}
+		}
+	}
+}
@@ -4,8 +4,10 @@ import (
 	"Q115-STRM/internal/helpers"
 	"Q115-STRM/internal/models"
 	"Q115-STRM/internal/openlist"
+	"Q115-STRM/internal/s3"
 	"Q115-STRM/internal/scrape/scan"
 	"Q115-STRM/internal/v115open"
+	"Q115-STRM/internal/webdav"
 	"context"
 	"fmt"
 	"os"
@@ -71,12 +73,15 @@ type Scrape struct {
 	ctxCancel      context.CancelFunc // 用来取消任务
 	V115Client     *v115open.OpenClient
 	OpenlistClient *openlist.Client
+	WebDAVClient   *webdav.Client
+	S3Client       *s3.Client
 }
 
 // scrapePath 要刮削的目录
 // ctx 控制刮削任务是否取消
 func NewScrape(scrapePath *models.ScrapePath) *Scrape {
-	cancelCtx, ctxCancel := context.WithCancel(context.Background())
+	// 115的请求进入刮削队列，排在播放和浏览后面
+	cancelCtx, ctxCancel := context.WithCancel(v115open.WithPriority(context.Background(), v115open.PRIORITY_SCRAPE))
 	return &Scrape{
 		scrapePath: scrapePath,
 		ctx:        cancelCtx,
@@ -98,6 +103,10 @@ func (s *Scrape) initOpenClient() error {
 		s.V115Client = account.Get115Client()
 	case models.SourceTypeOpenList:
 		s.OpenlistClient = account.GetOpenListClient()
+	case models.SourceTypeWebDAV:
+		s.WebDAVClient = account.GetWebDAVClient()
+	case models.SourceTypeS3:
+		s.S3Client = account.GetS3Client()
 	}
 	return nil
 }
@@ -132,12 +141,16 @@ func (s *Scrape) init() {
 		s.scanImpl = scan.New115ScanImpl(s.scrapePath, s.V115Client, s.ctx)
 	case models.SourceTypeOpenList:
 		s.scanImpl = scan.NewOpenlistScanImpl(s.scrapePath, s.OpenlistClient, s.ctx)
+	case models.SourceTypeWebDAV:
+		s.scanImpl = scan.NewWebDAVScanImpl(s.scrapePath, s.WebDAVClient, s.ctx)
+	case models.SourceTypeS3:
+		s.scanImpl = scan.NewS3ScanImpl(s.scrapePath, s.S3Client, s.ctx)
 	}
 	// 确定扫描接口，识别接口，刮削接口，重命名接口
 	if s.scrapePath.MediaType == models.MediaTypeTvShow {
-		s.scrapeImpl = NewTvShowScrapeImpl(s.scrapePath, s.ctx, s.V115Client, s.OpenlistClient)
+		s.scrapeImpl = NewTvShowScrapeImpl(s.scrapePath, s.ctx, s.V115Client, s.OpenlistClient, s.WebDAVClient, s.S3Client)
 	} else {
-		s.scrapeImpl = NewMovieScrapeImpl(s.scrapePath, s.ctx, s.V115Client, s.OpenlistClient)
+		s.scrapeImpl = NewMovieScrapeImpl(s.scrapePath, s.ctx, s.V115Client, s.OpenlistClient, s.WebDAVClient, s.S3Client)
 	}
 }
 
@@ -167,6 +180,8 @@ func (s *Scrape) Start() bool {
 	// 先生成所有二级分类
 	s.scrapePath.V115Client = s.V115Client
 	s.scrapePath.OpenListClient = s.OpenlistClient
+	s.scrapePath.WebDAVClient = s.WebDAVClient
+	s.scrapePath.S3Client = s.S3Client
 	s.scrapePath.GenerateCategory()
 	// 获取视频文件列表并从文件名中提取媒体信息用来刮削
 	eerr := s.scanImpl.GetNetFileFiles()
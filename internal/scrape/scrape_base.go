@@ -4,12 +4,14 @@ import (
 	"Q115-STRM/internal/helpers"
 	"Q115-STRM/internal/models"
 	"Q115-STRM/internal/openlist"
+	"Q115-STRM/internal/s3"
 	"Q115-STRM/internal/tmdb"
 	"Q115-STRM/internal/v115open"
+	"Q115-STRM/internal/webdav"
 	"context"
 	"fmt"
-	"net/url"
 	"path/filepath"
+	"time"
 )
 
 type uploadFile struct {
@@ -30,6 +32,8 @@ type ScrapeBase struct {
 	tmdbClient     *tmdb.Client
 	v115Client     *v115open.OpenClient
 	openlistClient *openlist.Client
+	webdavClient   *webdav.Client
+	s3Client       *s3.Client
 }
 
 // 下载图片到指定文件
@@ -73,7 +77,7 @@ func (s *ScrapeBase) FFprobe(mediaFile *models.ScrapeMediaFile) error {
 	}
 	if mediaFile.SourceType == models.SourceType115 {
 		// 代理访问
-		videoPathOrUrl = fmt.Sprintf("http://127.0.0.1:12333/proxy-115?url=%s", url.QueryEscape(videoPathOrUrl))
+		videoPathOrUrl = "http://127.0.0.1:12333" + helpers.MakeProxyUrl(videoPathOrUrl, helpers.ProxyFile{Source: string(models.SourceType115), AccountId: s.scrapePath.AccountId, FileId: mediaFile.VideoPickCode})
 	}
 	// 如果有下载连接，则提取视频信息
 	s.GetFFprobeInfoFromFileOrUrl(mediaFile, videoPathOrUrl)
@@ -171,9 +175,17 @@ func (s *ScrapeBase) GetDownloadUrl(mediaFile *models.ScrapeMediaFile) string {
 	videoPathOrUrl := mediaFile.VideoPickCode
 	switch mediaFile.SourceType {
 	case models.SourceType115:
-		videoPathOrUrl = s.v115Client.GetDownloadUrl(context.Background(), mediaFile.VideoPickCode, v115open.DEFAULTUA, false)
+		videoPathOrUrl = s.v115Client.GetDownloadUrl(s.ctx, mediaFile.VideoPickCode, v115open.DEFAULTUA, false)
 	case models.SourceTypeOpenList:
 		videoPathOrUrl = s.openlistClient.GetRawUrl(mediaFile.VideoPickCode)
+	case models.SourceTypeWebDAV:
+		videoPathOrUrl = s.webdavClient.DirectUrl(mediaFile.VideoPickCode)
+	case models.SourceTypeS3:
+		signedUrl, err := s.s3Client.PresignGet(mediaFile.VideoPickCode, time.Hour)
+		if err != nil {
+			helpers.AppLogger.Errorf("生成S3下载地址失败: %v", err)
+		}
+		videoPathOrUrl = signedUrl
 	case models.SourceType123:
 	}
 	return videoPathOrUrl
@@ -6,8 +6,10 @@ import (
 	"Q115-STRM/internal/models"
 	"Q115-STRM/internal/notificationmanager"
 	"Q115-STRM/internal/openlist"
+	"Q115-STRM/internal/s3"
 	"Q115-STRM/internal/tmdb"
 	"Q115-STRM/internal/v115open"
+	"Q115-STRM/internal/webdav"
 	"context"
 	"errors"
 	"fmt"
@@ -22,7 +24,7 @@ type movieScrapeImpl struct {
 	ScrapeBase
 }
 
-func NewMovieScrapeImpl(scrapePath *models.ScrapePath, ctx context.Context, v115Client *v115open.OpenClient, openlistClient *openlist.Client) scrapeImpl {
+func NewMovieScrapeImpl(scrapePath *models.ScrapePath, ctx context.Context, v115Client *v115open.OpenClient, openlistClient *openlist.Client, webdavClient *webdav.Client, s3Client *s3.Client) scrapeImpl {
 	tmdbImpl := NewTmdbMovieImpl(scrapePath, ctx)
 	return &movieScrapeImpl{
 		ScrapeBase: ScrapeBase{
@@ -31,9 +33,11 @@ func NewMovieScrapeImpl(scrapePath *models.ScrapePath, ctx context.Context, v115
 			identifyImpl:   NewIdMovieImpl(scrapePath, ctx, tmdbImpl),
 			tmdbClient:     tmdbImpl.Client,
 			categoryImpl:   NewCategoryMovieImpl(scrapePath),
-			renameImpl:     NewRenameMovieImpl(scrapePath, ctx, v115Client, openlistClient),
+			renameImpl:     NewRenameMovieImpl(scrapePath, ctx, v115Client, openlistClient, webdavClient, s3Client),
 			v115Client:     v115Client,
 			openlistClient: openlistClient,
+			webdavClient:   webdavClient,
+			s3Client:       s3Client,
 		},
 	}
 }
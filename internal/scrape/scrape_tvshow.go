@@ -5,8 +5,10 @@ import (
 	"Q115-STRM/internal/helpers"
 	"Q115-STRM/internal/models"
 	"Q115-STRM/internal/openlist"
+	"Q115-STRM/internal/s3"
 	"Q115-STRM/internal/tmdb"
 	"Q115-STRM/internal/v115open"
+	"Q115-STRM/internal/webdav"
 	"context"
 	"errors"
 	"fmt"
@@ -28,7 +30,7 @@ type tvshowTask struct {
 	seasons   []uint
 }
 
-func NewTvShowScrapeImpl(scrapePath *models.ScrapePath, ctx context.Context, v115Client *v115open.OpenClient, openlistClient *openlist.Client) scrapeImpl {
+func NewTvShowScrapeImpl(scrapePath *models.ScrapePath, ctx context.Context, v115Client *v115open.OpenClient, openlistClient *openlist.Client, webdavClient *webdav.Client, s3Client *s3.Client) scrapeImpl {
 	tmdbImpl := NewTmdbTvShowImpl(scrapePath, ctx)
 	return &tvShowScrapeImpl{
 		ScrapeBase: ScrapeBase{
@@ -36,10 +38,12 @@ func NewTvShowScrapeImpl(scrapePath *models.ScrapePath, ctx context.Context, v11
 			ctx:            ctx,
 			identifyImpl:   NewIdTvShowImpl(scrapePath, ctx, tmdbImpl),
 			categoryImpl:   NewCategoryTvShowImpl(scrapePath),
-			renameImpl:     NewRenameTvShowImpl(scrapePath, ctx, v115Client, openlistClient),
+			renameImpl:     NewRenameTvShowImpl(scrapePath, ctx, v115Client, openlistClient, webdavClient, s3Client),
 			tmdbClient:     tmdbImpl.Client,
 			v115Client:     v115Client,
 			openlistClient: openlistClient,
+			webdavClient:   webdavClient,
+			s3Client:       s3Client,
 		},
 	}
 }
@@ -0,0 +1,165 @@
+package streamproxy
+
+import (
+	"container/list"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// ChunkCache 磁盘上的分块缓存，总大小超过限制时删除最久没有使用的分块
+// 最近播放的文件再次播放或者拖动进度条时不需要重新从网盘下载
+type ChunkCache struct {
+	dir      string
+	maxBytes int64
+
+	mu      sync.Mutex
+	lru     *list.List               // 最近使用的在前面
+	entries map[string]*list.Element // 分块文件路径 -> lru元素
+	size    int64
+}
+
+type cacheEntry struct {
+	path string
+	size int64
+}
+
+// NewChunkCache 创建分块缓存，maxBytes<=0表示不缓存；启动时加载目录中已有的分块
+func NewChunkCache(dir string, maxBytes int64) *ChunkCache {
+	c := &ChunkCache{dir: dir, maxBytes: maxBytes, lru: list.New(), entries: make(map[string]*list.Element)}
+	if maxBytes <= 0 {
+		return c
+	}
+	type chunkFile struct {
+		path  string
+		size  int64
+		mtime time.Time
+	}
+	files := make([]chunkFile, 0)
+	filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return nil
+		}
+		if filepath.Ext(path) == ".tmp" {
+			os.Remove(path)
+			return nil
+		}
+		files = append(files, chunkFile{path: path, size: info.Size(), mtime: info.ModTime()})
+		return nil
+	})
+	sort.Slice(files, func(i, j int) bool { return files[i].mtime.After(files[j].mtime) })
+	for _, f := range files {
+		c.entries[f.path] = c.lru.PushBack(&cacheEntry{path: f.path, size: f.size})
+		c.size += f.size
+	}
+	c.mu.Lock()
+	c.evict()
+	c.mu.Unlock()
+	return c
+}
+
+func (c *ChunkCache) chunkPath(fileKey string, index int64) string {
+	return filepath.Join(c.dir, fileKey, strconv.FormatInt(index, 10))
+}
+
+// Get 读取分块，不存在或者大小不是want（缓存文件损坏）时返回false
+func (c *ChunkCache) Get(fileKey string, index int64, want int64) ([]byte, bool) {
+	if c == nil || c.maxBytes <= 0 {
+		return nil, false
+	}
+	path := c.chunkPath(fileKey, index)
+	c.mu.Lock()
+	elem, ok := c.entries[path]
+	if ok {
+		c.lru.MoveToFront(elem)
+	}
+	c.mu.Unlock()
+	if !ok {
+		return nil, false
+	}
+	data, err := os.ReadFile(path)
+	if err != nil || int64(len(data)) != want {
+		c.remove(path)
+		return nil, false
+	}
+	return data, true
+}
+
+// Put 写入分块，先写临时文件再改名，避免读到不完整的分块
+// 多个连接可能同时下载同一个分块，每次使用不同的临时文件
+func (c *ChunkCache) Put(fileKey string, index int64, data []byte) {
+	if c == nil || c.maxBytes <= 0 || int64(len(data)) > c.maxBytes {
+		return
+	}
+	path := c.chunkPath(fileKey, index)
+	if err := os.MkdirAll(filepath.Dir(path), 0777); err != nil {
+		return
+	}
+	f, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return
+	}
+	tmp := f.Name()
+	_, err = f.Write(data)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if elem, ok := c.entries[path]; ok {
+		c.size -= elem.Value.(*cacheEntry).size
+		c.lru.Remove(elem)
+	}
+	c.entries[path] = c.lru.PushFront(&cacheEntry{path: path, size: int64(len(data))})
+	c.size += int64(len(data))
+	c.evict()
+}
+
+// Size 缓存的总大小
+func (c *ChunkCache) Size() int64 {
+	if c == nil {
+		return 0
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.size
+}
+
+func (c *ChunkCache) remove(path string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if elem, ok := c.entries[path]; ok {
+		c.size -= elem.Value.(*cacheEntry).size
+		c.lru.Remove(elem)
+		delete(c.entries, path)
+	}
+	os.Remove(path)
+}
+
+// evict 删除最久没有使用的分块直到总大小不超过限制，调用时需要持有锁
+func (c *ChunkCache) evict() {
+	for c.size > c.maxBytes {
+		elem := c.lru.Back()
+		if elem == nil {
+			return
+		}
+		entry := elem.Value.(*cacheEntry)
+		c.lru.Remove(elem)
+		delete(c.entries, entry.path)
+		c.size -= entry.size
+		os.Remove(entry.path)
+		// 文件的分块全部删除后删除目录，目录不为空时删除失败，忽略错误
+		os.Remove(filepath.Dir(entry.path))
+	}
+}
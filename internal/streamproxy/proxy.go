@@ -0,0 +1,457 @@
+package streamproxy
+
+import (
+	"Q115-STRM/internal/helpers"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// Resolver 重新获取文件的直链，网盘直链在播放过程中过期时调用
+type Resolver func(ctx context.Context) (string, error)
+
+// File 需要反代的网盘文件
+type File struct {
+	Key     string   // 缓存键，同一个网盘文件的不同直链相同，例如 115:pickcode
+	Url     string   // 当前的直链
+	UA      string   // 请求直链使用的UA，网盘直链和UA绑定
+	Resolve Resolver // 为空时直链过期后不能继续播放
+}
+
+// Options 反代的参数
+type Options struct {
+	ChunkSize    int64         // 分块大小，按分块并行下载和缓存
+	Parallel     int           // 每个播放连接同时下载的分块数量
+	ChunkTimeout time.Duration // 下载一个分块的超时时间，超时后重试
+	Client       *http.Client  // 请求网盘使用的客户端，不要设置总超时时间
+	Cache        *ChunkCache   // 分块缓存，为空不缓存
+}
+
+// Stats 反代的统计数据
+type Stats struct {
+	CacheHits     int64 `json:"cache_hits"`     // 从缓存读取的分块数
+	CacheMisses   int64 `json:"cache_misses"`   // 从网盘下载的分块数
+	UpstreamBytes int64 `json:"upstream_bytes"` // 从网盘下载的字节数
+	Refreshes     int64 `json:"refreshes"`      // 直链过期后重新获取的次数
+	CacheBytes    int64 `json:"cache_bytes"`    // 分块缓存的大小
+}
+
+// ErrLinkExpired 直链过期并且不能重新获取
+var ErrLinkExpired = errors.New("直链已过期")
+
+const (
+	maxChunkRetry = 3
+	// 重新获取直链的超时时间
+	refreshTimeout = 30 * time.Second
+	// 播放停止后文件信息保留的时间
+	fileStateTTL = time.Hour
+)
+
+// fileState 文件的大小、类型和最新的直链，同一个文件的多个播放连接共享，直链刷新后所有连接都使用新的直链
+type fileState struct {
+	mu           sync.Mutex
+	url          string
+	size         int64
+	contentType  string
+	rangeSupport bool
+	lastUsed     time.Time
+}
+
+func (st *fileState) getUrl() string {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	return st.url
+}
+
+func (st *fileState) info() (size int64, contentType string, rangeSupport bool) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	return st.size, st.contentType, st.rangeSupport
+}
+
+// Proxy 网盘文件的流式反代：按分块并行下载、缓存最近播放的分块、直链过期后自动刷新
+type Proxy struct {
+	opts Options
+
+	mu     sync.Mutex
+	states map[string]*fileState
+	// 同一个文件同时只刷新一次直链，key是File.Key
+	refreshGroup singleflight.Group
+
+	cacheHits     atomic.Int64
+	cacheMisses   atomic.Int64
+	upstreamBytes atomic.Int64
+	refreshes     atomic.Int64
+}
+
+// New 创建反代，没有设置的参数使用默认值
+func New(opts Options) *Proxy {
+	if opts.ChunkSize <= 0 {
+		opts.ChunkSize = 4 << 20
+	}
+	if opts.Parallel <= 0 {
+		opts.Parallel = 4
+	}
+	if opts.ChunkTimeout <= 0 {
+		opts.ChunkTimeout = 2 * time.Minute
+	}
+	if opts.Client == nil {
+		opts.Client = &http.Client{Transport: &http.Transport{Proxy: http.ProxyFromEnvironment, ResponseHeaderTimeout: 30 * time.Second}}
+	}
+	return &Proxy{opts: opts, states: make(map[string]*fileState)}
+}
+
+// Stats 统计数据
+func (p *Proxy) Stats() Stats {
+	return Stats{
+		CacheHits:     p.cacheHits.Load(),
+		CacheMisses:   p.cacheMisses.Load(),
+		UpstreamBytes: p.upstreamBytes.Load(),
+		Refreshes:     p.refreshes.Load(),
+		CacheBytes:    p.opts.Cache.Size(),
+	}
+}
+
+// Serve 反代一个播放请求，支持单个Range，返回写给客户端的字节数
+func (p *Proxy) Serve(w http.ResponseWriter, r *http.Request, f *File) (int64, error) {
+	st, err := p.open(r.Context(), f)
+	if err != nil {
+		http.Error(w, "获取文件信息失败: "+err.Error(), http.StatusBadGateway)
+		return 0, err
+	}
+	size, contentType, rangeSupport := st.info()
+	if !rangeSupport {
+		// 网盘不支持Range时不能分块，直接转发
+		return p.passthrough(w, r, st, f)
+	}
+	start, end, partial, ok := parseRange(r.Header.Get("Range"), size)
+	if !ok {
+		w.Header().Set("Content-Range", fmt.Sprintf("bytes */%d", size))
+		http.Error(w, "Range无效", http.StatusRequestedRangeNotSatisfiable)
+		return 0, nil
+	}
+	header := w.Header()
+	header.Set("Accept-Ranges", "bytes")
+	if contentType != "" {
+		header.Set("Content-Type", contentType)
+	}
+	header.Set("Content-Length", strconv.FormatInt(end-start+1, 10))
+	if partial {
+		header.Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", start, end, size))
+		w.WriteHeader(http.StatusPartialContent)
+	} else {
+		w.WriteHeader(http.StatusOK)
+	}
+	if r.Method == http.MethodHead || size == 0 {
+		return 0, nil
+	}
+	return p.stream(r.Context(), w, st, f, size, start, end)
+}
+
+// open 获取文件信息，第一次播放时请求第一个字节得到文件大小
+func (p *Proxy) open(ctx context.Context, f *File) (*fileState, error) {
+	now := time.Now()
+	p.mu.Lock()
+	st, ok := p.states[f.Key]
+	if !ok {
+		st = &fileState{url: f.Url}
+		p.states[f.Key] = st
+		for key, s := range p.states {
+			if now.Sub(s.lastUsed) > fileStateTTL && s != st {
+				delete(p.states, key)
+			}
+		}
+	}
+	st.lastUsed = now
+	p.mu.Unlock()
+
+	st.mu.Lock()
+	known := st.size > 0 || st.contentType != ""
+	st.mu.Unlock()
+	if known {
+		return st, nil
+	}
+	for attempt := 0; attempt < maxChunkRetry; attempt++ {
+		link := st.getUrl()
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, link, nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("User-Agent", f.UA)
+		req.Header.Set("Range", "bytes=0-0")
+		resp, err := p.opts.Client.Do(req)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			continue
+		}
+		resp.Body.Close()
+		if isExpiredStatus(resp.StatusCode) {
+			if err := p.refresh(ctx, st, f, link); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		st.mu.Lock()
+		st.contentType = resp.Header.Get("Content-Type")
+		switch resp.StatusCode {
+		case http.StatusPartialContent:
+			st.size = parseContentRangeSize(resp.Header.Get("Content-Range"))
+			st.rangeSupport = st.size >= 0
+		case http.StatusOK:
+			st.size = resp.ContentLength
+		default:
+			st.mu.Unlock()
+			return nil, fmt.Errorf("网盘返回 %s", resp.Status)
+		}
+		st.mu.Unlock()
+		return st, nil
+	}
+	return nil, fmt.Errorf("请求网盘失败")
+}
+
+// refresh 直链过期后重新获取，多个分块同时发现过期时只刷新一次
+// 刷新时不持有st.mu，其他连接可以继续读取文件信息；刷新使用单独的ctx，发现过期的连接断开后不影响其他连接
+func (p *Proxy) refresh(ctx context.Context, st *fileState, f *File, expiredUrl string) error {
+	if st.getUrl() != expiredUrl {
+		// 其他分块已经刷新过了
+		return nil
+	}
+	if f.Resolve == nil {
+		return ErrLinkExpired
+	}
+	ch := p.refreshGroup.DoChan(f.Key, func() (any, error) {
+		if st.getUrl() != expiredUrl {
+			return nil, nil
+		}
+		resolveCtx, cancel := context.WithTimeout(context.Background(), refreshTimeout)
+		defer cancel()
+		link, err := f.Resolve(resolveCtx)
+		if err != nil || link == "" {
+			return nil, fmt.Errorf("%w，重新获取直链失败: %v", ErrLinkExpired, err)
+		}
+		p.refreshes.Add(1)
+		helpers.AppLogger.Infof("播放过程中直链过期，已重新获取: %s", f.Key)
+		st.mu.Lock()
+		st.url = link
+		st.mu.Unlock()
+		return nil, nil
+	})
+	select {
+	case res := <-ch:
+		return res.Err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// stream 按顺序输出[start, end]范围的数据，同时在后台并行下载后面的分块
+func (p *Proxy) stream(ctx context.Context, w io.Writer, st *fileState, f *File, size, start, end int64) (int64, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	chunkSize := p.opts.ChunkSize
+	first, last := start/chunkSize, end/chunkSize
+	// 文件大小变化（网盘中的文件被替换）后不使用旧的缓存
+	cacheKey := helpers.MD5Hash(fmt.Sprintf("%s:%d", f.Key, size))
+
+	type result struct {
+		data []byte
+		err  error
+	}
+	pending := make([]chan result, 0, p.opts.Parallel)
+	next := first
+	launch := func() {
+		ch := make(chan result, 1)
+		index := next
+		next++
+		go func() {
+			data, err := p.fetchChunk(ctx, st, f, cacheKey, size, index)
+			ch <- result{data: data, err: err}
+		}()
+		pending = append(pending, ch)
+	}
+	for next <= last && len(pending) < p.opts.Parallel {
+		launch()
+	}
+	var written int64
+	for index := first; index <= last; index++ {
+		res := <-pending[0]
+		pending = pending[1:]
+		if next <= last {
+			launch()
+		}
+		if res.err != nil {
+			return written, res.err
+		}
+		chunkStart := index * chunkSize
+		if want := min(chunkSize, size-chunkStart); int64(len(res.data)) != want {
+			// 不能发送比Content-Length少的数据
+			return written, fmt.Errorf("分块 %d 大小错误，期望%d字节，实际%d字节", index, want, len(res.data))
+		}
+		from := max(start, chunkStart) - chunkStart
+		to := min(end, chunkStart+int64(len(res.data))-1) - chunkStart
+		n, err := w.Write(res.data[from : to+1])
+		written += int64(n)
+		if err != nil {
+			return written, err
+		}
+	}
+	return written, nil
+}
+
+// fetchChunk 读取一个分块，缓存中没有时从网盘下载，失败时重试
+func (p *Proxy) fetchChunk(ctx context.Context, st *fileState, f *File, cacheKey string, size, index int64) ([]byte, error) {
+	from := index * p.opts.ChunkSize
+	to := min(from+p.opts.ChunkSize, size) - 1
+	if data, ok := p.opts.Cache.Get(cacheKey, index, to-from+1); ok {
+		p.cacheHits.Add(1)
+		return data, nil
+	}
+	p.cacheMisses.Add(1)
+	var lastErr error
+	for attempt := 0; attempt < maxChunkRetry; attempt++ {
+		link := st.getUrl()
+		data, status, err := p.fetchRange(ctx, link, f.UA, from, to)
+		if err == nil {
+			p.upstreamBytes.Add(int64(len(data)))
+			p.opts.Cache.Put(cacheKey, index, data)
+			return data, nil
+		}
+		lastErr = err
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		if isExpiredStatus(status) {
+			if err := p.refresh(ctx, st, f, link); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		helpers.AppLogger.Warnf("下载分块失败 %s [%d-%d]，第%d次: %v", f.Key, from, to, attempt+1, err)
+		time.Sleep(time.Duration(attempt+1) * time.Second)
+	}
+	return nil, lastErr
+}
+
+func (p *Proxy) fetchRange(ctx context.Context, link, ua string, from, to int64) ([]byte, int, error) {
+	ctx, cancel := context.WithTimeout(ctx, p.opts.ChunkTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, link, nil)
+	if err != nil {
+		return nil, 0, err
+	}
+	req.Header.Set("User-Agent", ua)
+	req.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", from, to))
+	resp, err := p.opts.Client.Do(req)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusPartialContent {
+		return nil, resp.StatusCode, fmt.Errorf("网盘返回 %s", resp.Status)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, to-from+1))
+	if err != nil {
+		return nil, resp.StatusCode, err
+	}
+	if int64(len(data)) != to-from+1 {
+		return nil, resp.StatusCode, fmt.Errorf("分块不完整，期望%d字节，实际%d字节", to-from+1, len(data))
+	}
+	return data, resp.StatusCode, nil
+}
+
+// passthrough 网盘不支持Range时直接转发，仍然会在直链过期时刷新
+func (p *Proxy) passthrough(w http.ResponseWriter, r *http.Request, st *fileState, f *File) (int64, error) {
+	for attempt := 0; attempt < 2; attempt++ {
+		link := st.getUrl()
+		req, err := http.NewRequestWithContext(r.Context(), r.Method, link, nil)
+		if err != nil {
+			return 0, err
+		}
+		req.Header.Set("User-Agent", f.UA)
+		resp, err := p.opts.Client.Do(req)
+		if err != nil {
+			http.Error(w, "反代请求失败: "+err.Error(), http.StatusBadGateway)
+			return 0, err
+		}
+		if isExpiredStatus(resp.StatusCode) && attempt == 0 {
+			resp.Body.Close()
+			if err := p.refresh(r.Context(), st, f, link); err != nil {
+				http.Error(w, err.Error(), http.StatusBadGateway)
+				return 0, err
+			}
+			continue
+		}
+		defer resp.Body.Close()
+		for k, v := range resp.Header {
+			for _, vv := range v {
+				w.Header().Add(k, vv)
+			}
+		}
+		w.WriteHeader(resp.StatusCode)
+		n, err := io.Copy(w, resp.Body)
+		p.upstreamBytes.Add(n)
+		return n, err
+	}
+	return 0, ErrLinkExpired
+}
+
+// isExpiredStatus 网盘直链过期时返回的状态码
+func isExpiredStatus(status int) bool {
+	return status == http.StatusUnauthorized || status == http.StatusForbidden || status == http.StatusNotFound || status == http.StatusGone
+}
+
+// parseContentRangeSize 解析 Content-Range: bytes 0-0/12345 中的文件大小，未知返回-1
+func parseContentRangeSize(contentRange string) int64 {
+	_, total, ok := strings.Cut(contentRange, "/")
+	if !ok {
+		return -1
+	}
+	size, err := strconv.ParseInt(strings.TrimSpace(total), 10, 64)
+	if err != nil {
+		return -1
+	}
+	return size
+}
+
+// parseRange 解析客户端的Range，只支持单个范围，多个范围时返回整个文件
+func parseRange(header string, size int64) (start, end int64, partial, ok bool) {
+	if header == "" || !strings.HasPrefix(header, "bytes=") || strings.Contains(header, ",") {
+		return 0, size - 1, false, true
+	}
+	spec := strings.TrimSpace(strings.TrimPrefix(header, "bytes="))
+	from, to, found := strings.Cut(spec, "-")
+	if !found {
+		return 0, 0, false, false
+	}
+	var err error
+	if from == "" {
+		// bytes=-n 最后n个字节
+		n, err := strconv.ParseInt(to, 10, 64)
+		if err != nil || n <= 0 {
+			return 0, 0, false, false
+		}
+		return max(size-n, 0), size - 1, true, size > 0
+	}
+	if start, err = strconv.ParseInt(from, 10, 64); err != nil || start < 0 || start >= size {
+		return 0, 0, false, false
+	}
+	end = size - 1
+	if to != "" {
+		if end, err = strconv.ParseInt(to, 10, 64); err != nil || end < start {
+			return 0, 0, false, false
+		}
+		end = min(end, size-1)
+	}
+	return start, end, true, true
+}
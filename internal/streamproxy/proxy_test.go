@@ -0,0 +1,214 @@
+package streamproxy
+
+import (
+	"Q115-STRM/internal/helpers"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func init() {
+	helpers.AppLogger = &helpers.QLogger{Logger: log.New(io.Discard, "", 0)}
+}
+
+func TestProxyServe(t *testing.T) {
+	content := make([]byte, 1<<20+123)
+	rand.New(rand.NewSource(1)).Read(content)
+
+	var mu sync.Mutex
+	validToken := "t1"
+	var upstreamRequests atomic.Int32
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		upstreamRequests.Add(1)
+		mu.Lock()
+		valid := r.URL.Query().Get("t") == validToken
+		mu.Unlock()
+		if !valid || r.UserAgent() != "ua" {
+			// 模拟CDN直链过期
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		http.ServeContent(w, r, "a.mkv", time.Time{}, bytes.NewReader(content))
+	}))
+	defer upstream.Close()
+
+	var resolves atomic.Int32
+	proxy := New(Options{ChunkSize: 64 << 10, Parallel: 3, Cache: NewChunkCache(t.TempDir(), 4<<20)})
+	file := func() *File {
+		return &File{Key: "115:pc", Url: upstream.URL + "/a.mkv?t=t1", UA: "ua", Resolve: func(ctx context.Context) (string, error) {
+			resolves.Add(1)
+			return upstream.URL + "/a.mkv?t=t2", nil
+		}}
+	}
+	get := func(rangeHeader string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, "/proxy-115", nil)
+		if rangeHeader != "" {
+			r.Header.Set("Range", rangeHeader)
+		}
+		w := httptest.NewRecorder()
+		if _, err := proxy.Serve(w, r, file()); err != nil {
+			t.Fatalf("Serve(%q) error: %v", rangeHeader, err)
+		}
+		return w
+	}
+
+	w := get("")
+	if w.Code != http.StatusOK || !bytes.Equal(w.Body.Bytes(), content) {
+		t.Fatalf("full response = %d, %d bytes", w.Code, w.Body.Len())
+	}
+	w = get("bytes=100000-300000")
+	if w.Code != http.StatusPartialContent || !bytes.Equal(w.Body.Bytes(), content[100000:300001]) {
+		t.Fatalf("range response = %d, %d bytes", w.Code, w.Body.Len())
+	}
+	if got := w.Header().Get("Content-Range"); got != "bytes 100000-300000/1048699" {
+		t.Fatalf("Content-Range = %q", got)
+	}
+
+	// 已经缓存的分块不再请求网盘
+	before := upstreamRequests.Load()
+	w = get("bytes=-1000")
+	if !bytes.Equal(w.Body.Bytes(), content[len(content)-1000:]) || upstreamRequests.Load() != before {
+		t.Fatalf("cached range: %d bytes, upstream requests %d -> %d", w.Body.Len(), before, upstreamRequests.Load())
+	}
+
+	// 直链过期后自动刷新，只刷新一次
+	mu.Lock()
+	validToken = "t2"
+	mu.Unlock()
+	proxy.opts.Cache = nil
+	w = get("bytes=0-")
+	if !bytes.Equal(w.Body.Bytes(), content) {
+		t.Fatalf("response after link expiry = %d bytes", w.Body.Len())
+	}
+	if n := resolves.Load(); n != 1 {
+		t.Fatalf("resolves = %d, want 1", n)
+	}
+	if stats := proxy.Stats(); stats.Refreshes != 1 || stats.CacheHits == 0 {
+		t.Fatalf("stats = %+v", stats)
+	}
+}
+
+// 缓存的分块被截断时重新从网盘下载
+func TestProxyTruncatedCache(t *testing.T) {
+	content := make([]byte, 200<<10)
+	rand.New(rand.NewSource(2)).Read(content)
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.ServeContent(w, r, "a.mkv", time.Time{}, bytes.NewReader(content))
+	}))
+	defer upstream.Close()
+
+	dir := t.TempDir()
+	cache := NewChunkCache(dir, 4<<20)
+	proxy := New(Options{ChunkSize: 64 << 10, Parallel: 2, Cache: cache})
+	file := &File{Key: "115:pc", Url: upstream.URL + "/a.mkv", UA: "ua"}
+	get := func() []byte {
+		w := httptest.NewRecorder()
+		if _, err := proxy.Serve(w, httptest.NewRequest(http.MethodGet, "/proxy-115", nil), file); err != nil {
+			t.Fatalf("Serve error: %v", err)
+		}
+		return w.Body.Bytes()
+	}
+	if !bytes.Equal(get(), content) {
+		t.Fatal("first response mismatch")
+	}
+	cacheKey := helpers.MD5Hash(fmt.Sprintf("%s:%d", file.Key, len(content)))
+	if err := os.Truncate(cache.chunkPath(cacheKey, 1), 100); err != nil {
+		t.Fatalf("truncate cached chunk: %v", err)
+	}
+	misses := proxy.Stats().CacheMisses
+	if !bytes.Equal(get(), content) {
+		t.Fatal("response with truncated cache mismatch")
+	}
+	if n := proxy.Stats().CacheMisses - misses; n != 1 {
+		t.Fatalf("cache misses = %d, want 1", n)
+	}
+}
+
+// 刷新直链时不阻塞其他连接读取文件信息，发现过期的连接断开后刷新继续完成
+func TestProxyRefreshDetached(t *testing.T) {
+	release := make(chan struct{})
+	started := make(chan struct{})
+	var resolves atomic.Int32
+	var resolveErr atomic.Value
+	file := &File{Key: "115:pc", Url: "old", Resolve: func(ctx context.Context) (string, error) {
+		resolves.Add(1)
+		close(started)
+		<-release
+		resolveErr.Store(fmt.Sprint(ctx.Err()))
+		return "new", nil
+	}}
+	proxy := New(Options{})
+	st := &fileState{url: "old"}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	first := make(chan error, 1)
+	go func() { first <- proxy.refresh(ctx, st, file, "old") }()
+	<-started
+	if st.getUrl() != "old" {
+		t.Fatal("url changed before refresh finished")
+	}
+	second := make(chan error, 1)
+	go func() { second <- proxy.refresh(context.Background(), st, file, "old") }()
+	cancel()
+	if err := <-first; err != context.Canceled {
+		t.Fatalf("canceled refresh = %v", err)
+	}
+	close(release)
+	if err := <-second; err != nil {
+		t.Fatalf("second refresh = %v", err)
+	}
+	if st.getUrl() != "new" || resolves.Load() != 1 || resolveErr.Load() != "<nil>" {
+		t.Fatalf("url = %s, resolves = %d, resolve ctx err = %v", st.getUrl(), resolves.Load(), resolveErr.Load())
+	}
+}
+
+func TestParseRange(t *testing.T) {
+	cases := []struct {
+		header      string
+		start, end  int64
+		partial, ok bool
+	}{
+		{"", 0, 999, false, true},
+		{"bytes=0-", 0, 999, true, true},
+		{"bytes=10-19", 10, 19, true, true},
+		{"bytes=990-2000", 990, 999, true, true},
+		{"bytes=-100", 900, 999, true, true},
+		{"bytes=1000-", 0, 0, false, false},
+		{"bytes=20-10", 0, 0, false, false},
+		{"bytes=0-1,5-6", 0, 999, false, true},
+	}
+	for _, c := range cases {
+		start, end, partial, ok := parseRange(c.header, 1000)
+		if ok != c.ok || (ok && (start != c.start || end != c.end || partial != c.partial)) {
+			t.Errorf("parseRange(%q) = %d, %d, %v, %v", c.header, start, end, partial, ok)
+		}
+	}
+}
+
+func TestChunkCacheEviction(t *testing.T) {
+	dir := t.TempDir()
+	cache := NewChunkCache(dir, 250)
+	for i := int64(0); i < 3; i++ {
+		cache.Put("f", i, make([]byte, 100))
+	}
+	if _, ok := cache.Get("f", 0, 100); ok {
+		t.Fatal("oldest chunk not evicted")
+	}
+	if _, ok := cache.Get("f", 2, 100); !ok || cache.Size() != 200 {
+		t.Fatalf("size = %d", cache.Size())
+	}
+	// 重启后加载已有的分块
+	if reloaded := NewChunkCache(dir, 250); reloaded.Size() != 200 {
+		t.Fatalf("reloaded size = %d", reloaded.Size())
+	}
+}
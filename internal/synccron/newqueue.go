@@ -8,6 +8,7 @@ import (
 	"context"
 	"fmt"
 	"runtime"
+	"slices"
 	"sync"
 	"sync/atomic"
 )
@@ -15,8 +16,10 @@ import (
 type SyncTaskType string
 
 const (
-	SyncTaskTypeStrm   SyncTaskType = "STRM同步"
-	SyncTaskTypeScrape SyncTaskType = "刮削整理"
+	SyncTaskTypeStrm        SyncTaskType = "STRM同步"
+	SyncTaskTypeStrmPartial SyncTaskType = "STRM局部同步" // 只同步部分目录，目前由本地目录实时监控触发
+	SyncTaskTypeStrmPlan    SyncTaskType = "STRM同步预览" // 只记录同步将要执行的操作，不修改任何文件
+	SyncTaskTypeScrape      SyncTaskType = "刮削整理"
 )
 
 func logInfo(format string, args ...interface{}) {
@@ -46,6 +49,7 @@ const (
 type NewSyncTask struct {
 	ID       uint
 	TaskType SyncTaskType
+	Paths    []string // 局部同步的目录
 }
 
 func (t *NewSyncTask) Key() string {
@@ -98,7 +102,24 @@ func (q *NewSyncQueuePerType) AddTask(task *NewSyncTask) error {
 	q.mutex.Lock()
 	defer q.mutex.Unlock()
 
-	if q.isTaskExistsUnsafe(task) {
+	if task.TaskType == SyncTaskTypeStrmPartial {
+		// 已有等待中的完整同步，局部同步没有必要再执行
+		fullKey := fmt.Sprintf("%d-%s", task.ID, SyncTaskTypeStrm)
+		if _, exists := q.waitingQueue[fullKey]; exists {
+			logInfo("已有等待中的完整同步任务，忽略局部同步: ID=%d", task.ID)
+			return nil
+		}
+		// 合并到等待中的局部同步任务
+		if waiting, exists := q.waitingQueue[task.Key()]; exists {
+			for _, p := range task.Paths {
+				if !slices.Contains(waiting.Paths, p) {
+					waiting.Paths = append(waiting.Paths, p)
+				}
+			}
+			logInfo("局部同步目录已合并到等待中的任务: ID=%d, 目录=%v", task.ID, waiting.Paths)
+			return nil
+		}
+	} else if q.isTaskExistsUnsafe(task) {
 		return fmt.Errorf("任务已存在: 类型=%s, ID=%d", task.TaskType, task.ID)
 	}
 
@@ -207,14 +228,16 @@ func (q *NewSyncQueuePerType) executeTask(task *NewSyncTask) {
 	}()
 
 	switch task.TaskType {
-	case SyncTaskTypeStrm:
-		q.executeStrmSync(task.ID)
+	case SyncTaskTypeStrm, SyncTaskTypeStrmPartial, SyncTaskTypeStrmPlan:
+		q.executeStrmSync(task)
 	case SyncTaskTypeScrape:
 		q.executeScrape(task.ID)
 	}
 }
 
-func (q *NewSyncQueuePerType) executeStrmSync(id uint) {
+// 局部同步时只同步task.Paths中的目录，预览同步时不修改任何文件
+func (q *NewSyncQueuePerType) executeStrmSync(task *NewSyncTask) {
+	id := task.ID
 	syncPath := models.GetSyncPathById(id)
 	if syncPath == nil {
 		logError("获取同步目录失败，ID=%d", id)
@@ -235,6 +258,14 @@ func (q *NewSyncQueuePerType) executeStrmSync(id uint) {
 	defer func() {
 		q.strmSync = nil
 	}()
+	if task.TaskType == SyncTaskTypeStrmPartial && len(task.Paths) > 0 {
+		logInfo("STRM同步任务只同步部分目录: ID=%d, 目录=%v", id, task.Paths)
+		q.strmSync.SetPartialPaths(task.Paths)
+	}
+	if task.TaskType == SyncTaskTypeStrmPlan {
+		logInfo("STRM同步任务为预览同步: ID=%d", id)
+		q.strmSync.SetDryRun()
+	}
 
 	if startErr := q.strmSync.Start(); startErr == nil {
 		logInfo("STRM同步任务执行成功: ID=%d", id)
@@ -285,7 +316,7 @@ func (q *NewSyncQueuePerType) CancelTask(id uint, taskType SyncTaskType) error {
 	}
 
 	if q.currentTask != nil && q.currentTask.Key() == key {
-		if taskType == SyncTaskTypeStrm && q.strmSync != nil {
+		if (taskType == SyncTaskTypeStrm || taskType == SyncTaskTypeStrmPartial || taskType == SyncTaskTypeStrmPlan) && q.strmSync != nil {
 			q.strmSync.Stop()
 			q.strmSync = nil
 			logInfo("STRM同步任务已取消: ID=%d", id)
@@ -435,10 +466,21 @@ func (m *NewSyncQueueManager) getQueue(sourceType models.SourceType) *NewSyncQue
 }
 
 func (m *NewSyncQueueManager) AddSyncTask(id uint, taskType SyncTaskType) error {
+	return m.addTask(&NewSyncTask{ID: id, TaskType: taskType})
+}
+
+// AddPartialSyncTask 添加只同步部分目录的STRM任务
+func (m *NewSyncQueueManager) AddPartialSyncTask(id uint, paths []string) error {
+	return m.addTask(&NewSyncTask{ID: id, TaskType: SyncTaskTypeStrmPartial, Paths: paths})
+}
+
+func (m *NewSyncQueueManager) addTask(task *NewSyncTask) error {
+	id := task.ID
+	taskType := task.TaskType
 	var sourceType models.SourceType
 
 	switch taskType {
-	case SyncTaskTypeStrm:
+	case SyncTaskTypeStrm, SyncTaskTypeStrmPartial, SyncTaskTypeStrmPlan:
 		syncPath := models.GetSyncPathById(id)
 		if syncPath == nil {
 			return fmt.Errorf("获取同步目录失败: ID=%d", id)
@@ -457,7 +499,6 @@ func (m *NewSyncQueueManager) AddSyncTask(id uint, taskType SyncTaskType) error
 	}
 
 	queue := m.getQueue(sourceType)
-	task := &NewSyncTask{ID: id, TaskType: taskType}
 
 	if err := queue.AddTask(task); err != nil {
 		return err
@@ -470,7 +511,7 @@ func (m *NewSyncQueueManager) CancelTask(id uint, taskType SyncTaskType) error {
 	var sourceType models.SourceType
 
 	switch taskType {
-	case SyncTaskTypeStrm:
+	case SyncTaskTypeStrm, SyncTaskTypeStrmPartial, SyncTaskTypeStrmPlan:
 		syncPath := models.GetSyncPathById(id)
 		if syncPath == nil {
 			return fmt.Errorf("获取同步目录失败: ID=%d", id)
@@ -496,7 +537,7 @@ func (m *NewSyncQueueManager) CheckTaskStatus(id uint, taskType SyncTaskType) in
 	var sourceType models.SourceType
 
 	switch taskType {
-	case SyncTaskTypeStrm:
+	case SyncTaskTypeStrm, SyncTaskTypeStrmPartial, SyncTaskTypeStrmPlan:
 		syncPath := models.GetSyncPathById(id)
 		if syncPath == nil {
 			return TaskStatusNone
@@ -562,6 +603,13 @@ func AddNewSyncTask(id uint, taskType SyncTaskType) error {
 	return GlobalNewSyncQueueManager.AddSyncTask(id, taskType)
 }
 
+func AddNewPartialSyncTask(id uint, paths []string) error {
+	if GlobalNewSyncQueueManager == nil {
+		InitNewSyncQueueManager()
+	}
+	return GlobalNewSyncQueueManager.AddPartialSyncTask(id, paths)
+}
+
 func CancelNewSyncTask(id uint, taskType SyncTaskType) error {
 	if GlobalNewSyncQueueManager == nil {
 		return fmt.Errorf("队列管理器未初始化")
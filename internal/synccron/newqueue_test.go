@@ -138,6 +138,39 @@ func TestPauseResume(t *testing.T) {
 	time.Sleep(100 * time.Millisecond)
 }
 
+func TestAddPartialTask(t *testing.T) {
+	queue := NewQueuePerType(models.SourceTypeLocal)
+	queue.Pause()
+
+	err := queue.AddTask(&NewSyncTask{ID: 1, TaskType: SyncTaskTypeStrmPartial, Paths: []string{"/media/a"}})
+	if err != nil {
+		t.Fatalf("Failed to add partial task: %v", err)
+	}
+	err = queue.AddTask(&NewSyncTask{ID: 1, TaskType: SyncTaskTypeStrmPartial, Paths: []string{"/media/a", "/media/b"}})
+	if err != nil {
+		t.Fatalf("Partial task should be merged, got error: %v", err)
+	}
+
+	waiting := queue.waitingQueue[(&NewSyncTask{ID: 1, TaskType: SyncTaskTypeStrmPartial}).Key()]
+	if waiting == nil {
+		t.Fatal("Partial task should be waiting")
+	}
+	if len(waiting.Paths) != 2 {
+		t.Errorf("Expected 2 merged paths, got %v", waiting.Paths)
+	}
+
+	// 已有等待中的完整同步时忽略局部同步
+	if err := queue.AddTask(&NewSyncTask{ID: 2, TaskType: SyncTaskTypeStrm}); err != nil {
+		t.Fatalf("Failed to add task: %v", err)
+	}
+	if err := queue.AddTask(&NewSyncTask{ID: 2, TaskType: SyncTaskTypeStrmPartial, Paths: []string{"/media/c"}}); err != nil {
+		t.Fatalf("Partial task should be ignored, got error: %v", err)
+	}
+	if queue.CheckTaskStatus(2, SyncTaskTypeStrmPartial) != TaskStatusNone {
+		t.Error("Partial task should not be queued when a full sync is waiting")
+	}
+}
+
 func TestNewSyncQueueManager(t *testing.T) {
 	manager := InitNewSyncQueueManager()
 	if manager == nil {
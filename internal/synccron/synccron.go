@@ -5,9 +5,11 @@ import (
 	"Q115-STRM/internal/db"
 	"Q115-STRM/internal/emby"
 	"Q115-STRM/internal/helpers"
+	"Q115-STRM/internal/linkcache"
 	"Q115-STRM/internal/models"
 	"Q115-STRM/internal/notificationmanager"
 	"Q115-STRM/internal/scrape"
+	"Q115-STRM/internal/syncstrm"
 	"Q115-STRM/internal/v115open"
 	"context"
 	"fmt"
@@ -67,6 +69,36 @@ func RefreshOAuthAccessToken() {
 	accounts, _ := models.GetAllAccount()
 	now := time.Now().Unix()
 	for _, account := range accounts {
+		if account.SourceType == models.SourceType123 {
+			// 123云盘没有refreshToken，使用clientID和clientSecret重新获取
+			if account.AppSecret == "" || account.TokenExpiriesTime-3600 > now {
+				continue
+			}
+			helpers.AppLogger.Infof("开始刷新123云盘账号token，账号ID: %d, 123用户名：%s", account.ID, account.Username)
+			// 刷新成功后会通过事件保存到数据库
+			client := account.Get123Client()
+			if err := client.RefreshAccessToken(); err != nil {
+				helpers.AppLogger.Errorf("刷新123云盘访问凭证失败: %s", err.Error())
+				// 清空token
+				account.ClearToken(err.Error())
+				ctx := context.Background()
+				notif := &models.Notification{
+					Type:      models.SystemAlert,
+					Title:     "🔐 123云盘开放平台访问凭证已失效",
+					Content:   fmt.Sprintf("账号ID：%d\n用户名：%s\n请检查clientID和clientSecret\n⏰ 时间: %s", int(account.ID), account.Username, time.Now().Format("2006-01-02 15:04:05")),
+					Timestamp: time.Now(),
+					Priority:  models.HighPriority,
+				}
+				if notificationmanager.GlobalEnhancedNotificationManager != nil {
+					if err := notificationmanager.GlobalEnhancedNotificationManager.SendNotification(ctx, notif); err != nil {
+						helpers.AppLogger.Errorf("发送访问凭证失效通知失败: %v", err)
+					}
+				}
+				continue
+			}
+			helpers.AppLogger.Infof("刷新123云盘账号token成功，账号ID: %d, 新到期时间: %s", account.ID, client.GetExpiredAt().Format("2006-01-02 15:04:05"))
+			continue
+		}
 		if account.RefreshToken == "" {
 			continue
 		}
@@ -219,6 +251,11 @@ func InitCron() {
 		// 每天0点清理过期的同步记录
 		// helpers.AppLogger.Info("清理过期的同步记录")
 		models.ClearExpiredSyncRecords(1) // 保留3天内的记录
+		models.ClearExpiredSyncChanges(models.SyncChangeRetentionDays)
+	})
+	GlobalCron.AddFunc("30 0 * * *", func() {
+		// 每天0点30分清理回收站中超过保留天数的文件
+		models.PurgeExpiredSyncTrash()
 	})
 	GlobalCron.AddFunc("*/5 * * * *", func() {
 		// helpers.AppLogger.Info("定时刷新115的访问凭证")
@@ -249,6 +286,18 @@ func InitCron() {
 			helpers.AppLogger.Infof("已清理24小时前的请求统计数据")
 		}
 	})
+	GlobalCron.AddFunc("10 * * * *", func() {
+		// 每小时清理一次过期的直链缓存
+		if count, err := linkcache.PurgeExpired(); err != nil {
+			helpers.AppLogger.Errorf("清理过期的直链缓存失败: %v", err)
+		} else if count > 0 {
+			helpers.AppLogger.Infof("已清理%d条过期的直链缓存", count)
+		}
+	})
+	GlobalCron.AddFunc("20 3 * * *", func() {
+		// 每天3点20分给快过期的STRM签名续期
+		syncstrm.RenewExpiringStrmSign()
+	})
 
 	addBackupCron()
 
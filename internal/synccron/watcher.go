@@ -0,0 +1,236 @@
+package synccron
+
+import (
+	"Q115-STRM/internal/helpers"
+	"Q115-STRM/internal/models"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+const (
+	localWatchBatchDelay    = 5 * time.Second  // 收到事件后等待多久再触发同步，期间的事件合并为一次同步
+	localWatchRetryInterval = 10 * time.Minute // 监控失效后重试间隔，每次重试失败都执行一次完整同步
+)
+
+// 本地来源同步目录的实时监控
+type localWatcher struct {
+	syncPathId uint
+	sourcePath string
+	targetPath string
+	watcher    *helpers.AdvancedFolderWatcher
+	dirs       map[string]struct{} // 等待同步的目录
+	timer      *time.Timer
+	stopped    bool
+	stopChan   chan struct{}
+	mutex      sync.Mutex
+}
+
+var localWatchers = make(map[uint]*localWatcher)
+var localWatchersMutex sync.Mutex
+
+// 启动所有开启了实时监控的本地同步目录
+func StartLocalWatchers() {
+	for _, syncPath := range models.GetWatchedLocalSyncPaths() {
+		startLocalWatcher(syncPath)
+	}
+}
+
+// 同步目录修改后重新加载监控，未开启监控时只停止
+func RefreshLocalWatcher(id uint) {
+	StopLocalWatcher(id)
+	syncPath := models.GetSyncPathById(id)
+	if syncPath == nil || syncPath.SourceType != models.SourceTypeLocal || !syncPath.EnableWatch {
+		return
+	}
+	startLocalWatcher(syncPath)
+}
+
+// 停止同步目录的监控
+func StopLocalWatcher(id uint) {
+	localWatchersMutex.Lock()
+	lw, ok := localWatchers[id]
+	delete(localWatchers, id)
+	localWatchersMutex.Unlock()
+	if ok {
+		lw.stop()
+	}
+}
+
+func startLocalWatcher(syncPath *models.SyncPath) {
+	lw := &localWatcher{
+		syncPathId: syncPath.ID,
+		sourcePath: filepath.Clean(syncPath.RemotePath),
+		targetPath: filepath.Clean(syncPath.GetFullLocalPath()),
+		dirs:       make(map[string]struct{}),
+		stopChan:   make(chan struct{}),
+	}
+	localWatchersMutex.Lock()
+	if old, ok := localWatchers[syncPath.ID]; ok {
+		old.stop()
+	}
+	localWatchers[syncPath.ID] = lw
+	localWatchersMutex.Unlock()
+	go lw.run()
+}
+
+func (lw *localWatcher) run() {
+	for {
+		err := lw.watch()
+		if lw.isStopped() {
+			helpers.AppLogger.Infof("已停止本地目录监控: 同步目录ID=%d, 目录=%s", lw.syncPathId, lw.sourcePath)
+			return
+		}
+		// 监控失效，改为定时完整同步，直到重新监控成功
+		helpers.AppLogger.Errorf("本地目录监控失效，执行完整同步，%s后重试监控: 同步目录ID=%d, 目录=%s, 错误=%v", localWatchRetryInterval, lw.syncPathId, lw.sourcePath, err)
+		lw.addSyncTask(nil)
+		select {
+		case <-lw.stopChan:
+			return
+		case <-time.After(localWatchRetryInterval):
+		}
+	}
+}
+
+// 开始监控，阻塞直到监控停止或失效
+func (lw *localWatcher) watch() error {
+	watcher, err := helpers.NewAdvancedFolderWatcher(lw.sourcePath)
+	if err != nil {
+		return err
+	}
+	// 监控所有文件，目标目录在同步源下时不监控目标目录
+	watcher.SetExtensions(nil)
+	watcher.SetIgnoreDirs(nil)
+	if strings.HasPrefix(lw.targetPath, lw.sourcePath+string(filepath.Separator)) {
+		watcher.SetIgnoreDirs([]string{lw.targetPath})
+	}
+	watcher.SetHandler(lw.handleEvent)
+
+	lw.mutex.Lock()
+	if lw.stopped {
+		lw.mutex.Unlock()
+		watcher.Close()
+		return nil
+	}
+	lw.watcher = watcher
+	lw.mutex.Unlock()
+
+	helpers.AppLogger.Infof("开始监控本地目录: 同步目录ID=%d, 目录=%s", lw.syncPathId, lw.sourcePath)
+	err = watcher.Start()
+	watcher.Close()
+	if err == nil && !lw.isStopped() {
+		err = fsnotify.ErrClosed
+	}
+	return err
+}
+
+func (lw *localWatcher) stop() {
+	lw.mutex.Lock()
+	if lw.stopped {
+		lw.mutex.Unlock()
+		return
+	}
+	lw.stopped = true
+	close(lw.stopChan)
+	if lw.timer != nil {
+		lw.timer.Stop()
+	}
+	watcher := lw.watcher
+	lw.mutex.Unlock()
+	// 事件回调也会加锁，关闭监控时不能持有锁
+	if watcher != nil {
+		watcher.Close()
+	}
+}
+
+func (lw *localWatcher) isStopped() bool {
+	lw.mutex.Lock()
+	defer lw.mutex.Unlock()
+	return lw.stopped
+}
+
+// 把事件对应的父目录加入待同步列表，延迟一段时间后合并触发
+func (lw *localWatcher) handleEvent(event fsnotify.Event) {
+	if event.Op&(fsnotify.Create|fsnotify.Write|fsnotify.Remove|fsnotify.Rename) == 0 {
+		return
+	}
+	path := filepath.Clean(event.Name)
+	if path == lw.targetPath || strings.HasPrefix(path, lw.targetPath+string(filepath.Separator)) {
+		return
+	}
+	dir := filepath.Dir(path)
+
+	lw.mutex.Lock()
+	defer lw.mutex.Unlock()
+	if lw.stopped {
+		return
+	}
+	lw.dirs[dir] = struct{}{}
+	if lw.timer == nil {
+		lw.timer = time.AfterFunc(localWatchBatchDelay, lw.flush)
+	} else {
+		lw.timer.Reset(localWatchBatchDelay)
+	}
+}
+
+// 将待同步的目录合并后加入同步队列
+func (lw *localWatcher) flush() {
+	lw.mutex.Lock()
+	dirs := lw.dirs
+	lw.dirs = make(map[string]struct{})
+	stopped := lw.stopped
+	lw.mutex.Unlock()
+	if stopped || len(dirs) == 0 {
+		return
+	}
+
+	// 目录可能已被删除，向上找到仍然存在的目录
+	existsDirs := make(map[string]struct{}, len(dirs))
+	for dir := range dirs {
+		for dir != lw.sourcePath && strings.HasPrefix(dir, lw.sourcePath+string(filepath.Separator)) {
+			if _, err := os.Stat(dir); err == nil {
+				break
+			}
+			dir = filepath.Dir(dir)
+		}
+		if dir == lw.sourcePath || !strings.HasPrefix(dir, lw.sourcePath+string(filepath.Separator)) {
+			// 涉及同步源根目录，直接完整同步
+			lw.addSyncTask(nil)
+			return
+		}
+		existsDirs[dir] = struct{}{}
+	}
+	// 去掉已被上级目录包含的目录
+	paths := make([]string, 0, len(existsDirs))
+	for dir := range existsDirs {
+		covered := false
+		for parent := filepath.Dir(dir); parent != lw.sourcePath && parent != filepath.Dir(parent); parent = filepath.Dir(parent) {
+			if _, ok := existsDirs[parent]; ok {
+				covered = true
+				break
+			}
+		}
+		if !covered {
+			paths = append(paths, dir)
+		}
+	}
+	lw.addSyncTask(paths)
+}
+
+// paths为空时完整同步，否则只同步这些目录
+func (lw *localWatcher) addSyncTask(paths []string) {
+	var err error
+	if len(paths) == 0 {
+		err = AddNewSyncTask(lw.syncPathId, SyncTaskTypeStrm)
+	} else {
+		helpers.AppLogger.Infof("本地目录有变化，添加局部同步任务: 同步目录ID=%d, 目录=%v", lw.syncPathId, paths)
+		err = AddNewPartialSyncTask(lw.syncPathId, paths)
+	}
+	if err != nil {
+		helpers.AppLogger.Warnf("将监控触发的同步任务添加到队列失败: %v", err)
+	}
+}
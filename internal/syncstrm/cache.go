@@ -0,0 +1,70 @@
+package syncstrm
+
+import (
+	"Q115-STRM/internal/helpers"
+	"Q115-STRM/internal/models"
+	"fmt"
+)
+
+// SyncCache 同步缓存，保存本次同步从网盘查询到的所有文件
+// 默认使用内存缓存，网盘文件很多时使用磁盘缓存，避免内存占用过高
+type SyncCache interface {
+	Insert(file *SyncFileCache) error
+	BatchInsert(files []*SyncFileCache) error
+	// 放入待下载索引
+	InsertDownloadIndex(file *SyncFileCache) error
+	GetByFileId(fileId string) (*SyncFileCache, error)
+	GetByLocalPath(localFilePath string) (*SyncFileCache, error)
+	GetByParentId(parentId string) ([]*SyncFileCache, error)
+	ExistsByLocalPath(localFilePath string) bool
+	DeleteByFileId(fileId string) error
+	DeleteByParentId(parentId string) error
+	UpdatePathByParentId(parentId string, newPath string, targetPath, sourcePath string) error
+	Count() int64
+	Clear()
+	// 遍历所有文件，fn返回false时停止，fn中可以删除文件
+	Range(fn func(file *SyncFileCache) bool)
+	// 遍历待下载索引
+	RangeDownload(fn func(file *SyncFileCache) bool)
+	// 在读锁中执行fn，用于序列化缓存中的文件
+	WithReadLock(fn func())
+	// 复制一份缓存给目标目录使用，本地路径按目标目录重新生成
+	CloneForTarget(targetPath, sourcePath string) SyncCache
+	// 释放缓存占用的内存或磁盘文件
+	Close()
+}
+
+// 根据网盘文件总数选择同步缓存，超过配置的阈值时切换为磁盘缓存
+func (s *SyncStrm) selectSyncCache(total int64) {
+	threshold := helpers.GlobalConfig.Strm.DiskCacheThreshold
+	if threshold <= 0 || total < threshold {
+		return
+	}
+	if _, ok := s.syncCache.(*DiskSyncCache); ok {
+		return
+	}
+	diskCache, err := NewDiskSyncCache(DiskSyncCacheDir(), fmt.Sprintf("%d_*.db", s.SyncPathId))
+	if err != nil {
+		s.Sync.Logger.Warnf("创建磁盘同步缓存失败，继续使用内存缓存: %v", err)
+		return
+	}
+	// 已经放入缓存的文件（例如局部同步的入口目录）复制到磁盘缓存
+	s.syncCache.Range(func(file *SyncFileCache) bool {
+		diskCache.Insert(file)
+		return true
+	})
+	s.syncCache.Close()
+	s.syncCache = diskCache
+	s.Sync.Logger.Infof("网盘文件总数 %d 超过 %d，同步缓存使用磁盘：%s", total, threshold, diskCache.file)
+}
+
+// 115以外的来源在遍历完目录之前不知道文件总数，遍历过程中多个协程同时写入缓存，不能中途切换
+// 使用上次同步保存的文件数量选择同步缓存，第一次同步使用内存缓存
+func (s *SyncStrm) selectSyncCacheByLastSync() {
+	if s.TmpSyncPath || s.IsPartial() {
+		return
+	}
+	if total := models.CountFilesBySyncPathId(s.SyncPathId); total > 0 {
+		s.selectSyncCache(total)
+	}
+}
@@ -0,0 +1,296 @@
+package syncstrm
+
+import (
+	"Q115-STRM/internal/helpers"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/logger"
+)
+
+const (
+	diskCacheFileTable     = "files"
+	diskCacheDownloadTable = "downloads"
+	diskCacheBatchSize     = 1000
+)
+
+// 磁盘缓存中的一条记录，CacheId是GetFileId()的值（OpenList和本地的FileId字段为空）
+type diskCacheRow struct {
+	Seq           int64  `gorm:"primaryKey;autoIncrement"`
+	CacheId       string `gorm:"uniqueIndex"`
+	SyncFileCache `gorm:"embedded"`
+}
+
+// DiskSyncCache 磁盘同步缓存，使用单独的SQLite文件保存文件列表，同步完成后删除
+// 接口和MemorySyncCache相同，查询返回的是副本，修改后需要重新写入
+type DiskSyncCache struct {
+	db   *gorm.DB
+	file string
+}
+
+// DiskSyncCacheDir 磁盘同步缓存文件所在的目录
+func DiskSyncCacheDir() string {
+	return filepath.Join(helpers.ConfigDir, "tmp", "sync_cache")
+}
+
+// ClearDiskSyncCache 删除上次异常退出时留下的缓存文件，只能在没有同步任务运行时（启动时）调用
+func ClearDiskSyncCache() {
+	os.RemoveAll(DiskSyncCacheDir())
+}
+
+// NewDiskSyncCache 在dir中创建磁盘同步缓存，pattern是os.CreateTemp的文件名格式
+// 每次创建新的文件，同一个同步路径的多次同步（上次的差异处理还没结束时）互不影响
+func NewDiskSyncCache(dir, pattern string) (*DiskSyncCache, error) {
+	if err := os.MkdirAll(dir, 0777); err != nil {
+		return nil, err
+	}
+	f, err := os.CreateTemp(dir, pattern)
+	if err != nil {
+		return nil, err
+	}
+	file := f.Name()
+	f.Close()
+	// 缓存只在本次同步使用，不需要日志和同步写入
+	cacheDb, err := gorm.Open(sqlite.Open(file+"?_pragma=journal_mode(OFF)&_pragma=synchronous(OFF)&_pragma=busy_timeout(30000)&_pragma=cache_size(-20000)"), &gorm.Config{
+		SkipDefaultTransaction: true,
+		Logger:                 logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		removeDiskCacheFile(file)
+		return nil, err
+	}
+	sqlDB, err := cacheDb.DB()
+	if err != nil {
+		removeDiskCacheFile(file)
+		return nil, err
+	}
+	// SQLite只能单线程写入
+	sqlDB.SetMaxOpenConns(1)
+	c := &DiskSyncCache{db: cacheDb, file: file}
+	for _, table := range []string{diskCacheFileTable, diskCacheDownloadTable} {
+		if err := cacheDb.Table(table).AutoMigrate(&diskCacheRow{}); err != nil {
+			c.Close()
+			return nil, err
+		}
+	}
+	cacheDb.Exec(fmt.Sprintf("CREATE INDEX IF NOT EXISTS idx_%s_parent_id ON %s(parent_id)", diskCacheFileTable, diskCacheFileTable))
+	cacheDb.Exec(fmt.Sprintf("CREATE INDEX IF NOT EXISTS idx_%s_local_file_path ON %s(local_file_path)", diskCacheFileTable, diskCacheFileTable))
+	return c, nil
+}
+
+func removeDiskCacheFile(file string) {
+	for _, suffix := range []string{"", "-journal", "-wal", "-shm"} {
+		os.Remove(file + suffix)
+	}
+}
+
+func (c *DiskSyncCache) files() *gorm.DB {
+	return c.db.Table(diskCacheFileTable)
+}
+
+func newDiskCacheRow(file *SyncFileCache) *diskCacheRow {
+	return &diskCacheRow{CacheId: file.GetFileId(), SyncFileCache: *file}
+}
+
+// 写入记录，已存在时覆盖
+func (c *DiskSyncCache) upsert(table string, rows []*diskCacheRow) error {
+	if len(rows) == 0 {
+		return nil
+	}
+	return c.db.Table(table).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "cache_id"}},
+		UpdateAll: true,
+	}).CreateInBatches(rows, 200).Error
+}
+
+// 查询一条记录，不存在时返回nil
+func (c *DiskSyncCache) first(query string, args ...any) *SyncFileCache {
+	var rows []*diskCacheRow
+	if err := c.files().Where(query, args...).Limit(1).Find(&rows).Error; err != nil || len(rows) == 0 {
+		return nil
+	}
+	return &rows[0].SyncFileCache
+}
+
+// Insert 插入单条记录
+func (c *DiskSyncCache) Insert(file *SyncFileCache) error {
+	if file.GetFileId() == "" {
+		return fmt.Errorf("file_id不能为空")
+	}
+	return c.upsert(diskCacheFileTable, []*diskCacheRow{newDiskCacheRow(file)})
+}
+
+// BatchInsert 批量插入
+func (c *DiskSyncCache) BatchInsert(files []*SyncFileCache) error {
+	rows := make([]*diskCacheRow, 0, len(files))
+	for _, file := range files {
+		if file.GetFileId() == "" {
+			return fmt.Errorf("file_id不能为空")
+		}
+		rows = append(rows, newDiskCacheRow(file))
+	}
+	return c.upsert(diskCacheFileTable, rows)
+}
+
+// 放入待下载索引
+func (c *DiskSyncCache) InsertDownloadIndex(file *SyncFileCache) error {
+	if file.GetFileId() == "" {
+		return nil
+	}
+	return c.upsert(diskCacheDownloadTable, []*diskCacheRow{newDiskCacheRow(file)})
+}
+
+// GetByFileId 根据 file_id 查询
+func (c *DiskSyncCache) GetByFileId(fileId string) (*SyncFileCache, error) {
+	file := c.first("cache_id = ?", fileId)
+	if file == nil {
+		return nil, fmt.Errorf("未找到记录: file_id=%s", fileId)
+	}
+	return file, nil
+}
+
+// GetByLocalPath 根据本地路径查询
+func (c *DiskSyncCache) GetByLocalPath(localFilePath string) (*SyncFileCache, error) {
+	file := c.first("local_file_path = ?", localFilePath)
+	if file == nil {
+		return nil, fmt.Errorf("未找到记录: local_file_path=%s", localFilePath)
+	}
+	return file, nil
+}
+
+// GetByParentId 根据 parent_id 查询
+func (c *DiskSyncCache) GetByParentId(parentId string) ([]*SyncFileCache, error) {
+	var rows []*diskCacheRow
+	if err := c.files().Where("parent_id = ?", parentId).Order("seq ASC").Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	files := make([]*SyncFileCache, 0, len(rows))
+	for _, row := range rows {
+		files = append(files, &row.SyncFileCache)
+	}
+	return files, nil
+}
+
+// ExistsByLocalPath 检查本地路径是否存在
+func (c *DiskSyncCache) ExistsByLocalPath(localFilePath string) bool {
+	return c.first("local_file_path = ?", localFilePath) != nil
+}
+
+// DeleteByFileId 根据 file_id 删除
+func (c *DiskSyncCache) DeleteByFileId(fileId string) error {
+	return c.files().Where("cache_id = ?", fileId).Delete(&diskCacheRow{}).Error
+}
+
+// DeleteByParentId 根据 parent_id 删除所有子项
+func (c *DiskSyncCache) DeleteByParentId(parentId string) error {
+	return c.files().Where("parent_id = ?", parentId).Delete(&diskCacheRow{}).Error
+}
+
+// UpdatePathByParentId 更新指定父目录下所有文件的路径
+func (c *DiskSyncCache) UpdatePathByParentId(parentId string, newPath string, targetPath, sourcePath string) error {
+	files, err := c.GetByParentId(parentId)
+	if err != nil || len(files) == 0 {
+		return err
+	}
+	return c.db.Transaction(func(tx *gorm.DB) error {
+		for _, file := range files {
+			file.Path = newPath
+			// 更新完整本地路径
+			file.GetLocalFilePath(targetPath, sourcePath)
+			err := tx.Table(diskCacheFileTable).Where("cache_id = ?", file.GetFileId()).Updates(map[string]any{
+				"path":            file.Path,
+				"local_file_path": file.LocalFilePath,
+			}).Error
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Count 统计记录数
+func (c *DiskSyncCache) Count() int64 {
+	var count int64
+	c.files().Count(&count)
+	return count
+}
+
+// Clear 清空所有数据
+func (c *DiskSyncCache) Clear() {
+	c.files().Where("1 = 1").Delete(&diskCacheRow{})
+}
+
+// 按写入顺序分批遍历，每批查询完再调用fn，fn中可以删除记录
+func (c *DiskSyncCache) rangeTable(table string, fn func(file *SyncFileCache) bool) {
+	var lastSeq int64
+	for {
+		var rows []*diskCacheRow
+		if err := c.db.Table(table).Where("seq > ?", lastSeq).Order("seq ASC").Limit(diskCacheBatchSize).Find(&rows).Error; err != nil {
+			return
+		}
+		for _, row := range rows {
+			if !fn(&row.SyncFileCache) {
+				return
+			}
+		}
+		if len(rows) < diskCacheBatchSize {
+			return
+		}
+		lastSeq = rows[len(rows)-1].Seq
+	}
+}
+
+// Range 遍历所有文件
+func (c *DiskSyncCache) Range(fn func(file *SyncFileCache) bool) {
+	c.rangeTable(diskCacheFileTable, fn)
+}
+
+// RangeDownload 遍历待下载索引
+func (c *DiskSyncCache) RangeDownload(fn func(file *SyncFileCache) bool) {
+	c.rangeTable(diskCacheDownloadTable, fn)
+}
+
+// 查询返回的都是副本，不需要加锁
+func (c *DiskSyncCache) WithReadLock(fn func()) {
+	fn()
+}
+
+// CloneForTarget 复制一份缓存给目标目录使用，复制失败时使用内存缓存
+func (c *DiskSyncCache) CloneForTarget(targetPath, sourcePath string) SyncCache {
+	pattern := strings.TrimSuffix(filepath.Base(c.file), ".db") + "_target_*.db"
+	var clone SyncCache
+	if diskClone, err := NewDiskSyncCache(filepath.Dir(c.file), pattern); err == nil {
+		clone = diskClone
+	} else {
+		clone = NewMemorySyncCache(0)
+	}
+	batch := make([]*SyncFileCache, 0, diskCacheBatchSize)
+	c.Range(func(file *SyncFileCache) bool {
+		file.LocalFilePath = ""
+		file.NeedDownload = false
+		if file.GetPath() != "" {
+			file.GetLocalFilePath(targetPath, sourcePath)
+		}
+		if batch = append(batch, file); len(batch) >= diskCacheBatchSize {
+			clone.BatchInsert(batch)
+			batch = batch[:0]
+		}
+		return true
+	})
+	clone.BatchInsert(batch)
+	return clone
+}
+
+// Close 关闭数据库并删除缓存文件，只删除自己创建的文件
+func (c *DiskSyncCache) Close() {
+	if sqlDB, err := c.db.DB(); err == nil {
+		sqlDB.Close()
+	}
+	removeDiskCacheFile(c.file)
+}
@@ -0,0 +1,161 @@
+package syncstrm
+
+import (
+	"Q115-STRM/internal/models"
+	"Q115-STRM/internal/v115open"
+	"fmt"
+	"os"
+	"testing"
+)
+
+func newTestDiskCache(t *testing.T, dir string) *DiskSyncCache {
+	t.Helper()
+	c, err := NewDiskSyncCache(dir, "1_*.db")
+	if err != nil {
+		t.Fatalf("NewDiskSyncCache: %v", err)
+	}
+	return c
+}
+
+func testCacheFile(id, parentId, path, name string) *SyncFileCache {
+	return &SyncFileCache{
+		FileId:     id,
+		ParentId:   parentId,
+		FileType:   v115open.TypeFile,
+		FileName:   name,
+		Path:       path,
+		IsVideo:    true,
+		SourceType: models.SourceType115,
+	}
+}
+
+func TestDiskSyncCache(t *testing.T) {
+	c := newTestDiskCache(t, t.TempDir())
+	defer c.Close()
+
+	a := testCacheFile("a", "p1", "/电影", "a.mkv")
+	a.GetLocalFilePath("/strm", "")
+	if err := c.Insert(a); err != nil {
+		t.Fatalf("Insert: %v", err)
+	}
+	if err := c.Insert(&SyncFileCache{SourceType: models.SourceType115}); err == nil {
+		t.Fatal("Insert without file id succeeded")
+	}
+	files := make([]*SyncFileCache, 0)
+	for i := 0; i < 2500; i++ {
+		f := testCacheFile(fmt.Sprintf("f%d", i), "p2", "/剧集", fmt.Sprintf("%d.mkv", i))
+		f.GetLocalFilePath("/strm", "")
+		files = append(files, f)
+	}
+	if err := c.BatchInsert(files); err != nil {
+		t.Fatalf("BatchInsert: %v", err)
+	}
+	if c.Count() != 2501 {
+		t.Fatalf("Count = %d, want 2501", c.Count())
+	}
+
+	// 已存在的记录覆盖
+	a.FileSize = 100
+	c.Insert(a)
+	if got, err := c.GetByFileId("a"); err != nil || got.FileSize != 100 || c.Count() != 2501 {
+		t.Fatalf("GetByFileId after upsert = %+v, %v, count %d", got, err, c.Count())
+	}
+	if got, err := c.GetByLocalPath("/strm/电影/a.strm"); err != nil || got.FileId != "a" {
+		t.Fatalf("GetByLocalPath = %+v, %v", got, err)
+	}
+	if !c.ExistsByLocalPath("/strm/剧集/7.strm") || c.ExistsByLocalPath("/strm/剧集/x.strm") {
+		t.Fatal("ExistsByLocalPath mismatch")
+	}
+	if _, err := c.GetByFileId("missing"); err == nil {
+		t.Fatal("GetByFileId of missing file succeeded")
+	}
+
+	// 按写入顺序分批遍历，遍历时可以删除
+	seen := 0
+	c.Range(func(file *SyncFileCache) bool {
+		if seen == 1 && file.FileId != "f0" {
+			t.Fatalf("Range order: second file = %s", file.FileId)
+		}
+		seen++
+		c.DeleteByFileId(file.FileId)
+		return true
+	})
+	if seen != 2501 || c.Count() != 0 {
+		t.Fatalf("Range visited %d, remaining %d", seen, c.Count())
+	}
+
+	// 修改父目录下文件的路径
+	c.BatchInsert([]*SyncFileCache{testCacheFile("b", "p3", "/旧", "b.mkv"), testCacheFile("c", "p3", "/旧", "c.mkv")})
+	if err := c.UpdatePathByParentId("p3", "/新", "/strm", ""); err != nil {
+		t.Fatalf("UpdatePathByParentId: %v", err)
+	}
+	children, _ := c.GetByParentId("p3")
+	if len(children) != 2 || children[0].FileId != "b" || children[1].LocalFilePath != "/strm/新/c.strm" {
+		t.Fatalf("GetByParentId after update = %+v", children)
+	}
+	c.DeleteByParentId("p3")
+	if c.Count() != 0 {
+		t.Fatalf("Count after DeleteByParentId = %d", c.Count())
+	}
+
+	// 待下载索引和文件列表分开保存
+	c.InsertDownloadIndex(a)
+	downloads := 0
+	c.RangeDownload(func(file *SyncFileCache) bool {
+		downloads++
+		return true
+	})
+	if downloads != 1 || c.Count() != 0 {
+		t.Fatalf("RangeDownload = %d, Count = %d", downloads, c.Count())
+	}
+	c.Insert(a)
+	c.Clear()
+	if c.Count() != 0 {
+		t.Fatalf("Count after Clear = %d", c.Count())
+	}
+}
+
+func TestDiskSyncCacheCloneForTarget(t *testing.T) {
+	c := newTestDiskCache(t, t.TempDir())
+	defer c.Close()
+	a := testCacheFile("a", "p1", "/电影", "a.mkv")
+	a.GetLocalFilePath("/strm", "")
+	a.NeedDownload = true
+	c.Insert(a)
+
+	clone := c.CloneForTarget("/other", "")
+	defer clone.Close()
+	if _, ok := clone.(*DiskSyncCache); !ok {
+		t.Fatalf("clone type = %T, want *DiskSyncCache", clone)
+	}
+	got, err := clone.GetByFileId("a")
+	if err != nil || got.LocalFilePath != "/other/电影/a.strm" || got.NeedDownload {
+		t.Fatalf("cloned file = %+v, %v", got, err)
+	}
+	// 原缓存不受影响
+	if orig, _ := c.GetByFileId("a"); orig.LocalFilePath != "/strm/电影/a.strm" {
+		t.Fatalf("original file = %+v", orig)
+	}
+}
+
+// 同一个同步路径的两次同步使用不同的文件，关闭一个不影响另一个
+func TestDiskSyncCacheIndependentFiles(t *testing.T) {
+	dir := t.TempDir()
+	first := newTestDiskCache(t, dir)
+	second := newTestDiskCache(t, dir)
+	defer second.Close()
+	if first.file == second.file {
+		t.Fatalf("both caches use %s", first.file)
+	}
+	second.Insert(testCacheFile("a", "p1", "/电影", "a.mkv"))
+	first.Close()
+	if _, err := os.Stat(first.file); !os.IsNotExist(err) {
+		t.Fatalf("closed cache file still exists: %v", err)
+	}
+	if _, err := os.Stat(second.file); err != nil {
+		t.Fatalf("other cache file removed: %v", err)
+	}
+	if second.Count() != 1 {
+		t.Fatalf("Count = %d, want 1", second.Count())
+	}
+}
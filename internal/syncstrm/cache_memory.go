@@ -48,7 +48,7 @@ func (sfc *SyncFileCache) GetPath() string {
 	switch sfc.SourceType {
 	case models.SourceType115:
 		return sfc.Path
-	case models.SourceTypeOpenList:
+	case models.SourceTypeOpenList, models.SourceTypeWebDAV, models.SourceTypeS3:
 		return sfc.ParentId
 	case models.SourceTypeLocal:
 		return sfc.ParentId
@@ -66,7 +66,7 @@ func (sfc *SyncFileCache) GetFileId() string {
 	switch sfc.SourceType {
 	case models.SourceType115:
 		return sfc.FileId
-	case models.SourceTypeOpenList:
+	case models.SourceTypeOpenList, models.SourceTypeWebDAV, models.SourceTypeS3:
 		return filePath
 	case models.SourceTypeLocal:
 		return filePath
@@ -83,7 +83,7 @@ func (sfc *SyncFileCache) GetPickCode(openlistBaseUrl string) string {
 	case models.SourceTypeOpenList:
 		// 计算出完整的下载链接
 		return helpers.MakeOpenListUrl(openlistBaseUrl, sfc.OpenlistSign, sfc.GetFileId())
-	case models.SourceTypeLocal:
+	case models.SourceTypeLocal, models.SourceTypeWebDAV, models.SourceTypeS3:
 		return sfc.GetFileId()
 	case models.SourceType123:
 		return sfc.PickCode
@@ -95,7 +95,7 @@ func (sfc *SyncFileCache) GetFullRemotePath() string {
 	switch sfc.SourceType {
 	case models.SourceType115:
 		return filepath.ToSlash(filepath.Join(sfc.Path, sfc.FileName))
-	case models.SourceTypeOpenList:
+	case models.SourceTypeOpenList, models.SourceTypeWebDAV, models.SourceTypeS3:
 		return filepath.ToSlash(filepath.Join(sfc.ParentId, sfc.FileName))
 	case models.SourceTypeLocal:
 		return filepath.ToSlash(filepath.Join(sfc.ParentId, sfc.FileName))
@@ -418,6 +418,60 @@ func (c *MemorySyncCache) Clear() {
 	c.parentIndex = make(map[string][]*SyncFileCache)
 }
 
-func (c *MemorySyncCache) GetAllFile() map[string]*SyncFileCache {
-	return c.fileIndex
+// Range 遍历所有文件，先复制一份列表，fn中可以删除文件
+func (c *MemorySyncCache) Range(fn func(file *SyncFileCache) bool) {
+	c.mu.RLock()
+	files := make([]*SyncFileCache, 0, len(c.fileIndex))
+	for _, file := range c.fileIndex {
+		files = append(files, file)
+	}
+	c.mu.RUnlock()
+	for _, file := range files {
+		if !fn(file) {
+			return
+		}
+	}
+}
+
+// RangeDownload 遍历待下载索引
+func (c *MemorySyncCache) RangeDownload(fn func(file *SyncFileCache) bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	for _, file := range c.downloadIndex {
+		if !fn(file) {
+			return
+		}
+	}
+}
+
+// 缓存中的文件会被并发修改，序列化时需要加读锁
+func (c *MemorySyncCache) WithReadLock(fn func()) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	fn()
+}
+
+// Close 释放内存
+func (c *MemorySyncCache) Close() {
+	c.Clear()
+	c.mu.Lock()
+	c.downloadIndex = make(map[string]*SyncFileCache)
+	c.mu.Unlock()
+}
+
+// CloneForTarget 复制一份缓存给目标目录使用，本地路径按目标目录重新生成
+func (c *MemorySyncCache) CloneForTarget(targetPath, sourcePath string) SyncCache {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	clone := NewMemorySyncCache(c.syncPathId)
+	for _, file := range c.fileIndex {
+		copied := *file
+		copied.LocalFilePath = ""
+		copied.NeedDownload = false
+		if copied.GetPath() != "" {
+			copied.GetLocalFilePath(targetPath, sourcePath)
+		}
+		clone.Insert(&copied)
+	}
+	return clone
 }
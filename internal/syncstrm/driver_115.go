@@ -139,7 +139,7 @@ func (d *open115Driver) CreateDirRecursively(ctx context.Context, path string) (
 			SourceType: models.SourceType115,
 		}
 		syncFileCache.GetLocalFilePath(d.s.TargetPath, d.s.SourcePath)
-		d.s.memSyncCache.Insert(syncFileCache)
+		d.s.syncCache.Insert(syncFileCache)
 		lastExistsPathId = currentFileId
 		d.s.Sync.Logger.Infof("创建目录成功: %s 目录ID: %s", dir, lastExistsPathId)
 	}
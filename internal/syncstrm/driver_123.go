@@ -0,0 +1,214 @@
+package syncstrm
+
+import (
+	"Q115-STRM/internal/baidupan"
+	"Q115-STRM/internal/models"
+	"Q115-STRM/internal/open123"
+	"Q115-STRM/internal/v115open"
+	"context"
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
+)
+
+type open123Driver struct {
+	s      *SyncStrm
+	client *open123.Client
+}
+
+func NewOpen123Driver(client *open123.Client) *open123Driver {
+	return &open123Driver{
+		client: client,
+	}
+}
+
+func (d *open123Driver) SetSyncStrm(s *SyncStrm) {
+	d.s = s
+}
+
+// 分页取出目录下的所有文件，过滤掉回收站中的文件
+func (d *open123Driver) listAll(ctx context.Context, parentId int64) ([]open123.FileInfo, error) {
+	allFiles, err := d.client.ListAllFiles(ctx, parentId)
+	if err != nil {
+		return nil, err
+	}
+	files := make([]open123.FileInfo, 0, len(allFiles))
+	for _, file := range allFiles {
+		if file.Trashed == 1 {
+			continue
+		}
+		files = append(files, file)
+	}
+	return files, nil
+}
+
+// 在目录下查找指定名称的子目录
+func (d *open123Driver) findDir(ctx context.Context, parentId int64, name string) (int64, bool, error) {
+	files, err := d.listAll(ctx, parentId)
+	if err != nil {
+		return 0, false, err
+	}
+	for _, file := range files {
+		if file.FileType == 1 && file.FileName == name {
+			return file.FileID, true, nil
+		}
+	}
+	return 0, false, nil
+}
+
+func (d *open123Driver) GetNetFileFiles(ctx context.Context, parentPath, parentPathId string) ([]*SyncFileCache, error) {
+	parentId, err := strconv.ParseInt(parentPathId, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("123云盘目录ID %s 格式错误: %v", parentPathId, err)
+	}
+	files, err := d.listAll(ctx, parentId)
+	if err != nil {
+		d.s.Sync.Logger.Errorf("获取123云盘文件列表失败: %v", err)
+		return nil, err
+	}
+	fileItems := make([]*SyncFileCache, 0, len(files))
+	for _, file := range files {
+		atomic.AddInt64(&d.s.TotalFile, 1)
+		fileId := strconv.FormatInt(file.FileID, 10)
+		fileItem := SyncFileCache{
+			ParentId:   parentPathId,
+			FileId:     fileId,
+			PickCode:   fileId,
+			Path:       parentPath,
+			FileName:   file.FileName,
+			FileType:   v115open.TypeFile,
+			FileSize:   file.FileSize,
+			Sha1:       file.Etag,
+			MTime:      file.UpdateTime(),
+			SourceType: models.SourceType123,
+		}
+		if file.FileType == 1 {
+			fileItem.FileType = v115open.TypeDir
+			fileItem.IsVideo = false
+			fileItem.IsMeta = false
+		}
+		fileItems = append(fileItems, &fileItem)
+	}
+	return fileItems, nil
+}
+
+// 检查每一部分是否存在，不存在就创建
+func (d *open123Driver) CreateDirRecursively(ctx context.Context, path string) (pathId, remotePath string, err error) {
+	relPath, err := filepath.Rel(d.s.TargetPath, path)
+	if err != nil {
+		return "", "", fmt.Errorf("计算相对路径失败: %s 错误：%v", path, err)
+	}
+	relPath = filepath.ToSlash(relPath)
+	// 如果不以/开头，则加上/
+	if !strings.HasPrefix(relPath, "/") {
+		relPath = "/" + relPath
+	}
+	// 从根目录开始逐级查找，不存在就创建
+	parentId := int64(0)
+	currentPath := "/"
+	for _, part := range strings.Split(relPath, "/") {
+		if part == "" {
+			continue
+		}
+		dirId, exists, err := d.findDir(ctx, parentId, part)
+		if err != nil {
+			return "", "", fmt.Errorf("查询目录失败: %s 错误：%v", filepath.ToSlash(filepath.Join(currentPath, part)), err)
+		}
+		if !exists {
+			resp, err := d.client.CreateFolder(ctx, part, parentId)
+			if err != nil {
+				return "", "", fmt.Errorf("创建目录失败: %s 错误：%v", filepath.ToSlash(filepath.Join(currentPath, part)), err)
+			}
+			dirId = resp.DirID
+			// 将新添加的目录加入同步缓存
+			syncFileCache := &SyncFileCache{
+				FileId:     strconv.FormatInt(dirId, 10),
+				ParentId:   strconv.FormatInt(parentId, 10),
+				Path:       currentPath,
+				FileName:   part,
+				FileType:   v115open.TypeDir,
+				IsVideo:    false,
+				IsMeta:     false,
+				SourceType: models.SourceType123,
+			}
+			syncFileCache.GetLocalFilePath(d.s.TargetPath, d.s.SourcePath)
+			d.s.syncCache.Insert(syncFileCache)
+			d.s.Sync.Logger.Infof("创建目录成功: %s 目录ID: %d", filepath.ToSlash(filepath.Join(currentPath, part)), dirId)
+		}
+		parentId = dirId
+		currentPath = filepath.ToSlash(filepath.Join(currentPath, part))
+	}
+	return strconv.FormatInt(parentId, 10), relPath, nil
+}
+
+func (d *open123Driver) GetPathIdByPath(ctx context.Context, path string) (string, error) {
+	parentId := int64(0)
+	for _, part := range strings.Split(filepath.ToSlash(path), "/") {
+		if part == "" {
+			continue
+		}
+		dirId, exists, err := d.findDir(ctx, parentId, part)
+		if err != nil {
+			return "", fmt.Errorf("查询路径 %s 失败: %v", path, err)
+		}
+		if !exists {
+			return "", fmt.Errorf("路径 %s 不存在", path)
+		}
+		parentId = dirId
+	}
+	return strconv.FormatInt(parentId, 10), nil
+}
+
+func (d *open123Driver) MakeStrmContent(sf *SyncFileCache) string {
+	// 生成URL
+	u, _ := url.Parse(d.s.Config.StrmBaseUrl)
+	ext := filepath.Ext(sf.FileName)
+	u.Path = fmt.Sprintf("/123/url/video%s", ext)
+	params := url.Values{}
+	params.Add("pickcode", sf.PickCode)
+	params.Add("userid", d.s.Account.UserId)
+	u.RawQuery = params.Encode()
+	urlStr := u.String()
+	if d.s.Config.StrmUrlNeedPath == 1 {
+		urlStr += fmt.Sprintf("&path=%s", d.s.GetRemoteFilePathUrlEncode(sf.GetFullRemotePath()))
+	}
+	return urlStr
+}
+
+func (d *open123Driver) GetTotalFileCount(ctx context.Context) (int64, string, error) {
+	return 0, "", nil
+}
+
+func (d *open123Driver) GetDirsByPathId(ctx context.Context, pathId string) ([]pathQueueItem, error) {
+	return nil, nil
+}
+
+func (d *open123Driver) GetFilesByPathId(ctx context.Context, rootPathId string, offset, limit int) ([]v115open.File, error) {
+	return nil, nil
+}
+
+// 所有文件详情，含路径
+func (d *open123Driver) DetailByFileId(ctx context.Context, fileId string) (*v115open.FileDetail, error) {
+	return nil, nil
+}
+
+// 删除目录下的某些文件
+func (d *open123Driver) DeleteFile(ctx context.Context, parentId string, fileIds []string) error {
+	for _, fileId := range fileIds {
+		id, err := strconv.ParseInt(fileId, 10, 64)
+		if err != nil {
+			return fmt.Errorf("123云盘文件ID %s 格式错误: %v", fileId, err)
+		}
+		if err := d.client.DeleteFile(ctx, id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (d *open123Driver) GetFilesByPathMtime(ctx context.Context, rootPathId string, offset, limit int, mtime int64) (*baidupan.FileListAllResponse, error) {
+	return nil, nil
+}
@@ -88,7 +88,7 @@ func (d *localDriver) CreateDirRecursively(ctx context.Context, path string) (pa
 		SourceType: models.SourceTypeLocal,
 	}
 	syncFileCache.GetLocalFilePath(d.s.TargetPath, d.s.SourcePath)
-	d.s.memSyncCache.Insert(syncFileCache)
+	d.s.syncCache.Insert(syncFileCache)
 	return targetPath, relPath, nil
 }
 
@@ -133,7 +133,7 @@ func (d *openListDriver) CreateDirRecursively(ctx context.Context, path string)
 			SourceType: models.SourceTypeOpenList,
 		}
 		syncFileCache.GetLocalFilePath(d.s.TargetPath, d.s.SourcePath)
-		d.s.memSyncCache.Insert(syncFileCache)
+		d.s.syncCache.Insert(syncFileCache)
 		d.s.Sync.Logger.Infof("创建网盘目录: %s", dir)
 	}
 	return relPath, relPath, nil
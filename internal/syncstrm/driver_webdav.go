@@ -0,0 +1,153 @@
+package syncstrm
+
+import (
+	"Q115-STRM/internal/baidupan"
+	"Q115-STRM/internal/models"
+	"Q115-STRM/internal/v115open"
+	"Q115-STRM/internal/webdav"
+	"context"
+	"fmt"
+	"net/url"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+)
+
+// WebDAV和OpenList一样使用路径作为文件ID和目录ID
+type webDAVDriver struct {
+	s      *SyncStrm
+	client *webdav.Client
+}
+
+func NewWebDAVDriver(client *webdav.Client) *webDAVDriver {
+	return &webDAVDriver{
+		client: client,
+	}
+}
+
+func (d *webDAVDriver) SetSyncStrm(s *SyncStrm) {
+	d.s = s
+}
+
+func (d *webDAVDriver) GetNetFileFiles(ctx context.Context, parentPath, parentPathId string) ([]*SyncFileCache, error) {
+	files, err := d.client.ReadDir(ctx, parentPath)
+	if err != nil {
+		d.s.Sync.Logger.Errorf("获取WebDAV文件列表失败: %s %v", parentPath, err)
+		return nil, err
+	}
+	fileItems := make([]*SyncFileCache, 0, len(files))
+	for _, file := range files {
+		atomic.AddInt64(&d.s.TotalFile, 1)
+		fileItem := SyncFileCache{
+			ParentId:   parentPathId,
+			FileName:   file.Name,
+			FileType:   v115open.TypeFile,
+			FileSize:   file.Size,
+			SourceType: models.SourceTypeWebDAV,
+		}
+		if !file.Modified.IsZero() {
+			fileItem.MTime = file.Modified.Unix()
+		}
+		if file.IsDir {
+			fileItem.FileType = v115open.TypeDir
+		}
+		fileItems = append(fileItems, &fileItem)
+	}
+	return fileItems, nil
+}
+
+// 逐级创建目录，新创建的目录加入同步缓存
+func (d *webDAVDriver) CreateDirRecursively(ctx context.Context, localPath string) (pathId, remotePath string, err error) {
+	relPath, err := filepath.Rel(d.s.TargetPath, localPath)
+	if err != nil {
+		return "", "", fmt.Errorf("计算相对路径失败:%s %w", localPath, err)
+	}
+	relPath = path.Clean("/" + filepath.ToSlash(relPath))
+	// 从上往下找到第一个不存在的目录，之后的都需要创建
+	parts := strings.Split(strings.Trim(relPath, "/"), "/")
+	dir := ""
+	created := false
+	for _, part := range parts {
+		if part == "" {
+			continue
+		}
+		dir = dir + "/" + part
+		if !created {
+			if info, err := d.client.Stat(ctx, dir); err == nil && info.IsDir {
+				continue
+			}
+			created = true
+		}
+		if err := d.client.Mkdir(dir); err != nil {
+			return "", "", fmt.Errorf("创建目录失败: %s 错误：%v", dir, err)
+		}
+		syncFileCache := &SyncFileCache{
+			ParentId:   path.Dir(dir),
+			FileName:   path.Base(dir),
+			FileType:   v115open.TypeDir,
+			SourceType: models.SourceTypeWebDAV,
+		}
+		syncFileCache.GetLocalFilePath(d.s.TargetPath, d.s.SourcePath)
+		d.s.syncCache.Insert(syncFileCache)
+		d.s.Sync.Logger.Infof("创建网盘目录: %s", dir)
+	}
+	return relPath, relPath, nil
+}
+
+func (d *webDAVDriver) GetPathIdByPath(ctx context.Context, p string) (string, error) {
+	info, err := d.client.Stat(ctx, p)
+	if err != nil {
+		return "", fmt.Errorf("路径 %s 不存在: %v", p, err)
+	}
+	if !info.IsDir {
+		return "", fmt.Errorf("路径 %s 不是目录", p)
+	}
+	return p, nil
+}
+
+// 没有账号密码的WebDAV开启直链时STRM使用WebDAV地址，否则使用QMS代理地址
+// STRM内容会通过Emby发给所有客户端，不能包含账号密码
+func (d *webDAVDriver) MakeStrmContent(sf *SyncFileCache) string {
+	if d.s.Account.DirectLink && d.s.Account.Username == "" && d.s.Account.Password == "" {
+		return d.client.FileUrl(sf.GetFullRemotePath())
+	}
+	u, _ := url.Parse(d.s.Config.StrmBaseUrl)
+	ext := filepath.Ext(sf.FileName)
+	u.Path = strings.TrimSuffix(u.Path, "/") + fmt.Sprintf("/webdav/url/video%s", ext)
+	params := url.Values{}
+	params.Add("account_id", fmt.Sprintf("%d", d.s.Account.ID))
+	params.Add("path", sf.GetFullRemotePath())
+	u.RawQuery = params.Encode()
+	return u.String()
+}
+
+func (d *webDAVDriver) GetTotalFileCount(ctx context.Context) (int64, string, error) {
+	return 0, "", nil
+}
+
+func (d *webDAVDriver) GetDirsByPathId(ctx context.Context, pathId string) ([]pathQueueItem, error) {
+	return nil, nil
+}
+
+func (d *webDAVDriver) GetFilesByPathId(ctx context.Context, rootPathId string, offset, limit int) ([]v115open.File, error) {
+	return nil, nil
+}
+
+func (d *webDAVDriver) DetailByFileId(ctx context.Context, fileId string) (*v115open.FileDetail, error) {
+	return nil, nil
+}
+
+// 删除目录下的某些文件，fileId是文件的完整路径
+func (d *webDAVDriver) DeleteFile(ctx context.Context, parentId string, fileIds []string) error {
+	for _, fileId := range fileIds {
+		if err := d.client.Remove(fileId); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (d *webDAVDriver) GetFilesByPathMtime(ctx context.Context, rootPathId string, offset, limit int, mtime int64) (*baidupan.FileListAllResponse, error) {
+	return nil, nil
+}
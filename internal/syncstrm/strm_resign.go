@@ -0,0 +1,127 @@
+package syncstrm
+
+import (
+	"Q115-STRM/internal/helpers"
+	"Q115-STRM/internal/models"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 正在重新签名的同步路径，同一个同步路径不能同时执行
+var resigningPaths sync.Map
+
+// 同步路径和所有目标目录存放STRM文件的本地目录
+func strmLocalDirs(syncPath *models.SyncPath) []string {
+	dirs := []string{syncPath.GetFullLocalPath()}
+	for _, target := range models.GetEnabledSyncPathTargets(syncPath.ID) {
+		dirs = append(dirs, target.GetFullLocalPath(syncPath))
+	}
+	return dirs
+}
+
+// ResignSyncPathStrm 按同步路径当前的签名设置重写已有的STRM文件，返回重写的文件数量
+// 关闭签名时去掉签名，密钥轮换后用新密钥重新签名，快过期的签名续期，全部完成后旧密钥失效
+// 不需要访问网盘，文件的修改时间保持不变
+func ResignSyncPathStrm(syncPath *models.SyncPath) (int, error) {
+	if _, loaded := resigningPaths.LoadOrStore(syncPath.ID, struct{}{}); loaded {
+		return 0, fmt.Errorf("同步路径 %d 正在重新签名STRM文件", syncPath.ID)
+	}
+	defer resigningPaths.Delete(syncPath.ID)
+
+	secret := syncPath.GetStrmSignSecret()
+	pathId := strconv.FormatUint(uint64(syncPath.ID), 10)
+	now := time.Now()
+	expireAt := syncPath.GetStrmSignExpireAt(now)
+	count := 0
+	failed := 0
+	for _, dir := range strmLocalDirs(syncPath) {
+		if !helpers.PathExists(dir) {
+			continue
+		}
+		err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				helpers.AppLogger.Warnf("重新签名时读取 %s 失败: %v", path, err)
+				return nil
+			}
+			if d.IsDir() || !strings.EqualFold(filepath.Ext(path), ".strm") {
+				return nil
+			}
+			data, err := os.ReadFile(path)
+			if err != nil {
+				failed++
+				helpers.AppLogger.Warnf("读取STRM文件 %s 失败: %v", path, err)
+				return nil
+			}
+			content := strings.TrimSpace(string(data))
+			if u, err := url.Parse(content); err != nil || (u.Query().Get(helpers.StrmSignParam) != "" && u.Query().Get(helpers.StrmSignPathParam) != pathId) {
+				// 其他同步路径签名的文件不处理
+				return nil
+			}
+			if !helpers.StrmSignNeedsRefresh(content, secret, syncPath.StrmSignExpireDays, now) {
+				return nil
+			}
+			newContent := helpers.StripStrmSign(content)
+			if secret != "" {
+				newContent = helpers.SignStrmUrl(newContent, syncPath.ID, secret, expireAt)
+			}
+			if newContent == content {
+				return nil
+			}
+			info, err := d.Info()
+			if err != nil {
+				failed++
+				return nil
+			}
+			if err := helpers.WriteFileWithPerm(path, []byte(newContent), 0777); err != nil {
+				failed++
+				helpers.AppLogger.Warnf("重写STRM文件 %s 失败: %v", path, err)
+				return nil
+			}
+			os.Chtimes(path, info.ModTime(), info.ModTime())
+			count++
+			return nil
+		})
+		if err != nil {
+			return count, err
+		}
+	}
+	if failed > 0 {
+		// 有文件没有重写成功，旧密钥继续保留
+		return count, fmt.Errorf("%d 个STRM文件重新签名失败", failed)
+	}
+	if syncPath.StrmSignPrevSecret != "" {
+		if err := syncPath.ClearStrmSignPrevSecret(); err != nil {
+			return count, err
+		}
+	}
+	helpers.AppLogger.Infof("同步路径 %d 重新签名STRM文件完成，共重写 %d 个文件", syncPath.ID, count)
+	return count, nil
+}
+
+// StartResignSyncPathStrm 在后台重新签名同步路径的STRM文件
+func StartResignSyncPathStrm(syncPath *models.SyncPath) {
+	go func() {
+		if _, err := ResignSyncPathStrm(syncPath); err != nil {
+			helpers.AppLogger.Errorf("同步路径 %d 重新签名STRM文件失败: %v", syncPath.ID, err)
+		}
+	}()
+}
+
+// RenewExpiringStrmSign 给所有设置了签名有效期的同步路径续期快过期的签名
+func RenewExpiringStrmSign() {
+	for _, syncPath := range models.GetAllSyncPaths() {
+		if !syncPath.StrmSignEnabled || syncPath.StrmSignExpireDays <= 0 {
+			continue
+		}
+		if _, err := ResignSyncPathStrm(syncPath); err != nil {
+			helpers.AppLogger.Errorf("同步路径 %d 续期STRM签名失败: %v", syncPath.ID, err)
+		}
+	}
+}
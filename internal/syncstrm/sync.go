@@ -5,6 +5,7 @@ import (
 	"Q115-STRM/internal/db"
 	"Q115-STRM/internal/helpers"
 	"Q115-STRM/internal/models"
+	"Q115-STRM/internal/scheduler"
 	"Q115-STRM/internal/v115open"
 	"context"
 	"errors"
@@ -14,6 +15,7 @@ import (
 	"runtime"
 	"slices"
 	"strings"
+	"sync"
 	"sync/atomic"
 	"time"
 )
@@ -68,7 +70,34 @@ type SyncStrm struct {
 	// 115 同步器
 	sync115 *Sync115
 
-	memSyncCache *MemorySyncCache // 同步缓存
+	syncCache SyncCache // 同步缓存，根据文件数量使用内存或磁盘
+
+	// 局部同步的目录，为空时完整同步
+	PartialPaths []string
+
+	// 预览同步：只记录将要执行的操作
+	DryRun      bool
+	planItems   []*models.SyncPlanItem
+	planMu      sync.Mutex
+	planRenamed sync.Map // 预览时会被重命名的本地文件
+
+	// 编译后的包含和排除规则
+	includeRules []*models.SyncPathRule
+	excludeRules []*models.SyncPathRule
+
+	// 变更记录：实际执行的文件操作，分批写入数据库
+	changes  []*models.SyncChange
+	changeMu sync.Mutex
+
+	// 目标目录：TargetId为0时是同步目录自己的本地路径，否则是目标目录的同步器
+	TargetId   uint
+	TargetName string
+	targets    []*models.SyncPathTarget
+
+	// 删除保护：对比本地文件时先收集要删除的文件，对比完成后统一检查阈值再删除
+	deleteCandidates []string
+	localFileTotal   int
+	deleteHeld       bool // 超过阈值，本次同步不删除
 }
 
 type pathQueueItem struct {
@@ -89,17 +118,28 @@ func NewSyncStrm(account *models.Account, syncPathId uint, sourcePath, sourcePat
 		syncDriver = NewLocalDriver()
 	case models.SourceTypeBaiduPan:
 		syncDriver = NewBaiduPanDriver(account.GetBaiDuPanClient())
+	case models.SourceType123:
+		syncDriver = NewOpen123Driver(account.Get123Client())
+	case models.SourceTypeWebDAV:
+		syncDriver = NewWebDAVDriver(account.GetWebDAVClient())
+	case models.SourceTypeS3:
+		syncDriver = NewS3Driver(account.GetS3Client())
 	}
 	pathWorkerMax := int64(models.SettingsGlobal.FileDetailThreads)
 	switch account.SourceType {
 	case models.SourceTypeLocal:
 		pathWorkerMax = int64(10) // 本地类型（CD2会自己限制并发），限制为10个并发
-	case models.SourceTypeOpenList:
+	case models.SourceTypeWebDAV, models.SourceTypeS3:
 		pathWorkerMax = int64(models.SettingsGlobal.OpenlistQPS)
 	case models.SourceType115:
 		pathWorkerMax = int64(models.SettingsGlobal.FileDetailThreads)
-	case models.SourceTypeBaiduPan:
-		pathWorkerMax = int64(models.SettingsGlobal.FileDetailThreads)
+	case models.SourceTypeOpenList, models.SourceTypeBaiduPan, models.SourceType123:
+		// 并发数跟随账号调度器的QPS，真正的速率由调度器控制
+		if qps := scheduler.Get(string(account.SourceType), account.ID).Limits().QPS; qps > 0 {
+			pathWorkerMax = int64(qps)
+		} else if account.SourceType == models.SourceTypeOpenList {
+			pathWorkerMax = int64(models.SettingsGlobal.OpenlistQPS)
+		}
 	}
 	if pathWorkerMax <= 1 {
 		pathWorkerMax = 2 // 最小为2，否则并发操作会出错
@@ -112,7 +152,8 @@ func NewSyncStrm(account *models.Account, syncPathId uint, sourcePath, sourcePat
 		}
 	}
 
-	ctx, cancel := context.WithCancel(context.Background())
+	// 115的请求进入后台同步队列，不影响播放和浏览
+	ctx, cancel := context.WithCancel(v115open.WithPriority(context.Background(), v115open.PRIORITY_SYNC))
 	s := &SyncStrm{
 		Context:       ctx,
 		Cancel:        cancel,
@@ -129,7 +170,7 @@ func NewSyncStrm(account *models.Account, syncPathId uint, sourcePath, sourcePat
 		PathErrChan:   make(chan error, 1),
 		LastSyncAt:    lastSyncAt,
 	}
-	s.memSyncCache = NewMemorySyncCache(syncPathId)
+	s.syncCache = NewMemorySyncCache(syncPathId)
 	if s.Account == nil {
 		s.Account = &models.Account{SourceType: models.SourceTypeLocal}
 	}
@@ -144,6 +185,7 @@ func NewSyncStrm(account *models.Account, syncPathId uint, sourcePath, sourcePat
 		return nil
 	}
 	s.Sync.InitLogger()
+	s.compileRules()
 	s.SyncDriver.SetSyncStrm(s)
 	return s
 }
@@ -170,8 +212,22 @@ func NewSyncStrmFromSyncPath(syncPath *models.SyncPath) *SyncStrm {
 		DelEmptyLocalDir:      syncPath.GetDeleteDir() == 1,
 		CheckMetaMtime:        syncPath.GetCheckMetaMtime(),
 		StrmBaseUrl:           syncPath.GetStrmBaseUrl(),
+		StrmTemplate:          syncPath.GetStrmTemplate(),
+		DeleteMaxCount:        syncPath.GetDeleteMaxCount(),
+		DeleteMaxPercent:      syncPath.GetDeleteMaxPercent(),
+		TrashRetentionDays:    syncPath.GetTrashRetentionDays(),
+		IncludeRules:          syncPath.GetIncludeRuleArr(),
+		ExcludeRules:          syncPath.GetExcludeRuleArr(),
+		MaxVideoSize:          syncPath.GetMaxVideoSize(),
+		MaxAgeDays:            syncPath.GetMaxAgeDays(),
+		StrmSignSecret:        syncPath.GetStrmSignSecret(),
+		StrmSignExpireDays:    syncPath.StrmSignExpireDays,
+	}
+	s := NewSyncStrm(account, syncPath.ID, syncPath.RemotePath, syncPath.BaseCid, syncPath.LocalPath, config, syncPath.IsFullSync, syncPath.LastSyncAt)
+	if s != nil {
+		s.targets = models.GetEnabledSyncPathTargets(syncPath.ID)
 	}
-	return NewSyncStrm(account, syncPath.ID, syncPath.RemotePath, syncPath.BaseCid, syncPath.LocalPath, config, syncPath.IsFullSync, syncPath.LastSyncAt)
+	return s
 }
 
 // 直接同步某个路径（可以是目录，也可以是文件）
@@ -187,6 +243,14 @@ func NewSyncStrmByPath(account *models.Account, sourcePath, sourcePathId string)
 		DelEmptyLocalDir:      models.SettingsGlobal.DeleteDir == 1,
 		CheckMetaMtime:        models.SettingsGlobal.CheckMetaMtime,
 		StrmBaseUrl:           models.SettingsGlobal.StrmBaseUrl,
+		StrmTemplate:          models.SettingsGlobal.StrmTemplate,
+		DeleteMaxCount:        models.SettingsGlobal.DeleteMaxCount,
+		DeleteMaxPercent:      models.SettingsGlobal.DeleteMaxPercent,
+		TrashRetentionDays:    models.SettingsGlobal.TrashRetentionDays,
+		IncludeRules:          models.SettingsGlobal.IncludeRuleArr,
+		ExcludeRules:          models.SettingsGlobal.ExcludeRuleArr,
+		MaxVideoSize:          models.SettingsGlobal.MaxVideoSize,
+		MaxAgeDays:            models.SettingsGlobal.MaxAgeDays,
 	}
 	return NewSyncStrm(account, 0, sourcePath, sourcePathId, "", config, false, 0)
 }
@@ -202,14 +266,27 @@ func (s *SyncStrm) Stop() {
 }
 
 func (s *SyncStrm) Start() error {
-	// 开始任务时先暂停下载和上传队列
-	// 关闭上传下载队列
-	models.GlobalDownloadQueue.Stop()
-	models.GlobalUploadQueue.Stop()
+	if !s.DryRun {
+		// 开始任务时先暂停下载和上传队列
+		// 关闭上传下载队列
+		models.GlobalDownloadQueue.Stop()
+		models.GlobalUploadQueue.Stop()
+		defer func() {
+			// 任务完成后启动上传下载队列
+			models.GlobalDownloadQueue.Start()
+			models.GlobalUploadQueue.Start()
+		}()
+	}
+	if !s.DryRun {
+		// 结束时（包括失败）保存剩余的变更记录
+		defer s.flushChanges(true)
+	}
+	// 同步缓存在处理完差异后释放，没有处理差异时在这里释放
+	keepCache := false
 	defer func() {
-		// 任务完成后启动上传下载队列
-		models.GlobalDownloadQueue.Start()
-		models.GlobalUploadQueue.Start()
+		if !keepCache {
+			s.syncCache.Close()
+		}
 	}()
 	atomic.StoreInt64(&s.NewMeta, 0)
 	atomic.StoreInt64(&s.NewStrm, 0)
@@ -217,6 +294,9 @@ func (s *SyncStrm) Start() error {
 	atomic.StoreInt64(&s.TotalFile, 0)
 	s.Sync.Logger.Infof("本次同步的入口目录：%s，目标目录：%s", s.SourcePath, s.TargetPath)
 	s.Sync.Logger.Infof("本次同步使用的STRM配置%+v", s.Config)
+	if s.IsPartial() {
+		s.Sync.Logger.Infof("本次为局部同步，只处理目录：%v", s.PartialPaths)
+	}
 	s.Sync.UpdateStatus(models.SyncStatusInProgress)
 	newPathId, err := s.SyncDriver.GetPathIdByPath(s.Context, s.SourcePath)
 	if err != nil {
@@ -234,7 +314,7 @@ func (s *SyncStrm) Start() error {
 	}
 	// 创建本地根目录
 	localBaseDir := s.GetLocalBaseDir()
-	if !s.checkPathExists(localBaseDir) {
+	if !s.DryRun && !s.checkPathExists(localBaseDir) {
 		if err := os.MkdirAll(localBaseDir, 0777); err != nil {
 			reason := fmt.Sprintf("创建本地根目录失败: %s %v", localBaseDir, err)
 			s.Sync.Failed(reason)
@@ -245,9 +325,11 @@ func (s *SyncStrm) Start() error {
 	case models.SourceType115:
 		s.Start115Sync()
 	case models.SourceTypeBaiduPan:
+		s.selectSyncCacheByLastSync()
 		s.StartBaiduPanSync()
 	default:
 		// 其他来源走一套逻辑
+		s.selectSyncCacheByLastSync()
 		s.StartOther()
 	}
 	s.Sync.Logger.Info("完成所有路径和文件的处理，检查是否有错误发生")
@@ -261,7 +343,7 @@ func (s *SyncStrm) Start() error {
 	default:
 	}
 	// 处理完所有路径和文件后，更新最后同步时间
-	if s.SyncPathId > 0 {
+	if s.SyncPathId > 0 && !s.DryRun {
 		syncPath := models.GetSyncPathById(s.SyncPathId)
 		if syncPath != nil {
 			syncPath.UpdateLastSync()
@@ -274,6 +356,14 @@ func (s *SyncStrm) Start() error {
 	if err := s.compareLocalFilesWithTempTable(); err != nil {
 		return err
 	}
+	// 使用同一份网盘文件列表生成所有目标目录
+	s.syncTargets()
+	if s.DryRun {
+		if err := s.savePlanItems(); err != nil {
+			s.Sync.Failed(fmt.Sprintf("保存预览同步操作失败: %v", err))
+			return err
+		}
+	}
 	s.Sync.NewMeta = int(s.NewMeta)
 	s.Sync.NewStrm = int(s.NewStrm)
 	s.Sync.NewUpload = int(s.NewUpload)
@@ -281,9 +371,11 @@ func (s *SyncStrm) Start() error {
 	s.Sync.Complete(s.Account.SourceType)
 	// 如果有syncpathid，则更新最后同步时间
 
-	if !s.TmpSyncPath {
+	if !s.TmpSyncPath && !s.DryRun {
+		// 同步成功，不再需要断点
+		s.clear115Checkpoint()
 		// 有syncPathId,将IsFullSync改为false
-		if s.FullSync {
+		if s.FullSync && !s.IsPartial() {
 			db.Db.Model(&models.SyncPath{}).Where("id = ?", s.SyncPathId).Update("is_full_sync", false)
 		}
 		db.Db.Model(&models.SyncPath{}).Where("id = ?", s.SyncPathId).Update("last_sync_at", s.Sync.FinishAt)
@@ -296,7 +388,9 @@ func (s *SyncStrm) Start() error {
 			}
 		}()
 		// 处理差异
+		keepCache = true
 		go func() {
+			defer s.syncCache.Close()
 			s.Sync.Logger.Info("115路径和文件同步完成，开始处理SyncFile表和临时表的数据差异")
 			s.handleTempTableDiff()
 			s.Sync.Logger.Info("完成差异比对，并更新了SyncFile表，任务彻底完成")
@@ -313,18 +407,27 @@ func (s *SyncStrm) processNetFile(file *SyncFileCache) error {
 	// s.Sync.Logger.Infof("本地文件路径: %s", localFilePath)
 	// 先处理重命名，只有非临时同步才会处理重命名，临时同步只会删除重建
 	var existingFile models.SyncFile
-	if !s.TmpSyncPath {
+	// 目标目录没有自己的SyncFile记录，重命名走删除重建流程
+	if !s.TmpSyncPath && s.TargetId == 0 {
 		err := db.Db.Where("file_id = ? AND sync_path_id = ?", file.GetFileId(), s.SyncPathId).First(&existingFile).Error
 		if err == nil {
 			// 如果SyncFiles存在，检查是否需要重命名，所在目录必须相同才可以重命名，否则只能走删除重建流程
 			if existingFile.FileName != file.FileName && existingFile.Path == file.Path {
 				// 需要重命名
+				if s.DryRun {
+					item := s.addPlanItem(models.SyncPlanActionRename, localFilePath, file.GetFullRemotePath(), "网盘文件已重命名")
+					item.OldLocalPath = existingFile.LocalFilePath
+					// 重命名后的文件不需要再生成，原文件也不会被删除
+					s.planRenamed.Store(filepath.ToSlash(existingFile.LocalFilePath), true)
+					return nil
+				}
 				err := os.Rename(existingFile.LocalFilePath, localFilePath)
 				if err != nil {
 					// 只记录日志，不报错（因为重命名失败不影响后续处理，会自动转入删除、重建流程）
 					s.Sync.Logger.Errorf("重命名失败 %s => %s: %w", existingFile.LocalFilePath, localFilePath, err)
 				} else {
 					s.Sync.Logger.Infof("重命名成功 %s => %s", existingFile.LocalFilePath, localFilePath)
+					s.addChange(models.SyncChangeActionRename, existingFile.LocalFilePath, localFilePath, file.GetFullRemotePath(), "网盘文件已重命名")
 				}
 			}
 		}
@@ -353,7 +456,7 @@ func (s *SyncStrm) processNetFile(file *SyncFileCache) error {
 func (s *SyncStrm) AddDownloadTaskTemp(file *SyncFileCache) {
 	file.NeedDownload = true
 	// 生成下载索引
-	s.memSyncCache.InsertDownloadIndex(file)
+	s.syncCache.InsertDownloadIndex(file)
 }
 
 // 遍历同步缓存，添加下载任务
@@ -364,39 +467,46 @@ func (s *SyncStrm) AddDownloadTaskFromMemCache() {
 	offset := 0
 	limit := 1000
 	type existDownloadTask struct {
-		RemoteFileId string `json:"remote_file_id"`
+		RemoteFileId  string `json:"remote_file_id"`
+		LocalFullPath string `json:"local_full_path"`
 	}
 	for {
 		var batch []existDownloadTask
-		err := db.Db.Model(models.DbDownloadTask{}).Select("remote_file_id").Where("source_type = ? AND status IN ?", s.Account.SourceType, []int{int(models.DownloadStatusPending), int(models.DownloadStatusDownloading)}).
+		err := db.Db.Model(models.DbDownloadTask{}).Select("remote_file_id, local_full_path").Where("source_type = ? AND status IN ?", s.Account.SourceType, []int{int(models.DownloadStatusPending), int(models.DownloadStatusDownloading)}).
 			Offset(offset).Limit(limit).Order("id ASC").Find(&batch).Error
 		if err != nil {
 			s.Sync.Logger.Errorf("获取未完成的下载任务失败: %v", err)
 			break
 		}
-		if len(batch) == 0 || len(batch) < limit {
-			break
-		}
 		for _, item := range batch {
-			existingDownloads[item.RemoteFileId] = true
+			existingDownloads[item.RemoteFileId+"|"+item.LocalFullPath] = true
+		}
+		if len(batch) < limit {
+			break
 		}
 		offset += limit
 	}
-	// 遍历内存同步缓存的下载索引
-	s.memSyncCache.mu.RLock()
-	for _, file := range s.memSyncCache.downloadIndex {
-		if _, exists := existingDownloads[file.GetPickCode(s.Account.BaseUrl)]; exists {
+	// 遍历同步缓存的下载索引
+	s.syncCache.RangeDownload(func(file *SyncFileCache) bool {
+		// 同一个网盘文件可能下载到多个目标目录，按本地路径区分
+		if _, exists := existingDownloads[file.GetPickCode(s.Account.BaseUrl)+"|"+file.GetLocalFilePath(s.TargetPath, s.SourcePath)]; exists {
 			// 已经存在下载任务，跳过
-			continue
+			return true
+		}
+		if s.DryRun {
+			s.addPlanItem(models.SyncPlanActionDownload, file.GetLocalFilePath(s.TargetPath, s.SourcePath), file.GetFullRemotePath(), "本地元数据文件不存在")
+			atomic.AddInt64(&s.NewMeta, 1)
+			return true
 		}
 		// 添加下载任务
 		err := models.AddDownloadTaskFromSyncFile(file.GetSyncFile(s, s.Account.BaseUrl))
 		if err == nil {
 			s.Sync.Logger.Infof("添加下载任务成功: %s=>%s", file.Path+"/"+file.FileName, file.GetLocalFilePath(s.TargetPath, s.SourcePath))
 			atomic.AddInt64(&s.NewMeta, 1)
+			s.addChange(models.SyncChangeActionDownload, "", file.GetLocalFilePath(s.TargetPath, s.SourcePath), file.GetFullRemotePath(), "本地元数据文件不存在")
 		}
-	}
-	s.memSyncCache.mu.RUnlock()
+		return true
+	})
 }
 
 // 对比本地文件和临时表中的文件
@@ -406,185 +516,228 @@ func (s *SyncStrm) compareLocalFilesWithTempTable() error {
 	case <-s.Context.Done():
 		return nil
 	default:
-		rootPath := filepath.Join(s.TargetPath, s.SourcePath)
-		s.Sync.Logger.Infof("开始对比本地文件和临时表中的文件，根目录: %s", rootPath)
-		// 对比本地文件和临时表中的文件
-		filepath.Walk(rootPath, func(path string, info os.FileInfo, err error) error {
-			path = filepath.ToSlash(path)
-			select {
-			case <-s.Context.Done():
+		rootPaths := []string{filepath.Join(s.TargetPath, s.SourcePath)}
+		if s.IsPartial() {
+			// 局部同步只对比局部目录对应的本地目录
+			rootPaths = s.partialLocalDirs()
+		}
+		for _, rootPath := range rootPaths {
+			s.compareLocalDir(rootPath)
+		}
+		s.applyLocalDeletes()
+	}
+	return nil
+}
+
+// 对比本地目录下的文件和临时表中的文件
+func (s *SyncStrm) compareLocalDir(rootPath string) {
+	s.Sync.Logger.Infof("开始对比本地文件和临时表中的文件，根目录: %s", rootPath)
+	// 对比本地文件和临时表中的文件
+	filepath.Walk(rootPath, func(path string, info os.FileInfo, err error) error {
+		path = filepath.ToSlash(path)
+		select {
+		case <-s.Context.Done():
+			return nil
+		default:
+			// 不处理目录（只处理文件）
+			if err != nil || path == "." || strings.Contains(path, ".verysync") || strings.Contains(path, ".deletedByTMM") {
+				// 跳过根目录本身
+				// 跳过微力同步和TMM的临时目录中的文件
 				return nil
-			default:
-				// 不处理目录（只处理文件）
-				if err != nil || path == "." || strings.Contains(path, ".verysync") || strings.Contains(path, ".deletedByTMM") {
-					// 跳过根目录本身
-					// 跳过微力同步和TMM的临时目录中的文件
-					return nil
-				}
-				if info.IsDir() {
-					if s.Config.DelEmptyLocalDir {
-						// 如果目录是空的则删除目录
-						dirEntries, rerr := os.ReadDir(path)
-						if rerr != nil {
-							s.Sync.Logger.Errorf("读取目录 %s 的文件列表失败: %v", path, err)
+			}
+			if info.IsDir() && info.Name() == models.SyncTrashDirName {
+				// 跳过回收站
+				return filepath.SkipDir
+			}
+			if info.IsDir() {
+				if s.Config.DelEmptyLocalDir {
+					// 如果目录是空的则删除目录
+					dirEntries, rerr := os.ReadDir(path)
+					if rerr != nil {
+						s.Sync.Logger.Errorf("读取目录 %s 的文件列表失败: %v", path, err)
+						return nil
+					}
+					if len(dirEntries) == 0 {
+						if s.DryRun {
+							s.addPlanItem(models.SyncPlanActionDeleteLocal, path, "", "空目录")
 							return nil
 						}
-						if len(dirEntries) == 0 {
-							os.Remove(path)
-							s.Sync.Logger.Infof("删除空目录 %s", path)
-						}
+						os.Remove(path)
+						s.Sync.Logger.Infof("删除空目录 %s", path)
 					}
-					return nil
 				}
-				ext := filepath.Ext(info.Name())
-				isVideo := ext == ".strm"
-				isMeta := s.IsValidMetaExt(info.Name())
-				if isMeta && s.Config.EnableDownloadMeta == 0 {
-					// 如果是元数据文件且设置为不下载，则跳过检查（代表着不上传）
-					s.Sync.Logger.Infof("本地元数据文件 %s 由于关闭了元数据下载所以不需要处理", info.Name())
+				return nil
+			}
+			ext := filepath.Ext(info.Name())
+			isVideo := ext == ".strm"
+			isMeta := s.IsValidMetaExt(info.Name())
+			if isMeta && s.Config.EnableDownloadMeta == 0 {
+				// 如果是元数据文件且设置为不下载，则跳过检查（代表着不上传）
+				s.Sync.Logger.Infof("本地元数据文件 %s 由于关闭了元数据下载所以不需要处理", info.Name())
+				return nil
+			}
+			if !isVideo && !isMeta {
+				// 非视频文件和元数据文件，跳过
+				s.Sync.Logger.Debugf("本地文件 %s 既不是STRM文件也不是元数据文件，跳过", path)
+				return nil
+			}
+			s.localFileTotal++
+			// 检查文件在临时表是否存在
+			// existsFile, _ := s.queryTempTableByLocalPath(path)
+			existsFile, err := s.syncCache.GetByLocalPath(path)
+			if err != nil {
+				s.Sync.Logger.Warnf("查询同步缓存失败 %s: %v", path, err)
+			}
+			// s.Sync.Logger.Infof("对比本地文件 %s，是否存在于网盘: %v", path, existsFile)
+			if isVideo {
+				// STRM文件，检查文件在临时表是否存在，不存在需要删除临时文件
+				if existsFile != nil {
 					return nil
 				}
-				if !isVideo && !isMeta {
-					// 非视频文件和元数据文件，跳过
-					s.Sync.Logger.Debugf("本地文件 %s 既不是STRM文件也不是元数据文件，跳过", path)
+				// s.Sync.Logger.Warnf("本地文件在网盘不存在，删除本地STRM文件: %s", path)
+				s.addDeleteCandidate(path)
+				return nil
+			}
+			if isMeta {
+				// 如果选择忽略，则跳过
+				if s.Config.NetNotFoundFileAction == models.SyncTreeItemMetaActionKeep {
+					s.Sync.Logger.Infof("本地元数据文件 %s 由于设置为保留所以不需要处理", path)
 					return nil
 				}
-				// 检查文件在临时表是否存在
-				// existsFile, _ := s.queryTempTableByLocalPath(path)
-				existsFile, err := s.memSyncCache.GetByLocalPath(path)
-				if err != nil {
-					s.Sync.Logger.Warnf("查询同步缓存失败 %s: %v", path, err)
-				}
-				// s.Sync.Logger.Infof("对比本地文件 %s，是否存在于网盘: %v", path, existsFile)
-				if isVideo {
-					// STRM文件，检查文件在临时表是否存在，不存在需要删除临时文件
-					if existsFile != nil {
-						return nil
-					}
-					// s.Sync.Logger.Warnf("本地文件在网盘不存在，删除本地STRM文件: %s", path)
-					s.RemoveFileAndCheckDirEmtry(path)
+				// 如果选择删除，则检查是否存在，不存在则删除
+				if s.Config.NetNotFoundFileAction == models.SyncTreeItemMetaActionDelete && existsFile == nil {
+					s.addDeleteCandidate(path)
 					return nil
 				}
-				if isMeta {
-					// 如果选择忽略，则跳过
-					if s.Config.NetNotFoundFileAction == models.SyncTreeItemMetaActionKeep {
-						s.Sync.Logger.Infof("本地元数据文件 %s 由于设置为保留所以不需要处理", path)
+				// 如果允许上传，则检查是否需要上传（文件在网盘不存在）
+				if s.Config.NetNotFoundFileAction == models.SyncTreeItemMetaActionUpload && existsFile == nil {
+					// 检查是否已经添加了上传任务，检查dbupload表中是否存在对应的记录
+					canUpload := models.CheckCanUploadByLocalPath(models.UploadSourceStrm, path)
+					if !canUpload {
+						s.Sync.Logger.Infof("本地元数据文件 %s 由于存在上传任务所以不需要处理", path)
 						return nil
 					}
-					// 如果选择删除，则检查是否存在，不存在则删除
-					if s.Config.NetNotFoundFileAction == models.SyncTreeItemMetaActionDelete && existsFile == nil {
-						s.RemoveFileAndCheckDirEmtry(path)
-						return nil
+					sourceRootPath := filepath.ToSlash(filepath.Join(s.TargetPath, s.Sync.RemotePath))
+					// 添加上传任务
+					// 检查文件是否可以上传
+					// 普通元数据文件需要父目录存在才可以上传，允许上传目录下的文件需要循环创建目录上传
+					parentDir := filepath.Dir(path)
+					parentName := filepath.Base(parentDir)
+					if parentDir != "" {
+						parentDir = filepath.ToSlash(parentDir)
 					}
-					// 如果允许上传，则检查是否需要上传（文件在网盘不存在）
-					if s.Config.NetNotFoundFileAction == models.SyncTreeItemMetaActionUpload && existsFile == nil {
-						// 检查是否已经添加了上传任务，检查dbupload表中是否存在对应的记录
-						canUpload := models.CheckCanUploadByLocalPath(models.UploadSourceStrm, path)
-						if !canUpload {
-							s.Sync.Logger.Infof("本地元数据文件 %s 由于存在上传任务所以不需要处理", path)
+					isAllowedUploadDir := slices.Contains(uploadDirNames, strings.ToLower(parentName))
+					// 检查父目录是否在网盘存在
+					existsPath, _ := s.syncCache.GetByLocalPath(parentDir)
+					// 如果不存在，检查是否可以创建目录
+					var parentPath, parentPathId, remotePath string
+					s.Sync.Logger.Infof("准备上传本地元数据文件 %s，检查父目录 %s 是否存在网盘", parentDir, sourceRootPath)
+					if existsPath == nil && parentDir != sourceRootPath {
+						if !isAllowedUploadDir {
+							s.Sync.Logger.Infof("父目录 %s 不存在网盘，进入删除流程 %s，", parentDir, path)
+							s.addDeleteCandidate(path)
 							return nil
-						}
-						sourceRootPath := filepath.ToSlash(filepath.Join(s.TargetPath, s.Sync.RemotePath))
-						// 添加上传任务
-						// 检查文件是否可以上传
-						// 普通元数据文件需要父目录存在才可以上传，允许上传目录下的文件需要循环创建目录上传
-						parentDir := filepath.Dir(path)
-						parentName := filepath.Base(parentDir)
-						if parentDir != "" {
-							parentDir = filepath.ToSlash(parentDir)
-						}
-						isAllowedUploadDir := slices.Contains(uploadDirNames, strings.ToLower(parentName))
-						// 检查父目录是否在网盘存在
-						existsPath, _ := s.memSyncCache.GetByLocalPath(parentDir)
-						// 如果不存在，检查是否可以创建目录
-						var parentPath, parentPathId, remotePath string
-						s.Sync.Logger.Infof("准备上传本地元数据文件 %s，检查父目录 %s 是否存在网盘", parentDir, sourceRootPath)
-						if existsPath == nil && parentDir != sourceRootPath {
-							if !isAllowedUploadDir {
-								s.Sync.Logger.Infof("父目录 %s 不存在网盘，进入删除流程 %s，", parentDir, path)
-								s.RemoveFileAndCheckDirEmtry(path)
+						} else {
+							if s.DryRun {
+								s.addPlanItem(models.SyncPlanActionUpload, path, "", "网盘不存在该文件，父目录也不存在，上传前先创建目录")
 								return nil
-							} else {
-								// 递归创建目录, 调用不同的driver
-								parentPathId, remotePath, err = s.SyncDriver.CreateDirRecursively(s.Context, parentDir)
-								if err != nil {
-									s.Sync.Logger.Errorf("创建目录 %s 失败: %v", parentDir, err)
-									return nil
-								}
-								parentPath = parentDir
 							}
-						} else {
-							if parentDir == sourceRootPath {
-								parentPath = sourceRootPath
-								parentPathId = s.SourcePathId
-								remotePath = s.SourcePath
-							} else {
-								parentPath = parentDir
-								parentPathId = existsPath.GetFileId()
-								remotePath = fmt.Sprintf("%s/%s", existsPath.Path, existsPath.FileName)
+							// 递归创建目录, 调用不同的driver
+							parentPathId, remotePath, err = s.SyncDriver.CreateDirRecursively(s.Context, parentDir)
+							if err != nil {
+								s.Sync.Logger.Errorf("创建目录 %s 失败: %v", parentDir, err)
+								return nil
 							}
+							parentPath = parentDir
 						}
-						// 加入上传队列
-						db115File := &models.SyncFile{
-							AccountId:     s.Account.ID,
-							SyncPathId:    s.SyncPathId,
-							SourceType:    s.Account.SourceType,
-							FileType:      v115open.TypeFile,
-							FileId:        "", // 上传前FileId为空
-							ParentId:      parentPathId,
-							FileName:      info.Name(),
-							Path:          remotePath,
-							FileSize:      info.Size(),
-							MTime:         info.ModTime().Unix(),
-							IsMeta:        isMeta,
-							IsVideo:       isVideo,
-							LocalFilePath: filepath.Join(parentPath, info.Name()),
-						}
-						if s.Account.SourceType != models.SourceType115 {
-							db115File.FileId = filepath.ToSlash(filepath.Join(db115File.Path, db115File.FileName))
+					} else {
+						if parentDir == sourceRootPath {
+							parentPath = sourceRootPath
+							parentPathId = s.SourcePathId
+							remotePath = s.SourcePath
+						} else {
+							parentPath = parentDir
+							parentPathId = existsPath.GetFileId()
+							remotePath = fmt.Sprintf("%s/%s", existsPath.Path, existsPath.FileName)
 						}
-						models.AddUploadTaskFromSyncFile(db115File)
+					}
+					// 加入上传队列
+					db115File := &models.SyncFile{
+						AccountId:     s.Account.ID,
+						SyncPathId:    s.SyncPathId,
+						SourceType:    s.Account.SourceType,
+						FileType:      v115open.TypeFile,
+						FileId:        "", // 上传前FileId为空
+						ParentId:      parentPathId,
+						FileName:      info.Name(),
+						Path:          remotePath,
+						FileSize:      info.Size(),
+						MTime:         info.ModTime().Unix(),
+						IsMeta:        isMeta,
+						IsVideo:       isVideo,
+						LocalFilePath: filepath.Join(parentPath, info.Name()),
+					}
+					if s.Account.SourceType != models.SourceType115 {
+						db115File.FileId = filepath.ToSlash(filepath.Join(db115File.Path, db115File.FileName))
+					}
+					if s.DryRun {
+						s.addPlanItem(models.SyncPlanActionUpload, path, filepath.ToSlash(filepath.Join(remotePath, info.Name())), "网盘不存在该文件")
 						return nil
 					}
-					// 网盘存在且设置为上传，需要检查本地是不是比网盘新，如果是的话，需要删除网盘文件并将本地文件上传
-					if existsFile != nil && s.Config.CheckMetaMtime == 1 {
-						localMTime := info.ModTime().Unix()
-						// 网盘比本地新，重新下载
+					models.AddUploadTaskFromSyncFile(db115File)
+					uploadPath := filepath.ToSlash(filepath.Join(remotePath, info.Name()))
+					s.addChange(models.SyncChangeActionUpload, path, uploadPath, uploadPath, "网盘不存在该文件")
+					return nil
+				}
+				// 网盘存在且设置为上传，需要检查本地是不是比网盘新，如果是的话，需要删除网盘文件并将本地文件上传
+				if existsFile != nil && s.Config.CheckMetaMtime == 1 {
+					localMTime := info.ModTime().Unix()
+					// 网盘比本地新，重新下载
+					// 1. 删除本地文件
+					// 2. 添加下载任务
+					if localMTime < existsFile.MTime {
+						s.Sync.Logger.Infof("本地元数据文件 %s 由于修改时间比网盘旧 %d < %d 所以需要重新下载", path, localMTime, existsFile.MTime)
+						if s.DryRun {
+							s.addPlanItem(models.SyncPlanActionDeleteLocal, path, existsFile.GetFullRemotePath(), "本地文件比网盘旧，删除后重新下载")
+							s.addPlanItem(models.SyncPlanActionDownload, path, existsFile.GetFullRemotePath(), "本地文件比网盘旧，删除后重新下载")
+							return nil
+						}
 						// 1. 删除本地文件
+						s.removeLocalFile(path, "本地文件比网盘旧，删除后重新下载")
+
 						// 2. 添加下载任务
-						if localMTime < existsFile.MTime {
-							s.Sync.Logger.Infof("本地元数据文件 %s 由于修改时间比网盘旧 %d < %d 所以需要重新下载", path, localMTime, existsFile.MTime)
-							// 1. 删除本地文件
-							s.RemoveFileAndCheckDirEmtry(path)
+						if err := models.AddDownloadTaskFromSyncFile(existsFile.GetSyncFile(s, s.Account.BaseUrl)); err == nil {
+							s.addChange(models.SyncChangeActionDownload, "", path, existsFile.GetFullRemotePath(), "本地文件比网盘旧，重新下载")
+						}
+						return nil
+					}
 
-							// 2. 添加下载任务
-							models.AddDownloadTaskFromSyncFile(existsFile.GetSyncFile(s, s.Account.BaseUrl))
+					if localMTime > existsFile.MTime && s.Config.NetNotFoundFileAction == models.SyncTreeItemMetaActionUpload {
+						// 本地比网盘新，需要删除网盘旧文件并上传新文件
+						s.Sync.Logger.Infof("本地元数据文件 %s 由于修改时间比网盘新 %d > %d 所以需要上传", path, localMTime, existsFile.MTime)
+						if s.DryRun {
+							s.addPlanItem(models.SyncPlanActionUpload, path, existsFile.GetFullRemotePath(), "本地文件比网盘新，删除网盘旧文件后上传")
 							return nil
 						}
-
-						if localMTime > existsFile.MTime && s.Config.NetNotFoundFileAction == models.SyncTreeItemMetaActionUpload {
-							// 本地比网盘新，需要删除网盘旧文件并上传新文件
-							s.Sync.Logger.Infof("本地元数据文件 %s 由于修改时间比网盘新 %d > %d 所以需要上传", path, localMTime, existsFile.MTime)
-							// 1. 删除网盘旧文件
-							err := s.SyncDriver.DeleteFile(s.Context, existsFile.ParentId, []string{existsFile.GetFileId()})
-							if err != nil {
-								s.Sync.Logger.Errorf("删除网盘旧文件 %s 失败: %v", existsFile.GetFileId(), err)
-								return nil
-							}
-							// 2. 添加上传任务
-							models.AddUploadTaskFromSyncFile(existsFile.GetSyncFile(s, s.Account.BaseUrl))
-
-							// 3. 删除数据库记录（下次同步时会将新上传的文件插入数据库）
-							s.memSyncCache.DeleteByFileId(existsFile.GetFileId())
+						// 1. 删除网盘旧文件
+						err := s.SyncDriver.DeleteFile(s.Context, existsFile.ParentId, []string{existsFile.GetFileId()})
+						if err != nil {
+							s.Sync.Logger.Errorf("删除网盘旧文件 %s 失败: %v", existsFile.GetFileId(), err)
 							return nil
 						}
+						// 2. 添加上传任务
+						models.AddUploadTaskFromSyncFile(existsFile.GetSyncFile(s, s.Account.BaseUrl))
+						s.addChange(models.SyncChangeActionUpload, path, existsFile.GetFullRemotePath(), existsFile.GetFullRemotePath(), "本地文件比网盘新，删除网盘旧文件后上传")
+
+						// 3. 删除数据库记录（下次同步时会将新上传的文件插入数据库）
+						s.syncCache.DeleteByFileId(existsFile.GetFileId())
+						return nil
 					}
 				}
 			}
-			return nil
-		})
-	}
-	return nil
+		}
+		return nil
+	})
 }
 
 // 处理SyncFile表和内存同步缓存的数据差异
@@ -596,10 +749,15 @@ func (s *SyncStrm) handleTempTableDiff() error {
 	limit := 1000
 	// 要删除的ID
 	waitDeleteIds := make([]uint, 0)
-	s.Sync.Logger.Infof("内存同步缓存中共有 %d 条数据，开始处理", s.memSyncCache.Count())
+	s.Sync.Logger.Infof("内存同步缓存中共有 %d 条数据，开始处理", s.syncCache.Count())
 	for {
 		var batch []models.SyncFile
-		err := db.Db.Where("sync_path_id = ?", s.SyncPathId).Offset(offset).Limit(limit).Order("id ASC").Find(&batch).Error
+		query := db.Db.Where("sync_path_id = ?", s.SyncPathId)
+		if s.IsPartial() {
+			// 局部同步只处理局部目录下的记录
+			query = s.partialSyncFileScope(query)
+		}
+		err := query.Offset(offset).Limit(limit).Order("id ASC").Find(&batch).Error
 		if err != nil {
 			s.Sync.Logger.Warnf("获取SyncFile表数据失败: %v", err)
 			return err
@@ -609,7 +767,11 @@ func (s *SyncStrm) handleTempTableDiff() error {
 			break
 		}
 		for _, file := range batch {
-			syncFileCache, _ := s.memSyncCache.GetByFileId(file.FileId)
+			if s.IsPartial() && !s.inPartialPaths(&file) {
+				// LIKE会匹配到_和%，这里再精确过滤一次
+				continue
+			}
+			syncFileCache, _ := s.syncCache.GetByFileId(file.FileId)
 			if syncFileCache == nil {
 				// 同步缓存中没有该文件，删除SyncFile记录
 				waitDeleteIds = append(waitDeleteIds, file.ID)
@@ -634,7 +796,7 @@ func (s *SyncStrm) handleTempTableDiff() error {
 					continue
 				}
 				// 然后从同步缓存中移除该记录
-				s.memSyncCache.DeleteByFileId(file.FileId)
+				s.syncCache.DeleteByFileId(file.FileId)
 				// s.Sync.Logger.Infof("SyncFile表数据 ID=%d 在同步缓存中存在，已更新并移除同步缓存记录", file.ID)
 			}
 		}
@@ -643,6 +805,11 @@ func (s *SyncStrm) handleTempTableDiff() error {
 		}
 		offset += limit
 	}
+	if len(waitDeleteIds) > 0 && s.deleteHeld {
+		// 本地文件没有删除，保留对应的记录，审批后下次同步再处理
+		s.Sync.Logger.Warnf("本次同步触发了删除保护，保留SyncFile表中 %d 条网盘不存在的记录", len(waitDeleteIds))
+		waitDeleteIds = nil
+	}
 	if len(waitDeleteIds) > 0 {
 		s.Sync.Logger.Infof("SyncFile表中共有 %d 条多余数据需要删除，开始分批删除，每批1000条", len(waitDeleteIds))
 		// 分批删除
@@ -674,23 +841,24 @@ func (s *SyncStrm) handleTempTableDiff() error {
 	waitDeleteIds = nil // 清空切片
 	// 然后插入同步缓存中剩余的新增数据
 	// 不会并发执行该方法，所以可以直接读取
-	fileItems := s.memSyncCache.GetAllFile()
-	s.Sync.Logger.Infof("内存同步缓存中共有 %d 条新增数据需要插入", len(fileItems))
-	if len(fileItems) == 0 {
+	remain := s.syncCache.Count()
+	s.Sync.Logger.Infof("同步缓存中共有 %d 条新增数据需要插入", remain)
+	if remain == 0 {
 		// s.Sync.Logger.Info("内存同步缓存数据全部处理完毕")
 		return nil
 	}
-	for _, file := range fileItems {
+	s.syncCache.Range(func(file *SyncFileCache) bool {
 		syncFile := file.GetSyncFile(s, s.Account.BaseUrl)
 		err := db.Db.Create(syncFile).Error
 		if err != nil {
 			s.Sync.Logger.Errorf("插入SyncFile表数据失败 FileID=%s: %v", file.GetFileId(), err)
-			continue
+			return true
 		}
 		// s.Sync.Logger.Infof("插入SyncFile表数据成功 FileID=%s", file.GetFileId())
 		// 插入成功后，从同步缓存中移除该记录
-		s.memSyncCache.DeleteByFileId(file.GetFileId())
-	}
-	s.Sync.Logger.Infof("已插入所有新增文件记录，内存同步缓存中剩余 %d 条数据", s.memSyncCache.Count())
+		s.syncCache.DeleteByFileId(file.GetFileId())
+		return true
+	})
+	s.Sync.Logger.Infof("已插入所有新增文件记录，同步缓存中剩余 %d 条数据", s.syncCache.Count())
 	return nil
 }
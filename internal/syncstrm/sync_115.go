@@ -13,8 +13,11 @@ import (
 // 路径处理器（从数据库中查询没有路径的文件的path_id，然后查询路径，然后更新到所有这个path_id的数据库记录中）
 
 type Sync115 struct {
-	existsPathes  sync.Map // file_id => path
-	excludePathId sync.Map // 排除的路径ID列表
+	existsPathes    sync.Map // file_id => path
+	excludePathId   sync.Map // 排除的路径ID列表
+	resolvedPathIds sync.Map // 从断点恢复的已补全的目录ID
+
+	checkpoint *sync115Checkpoint // 同步断点，为nil时不保存
 }
 
 func (s *SyncStrm) Start115Sync() {
@@ -23,14 +26,6 @@ func (s *SyncStrm) Start115Sync() {
 		existsPathes:  sync.Map{},
 		excludePathId: sync.Map{},
 	}
-	// 115 同步器
-	var existsPathesCount int64 = 0
-	// 先将已存在的路径全部读取到内存中（使用sync.Map)
-	if !s.TmpSyncPath && !s.FullSync {
-		// 非SyncPath同步和全量同步不能执行该操作
-		existsPathesCount = s.GetExistsPath()
-		s.Sync.Logger.Infof("已存在路径总数: %d", existsPathesCount)
-	}
 	// 查询文件总数
 	total, firstFileId, totalErr := s.SyncDriver.GetTotalFileCount(s.Context)
 	if totalErr != nil {
@@ -43,10 +38,25 @@ func (s *SyncStrm) Start115Sync() {
 		return
 	}
 	s.Sync.Logger.Infof("115 网盘文件总数: %d", total)
+	s.selectSyncCache(total)
 	s.TotalFile = total
 	s.Sync.Total = int(total)
 	// 更新回数据库
 	s.Sync.UpdateTotal()
+	// 中断或失败时保存断点，下次同步从断点继续
+	defer s.flush115Checkpoint(true)
+	if s.load115Checkpoint(total) {
+		s.start115Dispatchers(total)
+		return
+	}
+	// 115 同步器
+	var existsPathesCount int64 = 0
+	// 先将已存在的路径全部读取到内存中（使用sync.Map)
+	if !s.TmpSyncPath && !s.FullSync {
+		// 非SyncPath同步和全量同步不能执行该操作
+		existsPathesCount = s.GetExistsPath()
+		s.Sync.Logger.Infof("已存在路径总数: %d", existsPathesCount)
+	}
 	// 如果没有路径缓存或者全量同步，则先预取
 	if existsPathesCount == 0 || s.FullSync {
 		// 如果没有已存在的路径，则开始预取两层目录，入库，加入existsPathes
@@ -59,6 +69,12 @@ func (s *SyncStrm) Start115Sync() {
 		}
 		s.Sync.Logger.Infof("完成预取两层目录")
 	}
+	s.checkpoint115Dirs()
+	s.start115Dispatchers(total)
+}
+
+// 启动文件调度器和路径调度器
+func (s *SyncStrm) start115Dispatchers(total int64) {
 	// 启动文件调度器
 	s.Sync.Logger.Infof("启动115文件调度器，文件总数: %d", total)
 	filerr := s.Start115FileDispathcer(total)
@@ -116,9 +132,9 @@ func (s *SyncStrm) GetExistsPath() int64 {
 				IsVideo:    false,
 				IsMeta:     false,
 			}
-			s.memSyncCache.Insert(fileItem)
+			s.syncCache.Insert(fileItem)
 			fileItem.GetLocalFilePath(s.TargetPath, s.SourcePath) // 生成本地路径缓存
-			s.memSyncCache.Insert(fileItem)
+			s.syncCache.Insert(fileItem)
 		}
 		// 如果查询到的路径数量小于1000，说明已经查询完所有路径
 		if len(pathes) < limit {
@@ -0,0 +1,333 @@
+package syncstrm
+
+import (
+	"Q115-STRM/internal/db"
+	"Q115-STRM/internal/helpers"
+	"Q115-STRM/internal/models"
+	"Q115-STRM/internal/v115open"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+)
+
+const (
+	checkpointFlushInterval = 30 * time.Second // 断点写入数据库的间隔
+	checkpointMaxAge        = 24 * time.Hour   // 超过这个时间的断点不再使用
+	checkpointChunkSize     = 1000             // 目录缓存每条断点数据保存的数量
+	checkpointDriftPercent  = 1                // 网盘文件总数变化不超过这个百分比时继续使用断点
+	checkpointDriftMin      = 100              // 文件较少时允许变化的数量
+)
+
+// 补全目录路径的结果
+const (
+	checkpointPathUpdate  = "update"  // 补全路径
+	checkpointPathExclude = "exclude" // 目录被排除
+	checkpointPathSkip    = "skip"    // 目录名称不完整，跳过
+)
+
+// 断点数据，不同类型只使用其中一部分字段
+type checkpointData struct {
+	Files      []*SyncFileCache  `json:"files,omitempty"`
+	Paths      map[string]string `json:"paths,omitempty"` // existsPathes
+	ExcludeIds []string          `json:"exclude_ids,omitempty"`
+	PathId     string            `json:"path_id,omitempty"`
+	PathStr    string            `json:"path_str,omitempty"`
+	Action     string            `json:"action,omitempty"`
+}
+
+// 115同步断点，处理过程中的数据先放在内存中，定时写入数据库
+type sync115Checkpoint struct {
+	checkpoint *models.SyncCheckpoint
+	pending    []*models.SyncCheckpointItem
+	donePages  map[int]bool
+	recheck    bool // 文件总数有变化，按offset分页的内容可能偏移，已完成的页也要重新查询
+	lastFlush  time.Time
+	mutex      sync.Mutex
+}
+
+// 是否需要保存断点，只有同步目录的完整同步才保存
+func (s *SyncStrm) enable115Checkpoint() bool {
+	return !s.TmpSyncPath && !s.DryRun && !s.IsPartial()
+}
+
+// 同步配置的摘要，配置变化后文件的过滤结果和本地路径都会变化，断点不能继续使用
+func (s *SyncStrm) checkpointConfigHash() string {
+	data, _ := json.Marshal(s.Config)
+	return helpers.MD5Hash(fmt.Sprintf("%s|%s|%s", data, s.SourcePath, s.TargetPath))
+}
+
+// 断点允许的文件总数变化，网盘中新增或删除少量文件时不需要从头同步
+func checkpointAllowedDrift(total int64) int64 {
+	return max(total*checkpointDriftPercent/100, checkpointDriftMin)
+}
+
+// 加载同步目录的断点，断点有效时恢复内存缓存并返回true，否则创建新的断点
+func (s *SyncStrm) load115Checkpoint(total int64) bool {
+	if !s.enable115Checkpoint() {
+		return false
+	}
+	configHash := s.checkpointConfigHash()
+	old := models.GetSyncCheckpointByPathId(s.SyncPathId)
+	if old != nil {
+		reason := ""
+		switch {
+		case old.SourcePathId != s.SourcePathId:
+			reason = "同步目录ID已变化"
+		case absInt64(old.Total-total) > checkpointAllowedDrift(old.Total):
+			reason = fmt.Sprintf("网盘文件总数已变化 %d => %d", old.Total, total)
+		case old.FullSync != s.FullSync:
+			reason = "同步类型已变化"
+		case old.ConfigHash != configHash:
+			reason = "同步配置已变化"
+		case time.Since(time.Unix(old.UpdatedAt, 0)) > checkpointMaxAge:
+			reason = "断点已过期"
+		}
+		if reason == "" {
+			cp := &sync115Checkpoint{checkpoint: old, donePages: make(map[int]bool), recheck: old.Total != total, lastFlush: time.Now()}
+			err := s.restore115Checkpoint(cp)
+			if err == nil {
+				s.sync115.checkpoint = cp
+				cp.checkpoint.SyncId = s.Sync.ID
+				s.Sync.FileOffset = len(cp.donePages) * sync115PageLimit
+				db.Db.Model(s.Sync).Update("file_offset", s.Sync.FileOffset)
+				s.Sync.Logger.Infof("从断点继续同步，已完成文件页 %d 个，已补全目录 %d 个", len(cp.donePages), cp.checkpoint.ResolvedPaths)
+				if cp.recheck {
+					s.Sync.Logger.Infof("网盘文件总数已变化 %d => %d，重新查询已完成的文件页，只处理新出现的文件", old.Total, total)
+				}
+				return true
+			}
+			reason = fmt.Sprintf("恢复断点失败: %v", err)
+		}
+		s.Sync.Logger.Warnf("放弃同步断点: %s", reason)
+		// 恢复失败时内存缓存可能不完整，需要清空
+		s.syncCache.Clear()
+		s.sync115.existsPathes.Clear()
+		s.sync115.excludePathId.Clear()
+		s.sync115.resolvedPathIds.Clear()
+	}
+	checkpoint := &models.SyncCheckpoint{
+		SyncPathId:   s.SyncPathId,
+		SyncId:       s.Sync.ID,
+		SourcePathId: s.SourcePathId,
+		Total:        total,
+		FullSync:     s.FullSync,
+		ConfigHash:   configHash,
+	}
+	if err := models.CreateSyncCheckpoint(checkpoint); err != nil {
+		s.Sync.Logger.Errorf("创建同步断点失败，本次同步不保存断点: %v", err)
+		return false
+	}
+	s.sync115.checkpoint = &sync115Checkpoint{checkpoint: checkpoint, donePages: make(map[int]bool), lastFlush: time.Now()}
+	return false
+}
+
+// 按写入顺序恢复目录缓存、文件页和补全的路径
+func (s *SyncStrm) restore115Checkpoint(cp *sync115Checkpoint) error {
+	if !cp.checkpoint.DirsLoaded {
+		return fmt.Errorf("目录缓存未保存")
+	}
+	restore := func(kind models.SyncCheckpointKind, fn func(item *models.SyncCheckpointItem, data *checkpointData) error) error {
+		return cp.checkpoint.EachItem(kind, func(item *models.SyncCheckpointItem) error {
+			var data checkpointData
+			if err := json.Unmarshal([]byte(item.Data), &data); err != nil {
+				return err
+			}
+			return fn(item, &data)
+		})
+	}
+	err := restore(models.SyncCheckpointKindDirs, func(item *models.SyncCheckpointItem, data *checkpointData) error {
+		for id, path := range data.Paths {
+			s.sync115.existsPathes.Store(id, path)
+		}
+		for _, id := range data.ExcludeIds {
+			s.sync115.excludePathId.Store(id, true)
+		}
+		return s.syncCache.BatchInsert(data.Files)
+	})
+	if err != nil {
+		return err
+	}
+	err = restore(models.SyncCheckpointKindPage, func(item *models.SyncCheckpointItem, data *checkpointData) error {
+		cp.donePages[item.Page] = true
+		return s.syncCache.BatchInsert(data.Files)
+	})
+	if err != nil {
+		return err
+	}
+	err = restore(models.SyncCheckpointKindPath, func(item *models.SyncCheckpointItem, data *checkpointData) error {
+		s.sync115.resolvedPathIds.Store(data.PathId, true)
+		for _, dir := range data.Files {
+			if _, ok := s.sync115.existsPathes.Load(dir.FileId); !ok {
+				s.syncCache.Insert(dir)
+				s.sync115.existsPathes.Store(dir.FileId, dir.Path)
+			}
+		}
+		switch data.Action {
+		case checkpointPathUpdate:
+			return s.syncCache.UpdatePathByParentId(data.PathId, data.PathStr, s.TargetPath, s.SourcePath)
+		case checkpointPathExclude:
+			return s.syncCache.DeleteByParentId(data.PathId)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	// 已有完整路径的文件重新处理一遍，只检查本地文件，不会请求115接口
+	s.syncCache.Range(func(file *SyncFileCache) bool {
+		if file.FileType == v115open.TypeDir || file.LocalFilePath == "" {
+			return true
+		}
+		if !s.MatchPathRules(file) {
+			s.syncCache.DeleteByFileId(file.GetFileId())
+			return true
+		}
+		s.processNetFile(file)
+		return true
+	})
+	return nil
+}
+
+// 文件页是否已在断点中完成，需要重新查询时返回false
+func (s *SyncStrm) is115PageDone(page int) bool {
+	cp := s.sync115.checkpoint
+	if cp == nil {
+		return false
+	}
+	cp.mutex.Lock()
+	defer cp.mutex.Unlock()
+	return cp.donePages[page] && !cp.recheck
+}
+
+// 重新查询文件页时，已经从断点恢复到缓存中的文件不再处理
+func (s *SyncStrm) is115FileRestored(fileId string) bool {
+	cp := s.sync115.checkpoint
+	if cp == nil || !cp.recheck {
+		return false
+	}
+	_, err := s.syncCache.GetByFileId(fileId)
+	return err == nil
+}
+
+// 保存目录预取（或从数据库加载）完成后的目录缓存
+func (s *SyncStrm) checkpoint115Dirs() {
+	cp := s.sync115.checkpoint
+	if cp == nil {
+		return
+	}
+	var items []*models.SyncCheckpointItem
+	data := &checkpointData{Paths: make(map[string]string)}
+	size := 0
+	appendItem := func() {
+		if size == 0 {
+			return
+		}
+		items = append(items, s.makeCheckpointItem(models.SyncCheckpointKindDirs, 0, data))
+		data = &checkpointData{Paths: make(map[string]string)}
+		size = 0
+	}
+	s.syncCache.Range(func(file *SyncFileCache) bool {
+		data.Files = append(data.Files, file)
+		if size++; size >= checkpointChunkSize {
+			appendItem()
+		}
+		return true
+	})
+	s.sync115.existsPathes.Range(func(key, value any) bool {
+		data.Paths[key.(string)] = value.(string)
+		if size++; size >= checkpointChunkSize {
+			appendItem()
+		}
+		return true
+	})
+	s.sync115.excludePathId.Range(func(key, value any) bool {
+		data.ExcludeIds = append(data.ExcludeIds, key.(string))
+		if size++; size >= checkpointChunkSize {
+			appendItem()
+		}
+		return true
+	})
+	appendItem()
+
+	cp.mutex.Lock()
+	cp.pending = append(cp.pending, items...)
+	cp.checkpoint.DirsLoaded = true
+	cp.mutex.Unlock()
+	s.flush115Checkpoint(true)
+}
+
+// 记录处理完成的文件页和放入缓存的文件
+func (s *SyncStrm) checkpoint115Page(page int, files []*SyncFileCache) {
+	cp := s.sync115.checkpoint
+	if cp == nil {
+		return
+	}
+	item := s.makeCheckpointItem(models.SyncCheckpointKindPage, page, &checkpointData{Files: files})
+	cp.mutex.Lock()
+	cp.pending = append(cp.pending, item)
+	cp.donePages[page] = true
+	cp.checkpoint.DonePages = len(cp.donePages)
+	cp.mutex.Unlock()
+	s.flush115Checkpoint(false)
+}
+
+// 记录补全完成的目录，dirs是补全时新放入缓存的上级目录
+func (s *SyncStrm) checkpoint115Path(pathId, pathStr, action string, dirs []*SyncFileCache) {
+	cp := s.sync115.checkpoint
+	if cp == nil {
+		return
+	}
+	item := s.makeCheckpointItem(models.SyncCheckpointKindPath, 0, &checkpointData{Files: dirs, PathId: pathId, PathStr: pathStr, Action: action})
+	cp.mutex.Lock()
+	cp.pending = append(cp.pending, item)
+	cp.checkpoint.ResolvedPaths++
+	cp.mutex.Unlock()
+	s.flush115Checkpoint(false)
+}
+
+// 立即序列化，缓存中的文件后续还会被修改
+func (s *SyncStrm) makeCheckpointItem(kind models.SyncCheckpointKind, page int, data *checkpointData) *models.SyncCheckpointItem {
+	var bytes []byte
+	s.syncCache.WithReadLock(func() {
+		bytes, _ = json.Marshal(data)
+	})
+	return &models.SyncCheckpointItem{Kind: kind, Page: page, Data: string(bytes)}
+}
+
+// 将断点写入数据库，force为false时间隔checkpointFlushInterval写入一次
+func (s *SyncStrm) flush115Checkpoint(force bool) {
+	cp := s.sync115.checkpoint
+	if cp == nil {
+		return
+	}
+	cp.mutex.Lock()
+	defer cp.mutex.Unlock()
+	if !force && time.Since(cp.lastFlush) < checkpointFlushInterval {
+		return
+	}
+	if err := cp.checkpoint.Save(cp.pending); err != nil {
+		s.Sync.Logger.Errorf("保存同步断点失败: %v", err)
+		return
+	}
+	cp.pending = nil
+	cp.lastFlush = time.Now()
+}
+
+// 同步成功完成后删除断点
+func (s *SyncStrm) clear115Checkpoint() {
+	if s.sync115 == nil || s.sync115.checkpoint == nil {
+		return
+	}
+	if err := models.DeleteSyncCheckpointByPathId(s.SyncPathId); err != nil {
+		s.Sync.Logger.Errorf("删除同步断点失败: %v", err)
+	}
+	s.sync115.checkpoint = nil
+}
+
+func absInt64(n int64) int64 {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
@@ -19,18 +19,22 @@ func (s *SyncStrm) Start115PathDispathcer() error {
 	eg.SetLimit(int(s.PathWorkerMax))
 	// 先找到所有路径为空的目录ID，去重
 	parentIds := make(map[string]bool)
-	c := s.memSyncCache.Count()
+	c := s.syncCache.Count()
 	if c == 0 {
 		s.Sync.Logger.Infof("同步缓存中没有文件记录需要处理")
 		return nil
 	}
-	fileItems := s.memSyncCache.GetAllFile()
-	for _, item := range fileItems {
+	s.syncCache.Range(func(item *SyncFileCache) bool {
 		if item.FileType == v115open.TypeDir || item.Path != "" {
-			continue
+			return true
+		}
+		if _, ok := s.sync115.resolvedPathIds.Load(item.ParentId); ok {
+			// 断点中已处理过的目录
+			return true
 		}
 		parentIds[item.ParentId] = true
-	}
+		return true
+	})
 	// 将路径ID加入任务队列
 	s.Sync.Logger.Infof("开始路径补全任务，共有 %d 个需要补全路径的目录", len(parentIds))
 	for pathId := range parentIds {
@@ -69,6 +73,7 @@ func (s *SyncStrm) process115Path(ctx context.Context, pathId string) error {
 	}
 	if strings.Contains(detail.FileName, "***") {
 		s.Sync.Logger.Infof("目录ID %s 名称：%s 包含 *** 号，跳过", pathId, detail.FileName)
+		s.checkpoint115Path(pathId, "", checkpointPathSkip, nil)
 		return nil
 	}
 	// 把当前目录加入路径数组
@@ -81,6 +86,7 @@ func (s *SyncStrm) process115Path(ctx context.Context, pathId string) error {
 	lastRemotePathPart := filepath.Base(s.SourcePath)
 	isExclude := false
 	lastPathId := ""
+	newDirs := make([]*SyncFileCache, 0)
 pathloop:
 	for _, p := range detail.Paths {
 		if p.FileId == "" || p.FileId == "0" {
@@ -126,8 +132,9 @@ pathloop:
 		s.Sync.Logger.Infof("目录ID %s 名称：%s 路径：%s 本地路径：%s", pathId, detail.FileName, pathSyncFile.Path, pathSyncFile.LocalFilePath)
 		// 判断缓存中是否存在
 		if _, ok := s.sync115.existsPathes.Load(p.FileId); !ok {
-			s.memSyncCache.Insert(pathSyncFile)
+			s.syncCache.Insert(pathSyncFile)
 			s.sync115.existsPathes.Store(p.FileId, insertPath)
+			newDirs = append(newDirs, pathSyncFile)
 			s.Sync.Logger.Infof("目录ID %s 名称：%s 路径：%s 放入同步缓存成功", p.FileId, p.Name, insertPath)
 		}
 	}
@@ -136,14 +143,15 @@ pathloop:
 		// 从临时表中删除所有该目录下的文件
 		s.Sync.Logger.Infof("目录ID %s 名称：%s 被排除，从同步缓存中删除所有该目录下的文件", pathId, detail.FileName)
 
-		if err := s.memSyncCache.DeleteByParentId(pathId); err != nil {
+		if err := s.syncCache.DeleteByParentId(pathId); err != nil {
 			s.Sync.Logger.Errorf("删除同步缓存中记录失败: parent_id=%s, %v", pathId, err)
 		}
+		s.checkpoint115Path(pathId, "", checkpointPathExclude, newDirs)
 		return nil
 	}
 	pathName = detail.FileName
 	// 将完整路径更新到所有文件记录中
-	if err := s.memSyncCache.UpdatePathByParentId(pathId, pathStr, s.TargetPath, s.SourcePath); err != nil {
+	if err := s.syncCache.UpdatePathByParentId(pathId, pathStr, s.TargetPath, s.SourcePath); err != nil {
 		s.Sync.Logger.Errorf("更新临时表路径失败: parent_id=%s, path=%s, %v", pathId, pathStr, err)
 	} else {
 		s.Sync.Logger.Infof("目录ID %s 名称：%s 路径：%s 更新所有该目录下的文件路径成功", pathId, pathName, pathStr)
@@ -152,13 +160,14 @@ pathloop:
 	if updateErr := s.handelTempFileByPathId(pathId); updateErr != nil {
 		return updateErr
 	}
+	s.checkpoint115Path(pathId, pathStr, checkpointPathUpdate, newDirs)
 	return nil
 }
 
 // 更新路径下的所有文件并处理他们
 func (s *SyncStrm) handelTempFileByPathId(pathId string) error {
 	// 加锁
-	files, err := s.memSyncCache.GetByParentId(pathId)
+	files, err := s.syncCache.GetByParentId(pathId)
 	if err != nil {
 		s.Sync.Logger.Errorf("查询临时表文件失败: parent_id=%s, %v", pathId, err.Error)
 		return err
@@ -168,7 +177,18 @@ func (s *SyncStrm) handelTempFileByPathId(pathId string) error {
 		// 如果没有更多文件，退出
 		return nil
 	}
+	// 补全路径后不符合过滤规则的文件，处理完后从同步缓存中删除（files和缓存共用同一个切片，不能边遍历边删除）
+	var skipped []string
+	defer func() {
+		for _, fileId := range skipped {
+			s.syncCache.DeleteByFileId(fileId)
+		}
+	}()
 	for _, file := range files {
+		if file.FileType != v115open.TypeDir && !s.MatchPathRules(file) {
+			skipped = append(skipped, file.GetFileId())
+			continue
+		}
 		// 更新文件路径
 		file.GetLocalFilePath(s.TargetPath, s.SourcePath)
 		// s.Sync.Logger.Infof("文件ID %s 路径 %s 本地路径 %s 路径已补全，开始处理文件", file.FileId, file.Path, file.LocalFilePath)
@@ -9,10 +9,13 @@ import (
 	"golang.org/x/sync/errgroup"
 )
 
+// 115文件列表每页的数量，断点按页记录，不能随意修改
+const sync115PageLimit = 1150
+
 // 启动115文件处理调度器
 // 启动N个文件处理，将所有文件查询回来入临时表
 func (s *SyncStrm) Start115FileDispathcer(total int64) error {
-	limit := 1150
+	limit := sync115PageLimit
 	// 根据文件总数来计算需要多少个处理器
 	pageCount := total / int64(limit)
 	if total%int64(limit) != 0 {
@@ -32,6 +35,10 @@ func (s *SyncStrm) Start115FileDispathcer(total int64) error {
 	// 加入文件任务
 	for page := 0; page < int(pageCount); page++ {
 		page := page // 捕获循环变量
+		if s.is115PageDone(page) {
+			// 断点中已完成的页不再查询
+			continue
+		}
 		eg.Go(func() error {
 			return s.process115FilePage(ctx, page, limit)
 		})
@@ -60,8 +67,12 @@ func (s *SyncStrm) process115FilePage(ctx context.Context, page, limit int) erro
 		return err
 	}
 	// 处理查询到的文件
+	cachedFiles := make([]*SyncFileCache, 0, len(files))
 	for _, file := range files {
 		// s.Sync.Logger.Infof("文件 %s => %s 开始处理", file.FileId, file.FileName)
+		if s.is115FileRestored(file.FileId) {
+			continue
+		}
 		// 检查文件是否被排除
 		if s.IsExcludeName(file.FileName) {
 			s.Sync.Logger.Warnf("文件 %s 被排除", file.FileName)
@@ -100,13 +111,17 @@ func (s *SyncStrm) process115FilePage(ctx context.Context, page, limit int) erro
 				s.Sync.Logger.Warnf("文件 %s 的路径 %s 中有排除项，被排除", file.FileName, syncFile.LocalFilePath)
 				continue
 			}
+			if !s.MatchPathRules(&syncFile) {
+				continue
+			}
 		}
 		// 放入同步缓存
-		err := s.memSyncCache.Insert(&syncFile)
+		err := s.syncCache.Insert(&syncFile)
 		if err != nil {
 			s.Sync.Logger.Errorf("文件 %s => %s 插入同步缓存失败: %v", syncFile.FileId, syncFile.FileName, err)
 			return err
 		}
+		cachedFiles = append(cachedFiles, &syncFile)
 		// s.Sync.Logger.Infof("文件 %s => %s 插入同步缓存成功, 路径 %s", syncFile.FileId, syncFile.FileName, syncFile.LocalFilePath)
 		// 如果路径完整，直接处理文件
 		if syncFile.LocalFilePath != "" {
@@ -114,6 +129,7 @@ func (s *SyncStrm) process115FilePage(ctx context.Context, page, limit int) erro
 		}
 		// s.Sync.Logger.Infof("文件 %s => %s 处理完成", syncFile.FileId, syncFile.FileName)
 	}
+	s.checkpoint115Page(page, cachedFiles)
 	s.Sync.Logger.Infof("文件处理器处理完成offset=%d, limit=%d，共处理 %d 个文件", offset, limit, len(files))
 	return nil
 }
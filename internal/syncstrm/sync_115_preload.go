@@ -132,7 +132,7 @@ func (s *SyncStrm) Preload115Dirs(firstFileId string) error {
 				IsMeta:     false,
 			}
 			fileItem.GetLocalFilePath(s.TargetPath, s.SourcePath) // 生成本地路径缓存
-			s.memSyncCache.Insert(fileItem)
+			s.syncCache.Insert(fileItem)
 			// 更新缓存
 			if _, ok := s.sync115.existsPathes.Load(pathItem.PathId); !ok {
 				s.sync115.existsPathes.Store(pathItem.PathId, pathItem.Path)
@@ -101,7 +101,7 @@ mainloop:
 					syncFile.GetLocalFilePath(s.TargetPath, s.SourcePath) // 生成本地路径缓存
 				}
 				// 放入同步缓存
-				err := s.memSyncCache.Insert(&syncFile)
+				err := s.syncCache.Insert(&syncFile)
 				if err != nil {
 					s.Sync.Logger.Errorf("文件 %s => %s 插入同步缓存失败: %v", syncFile.FileId, syncFile.FileName, err)
 					return err
@@ -151,7 +151,7 @@ func (s *SyncStrm) LoadSyncFileToCache() {
 				IsVideo:       item.IsVideo,
 				IsMeta:        item.IsMeta,
 			}
-			err := s.memSyncCache.Insert(&syncFileCache)
+			err := s.syncCache.Insert(&syncFileCache)
 			if err != nil {
 				s.Sync.Logger.Errorf("文件 %s => %s 插入同步缓存失败: %v", syncFileCache.FileId, syncFileCache.FileName, err)
 				return
@@ -0,0 +1,43 @@
+package syncstrm
+
+import (
+	"Q115-STRM/internal/models"
+)
+
+// 变更记录达到这个数量时写入数据库
+const changeFlushSize = 500
+
+// 记录实际执行的文件操作，预览同步不记录
+func (s *SyncStrm) addChange(action models.SyncChangeAction, oldPath, newPath, remotePath, reason string) {
+	if s.DryRun || s.Sync == nil {
+		return
+	}
+	s.changeMu.Lock()
+	s.changes = append(s.changes, &models.SyncChange{
+		SyncId:     s.Sync.ID,
+		SyncPathId: s.SyncPathId,
+		Action:     action,
+		OldPath:    oldPath,
+		NewPath:    newPath,
+		RemotePath: remotePath,
+		Reason:     reason,
+	})
+	full := len(s.changes) >= changeFlushSize
+	s.changeMu.Unlock()
+	if full {
+		s.flushChanges(false)
+	}
+}
+
+// 将变更记录写入数据库，force为false时只在达到changeFlushSize时写入
+func (s *SyncStrm) flushChanges(force bool) {
+	s.changeMu.Lock()
+	defer s.changeMu.Unlock()
+	if len(s.changes) == 0 || (!force && len(s.changes) < changeFlushSize) {
+		return
+	}
+	if err := models.CreateSyncChanges(s.changes); err != nil {
+		s.Sync.Logger.Errorf("保存同步变更记录失败: %v", err)
+	}
+	s.changes = nil
+}
@@ -2,9 +2,11 @@ package syncstrm
 
 import (
 	"Q115-STRM/internal/models"
+	"fmt"
 	"path/filepath"
 	"slices"
 	"strings"
+	"time"
 )
 
 // 允许上传的目录，网盘中哪怕没有这些目录，也可以上传这些目录下的文件到网盘
@@ -34,6 +36,37 @@ type SyncStrmConfig struct {
 	StrmUrlNeedPath       int                           `json:"strm_url_need_path"`        // 视频文件URL是否需要路径，2为不需要，1为需要
 	DelEmptyLocalDir      bool                          `json:"del_empty_local_dir"`       // 是否删除本地空目录
 	CheckMetaMtime        int                           `json:"check_meta_mtime"`          // 是否检查元数据文件修改时间，默认0， 如果1，网盘新则下载，网盘旧就上传（UploadMeta=1时）
+	StrmTemplate          string                        `json:"strm_template"`             // STRM内容模板，为空则使用驱动的默认格式
+	DeleteMaxCount        int                           `json:"delete_max_count"`          // 删除保护：一次最多删除的本地文件数量，0为不限制
+	DeleteMaxPercent      int                           `json:"delete_max_percent"`        // 删除保护：一次最多删除的本地文件百分比，0为不限制
+	TrashRetentionDays    int                           `json:"trash_retention_days"`      // 回收站保留天数，0为不使用回收站，直接删除
+	IncludeRules          []string                      `json:"include_rules"`             // 包含规则，匹配相对路径，为空表示包含全部
+	ExcludeRules          []string                      `json:"exclude_rules"`             // 排除规则，匹配相对路径和其中的每一级目录
+	MaxVideoSize          int64                         `json:"max_video_size"`            // 视频文件最大大小，单位为MB，0为不限制
+	MaxAgeDays            int                           `json:"max_age_days"`              // 只同步最近N天修改的文件，0为不限制
+	StrmSignSecret        string                        `json:"-"`                         // STRM地址签名密钥，为空则不签名
+	StrmSignExpireDays    int                           `json:"strm_sign_expire_days"`     // STRM地址签名有效天数，0为不过期
+}
+
+// 编译包含和排除规则，规则在保存时已经检查过，这里出错只记录日志并忽略
+func (s *SyncStrm) compileRules() {
+	compile := func(name string, rules []string) []*models.SyncPathRule {
+		compiled := make([]*models.SyncPathRule, 0, len(rules))
+		for _, rule := range rules {
+			if strings.TrimSpace(rule) == "" {
+				continue
+			}
+			r, err := models.CompileSyncPathRule(rule)
+			if err != nil {
+				s.Sync.Logger.Errorf("%s %s 不正确，已忽略: %v", name, rule, err)
+				continue
+			}
+			compiled = append(compiled, r)
+		}
+		return compiled
+	}
+	s.includeRules = compile("包含规则", s.Config.IncludeRules)
+	s.excludeRules = compile("排除规则", s.Config.ExcludeRules)
 }
 
 func (s *SyncStrm) ValidFile(file *SyncFileCache) bool {
@@ -60,7 +93,87 @@ func (s *SyncStrm) ValidFile(file *SyncFileCache) bool {
 		// s.Sync.Logger.Infof("网盘元数据文件 %s 由于关闭了元数据下载所以不需要处理", file.FileName)
 		return false
 	}
-	return true
+	if reason := s.filterByAttr(file); reason != "" {
+		s.skipFile(file, reason)
+		return false
+	}
+	// 115的文件这时可能还没有路径，补全路径后再检查规则
+	return s.MatchPathRules(file)
+}
+
+// 按大小和修改时间过滤，返回过滤的原因
+func (s *SyncStrm) filterByAttr(file *SyncFileCache) string {
+	if file.IsVideo && s.Config.MaxVideoSize > 0 && file.FileSize > s.Config.MaxVideoSize*1024*1024 {
+		return fmt.Sprintf("视频文件大小 %d 超过最大限制 %dMB", file.FileSize, s.Config.MaxVideoSize)
+	}
+	if s.Config.MaxAgeDays > 0 && file.MTime > 0 && file.MTime < time.Now().AddDate(0, 0, -s.Config.MaxAgeDays).Unix() {
+		return fmt.Sprintf("修改时间 %s 早于 %d 天前", time.Unix(file.MTime, 0).Format("2006-01-02"), s.Config.MaxAgeDays)
+	}
+	return ""
+}
+
+// 文件相对于同步源路径的路径，路径还不完整时返回false
+func (s *SyncStrm) relativeRemotePath(file *SyncFileCache) (string, bool) {
+	fullPath := strings.TrimPrefix(file.GetFullRemotePath(), "/")
+	sourcePath := strings.Trim(filepath.ToSlash(s.SourcePath), "/")
+	if sourcePath == "" {
+		return fullPath, true
+	}
+	relPath, ok := strings.CutPrefix(fullPath, sourcePath+"/")
+	return relPath, ok
+}
+
+// 检查文件的相对路径是否符合包含和排除规则，不符合时记录原因
+// 路径还不完整时返回true，等补全路径后再次检查
+func (s *SyncStrm) MatchPathRules(file *SyncFileCache) bool {
+	if len(s.includeRules) == 0 && len(s.excludeRules) == 0 {
+		return true
+	}
+	relPath, ok := s.relativeRemotePath(file)
+	if !ok {
+		return true
+	}
+	if rule := s.matchExcludeRule(relPath); rule != nil {
+		s.skipFile(file, fmt.Sprintf("匹配排除规则 %s", rule.Raw))
+		return false
+	}
+	if len(s.includeRules) == 0 {
+		return true
+	}
+	for _, rule := range s.includeRules {
+		if rule.Match(relPath) {
+			return true
+		}
+	}
+	s.skipFile(file, "不匹配任何包含规则")
+	return false
+}
+
+// 检查路径和它的每一级上级目录是否匹配排除规则，返回匹配的规则
+func (s *SyncStrm) matchExcludeRule(relPath string) *models.SyncPathRule {
+	if len(s.excludeRules) == 0 || relPath == "" {
+		return nil
+	}
+	parts := strings.Split(relPath, "/")
+	for i := range parts {
+		p := strings.Join(parts[:i+1], "/")
+		for _, rule := range s.excludeRules {
+			if rule.Match(p) {
+				return rule
+			}
+		}
+	}
+	return nil
+}
+
+// 记录被过滤的文件，预览同步时显示在结果中
+func (s *SyncStrm) skipFile(file *SyncFileCache, reason string) {
+	remotePath := file.GetFullRemotePath()
+	if s.DryRun {
+		s.addPlanItem(models.SyncPlanActionSkip, file.LocalFilePath, remotePath, reason)
+		return
+	}
+	s.Sync.Logger.Infof("文件 %s 被过滤: %s", remotePath, reason)
 }
 func (s *SyncStrm) GetMinVideoSize() int64 {
 	if s.Config.MinVideoSize > 0 {
@@ -110,5 +223,14 @@ func (s *SyncStrm) IsExcludePath(path string) bool {
 			return true
 		}
 	}
-	return false
+	// 排除规则只匹配同步源路径下的部分
+	sourcePath := strings.Trim(filepath.ToSlash(s.SourcePath), "/")
+	relPath := strings.TrimPrefix(filepath.ToSlash(path), "/")
+	if sourcePath != "" {
+		var ok bool
+		if relPath, ok = strings.CutPrefix(relPath, sourcePath+"/"); !ok {
+			return false
+		}
+	}
+	return s.matchExcludeRule(relPath) != nil
 }
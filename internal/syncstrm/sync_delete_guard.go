@@ -0,0 +1,72 @@
+package syncstrm
+
+import (
+	"Q115-STRM/internal/models"
+	"fmt"
+)
+
+// 删除的文件少于这个数量时不检查百分比，避免文件很少的目录删除一两个文件就触发删除保护
+const deleteGuardPercentMinCount = 10
+
+// 记录网盘中已不存在、需要删除的本地文件，对比完成后统一处理
+func (s *SyncStrm) addDeleteCandidate(path string) {
+	s.deleteCandidates = append(s.deleteCandidates, path)
+}
+
+// 检查要删除的文件是否超过删除保护阈值，超过返回原因
+func (s *SyncStrm) checkDeleteGuard() string {
+	count := len(s.deleteCandidates)
+	if count == 0 || s.TmpSyncPath {
+		return ""
+	}
+	if s.Config.DeleteMaxCount > 0 && count > s.Config.DeleteMaxCount {
+		return fmt.Sprintf("要删除 %d 个文件，超过了最多删除 %d 个的限制", count, s.Config.DeleteMaxCount)
+	}
+	// 局部同步只统计了局部目录的本地文件，百分比没有意义
+	if s.IsPartial() || count < deleteGuardPercentMinCount {
+		return ""
+	}
+	if s.Config.DeleteMaxPercent > 0 && s.localFileTotal > 0 && count*100 > s.Config.DeleteMaxPercent*s.localFileTotal {
+		return fmt.Sprintf("要删除 %d 个文件，占本地文件 %d 个的 %d%%，超过了 %d%% 的限制", count, s.localFileTotal, count*100/s.localFileTotal, s.Config.DeleteMaxPercent)
+	}
+	return ""
+}
+
+// 删除收集到的本地文件，超过删除保护阈值时保存为等待审批的删除批次
+func (s *SyncStrm) applyLocalDeletes() {
+	reason := s.checkDeleteGuard()
+	candidates := s.deleteCandidates
+	s.deleteCandidates = nil
+	if reason != "" {
+		if !s.DryRun {
+			s.holdLocalDeletes(candidates, reason)
+			return
+		}
+		s.Sync.Logger.Warnf("[预览] 将触发删除保护，删除操作需要审批: %s", reason)
+	} else if !s.DryRun && !s.TmpSyncPath && !s.IsPartial() && s.Context.Err() == nil {
+		// 完整同步没有触发删除保护，之前等待审批的批次已经过时
+		if n, err := models.SupersedeSyncDeleteBatches(s.SyncPathId, s.TargetId); err != nil {
+			s.Sync.Logger.Errorf("标记过时的删除批次失败: %v", err)
+		} else if n > 0 {
+			s.Sync.Logger.Infof("本次同步没有触发删除保护，之前等待审批的删除批次已失效")
+		}
+	}
+	for _, path := range candidates {
+		s.RemoveFileAndCheckDirEmtry(path)
+	}
+}
+
+// 暂停删除，保存删除批次并发送通知
+func (s *SyncStrm) holdLocalDeletes(candidates []string, reason string) {
+	s.deleteHeld = true
+	s.Sync.Logger.Warnf("触发删除保护，本次同步不删除本地文件: %s", reason)
+	batch, isNew, err := models.CreateSyncDeleteBatch(s.SyncPathId, s.TargetId, s.Sync.ID, candidates, s.localFileTotal, reason)
+	if err != nil {
+		s.Sync.Logger.Errorf("保存删除批次失败: %v", err)
+		return
+	}
+	s.Sync.Logger.Warnf("要删除的 %d 个文件已保存到删除批次 %d，等待审批", len(candidates), batch.ID)
+	if isNew {
+		batch.Notify(s.targetLabel())
+	}
+}
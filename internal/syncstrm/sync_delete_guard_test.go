@@ -0,0 +1,49 @@
+package syncstrm
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestCheckDeleteGuard(t *testing.T) {
+	cases := []struct {
+		name       string
+		count      int
+		localTotal int
+		maxCount   int
+		maxPercent int
+		partial    bool
+		tmp        bool
+		blocked    bool
+	}{
+		{name: "没有要删除的文件", count: 0, localTotal: 100, maxCount: 1, maxPercent: 1},
+		{name: "未超过数量和百分比", count: 20, localTotal: 100, maxCount: 50, maxPercent: 30},
+		{name: "超过数量", count: 51, localTotal: 1000, maxCount: 50, maxPercent: 30, blocked: true},
+		{name: "等于数量不拦截", count: 50, localTotal: 1000, maxCount: 50},
+		{name: "数量不限制", count: 5000, localTotal: 100000, maxCount: 0},
+		{name: "超过百分比", count: 31, localTotal: 100, maxPercent: 30, blocked: true},
+		{name: "等于百分比不拦截", count: 30, localTotal: 100, maxPercent: 30},
+		{name: "百分比不限制", count: 90, localTotal: 100, maxPercent: 0},
+		{name: "少于最小数量不检查百分比", count: deleteGuardPercentMinCount - 1, localTotal: 10, maxPercent: 30},
+		{name: "达到最小数量检查百分比", count: deleteGuardPercentMinCount, localTotal: 10, maxPercent: 30, blocked: true},
+		{name: "局部同步不检查百分比", count: 50, localTotal: 60, maxPercent: 30, partial: true},
+		{name: "局部同步仍然检查数量", count: 51, localTotal: 60, maxCount: 50, partial: true, blocked: true},
+		{name: "临时同步不检查", count: 5000, localTotal: 5000, maxCount: 1, maxPercent: 1, tmp: true},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			s := &SyncStrm{TmpSyncPath: c.tmp, localFileTotal: c.localTotal}
+			s.Config.DeleteMaxCount = c.maxCount
+			s.Config.DeleteMaxPercent = c.maxPercent
+			if c.partial {
+				s.PartialPaths = []string{"/电影"}
+			}
+			for i := 0; i < c.count; i++ {
+				s.addDeleteCandidate(fmt.Sprintf("/strm/%d.strm", i))
+			}
+			if reason := s.checkDeleteGuard(); (reason != "") != c.blocked {
+				t.Errorf("checkDeleteGuard() = %q, blocked want %v", reason, c.blocked)
+			}
+		})
+	}
+}
@@ -127,9 +127,13 @@ func (s *SyncStrm) StartOther() {
 				continue
 			}
 			if fileItem.FileType == v115open.TypeDir {
+				if s.IsExcludePath(fileItem.GetFullRemotePath()) {
+					s.Sync.Logger.Warnf("目录 %s 匹配排除规则，跳过它和其下所有内容", fileItem.GetFullRemotePath())
+					continue
+				}
 				fileItem.GetLocalFilePath(s.TargetPath, s.SourcePath) // 生成本地路径缓存
 				// 放入临时表
-				s.memSyncCache.Insert(fileItem)
+				s.syncCache.Insert(fileItem)
 				// 继续处理该目录下的文件
 				subPath := pathQueueItem{
 					Path:   fileItem.GetFullRemotePath(),
@@ -145,7 +149,7 @@ func (s *SyncStrm) StartOther() {
 				fileItem.GetLocalFilePath(s.TargetPath, s.SourcePath) // 生成本地路径缓存
 				// s.Sync.Logger.Infof("发现文件: %s 文件名：%s", fileItem.LocalFilePath, fileItem.FileName)
 				// 放入临时表
-				s.memSyncCache.Insert(fileItem)
+				s.syncCache.Insert(fileItem)
 				// s.Sync.Logger.Infof("文件加入临时表: %s", fileItem.LocalFilePath)
 				// 处理文件
 				s.processNetFile(fileItem)
@@ -181,10 +185,19 @@ func (s *SyncStrm) StartOther() {
 		})
 	}
 
-	enqueue(pathQueueItem{
+	startItems := []pathQueueItem{{
 		Path:   s.SourcePath,
 		PathId: s.SourcePathId,
-	})
+	}}
+	if s.IsPartial() {
+		startItems = s.partialPathItems()
+	}
+	if len(startItems) == 0 {
+		closeQueue()
+	}
+	for _, item := range startItems {
+		enqueue(item)
+	}
 
 	if err := eg.Wait(); err != nil {
 		s.Sync.Logger.Errorf("路径处理失败: %v", err)
@@ -0,0 +1,99 @@
+package syncstrm
+
+import (
+	"Q115-STRM/internal/models"
+	"Q115-STRM/internal/v115open"
+	"path/filepath"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// 设置局部同步的目录，只处理这些目录和它们的子目录，目前只支持本地来源
+// 目录必须位于SourcePath下，传入SourcePath本身等同于全量同步
+func (s *SyncStrm) SetPartialPaths(paths []string) {
+	if s.Account.SourceType != models.SourceTypeLocal {
+		s.Sync.Logger.Warnf("来源 %s 不支持局部同步，执行完整同步", s.Account.SourceType)
+		return
+	}
+	sourcePath := filepath.ToSlash(filepath.Clean(s.SourcePath))
+	partialPaths := make([]string, 0, len(paths))
+	for _, p := range paths {
+		p = filepath.ToSlash(filepath.Clean(p))
+		if p == sourcePath {
+			// 包含根目录，直接完整同步
+			s.PartialPaths = nil
+			return
+		}
+		if !strings.HasPrefix(p, sourcePath+"/") {
+			s.Sync.Logger.Warnf("目录 %s 不在同步源 %s 下，忽略", p, sourcePath)
+			continue
+		}
+		partialPaths = append(partialPaths, p)
+	}
+	s.PartialPaths = partialPaths
+}
+
+// 是否是局部同步
+func (s *SyncStrm) IsPartial() bool {
+	return len(s.PartialPaths) > 0
+}
+
+// 局部同步的入口目录，同时把入口目录本身放入同步缓存，防止对比本地文件时被当成网盘不存在的目录
+func (s *SyncStrm) partialPathItems() []pathQueueItem {
+	items := make([]pathQueueItem, 0, len(s.PartialPaths))
+	for _, p := range s.PartialPaths {
+		pathId, err := s.SyncDriver.GetPathIdByPath(s.Context, p)
+		if err != nil {
+			s.Sync.Logger.Warnf("局部同步目录 %s 不存在，跳过: %v", p, err)
+			continue
+		}
+		dirCache := &SyncFileCache{
+			ParentId:   filepath.ToSlash(filepath.Dir(p)),
+			FileName:   filepath.Base(p),
+			FileType:   v115open.TypeDir,
+			IsVideo:    false,
+			IsMeta:     false,
+			SourceType: s.Account.SourceType,
+		}
+		dirCache.GetLocalFilePath(s.TargetPath, s.SourcePath)
+		s.syncCache.Insert(dirCache)
+		items = append(items, pathQueueItem{
+			Path:   p,
+			PathId: pathId,
+		})
+	}
+	return items
+}
+
+// 局部同步时需要对比的本地目录
+func (s *SyncStrm) partialLocalDirs() []string {
+	dirs := make([]string, 0, len(s.PartialPaths))
+	for _, p := range s.PartialPaths {
+		relPath, err := filepath.Rel(s.SourcePath, p)
+		if err != nil {
+			continue
+		}
+		dirs = append(dirs, filepath.ToSlash(filepath.Join(s.TargetPath, relPath)))
+	}
+	return dirs
+}
+
+// SyncFile记录是否属于局部同步的范围
+func (s *SyncStrm) inPartialPaths(file *models.SyncFile) bool {
+	for _, p := range s.PartialPaths {
+		if file.FileId == p || file.Path == p || strings.HasPrefix(file.Path, p+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// 局部同步时只查询局部目录下的SyncFile记录
+func (s *SyncStrm) partialSyncFileScope(tx *gorm.DB) *gorm.DB {
+	cond := tx.Session(&gorm.Session{NewDB: true}).Where("file_id IN ?", s.PartialPaths)
+	for _, p := range s.PartialPaths {
+		cond = cond.Or("path = ?", p).Or("path LIKE ?", p+"/%")
+	}
+	return tx.Where(cond)
+}
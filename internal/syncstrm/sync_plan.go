@@ -0,0 +1,60 @@
+package syncstrm
+
+import (
+	"Q115-STRM/internal/db"
+	"Q115-STRM/internal/models"
+	"os"
+)
+
+// 设置为预览同步，只记录将要执行的操作，不修改本地文件、网盘文件和同步数据
+func (s *SyncStrm) SetDryRun() {
+	s.DryRun = true
+	s.Sync.IsPlan = true
+	db.Db.Model(s.Sync).Update("is_plan", true)
+	s.Sync.Logger.Infof("本次为预览同步，不会修改任何文件")
+}
+
+// 记录预览同步将要执行的操作
+func (s *SyncStrm) addPlanItem(action models.SyncPlanAction, localPath, remotePath, reason string) *models.SyncPlanItem {
+	item := &models.SyncPlanItem{
+		SyncId:     s.Sync.ID,
+		SyncPathId: s.SyncPathId,
+		Action:     action,
+		LocalPath:  localPath,
+		RemotePath: remotePath,
+		Reason:     reason,
+	}
+	s.planMu.Lock()
+	s.planItems = append(s.planItems, item)
+	s.planMu.Unlock()
+	s.Sync.Logger.Infof("[预览] %s %s %s", action, localPath, reason)
+	return item
+}
+
+// 记录STRM的新建或重写，需要在写入前调用
+func (s *SyncStrm) addStrmPlanItem(sf *SyncFileCache, strmPath, content string) {
+	action := models.SyncPlanActionCreateStrm
+	reason := "本地STRM文件不存在"
+	oldContent := ""
+	if data, err := os.ReadFile(strmPath); err == nil {
+		action = models.SyncPlanActionUpdateStrm
+		reason = "STRM内容与当前配置不一致"
+		oldContent = string(data)
+	}
+	item := s.addPlanItem(action, strmPath, sf.GetFullRemotePath(), reason)
+	item.Content = content
+	item.OldContent = oldContent
+}
+
+// 保存预览同步记录的所有操作
+func (s *SyncStrm) savePlanItems() error {
+	s.planMu.Lock()
+	defer s.planMu.Unlock()
+	s.Sync.Logger.Infof("预览同步共有 %d 个将要执行的操作", len(s.planItems))
+	if err := models.CreateSyncPlanItems(s.planItems); err != nil {
+		s.Sync.Logger.Errorf("保存预览同步操作失败: %v", err)
+		return err
+	}
+	s.planItems = nil
+	return nil
+}
@@ -19,6 +19,7 @@ type StrmData struct {
 	Path     string `json:"path"`      // 115的路径
 	BaseUrl  string `json:"base_url"`  // 115的base_url
 	UrlPath  string `json:"url_path"`  // 115的url_path
+	Raw      string `json:"raw"`       // STRM文件的完整内容
 }
 
 // 生成strm文件
@@ -31,7 +32,20 @@ func (s *SyncStrm) ProcessStrmFile(sf *SyncFileCache) error {
 	}
 	// localFilePath := sf.GetLocalFilePath()
 	strmFullPath := sf.GetLocalFilePath(s.TargetPath, s.SourcePath)
-	strmContent := s.SyncDriver.MakeStrmContent(sf)
+	strmContent := s.MakeStrmContent(sf)
+	if s.DryRun {
+		s.addStrmPlanItem(sf, strmFullPath, strmContent)
+		atomic.AddInt64(&s.NewStrm, 1)
+		return nil
+	}
+	action := models.SyncChangeActionCreateStrm
+	reason := "本地STRM文件不存在"
+	oldPath := ""
+	if helpers.PathExists(strmFullPath) {
+		action = models.SyncChangeActionUpdateStrm
+		reason = "STRM内容与当前配置不一致"
+		oldPath = strmFullPath
+	}
 	// 写入文件并设置所有者
 	err := helpers.WriteFileWithPerm(strmFullPath, []byte(strmContent), 0777)
 	if err != nil {
@@ -48,6 +62,7 @@ func (s *SyncStrm) ProcessStrmFile(sf *SyncFileCache) error {
 	}
 	s.Sync.Logger.Infof("[生成strm] %s => %s", strmFullPath, strmContent)
 	atomic.AddInt64(&s.NewStrm, 1)
+	s.addChange(action, oldPath, strmFullPath, sf.GetFullRemotePath(), reason)
 	return nil
 }
 
@@ -58,12 +73,37 @@ func (s *SyncStrm) CompareStrm(st *SyncFileCache) int {
 		// s.Sync.Logger.Infof("文件 %s 不存在，需要生成strm文件", st.LocalFilePath)
 		return 0
 	}
-	if st.SourceType == models.SourceTypeLocal {
-		// s.Sync.Logger.Infof("文件 %s 来源本地，不需要生成strm文件", filepath.Join(st.Path, st.FileName))
+	// 读取strm文件内容
+	data, err := os.ReadFile(localFilePath)
+	if err != nil {
+		s.Sync.Logger.Errorf("读取strm文件失败: %v", err)
+		return 0
+	}
+	raw := strings.TrimSpace(string(data))
+	if helpers.StrmSignNeedsRefresh(raw, s.Config.StrmSignSecret, s.Config.StrmSignExpireDays, time.Now()) {
+		s.Sync.Logger.Warnf("文件 %s 的STRM签名需要更新: %s", filepath.Join(st.Path, st.FileName), raw)
+		return 0
+	}
+	if s.Config.StrmTemplate != "" || st.SourceType == models.SourceTypeLocal || st.SourceType == models.SourceTypeWebDAV || st.SourceType == models.SourceTypeS3 {
+		// 使用模板时直接比较完整内容，模板或者模板中的变量变化都需要重新生成
+		// 本地来源的内容就是文件路径，WebDAV和S3的内容只由地址和路径决定，同样直接比较
+		// 本地路径不是URL（可能含有%等字符），不能解析，按字符串比较
+		strmContent := s.MakeStrmContent(st)
+		if st.SourceType == models.SourceTypeLocal && s.Config.StrmTemplate == "" {
+			if raw != strmContent {
+				s.Sync.Logger.Warnf("文件 %s 的STRM内容与本地路径不一致, 本地: %s, 新: %s", filepath.Join(st.Path, st.FileName), raw, strmContent)
+				return 0
+			}
+			return 1
+		}
+		// 签名每次生成都不同，去掉签名后比较
+		if helpers.StripStrmSign(raw) != helpers.StripStrmSign(strmContent) {
+			s.Sync.Logger.Warnf("文件 %s 的STRM内容与当前配置生成的不一致, 本地: %s, 新: %s", filepath.Join(st.Path, st.FileName), raw, strmContent)
+			return 0
+		}
 		return 1
 	}
-	// 读取strm文件内容
-	strmData := s.LoadDataFromStrm(localFilePath)
+	strmData := s.LoadDataFromStrm(raw)
 	if strmData == nil {
 		return 0
 	}
@@ -83,7 +123,7 @@ func (s *SyncStrm) CompareStrm(st *SyncFileCache) int {
 			return 0
 		}
 	}
-	if st.SourceType == models.SourceType115 || st.SourceType == models.SourceTypeBaiduPan {
+	if st.SourceType == models.SourceType115 || st.SourceType == models.SourceTypeBaiduPan || st.SourceType == models.SourceType123 {
 		// 比较路径是否相同
 		if s.Config.StrmUrlNeedPath == 1 {
 			stPath := filepath.ToSlash(filepath.Join(st.Path, st.FileName))
@@ -125,19 +165,11 @@ func (s *SyncStrm) CompareStrm(st *SyncFileCache) int {
 	return 1
 }
 
-// 解析strm文件内url的参数并返回
-func (s *SyncStrm) LoadDataFromStrm(strmPath string) *StrmData {
-	if !helpers.PathExists(strmPath) {
-		// s.Sync.Logger.Errorf("strm文件不存在: %s", strmPath)
-		return nil
-	}
-	data, err := os.ReadFile(strmPath)
-	if err != nil {
-		s.Sync.Logger.Errorf("读取strm文件失败: %v", err)
-		return nil
-	}
+// 解析strm文件内url的参数并返回，raw是strm文件的内容
+func (s *SyncStrm) LoadDataFromStrm(raw string) *StrmData {
 	var strmData StrmData
-	strmUrl, urlErr := url.Parse(string(data))
+	strmData.Raw = raw
+	strmUrl, urlErr := url.Parse(strmData.Raw)
 	if urlErr != nil {
 		s.Sync.Logger.Errorf("解析strm文件失败: %v", urlErr)
 		return nil
@@ -0,0 +1,108 @@
+package syncstrm
+
+import (
+	"Q115-STRM/internal/models"
+	"Q115-STRM/internal/v115open"
+	"fmt"
+	"os"
+	"sync/atomic"
+)
+
+// 同步目录自己的本地路径处理完成后，使用同一份网盘文件列表依次生成每个目标目录
+func (s *SyncStrm) syncTargets() {
+	if len(s.targets) == 0 || s.TargetId != 0 {
+		return
+	}
+	syncPath := models.GetSyncPathById(s.SyncPathId)
+	if syncPath == nil {
+		return
+	}
+	// 驱动生成STRM内容时读取的是当前同步器的配置，处理完目标目录后还原
+	defer s.SyncDriver.SetSyncStrm(s)
+	for _, target := range s.targets {
+		select {
+		case <-s.Context.Done():
+			return
+		default:
+		}
+		child := s.newTargetSyncStrm(syncPath, target)
+		s.Sync.Logger.Infof("开始生成目标目录 %s：%s", target.Name, child.GetLocalBaseDir())
+		if err := child.runTarget(); err != nil {
+			s.Sync.Logger.Errorf("生成目标目录 %s 失败: %v", target.Name, err)
+		}
+		// 合并计数和预览操作
+		atomic.AddInt64(&s.NewMeta, atomic.LoadInt64(&child.NewMeta))
+		atomic.AddInt64(&s.NewStrm, atomic.LoadInt64(&child.NewStrm))
+		s.planMu.Lock()
+		s.planItems = append(s.planItems, child.planItems...)
+		s.planMu.Unlock()
+		child.flushChanges(true)
+		child.syncCache.Close()
+	}
+}
+
+// 创建目标目录的同步器，共享同步记录、驱动和过滤规则，使用目标目录自己的STRM和元数据设置
+func (s *SyncStrm) newTargetSyncStrm(syncPath *models.SyncPath, target *models.SyncPathTarget) *SyncStrm {
+	config := s.Config
+	config.StrmBaseUrl = target.GetStrmBaseUrl(syncPath)
+	config.StrmTemplate = target.GetStrmTemplate(syncPath)
+	config.StrmUrlNeedPath = target.GetAddPath(syncPath)
+	config.EnableDownloadMeta = int64(target.GetDownloadMeta(syncPath))
+	config.NetNotFoundFileAction = models.SyncTreeItemMetaAction(target.GetUploadMeta(syncPath))
+	config.DelEmptyLocalDir = target.GetDeleteDir(syncPath) == 1
+	child := &SyncStrm{
+		SyncDriver:    s.SyncDriver,
+		Account:       s.Account,
+		Sync:          s.Sync,
+		SourcePath:    s.SourcePath,
+		SourcePathId:  s.SourcePathId,
+		LastSyncAt:    s.LastSyncAt,
+		TmpSyncPath:   s.TmpSyncPath,
+		TargetPath:    target.LocalPath,
+		Config:        config,
+		Context:       s.Context,
+		Cancel:        s.Cancel,
+		FullSync:      s.FullSync,
+		PathWorkerMax: s.PathWorkerMax,
+		PathErrChan:   s.PathErrChan,
+		SyncPathId:    s.SyncPathId,
+		PartialPaths:  s.PartialPaths,
+		DryRun:        s.DryRun,
+		includeRules:  s.includeRules,
+		excludeRules:  s.excludeRules,
+		TargetId:      target.ID,
+		TargetName:    target.Name,
+	}
+	child.syncCache = s.syncCache.CloneForTarget(child.TargetPath, child.SourcePath)
+	return child
+}
+
+// 生成目标目录的STRM和元数据，然后对比删除多余的本地文件
+func (s *SyncStrm) runTarget() error {
+	localBaseDir := s.GetLocalBaseDir()
+	if !s.DryRun && !s.checkPathExists(localBaseDir) {
+		if err := os.MkdirAll(localBaseDir, 0777); err != nil {
+			return fmt.Errorf("创建本地根目录失败: %s %v", localBaseDir, err)
+		}
+	}
+	s.SyncDriver.SetSyncStrm(s)
+	s.syncCache.Range(func(file *SyncFileCache) bool {
+		if file.FileType == v115open.TypeDir || file.LocalFilePath == "" {
+			return true
+		}
+		if err := s.processNetFile(file); err != nil {
+			s.Sync.Logger.Errorf("目标目录处理文件 %s 失败: %v", file.LocalFilePath, err)
+		}
+		return true
+	})
+	s.AddDownloadTaskFromMemCache()
+	return s.compareLocalFilesWithTempTable()
+}
+
+// 删除保护通知中显示的目录
+func (s *SyncStrm) targetLabel() string {
+	if s.TargetId == 0 {
+		return s.SourcePath
+	}
+	return fmt.Sprintf("%s（目标目录 %s）", s.SourcePath, s.TargetName)
+}
@@ -0,0 +1,62 @@
+package syncstrm
+
+import (
+	"Q115-STRM/internal/helpers"
+	"Q115-STRM/internal/models"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// 生成STRM文件内容，配置了模板则使用模板，否则使用驱动的默认格式
+// 同步路径启用了签名时，给指向QMS直链接口的地址加上签名
+func (s *SyncStrm) MakeStrmContent(sf *SyncFileCache) string {
+	var content string
+	if s.Config.StrmTemplate == "" {
+		content = s.SyncDriver.MakeStrmContent(sf)
+	} else {
+		content = s.renderStrmTemplate(sf)
+	}
+	return s.signStrmContent(content)
+}
+
+func (s *SyncStrm) signStrmContent(content string) string {
+	if s.Config.StrmSignSecret == "" {
+		return content
+	}
+	expireAt := helpers.StrmSignExpireAt(s.Config.StrmSignExpireDays, time.Now())
+	return helpers.SignStrmUrl(content, s.SyncPathId, s.Config.StrmSignSecret, expireAt)
+}
+
+// 替换模板中的变量，变量列表见models.StrmTemplateVars
+// 在?后面（查询参数中）的{path}和{fileid}按查询参数编码，避免文件名中的&、#、+等字符截断参数
+func (s *SyncStrm) renderStrmTemplate(sf *SyncFileCache) string {
+	baseUrl := strings.TrimSuffix(s.Config.StrmBaseUrl, "/")
+	if baseUrl == "" && s.Account.SourceType == models.SourceTypeOpenList {
+		// OpenList默认直接访问OpenList服务
+		baseUrl = strings.TrimSuffix(s.Account.BaseUrl, "/")
+	}
+	fullPath := sf.GetFullRemotePath()
+	fileId := sf.GetFileId()
+	vars := func(path, fileId string) *strings.Replacer {
+		return strings.NewReplacer(
+			"{base_url}", baseUrl,
+			"{pickcode}", sf.PickCode,
+			"{fileid}", fileId,
+			"{userid}", s.Account.UserId,
+			"{path}", path,
+			"{path_urlencode}", s.GetRemoteFilePathUrlEncode(fullPath),
+			"{ext}", filepath.Ext(sf.FileName),
+			"{sign}", sf.OpenlistSign,
+		)
+	}
+	tpl := s.Config.StrmTemplate
+	content := ""
+	if before, query, ok := strings.Cut(tpl, "?"); ok {
+		content = vars(fullPath, fileId).Replace(before) + "?" + vars(url.QueryEscape(fullPath), url.QueryEscape(fileId)).Replace(query)
+	} else {
+		content = vars(fullPath, fileId).Replace(tpl)
+	}
+	return strings.TrimSpace(content)
+}
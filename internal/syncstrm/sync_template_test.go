@@ -0,0 +1,31 @@
+package syncstrm
+
+import (
+	"Q115-STRM/internal/models"
+	"testing"
+)
+
+func TestRenderStrmTemplate(t *testing.T) {
+	s := &SyncStrm{Account: &models.Account{SourceType: models.SourceTypeWebDAV, UserId: "1"}}
+	s.Config.StrmBaseUrl = "http://qms:12333/"
+	sf := &SyncFileCache{
+		FileId:     "/电影/A&B #1+.mkv",
+		ParentId:   "/电影",
+		FileName:   "A&B #1+.mkv",
+		SourceType: models.SourceTypeWebDAV,
+	}
+	cases := []struct {
+		tpl  string
+		want string
+	}{
+		{"{base_url}/webdav/?path={path}&id={fileid}", "http://qms:12333/webdav/?path=%2F%E7%94%B5%E5%BD%B1%2FA%26B+%231%2B.mkv&id=%2F%E7%94%B5%E5%BD%B1%2FA%26B+%231%2B.mkv"},
+		{"{base_url}/d{path}", "http://qms:12333/d/电影/A&B #1+.mkv"},
+		{"{base_url}/d?path={path_urlencode}{ext}", "http://qms:12333/d?path=%2F电影%2FA%26B%20%231%2B.mkv.mkv"},
+	}
+	for _, c := range cases {
+		s.Config.StrmTemplate = c.tpl
+		if got := s.renderStrmTemplate(sf); got != c.want {
+			t.Errorf("renderStrmTemplate(%q) = %q, want %q", c.tpl, got, c.want)
+		}
+	}
+}
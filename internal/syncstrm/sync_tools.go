@@ -45,12 +45,38 @@ func (s *SyncStrm) MakeFullLocalPath(file *models.SyncFile) string {
 	return fullPath
 }
 
+// 是否将删除的文件移动到回收站，临时同步没有同步目录，直接删除
+func (s *SyncStrm) useTrash() bool {
+	return !s.TmpSyncPath && s.Config.TrashRetentionDays > 0
+}
+
 func (s *SyncStrm) RemoveFileAndCheckDirEmtry(filePath string) error {
-	// 删除文件
-	if err := os.Remove(filePath); err != nil {
+	return s.removeLocalFile(filePath, "网盘中不存在对应的文件")
+}
+
+// 删除本地文件，reason记录到预览和变更记录中
+func (s *SyncStrm) removeLocalFile(filePath, reason string) error {
+	if s.DryRun {
+		if _, ok := s.planRenamed.Load(filepath.ToSlash(filePath)); ok {
+			return nil
+		}
+		s.addPlanItem(models.SyncPlanActionDeleteLocal, filePath, "", reason)
+		return nil
+	}
+	if s.useTrash() {
+		// 移动到回收站
+		item, err := models.MoveToSyncTrash(s.SyncPathId, s.GetLocalBaseDir(), filePath)
+		if err != nil {
+			return err
+		}
+		s.Sync.Logger.Infof("文件已移动到回收站: %s", filePath)
+		s.addChange(models.SyncChangeActionDeleteLocal, filePath, item.TrashPath, "", reason+"，已移动到回收站")
+	} else if err := os.Remove(filePath); err != nil {
+		// 删除文件
 		return fmt.Errorf("删除文件失败: %w", err)
 	} else {
 		s.Sync.Logger.Infof("删除文件成功: %s", filePath)
+		s.addChange(models.SyncChangeActionDeleteLocal, filePath, "", "", reason)
 	}
 	if !s.Config.DelEmptyLocalDir {
 		return nil
@@ -63,13 +89,13 @@ func (s *SyncStrm) RemoveFileAndCheckDirEmtry(filePath string) error {
 		} else {
 			s.Sync.Logger.Infof("删除空目录成功: %s", dir)
 			// 删除网盘目录
-			file, err := s.memSyncCache.GetByLocalPath(dir)
+			file, err := s.syncCache.GetByLocalPath(dir)
 			if err != nil {
 				s.Sync.Logger.Warnf("查询空目录对应的网盘记录失败:  %s %s", filePath, err.Error())
 				return nil
 			}
 			// 从同步缓存中删除
-			err = s.memSyncCache.DeleteByFileId(file.GetFileId())
+			err = s.syncCache.DeleteByFileId(file.GetFileId())
 			if err != nil {
 				s.Sync.Logger.Warnf("删除空目录对应的网盘记录失败:  %s %s", file.GetFileId(), err.Error())
 				return nil
@@ -60,7 +60,7 @@ func (c *OpenClient) SetAuthToken(token string, refreshToken string) {
 	c.RefreshTokenStr = refreshToken
 }
 
-// doRequest 带重试的请求方法（使用全局队列）
+// doRequest 带重试的请求方法（使用账号的请求队列）
 func (c *OpenClient) doRequest(url string, req *resty.Request, options *RequestConfig) (*resty.Response, *RespBase[json.RawMessage], error) {
 	// 设置超时时间
 	req.SetTimeout(options.Timeout)
@@ -71,18 +71,18 @@ func (c *OpenClient) doRequest(url string, req *resty.Request, options *RequestC
 
 	var lastErr error
 	for attempt := 0; attempt <= options.MaxRetries; attempt++ {
-		// 使用全局队列执行器处理请求
-		executor := GetGlobalExecutor()
+		// 使用账号的队列执行器处理请求
+		executor := GetExecutor(c.AccountId)
 		respChan := make(chan *RequestResponse, 1)
 
 		queuedReq := &QueuedRequest{
-			URL:             url,
-			Method:          req.Method,
-			Request:         req,
-			BypassRateLimit: options.BypassRateLimit,
-			ResponseChan:    respChan,
-			CreatedAt:       time.Now(),
-			Ctx:             context.Background(),
+			URL:          url,
+			Method:       req.Method,
+			Request:      req,
+			Priority:     options.priority(context.Background()),
+			ResponseChan: respChan,
+			CreatedAt:    time.Now(),
+			Ctx:          context.Background(),
 		}
 
 		// 将请求加入队列
@@ -153,7 +153,7 @@ func (c *OpenClient) doRequest(url string, req *resty.Request, options *RequestC
 	return nil, nil, lastErr
 }
 
-// doAuthRequest 带重试的认证请求方法（使用全局队列）
+// doAuthRequest 带重试的认证请求方法（使用账号的请求队列）
 func (c *OpenClient) doAuthRequest(ctx context.Context, url string, req *resty.Request, options *RequestConfig, respData any) (*resty.Response, []byte, error) {
 	if c.AccessToken == "" {
 		// 没有token，直接报错
@@ -168,18 +168,18 @@ func (c *OpenClient) doAuthRequest(ctx context.Context, url string, req *resty.R
 
 	var lastErr error
 	for attempt := 0; attempt <= options.MaxRetries; attempt++ {
-		// 使用全局队列执行器处理请求
-		executor := GetGlobalExecutor()
+		// 使用账号的队列执行器处理请求
+		executor := GetExecutor(c.AccountId)
 		respChan := make(chan *RequestResponse, 1)
 
 		queuedReq := &QueuedRequest{
-			URL:             url,
-			Method:          req.Method,
-			Request:         req,
-			BypassRateLimit: options.BypassRateLimit,
-			ResponseChan:    respChan,
-			CreatedAt:       time.Now(),
-			Ctx:             ctx,
+			URL:          url,
+			Method:       req.Method,
+			Request:      req,
+			Priority:     options.priority(ctx),
+			ResponseChan: respChan,
+			CreatedAt:    time.Now(),
+			Ctx:          ctx,
 		}
 
 		// 将请求加入队列
@@ -215,6 +215,10 @@ func (c *OpenClient) doAuthRequest(ctx context.Context, url string, req *resty.R
 		}
 
 		// 如果是限流错误，不重试
+		if queueResp.IsThrottled && options.BypassRateLimit {
+			// 播放请求不等待，直接返回让调用方切换到其他账号
+			return queueResp.Response, queueResp.RespBytes, lastErr
+		}
 		if queueResp.IsThrottled {
 			helpers.V115Log.Warn("检测到限流，等待1分钟后重试")
 			// 等待1分钟后重试
@@ -3,12 +3,51 @@ package v115open
 import (
 	"context"
 	"encoding/json"
-	"sync"
 	"time"
 
 	"resty.dev/v3"
 )
 
+// Priority 请求优先级，数值越小越优先
+type Priority int
+
+const (
+	PRIORITY_PLAYBACK Priority = iota // 播放（获取下载链接），使用预留容量
+	PRIORITY_BROWSE                   // 界面浏览，没有指定优先级的请求也使用这个
+	PRIORITY_SCRAPE                   // 刮削整理
+	PRIORITY_SYNC                     // 后台同步、上传下载
+	priorityCount
+)
+
+var priorityNames = [priorityCount]string{"playback", "browse", "scrape", "sync"}
+
+func (p Priority) String() string {
+	if p < 0 || p >= priorityCount {
+		return "unknown"
+	}
+	return priorityNames[p]
+}
+
+type priorityKey struct{}
+
+// WithPriority 设置上下文中115请求的优先级，使用这个上下文的请求都会进入对应的队列
+func WithPriority(ctx context.Context, p Priority) context.Context {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return context.WithValue(ctx, priorityKey{}, p)
+}
+
+// PriorityFromContext 获取上下文中的请求优先级，没有设置时为界面浏览
+func PriorityFromContext(ctx context.Context) Priority {
+	if ctx != nil {
+		if p, ok := ctx.Value(priorityKey{}).(Priority); ok && p >= 0 && p < priorityCount {
+			return p
+		}
+	}
+	return PRIORITY_BROWSE
+}
+
 // QueuedRequest 队列中的请求
 type QueuedRequest struct {
 	// 请求的URL
@@ -17,8 +56,8 @@ type QueuedRequest struct {
 	Method string
 	// Resty请求对象
 	Request *resty.Request
-	// 是否绕过速率限制（播放请求等）
-	BypassRateLimit bool
+	// 请求优先级
+	Priority Priority
 	// 响应数据接收通道
 	ResponseChan chan *RequestResponse
 	// 创建时间
@@ -38,161 +77,3 @@ type RequestResponse struct {
 	// 是否是限流响应
 	IsThrottled bool
 }
-
-// RequestStats 请求统计数据
-type RequestStats struct {
-	sync.RWMutex
-	// 请求计数
-	TotalRequests int64 // 总请求数
-	// 限流相关
-	ThrottledCount      int64         // 限流次数
-	ThrottledStartTime  time.Time     // 最后一次限流开始时间
-	ThrottledWaitTime   time.Duration // 本次限流等待时间
-	LastThrottleTime    time.Time     // 最后一次触发限流的时间
-	ThrottleRecoverTime time.Time     // 限流恢复时间
-	// 响应时间统计
-	ResponseTimes []int64 // 最近响应时间记录（毫秒）
-	MaxRecords    int     // 最多保留记录数
-	// 时间窗口统计
-	RequestLog []RequestLogEntry // 请求日志，用于统计qps/qpm/qph
-}
-
-// RequestLogEntry 请求日志条目
-type RequestLogEntry struct {
-	Timestamp   time.Time // 请求时间
-	Duration    int64     // 响应时间（毫秒）
-	IsThrottled bool      // 是否限流
-	URL         string    // 请求URL
-	Method      string    // 请求方法
-}
-
-// NewRequestStats 创建新的请求统计
-func NewRequestStats(maxRecords int) *RequestStats {
-	if maxRecords <= 0 {
-		maxRecords = 10000
-	}
-	return &RequestStats{
-		ResponseTimes: make([]int64, 0, maxRecords),
-		MaxRecords:    maxRecords,
-		RequestLog:    make([]RequestLogEntry, 0, maxRecords),
-	}
-}
-
-// RecordRequest 记录一个请求
-func (s *RequestStats) RecordRequest(entry RequestLogEntry) {
-	s.Lock()
-	defer s.Unlock()
-
-	s.TotalRequests++
-
-	// 记录响应时间
-	if len(s.ResponseTimes) >= s.MaxRecords {
-		// 移除最老的响应时间
-		s.ResponseTimes = s.ResponseTimes[1:]
-	}
-	s.ResponseTimes = append(s.ResponseTimes, entry.Duration)
-
-	// 记录请求日志
-	if len(s.RequestLog) >= s.MaxRecords {
-		// 移除最老的日志条目
-		s.RequestLog = s.RequestLog[1:]
-	}
-	s.RequestLog = append(s.RequestLog, entry)
-
-	// 记录限流统计
-	if entry.IsThrottled {
-		s.ThrottledCount++
-		s.LastThrottleTime = entry.Timestamp
-	}
-}
-
-// RecordThrottle 记录限流事件
-func (s *RequestStats) RecordThrottle(startTime time.Time, waitTime time.Duration) {
-	s.Lock()
-	defer s.Unlock()
-
-	s.ThrottledStartTime = startTime
-	s.ThrottledWaitTime = waitTime
-	s.ThrottleRecoverTime = time.Now()
-}
-
-// GetStats 获取统计数据
-func (s *RequestStats) GetStats(duration time.Duration) *StatsSnapshot {
-	s.RLock()
-	defer s.RUnlock()
-
-	now := time.Now()
-	cutoff := now.Add(-duration)
-
-	var (
-		qpsCount         int64 // 最近1秒的请求数
-		qpmCount         int64 // 最近1分钟的请求数
-		qphCount         int64 // 最近1小时的请求数
-		windowCount      int64 // 指定时间窗口内的请求数
-		throttledCount   int64 // 限流请求数
-		totalDuration    int64 // 总耗时
-		avgResponseTime  int64 // 平均响应时间
-		lastThrottleTime *time.Time
-	)
-
-	// 遍历请求日志，统计
-	for _, entry := range s.RequestLog {
-		// 统计指定时间窗口内的请求
-		if entry.Timestamp.After(cutoff) {
-			windowCount++
-			totalDuration += entry.Duration
-			if entry.IsThrottled {
-				throttledCount++
-			}
-		}
-
-		// 分别统计不同时间窗口
-		// 最近1秒
-		if entry.Timestamp.After(now.Add(-time.Second)) {
-			qpsCount++
-		}
-		// 最近1分钟
-		if entry.Timestamp.After(now.Add(-time.Minute)) {
-			qpmCount++
-		}
-		// 最近1小时
-		if entry.Timestamp.After(now.Add(-time.Hour)) {
-			qphCount++
-		}
-	}
-
-	// 计算平均响应时间
-	if windowCount > 0 {
-		avgResponseTime = totalDuration / windowCount
-	}
-
-	// 获取最后一次限流时间
-	if !s.LastThrottleTime.IsZero() {
-		lastThrottleTime = &s.LastThrottleTime
-	}
-
-	return &StatsSnapshot{
-		TotalRequests:       s.TotalRequests,
-		QPSCount:            qpsCount,
-		QPMCount:            qpmCount,
-		QPHCount:            qphCount,
-		ThrottledCount:      throttledCount,
-		AvgResponseTime:     avgResponseTime,
-		LastThrottleTime:    lastThrottleTime,
-		ThrottledWaitTime:   s.ThrottledWaitTime,
-		ThrottleRecoverTime: &s.ThrottleRecoverTime,
-	}
-}
-
-// StatsSnapshot 统计数据快照
-type StatsSnapshot struct {
-	TotalRequests       int64         // 总请求数
-	QPSCount            int64         // 最近1秒请求数
-	QPMCount            int64         // 最近1分钟请求数
-	QPHCount            int64         // 最近1小时请求数
-	ThrottledCount      int64         // 限流总次数
-	AvgResponseTime     int64         // 平均响应时间（毫秒）
-	LastThrottleTime    *time.Time    // 最后一次限流时间
-	ThrottledWaitTime   time.Duration // 本次限流等待时间
-	ThrottleRecoverTime *time.Time    // 限流恢复时间
-}
@@ -2,134 +2,166 @@ package v115open
 
 import (
 	"Q115-STRM/internal/helpers"
+	"Q115-STRM/internal/scheduler"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
+	"sort"
 	"sync"
 	"time"
 
-	"golang.org/x/time/rate"
 	"resty.dev/v3"
 )
 
-// RequestStatSaver 请求统计保存回调函数类型
-type RequestStatSaver func(requestTime int64, url, method string, duration int64, isThrottled bool)
+// 预留给播放请求的每秒请求数，以及只处理播放请求的Worker数量
+const (
+	PLAYBACK_RESERVED_QPS = 1
+	PLAYBACK_WORKERS      = 2
+)
 
-// QueueExecutor 请求队列执行器，负责管理所有API请求的队列和执行
+// QueueExecutor 请求队列执行器，每个115账号一个，负责管理账号API请求的队列和执行
+// 请求按优先级分队列，Worker先等到速率限制的令牌再取优先级最高的请求，
+// 播放请求使用预留容量和单独的Worker，不会排在同步请求后面
+// 速率限制、限流恢复和统计由scheduler.Executor负责
 type QueueExecutor struct {
-	sync.RWMutex
-	// 请求队列通道，缓冲100
-	requestQueue chan *QueuedRequest
-	// Worker数量
+	sync.Mutex
+	// 所属115账号
+	AccountId uint
+	// 有新请求或者停止时通知Worker
+	cond *sync.Cond
+	// 各优先级的请求队列
+	lanes [priorityCount][]*QueuedRequest
+	// 各优先级的统计
+	laneStats [priorityCount]laneCounter
+	// Worker数量（不包含播放Worker）
 	workerCount int
 	// 是否正在运行
 	running bool
-	// 停止通道
-	stopChan chan struct{}
-	// Worker停止信号
-	workerStopChans []chan struct{}
-	// 速率限制器（支持qps/qpm/qph）
-	qpsLimiter *rate.Limiter // 每秒请求数限制
-	qpmLimiter *rate.Limiter // 每分钟请求数限制
-	qphLimiter *rate.Limiter // 每小时请求数限制
-	// 全局限流管理器
-	throttleManager *ThrottleManager
-	// 统计数据
-	stats *RequestStats
-	// QPS配置
-	qpsConfig int
-	qpmConfig int
-	qphConfig int
-	// 请求统计保存回调函数
-	statSaver RequestStatSaver
+	// 每次启动加1，旧的Worker发现不一致时退出
+	generation int
+	// 请求调度器
+	executor *scheduler.Executor
 }
 
-// 全局队列执行器实例
-var globalExecutor *QueueExecutor
-var executorOnce sync.Once
-
-// GetGlobalExecutor 获取全局队列执行器实例（单例）
-func GetGlobalExecutor() *QueueExecutor {
-	executorOnce.Do(func() {
-		// 默认QPS=10，QPM=500，QPH=10000
-		globalExecutor = NewQueueExecutor(3, 200, 12000)
-		globalExecutor.Start()
-	})
-	return globalExecutor
+// 单个优先级队列的累计统计
+type laneCounter struct {
+	processed   int64 // 已处理的请求数
+	canceled    int64 // 排队时被取消的请求数
+	totalWaitMs int64 // 累计排队时间（毫秒）
+	maxWaitMs   int64 // 最长排队时间（毫秒）
 }
 
-// SetGlobalExecutorConfig 设置全局执行器的速率限制配置
-func SetGlobalExecutorConfig(qps, qpm, qph int) {
-	executor := GetGlobalExecutor()
-	executor.SetRateLimitConfig(qps, qpm, qph)
+// LaneStats 优先级队列统计数据
+type LaneStats struct {
+	Priority      string `json:"priority"`
+	Queued        int    `json:"queued"`           // 正在排队的请求数
+	Processed     int64  `json:"processed"`        // 已处理的请求数
+	Canceled      int64  `json:"canceled"`         // 排队时被取消的请求数
+	AvgWaitTimeMs int64  `json:"avg_wait_time_ms"` // 平均排队时间（毫秒）
+	MaxWaitTimeMs int64  `json:"max_wait_time_ms"` // 最长排队时间（毫秒）
 }
 
-// SetGlobalExecutorStatSaver 设置全局执行器的统计保存回调函数
-func SetGlobalExecutorStatSaver(saver RequestStatSaver) {
-	executor := GetGlobalExecutor()
-	executor.SetStatSaver(saver)
+// 全局上限是单个账号默认速率的几倍，多个账号同时请求时整个程序不超过这个速率
+const GLOBAL_CEILING_FACTOR = 3
+
+var (
+	// 每个115账号一个队列执行器，key为账号ID
+	executors   = make(map[uint]*QueueExecutor)
+	executorsMu sync.Mutex
+	// 所有115账号共用的全局上限，只用于速率限制和汇总统计
+	globalCeiling = newGlobalCeiling()
+)
+
+func newGlobalCeiling() *scheduler.Executor {
+	ceiling := scheduler.NewExecutor(scheduler.SOURCE_115, 0, globalCeilingLimits(scheduler.DefaultLimits(scheduler.SOURCE_115)))
+	ceiling.SetReservedQPS(PLAYBACK_RESERVED_QPS)
+	return ceiling
 }
 
-// NewQueueExecutor 创建新的队列执行器
-func NewQueueExecutor(qps, qpm, qph int) *QueueExecutor {
-	// 计算Worker数量：max(qps, 5) + 3
-	workerCount := qps
-	if workerCount < 5 {
-		workerCount = 5
+func globalCeilingLimits(limits scheduler.Limits) scheduler.Limits {
+	return scheduler.Limits{
+		QPS: limits.QPS * GLOBAL_CEILING_FACTOR,
+		QPM: limits.QPM * GLOBAL_CEILING_FACTOR,
+		QPH: limits.QPH * GLOBAL_CEILING_FACTOR,
 	}
-	workerCount += 3
-
-	executor := &QueueExecutor{
-		requestQueue:    make(chan *QueuedRequest, 100), // 缓冲100
-		workerCount:     workerCount,
-		stopChan:        make(chan struct{}),
-		workerStopChans: make([]chan struct{}, 0, workerCount),
-		throttleManager: NewThrottleManager(),
-		stats:           NewRequestStats(10000),
-		qpsConfig:       qps,
-		qpmConfig:       qpm,
-		qphConfig:       qph,
+}
+
+// GetExecutor 获取账号的队列执行器，不存在时创建并启动
+func GetExecutor(accountId uint) *QueueExecutor {
+	executorsMu.Lock()
+	defer executorsMu.Unlock()
+	if qe, ok := executors[accountId]; ok {
+		return qe
 	}
+	qe := NewQueueExecutor(accountId)
+	qe.Start()
+	executors[accountId] = qe
+	return qe
+}
 
-	// 创建速率限制器
-	// qps: 每秒请求数
-	executor.qpsLimiter = rate.NewLimiter(rate.Limit(qps), qps)
-	// qpm: 每分钟请求数
-	executor.qpmLimiter = rate.NewLimiter(rate.Every(time.Minute/time.Duration(qpm)), qpm)
-	// qph: 每小时请求数
-	executor.qphLimiter = rate.NewLimiter(rate.Every(time.Hour/time.Duration(qph)), qph)
+// Executors 获取所有已创建的队列执行器，按账号ID排序
+func Executors() []*QueueExecutor {
+	executorsMu.Lock()
+	defer executorsMu.Unlock()
+	list := make([]*QueueExecutor, 0, len(executors))
+	for _, qe := range executors {
+		list = append(list, qe)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].AccountId < list[j].AccountId })
+	return list
+}
 
-	return executor
+// SetExecutorConfig 设置每个账号默认的速率限制，全局上限按GLOBAL_CEILING_FACTOR倍计算
+// 单独设置了速率限制的账号不受影响
+func SetExecutorConfig(qps, qpm, qph int) {
+	limits := scheduler.Limits{QPS: qps, QPM: qpm, QPH: qph}
+	scheduler.SetDefaultLimits(scheduler.SOURCE_115, limits)
+	globalCeiling.SetLimits(globalCeilingLimits(limits))
+	for _, qe := range Executors() {
+		qe.RefreshWorkers()
+	}
 }
 
-// SetRateLimitConfig 设置速率限制配置
-func (qe *QueueExecutor) SetRateLimitConfig(qps, qpm, qph int) {
-	qe.Lock()
+// GetGlobalCeiling 获取所有115账号共用的全局上限
+func GetGlobalCeiling() *scheduler.Executor {
+	return globalCeiling
+}
 
-	qe.qpsConfig = qps
-	qe.qpmConfig = qpm
-	qe.qphConfig = qph
+// NewQueueExecutor 创建账号的队列执行器，一般使用GetExecutor获取
+func NewQueueExecutor(accountId uint) *QueueExecutor {
+	executor := scheduler.Get(scheduler.SOURCE_115, accountId)
+	executor.SetReservedQPS(PLAYBACK_RESERVED_QPS)
+	// 配置的速率作为上限，自动学习账号安全的速率
+	executor.EnableAdaptive(scheduler.DefaultAdaptiveConfig)
+	executor.RestoreAdaptive(scheduler.LoadLearnedLimits(scheduler.SOURCE_115, accountId))
+	executor.SetParent(globalCeiling)
+	qe := &QueueExecutor{
+		AccountId:   accountId,
+		workerCount: workerCountForQPS(executor.Limits().QPS),
+		executor:    executor,
+	}
+	qe.cond = sync.NewCond(&qe.Mutex)
+	return qe
+}
 
-	// 更新限制器
-	qe.qpsLimiter = rate.NewLimiter(rate.Limit(qps), qps)
-	qe.qpmLimiter = rate.NewLimiter(rate.Every(time.Minute/time.Duration(qpm)), qpm)
-	qe.qphLimiter = rate.NewLimiter(rate.Every(time.Hour/time.Duration(qph)), qph)
+// 计算Worker数量：max(qps, 5) + 3
+func workerCountForQPS(qps int) int {
+	return max(qps, 5) + 3
+}
 
-	// 重新计算Worker数量
-	newWorkerCount := qps
-	if newWorkerCount < 5 {
-		newWorkerCount = 5
-	}
-	newWorkerCount += 3
+// RefreshWorkers 速率限制修改后重新计算Worker数量
+func (qe *QueueExecutor) RefreshWorkers() {
+	newWorkerCount := workerCountForQPS(qe.executor.Limits().QPS)
 
+	qe.Lock()
 	needRestart := newWorkerCount != qe.workerCount && qe.running
 	oldWorkerCount := qe.workerCount
-
 	qe.Unlock() // 在可能调用Stop/Start之前释放锁，避免死锁
 
 	if needRestart {
-		// 如果Worker数量改变且正在运行，需要重启
-		helpers.V115Log.Warnf("速率限制配置已更改，将重启执行器以应用新的Worker数量：%d -> %d", oldWorkerCount, newWorkerCount)
+		// 如果Worker数量改变且正在运行，需要重启，排队中的请求会保留
+		helpers.V115Log.Warnf("账号 %d 的速率限制已更改，将重启执行器以应用新的Worker数量：%d -> %d", qe.AccountId, oldWorkerCount, newWorkerCount)
 		qe.Stop()
 		qe.Lock()
 		qe.workerCount = newWorkerCount
@@ -138,13 +170,6 @@ func (qe *QueueExecutor) SetRateLimitConfig(qps, qpm, qph int) {
 	}
 }
 
-// SetStatSaver 设置统计保存回调函数
-func (qe *QueueExecutor) SetStatSaver(saver RequestStatSaver) {
-	qe.Lock()
-	defer qe.Unlock()
-	qe.statSaver = saver
-}
-
 // Start 启动队列执行器
 func (qe *QueueExecutor) Start() {
 	qe.Lock()
@@ -153,20 +178,21 @@ func (qe *QueueExecutor) Start() {
 		return
 	}
 	qe.running = true
-	// 检查queueRequest通道是否已关闭，若已关闭则重新创建
-	if qe.requestQueue == nil {
-		qe.requestQueue = make(chan *QueuedRequest, 100)
-	}
+	qe.generation++
+	generation := qe.generation
+	workerCount := qe.workerCount
 	qe.Unlock()
 
-	helpers.V115Log.Infof("启动115 OpenAPI队列执行器，Worker数量: %d, QPS: %d, QPM: %d, QPH: %d",
-		qe.workerCount, qe.qpsConfig, qe.qpmConfig, qe.qphConfig)
+	limits := qe.executor.Limits()
+	helpers.V115Log.Infof("启动115账号 %d 的OpenAPI队列执行器，Worker数量: %d（播放Worker: %d）, QPS: %d, QPM: %d, QPH: %d",
+		qe.AccountId, workerCount, PLAYBACK_WORKERS, limits.QPS, limits.QPM, limits.QPH)
 
 	// 启动Worker
-	for i := 0; i < qe.workerCount; i++ {
-		stopChan := make(chan struct{})
-		qe.workerStopChans = append(qe.workerStopChans, stopChan)
-		go qe.worker(i, stopChan)
+	for i := 0; i < PLAYBACK_WORKERS; i++ {
+		go qe.playbackWorker(i, generation)
+	}
+	for i := 0; i < workerCount; i++ {
+		go qe.worker(i, generation)
 	}
 }
 
@@ -179,129 +205,142 @@ func (qe *QueueExecutor) Stop() {
 	}
 	qe.running = false
 	qe.Unlock()
+	qe.cond.Broadcast()
 
-	helpers.V115Log.Infof("停止115 OpenAPI队列执行器")
+	helpers.V115Log.Infof("停止115账号 %d 的OpenAPI队列执行器", qe.AccountId)
+}
 
-	// 停止所有Worker
-	for _, stopChan := range qe.workerStopChans {
-		select {
-		case stopChan <- struct{}{}:
-		default:
+// 等待maxPriority及以上的队列中有请求，返回最高的优先级；执行器停止或重启后返回false
+// 需要持有锁
+func (qe *QueueExecutor) waitForRequest(generation int, maxPriority Priority) (Priority, bool) {
+	for {
+		if !qe.running || qe.generation != generation {
+			return 0, false
 		}
+		for p := PRIORITY_PLAYBACK; p <= maxPriority; p++ {
+			if len(qe.lanes[p]) > 0 {
+				return p, true
+			}
+		}
+		qe.cond.Wait()
 	}
-
-	// 关闭队列通道
-	close(qe.requestQueue)
-	qe.requestQueue = nil
 }
 
-// worker Worker协程，处理队列中的请求
-func (qe *QueueExecutor) worker(id int, stopChan chan struct{}) {
-	for {
-		select {
-		case <-stopChan:
-			helpers.V115Log.Debugf("Worker %d 已停止", id)
-			return
-		case req, ok := <-qe.requestQueue:
-			if !ok {
-				// 通道已关闭
-				return
+// 取出maxPriority及以上优先级最高的请求，已经取消的请求直接返回错误
+// 需要持有锁
+func (qe *QueueExecutor) dequeue(maxPriority Priority) *QueuedRequest {
+	for p := PRIORITY_PLAYBACK; p <= maxPriority; p++ {
+		for len(qe.lanes[p]) > 0 {
+			req := qe.lanes[p][0]
+			qe.lanes[p][0] = nil
+			qe.lanes[p] = qe.lanes[p][1:]
+			counter := &qe.laneStats[p]
+			if req.Ctx != nil && req.Ctx.Err() != nil {
+				counter.canceled++
+				respond(req, &RequestResponse{Error: req.Ctx.Err()})
+				continue
 			}
-
-			// 处理请求
-			qe.handleRequest(req)
+			waitMs := time.Since(req.CreatedAt).Milliseconds()
+			counter.processed++
+			counter.totalWaitMs += waitMs
+			counter.maxWaitMs = max(counter.maxWaitMs, waitMs)
+			return req
 		}
 	}
+	return nil
 }
 
-// handleRequest 处理单个请求
-func (qe *QueueExecutor) handleRequest(req *QueuedRequest) {
-	startTime := time.Now()
-
-	// 检查限流状态
-	if qe.throttleManager.IsThrottled() {
-		helpers.V115Log.Debugf("系统处于限流状态，等待恢复...")
-		qe.throttleManager.WaitThrottleRecovery(req.Ctx)
+// playbackWorker 只处理播放请求的Worker，使用预留容量
+func (qe *QueueExecutor) playbackWorker(id int, generation int) {
+	for {
+		qe.Lock()
+		if _, ok := qe.waitForRequest(generation, PRIORITY_PLAYBACK); !ok {
+			qe.Unlock()
+			helpers.V115Log.Debugf("播放Worker %d 已停止", id)
+			return
+		}
+		req := qe.dequeue(PRIORITY_PLAYBACK)
+		qe.Unlock()
+		if req != nil {
+			qe.handleRequest(req, false)
+		}
 	}
+}
 
-	// 如果不绕过速率限制，则检查三层限制
-	if !req.BypassRateLimit {
-		// 等待QPS限制
-		if err := qe.qpsLimiter.Wait(req.Ctx); err != nil {
-			req.ResponseChan <- &RequestResponse{
-				Error:    fmt.Errorf("QPS限制错误: %w", err),
-				Duration: time.Since(startTime).Milliseconds(),
-			}
+// worker Worker协程，先等待速率限制的令牌，再处理优先级最高的请求
+func (qe *QueueExecutor) worker(id int, generation int) {
+	for {
+		qe.Lock()
+		priority, ok := qe.waitForRequest(generation, PRIORITY_SYNC)
+		qe.Unlock()
+		if !ok {
+			helpers.V115Log.Debugf("Worker %d 已停止", id)
 			return
 		}
 
-		// 等待QPM限制
-		if err := qe.qpmLimiter.Wait(req.Ctx); err != nil {
-			req.ResponseChan <- &RequestResponse{
-				Error:    fmt.Errorf("QPM限制错误: %w", err),
-				Duration: time.Since(startTime).Milliseconds(),
+		// 播放请求不需要等待普通请求的速率限制
+		waited := false
+		if priority != PRIORITY_PLAYBACK {
+			if err := qe.executor.Wait(context.Background()); err != nil {
+				helpers.V115Log.Errorf("Worker %d 等待速率限制失败: %v", id, err)
+				continue
 			}
-			return
+			waited = true
 		}
 
-		// 等待QPH限制
-		if err := qe.qphLimiter.Wait(req.Ctx); err != nil {
-			req.ResponseChan <- &RequestResponse{
-				Error:    fmt.Errorf("QPH限制错误: %w", err),
-				Duration: time.Since(startTime).Milliseconds(),
-			}
+		qe.Lock()
+		if !qe.running || qe.generation != generation {
+			qe.Unlock()
+			helpers.V115Log.Debugf("Worker %d 已停止", id)
 			return
 		}
-	} else {
-		// 播放请求：只检查限流状态，不检查速率限制
-		// 但仍然等待限流恢复
-		if qe.throttleManager.IsThrottled() {
-			qe.throttleManager.WaitThrottleRecovery(req.Ctx)
+		req := qe.dequeue(PRIORITY_SYNC)
+		qe.Unlock()
+		if req != nil {
+			qe.handleRequest(req, waited)
 		}
 	}
+}
 
-	// 发送请求
-	response, respData, respBytes, err := qe.executeRequest(req)
-
-	duration := time.Since(startTime).Milliseconds()
-
-	// 检查是否是限流响应
-	isThrottled := false
-	if respData != nil && respData.Code == REQUEST_MAX_LIMIT_CODE {
-		isThrottled = true
-		qe.throttleManager.MarkThrottled(qe.stats)
-	}
-
-	// 记录请求
-	qe.stats.RecordRequest(RequestLogEntry{
-		Timestamp:   time.Now(),
-		Duration:    duration,
-		IsThrottled: isThrottled,
-		URL:         req.URL,
-		Method:      req.Method,
+// handleRequest 处理单个请求，waited表示已经等待过速率限制
+func (qe *QueueExecutor) handleRequest(req *QueuedRequest, waited bool) {
+	var (
+		response  *resty.Response
+		respData  *RespBaseBool[json.RawMessage]
+		respBytes []byte
+		err       error
+	)
+	result := qe.executor.Execute(req.Ctx, scheduler.Call{
+		URL:             req.URL,
+		Method:          req.Method,
+		BypassRateLimit: waited,
+		Reserved:        req.Priority == PRIORITY_PLAYBACK,
+		Do: func() (bool, error) {
+			// 发送请求
+			response, respData, respBytes, err = qe.executeRequest(req)
+			// 检查是否是限流响应
+			return respData != nil && respData.Code == REQUEST_MAX_LIMIT_CODE, err
+		},
 	})
 
-	// 异步写入数据库（如果设置了回调函数）
-	if qe.statSaver != nil {
-		go qe.statSaver(time.Now().Unix(), req.URL, req.Method, duration, isThrottled)
-	}
-
-	// 发送响应
-	respChan := req.ResponseChan
-	select {
-	case respChan <- &RequestResponse{
+	respond(req, &RequestResponse{
 		Response:    response,
 		RespData:    respData,
 		RespBytes:   respBytes,
-		Error:       err,
-		Duration:    duration,
-		IsThrottled: isThrottled,
-	}:
+		Error:       result.Error,
+		Duration:    result.Duration,
+		IsThrottled: result.IsThrottled,
+	})
+}
+
+// 发送响应并关闭响应通道
+func respond(req *QueuedRequest, resp *RequestResponse) {
+	respChan := req.ResponseChan
+	select {
+	case respChan <- resp:
 	default:
 		helpers.V115Log.Warnf("响应通道已关闭或已满，丢弃响应: %s %s", req.Method, req.URL)
 	}
-
-	// 关闭响应通道
 	close(respChan)
 }
 
@@ -373,35 +412,65 @@ func (qe *QueueExecutor) executeRequest(req *QueuedRequest) (*resty.Response, *R
 	return response, resp, resBytes, nil
 }
 
-// EnqueueRequest 将请求加入队列
+// EnqueueRequest 将请求加入对应优先级的队列
 func (qe *QueueExecutor) EnqueueRequest(req *QueuedRequest) {
-	qe.RLock()
+	if req.Priority < 0 || req.Priority >= priorityCount {
+		req.Priority = PRIORITY_BROWSE
+	}
+	qe.Lock()
 	if !qe.running {
-		qe.RUnlock()
+		qe.Unlock()
 		helpers.V115Log.Error("队列执行器未启动")
+		respond(req, &RequestResponse{Error: fmt.Errorf("115请求队列执行器未启动")})
 		return
 	}
-	qe.RUnlock()
+	qe.lanes[req.Priority] = append(qe.lanes[req.Priority], req)
+	qe.Unlock()
+	qe.cond.Broadcast()
+}
 
-	// 发送到队列（如果缓冲满则阻塞）
-	qe.requestQueue <- req
+// GetLaneStats 获取各优先级队列的统计数据
+func (qe *QueueExecutor) GetLaneStats() []LaneStats {
+	qe.Lock()
+	defer qe.Unlock()
+	stats := make([]LaneStats, 0, priorityCount)
+	for p := PRIORITY_PLAYBACK; p < priorityCount; p++ {
+		counter := qe.laneStats[p]
+		lane := LaneStats{
+			Priority:      p.String(),
+			Queued:        len(qe.lanes[p]),
+			Processed:     counter.processed,
+			Canceled:      counter.canceled,
+			MaxWaitTimeMs: counter.maxWaitMs,
+		}
+		if counter.processed > 0 {
+			lane.AvgWaitTimeMs = counter.totalWaitMs / counter.processed
+		}
+		stats = append(stats, lane)
+	}
+	return stats
 }
 
 // GetStats 获取统计数据
-func (qe *QueueExecutor) GetStats(duration time.Duration) *StatsSnapshot {
-	return qe.stats.GetStats(duration)
+func (qe *QueueExecutor) GetStats(duration time.Duration) *scheduler.StatsSnapshot {
+	return qe.executor.GetStats(duration)
+}
+
+// GetAdaptiveStatus 获取自适应速率控制的状态
+func (qe *QueueExecutor) GetAdaptiveStatus() *scheduler.AdaptiveStatus {
+	return qe.executor.AdaptiveStatus()
 }
 
 // GetThrottleStatus 获取限流状态
-func (qe *QueueExecutor) GetThrottleStatus() ThrottleStatus {
-	return qe.throttleManager.GetThrottleStatus()
+func (qe *QueueExecutor) GetThrottleStatus() scheduler.ThrottleStatus {
+	return qe.executor.GetThrottleStatus()
 }
 
 // SetThrottledForTesting 手动设置限流状态（仅用于测试）
 func (qe *QueueExecutor) SetThrottledForTesting(throttled bool) {
 	if throttled {
-		qe.throttleManager.MarkThrottled(qe.stats)
+		qe.executor.MarkThrottled()
 	} else {
-		qe.throttleManager.ClearThrottled()
+		qe.executor.ClearThrottled()
 	}
 }
@@ -0,0 +1,109 @@
+package v115open
+
+import (
+	"Q115-STRM/internal/helpers"
+	"Q115-STRM/internal/scheduler"
+	"context"
+	"io"
+	"log"
+	"testing"
+	"time"
+)
+
+func init() {
+	helpers.V115Log = &helpers.QLogger{Logger: log.New(io.Discard, "", 0)}
+}
+
+func TestQueuePriority(t *testing.T) {
+	qe := NewQueueExecutor(1)
+	qe.running = true
+
+	canceledCtx, cancel := context.WithCancel(context.Background())
+	cancel()
+	requests := []*QueuedRequest{
+		{URL: "sync", Priority: PRIORITY_SYNC, Ctx: context.Background()},
+		{URL: "scrape", Priority: PRIORITY_SCRAPE, Ctx: context.Background()},
+		{URL: "canceled", Priority: PRIORITY_BROWSE, Ctx: canceledCtx},
+		{URL: "browse", Priority: PRIORITY_BROWSE, Ctx: context.Background()},
+		{URL: "playback", Priority: PRIORITY_PLAYBACK, Ctx: context.Background()},
+	}
+	for _, req := range requests {
+		req.ResponseChan = make(chan *RequestResponse, 1)
+		req.CreatedAt = time.Now()
+		qe.EnqueueRequest(req)
+	}
+
+	// 播放Worker只取播放请求
+	if p, ok := qe.waitForRequest(qe.generation, PRIORITY_PLAYBACK); !ok || p != PRIORITY_PLAYBACK {
+		t.Fatalf("waitForRequest = %v, %v", p, ok)
+	}
+	if req := qe.dequeue(PRIORITY_PLAYBACK); req == nil || req.URL != "playback" {
+		t.Fatalf("playback dequeue = %+v", req)
+	}
+	if req := qe.dequeue(PRIORITY_PLAYBACK); req != nil {
+		t.Fatalf("playback worker took %s", req.URL)
+	}
+
+	for _, want := range []string{"browse", "scrape", "sync"} {
+		req := qe.dequeue(PRIORITY_SYNC)
+		if req == nil || req.URL != want {
+			t.Fatalf("dequeue = %+v, want %s", req, want)
+		}
+	}
+	if resp := <-requests[2].ResponseChan; resp == nil || resp.Error == nil {
+		t.Fatal("canceled request got no error response")
+	}
+
+	stats := qe.GetLaneStats()
+	if len(stats) != int(priorityCount) {
+		t.Fatalf("lane stats = %d lanes", len(stats))
+	}
+	if browse := stats[PRIORITY_BROWSE]; browse.Priority != "browse" || browse.Processed != 1 || browse.Canceled != 1 || browse.Queued != 0 {
+		t.Errorf("browse lane stats = %+v", browse)
+	}
+}
+
+func TestPriorityFromContext(t *testing.T) {
+	if p := PriorityFromContext(context.Background()); p != PRIORITY_BROWSE {
+		t.Errorf("default priority = %v", p)
+	}
+	ctx := WithPriority(context.Background(), PRIORITY_SYNC)
+	if p := PriorityFromContext(ctx); p != PRIORITY_SYNC {
+		t.Errorf("priority = %v, want sync", p)
+	}
+	config := MakeRequestConfig(0, 0, 0)
+	config.BypassRateLimit = true
+	if p := config.priority(ctx); p != PRIORITY_PLAYBACK {
+		t.Errorf("bypass priority = %v, want playback", p)
+	}
+}
+
+func TestPerAccountExecutors(t *testing.T) {
+	SetExecutorConfig(2, 120, 7200)
+	first := GetExecutor(101)
+	second := GetExecutor(102)
+	if GetExecutor(101) != first || first == second {
+		t.Fatal("GetExecutor should return one executor per account")
+	}
+	defer first.Stop()
+	defer second.Stop()
+
+	// 一个账号限流不影响其他账号
+	first.SetThrottledForTesting(true)
+	defer first.SetThrottledForTesting(false)
+	if !first.GetThrottleStatus().IsThrottled || second.GetThrottleStatus().IsThrottled {
+		t.Fatal("throttle state is shared between accounts")
+	}
+
+	if got := GetGlobalCeiling().Limits(); got != (scheduler.Limits{QPS: 2 * GLOBAL_CEILING_FACTOR, QPM: 120 * GLOBAL_CEILING_FACTOR, QPH: 7200 * GLOBAL_CEILING_FACTOR}) {
+		t.Fatalf("global ceiling = %+v", got)
+	}
+
+	// 账号单独的速率限制只影响自己的Worker数量
+	scheduler.SetAccountLimits(scheduler.SOURCE_115, 102, scheduler.Limits{QPS: 10})
+	defer scheduler.SetAccountLimits(scheduler.SOURCE_115, 102, scheduler.Limits{})
+	second.RefreshWorkers()
+	if first.workerCount != workerCountForQPS(2) || second.workerCount != workerCountForQPS(10) {
+		t.Fatalf("worker counts = %d, %d", first.workerCount, second.workerCount)
+	}
+}
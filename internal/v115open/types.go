@@ -1,6 +1,7 @@
 package v115open
 
 import (
+	"context"
 	"time"
 )
 
@@ -25,7 +26,15 @@ type RequestConfig struct {
 	MaxRetries      int           `json:"max_retries"`
 	RetryDelay      time.Duration `json:"retry_delay"`
 	Timeout         time.Duration `json:"timeout"`
-	BypassRateLimit bool          `json:"bypass_rate_limit"` // 是否绕过速率限制（播放请求等）
+	BypassRateLimit bool          `json:"bypass_rate_limit"` // 是否是播放请求，使用预留容量，不排在其他请求后面
+}
+
+// 请求的优先级，播放请求最优先，其他请求使用上下文中设置的优先级
+func (o *RequestConfig) priority(ctx context.Context) Priority {
+	if o.BypassRateLimit {
+		return PRIORITY_PLAYBACK
+	}
+	return PriorityFromContext(ctx)
 }
 
 // DefaultRequestConfig 默认请求配置
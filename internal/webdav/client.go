@@ -0,0 +1,379 @@
+package webdav
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// 列表、创建目录等请求的超时时间，下载和上传不限制
+	DEFAULT_TIMEOUT = 60 // 秒
+
+	DEFAULTUA = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/138.0.0.0 Safari/537.36 Edg/138.0.0.0 QMediaSyncReq/1.0"
+)
+
+// Client WebDAV客户端，使用Basic认证
+// 所有的path参数都是相对BaseUrl的路径，以/开头
+type Client struct {
+	AccountId uint
+	BaseUrl   string // WebDAV的访问地址，例如 http://ip:5244/dav
+	Username  string
+	Password  string
+	client    *http.Client
+}
+
+// FileInfo 文件或目录信息
+type FileInfo struct {
+	Path     string    `json:"path"` // 相对BaseUrl的完整路径
+	Name     string    `json:"name"`
+	Size     int64     `json:"size"`
+	IsDir    bool      `json:"is_dir"`
+	Modified time.Time `json:"modified"`
+	ETag     string    `json:"etag"`
+}
+
+var cachedClients map[uint]*Client = make(map[uint]*Client, 0)
+var cachedClientsMutex sync.Mutex
+
+// NewClient 创建客户端，同一个账号复用同一个客户端
+func NewClient(accountId uint, baseUrl, username, password string) *Client {
+	cachedClientsMutex.Lock()
+	defer cachedClientsMutex.Unlock()
+	baseUrl = strings.TrimSuffix(baseUrl, "/")
+	if client, exists := cachedClients[accountId]; exists && accountId != 0 {
+		client.BaseUrl = baseUrl
+		client.Username = username
+		client.Password = password
+		return client
+	}
+	client := &Client{
+		AccountId: accountId,
+		BaseUrl:   baseUrl,
+		Username:  username,
+		Password:  password,
+		client:    &http.Client{},
+	}
+	if accountId != 0 {
+		cachedClients[accountId] = client
+	}
+	return client
+}
+
+// 规范化路径，以/开头，不以/结尾
+func cleanPath(p string) string {
+	p = path.Clean("/" + strings.ReplaceAll(p, "\\", "/"))
+	return p
+}
+
+// FileUrl 文件的完整访问地址，不含账号密码
+func (c *Client) FileUrl(p string) string {
+	u, err := url.Parse(c.BaseUrl)
+	if err != nil {
+		return c.BaseUrl + cleanPath(p)
+	}
+	u.Path = strings.TrimSuffix(u.Path, "/") + cleanPath(p)
+	u.RawPath = ""
+	return u.String()
+}
+
+// DirectUrl 文件的直链，地址中包含账号密码，播放器可以直接访问
+func (c *Client) DirectUrl(p string) string {
+	u, err := url.Parse(c.FileUrl(p))
+	if err != nil {
+		return c.FileUrl(p)
+	}
+	if c.Username != "" {
+		u.User = url.UserPassword(c.Username, c.Password)
+	}
+	return u.String()
+}
+
+// 根据服务器返回的href计算相对BaseUrl的路径
+func (c *Client) hrefToPath(href string) string {
+	u, err := url.Parse(href)
+	if err != nil {
+		return ""
+	}
+	basePath := ""
+	if bu, err := url.Parse(c.BaseUrl); err == nil {
+		basePath = strings.TrimSuffix(bu.Path, "/")
+	}
+	return cleanPath(strings.TrimPrefix(u.Path, basePath))
+}
+
+func (c *Client) newRequest(ctx context.Context, method, p string, body io.Reader) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, method, c.FileUrl(p), body)
+	if err != nil {
+		return nil, err
+	}
+	// 目录请求保留末尾的/，部分服务器列目录和创建目录时必须以/结尾
+	if strings.HasSuffix(p, "/") && !strings.HasSuffix(req.URL.Path, "/") {
+		req.URL.Path += "/"
+	}
+	if c.Username != "" {
+		req.SetBasicAuth(c.Username, c.Password)
+	}
+	req.Header.Set("User-Agent", DEFAULTUA)
+	return req, nil
+}
+
+// 执行不需要响应内容的请求
+func (c *Client) do(method, p string, header http.Header, okStatus ...int) error {
+	ctx, cancel := context.WithTimeout(context.Background(), DEFAULT_TIMEOUT*time.Second)
+	defer cancel()
+	req, err := c.newRequest(ctx, method, p, nil)
+	if err != nil {
+		return err
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("%s %s 请求失败: %w", method, p, err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+	return checkStatus(method, p, resp.StatusCode, okStatus...)
+}
+
+func checkStatus(method, p string, status int, okStatus ...int) error {
+	for _, s := range okStatus {
+		if status == s {
+			return nil
+		}
+	}
+	if status == http.StatusNotFound {
+		return fmt.Errorf("%s %s 失败: %w", method, p, os.ErrNotExist)
+	}
+	if status == http.StatusUnauthorized || status == http.StatusForbidden {
+		return fmt.Errorf("%s %s 失败: 账号或密码错误，状态码 %d", method, p, status)
+	}
+	return fmt.Errorf("%s %s 失败: 状态码 %d", method, p, status)
+}
+
+type multiStatus struct {
+	Responses []propResponse `xml:"DAV: response"`
+}
+
+type propResponse struct {
+	Href      string     `xml:"DAV: href"`
+	PropStats []propStat `xml:"DAV: propstat"`
+}
+
+type propStat struct {
+	Status string `xml:"DAV: status"`
+	Prop   struct {
+		DisplayName   string `xml:"DAV: displayname"`
+		ContentLength string `xml:"DAV: getcontentlength"`
+		LastModified  string `xml:"DAV: getlastmodified"`
+		ETag          string `xml:"DAV: getetag"`
+		ResourceType  struct {
+			Collection *struct{} `xml:"DAV: collection"`
+		} `xml:"DAV: resourcetype"`
+	} `xml:"DAV: prop"`
+}
+
+const propfindBody = `<?xml version="1.0" encoding="utf-8"?>
+<D:propfind xmlns:D="DAV:"><D:prop><D:displayname/><D:getcontentlength/><D:getlastmodified/><D:getetag/><D:resourcetype/></D:prop></D:propfind>`
+
+func (c *Client) propfind(ctx context.Context, p string, depth string) ([]*FileInfo, error) {
+	ctx, cancel := context.WithTimeout(ctx, DEFAULT_TIMEOUT*time.Second)
+	defer cancel()
+	reqPath := p
+	if depth == "1" {
+		reqPath += "/"
+	}
+	req, err := c.newRequest(ctx, "PROPFIND", reqPath, strings.NewReader(propfindBody))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Depth", depth)
+	req.Header.Set("Content-Type", "application/xml; charset=utf-8")
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("PROPFIND %s 请求失败: %w", p, err)
+	}
+	defer resp.Body.Close()
+	if err := checkStatus("PROPFIND", p, resp.StatusCode, http.StatusMultiStatus); err != nil {
+		return nil, err
+	}
+	var ms multiStatus
+	if err := xml.NewDecoder(resp.Body).Decode(&ms); err != nil {
+		return nil, fmt.Errorf("PROPFIND %s 解析响应失败: %w", p, err)
+	}
+	files := make([]*FileInfo, 0, len(ms.Responses))
+	for _, r := range ms.Responses {
+		if info := c.toFileInfo(&r); info != nil {
+			files = append(files, info)
+		}
+	}
+	return files, nil
+}
+
+func (c *Client) toFileInfo(r *propResponse) *FileInfo {
+	for _, ps := range r.PropStats {
+		// 只使用状态为200的属性
+		if ps.Status != "" && !strings.Contains(ps.Status, " 200") {
+			continue
+		}
+		info := &FileInfo{
+			Path:  c.hrefToPath(r.Href),
+			IsDir: ps.Prop.ResourceType.Collection != nil,
+			ETag:  strings.Trim(ps.Prop.ETag, `"`),
+		}
+		info.Name = path.Base(info.Path)
+		if info.Path == "/" {
+			info.Name = ""
+		}
+		if ps.Prop.ContentLength != "" {
+			info.Size, _ = strconv.ParseInt(ps.Prop.ContentLength, 10, 64)
+		}
+		if ps.Prop.LastModified != "" {
+			info.Modified, _ = http.ParseTime(ps.Prop.LastModified)
+		}
+		return info
+	}
+	return nil
+}
+
+// Stat 查询文件或目录的信息，不存在时返回的错误可以用errors.Is(err, os.ErrNotExist)判断
+func (c *Client) Stat(ctx context.Context, p string) (*FileInfo, error) {
+	p = cleanPath(p)
+	files, err := c.propfind(ctx, p, "0")
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("PROPFIND %s 失败: %w", p, os.ErrNotExist)
+	}
+	return files[0], nil
+}
+
+// ReadDir 列出目录下的文件和子目录，不包含目录自身
+func (c *Client) ReadDir(ctx context.Context, p string) ([]*FileInfo, error) {
+	p = cleanPath(p)
+	files, err := c.propfind(ctx, p, "1")
+	if err != nil {
+		return nil, err
+	}
+	result := make([]*FileInfo, 0, len(files))
+	for _, f := range files {
+		if f.Path == p {
+			continue
+		}
+		result = append(result, f)
+	}
+	return result, nil
+}
+
+// Mkdir 创建目录，父目录必须存在，目录已存在时不报错
+func (c *Client) Mkdir(p string) error {
+	p = cleanPath(p)
+	err := c.do("MKCOL", p+"/", nil, http.StatusCreated, http.StatusOK, http.StatusNoContent)
+	if err == nil {
+		return nil
+	}
+	// 目录已存在时返回405
+	if info, statErr := c.Stat(context.Background(), p); statErr == nil && info.IsDir {
+		return nil
+	}
+	return err
+}
+
+// MkdirAll 逐级创建目录
+func (c *Client) MkdirAll(p string) error {
+	p = cleanPath(p)
+	if p == "/" {
+		return nil
+	}
+	if info, err := c.Stat(context.Background(), p); err == nil && info.IsDir {
+		return nil
+	}
+	if err := c.MkdirAll(path.Dir(p)); err != nil {
+		return err
+	}
+	return c.Mkdir(p)
+}
+
+// Remove 删除文件或目录（目录会递归删除）
+func (c *Client) Remove(p string) error {
+	return c.do("DELETE", cleanPath(p), nil, http.StatusOK, http.StatusNoContent, http.StatusAccepted)
+}
+
+func (c *Client) moveOrCopy(method, oldPath, newPath string, overwrite bool) error {
+	header := http.Header{}
+	header.Set("Destination", c.FileUrl(newPath))
+	header.Set("Overwrite", "F")
+	if overwrite {
+		header.Set("Overwrite", "T")
+	}
+	return c.do(method, cleanPath(oldPath), header, http.StatusCreated, http.StatusNoContent, http.StatusOK)
+}
+
+// Move 移动或重命名文件和目录，newPath是新的完整路径
+func (c *Client) Move(oldPath, newPath string, overwrite bool) error {
+	return c.moveOrCopy("MOVE", oldPath, newPath, overwrite)
+}
+
+// Copy 复制文件或目录，newPath是新的完整路径
+func (c *Client) Copy(oldPath, newPath string, overwrite bool) error {
+	return c.moveOrCopy("COPY", oldPath, newPath, overwrite)
+}
+
+// Upload 上传本地文件到remotePath，已存在时覆盖
+func (c *Client) Upload(localFile string, remotePath string) error {
+	f, err := os.Open(localFile)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	stat, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	req, err := c.newRequest(context.Background(), http.MethodPut, cleanPath(remotePath), f)
+	if err != nil {
+		return err
+	}
+	req.ContentLength = stat.Size()
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("PUT %s 请求失败: %w", remotePath, err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+	return checkStatus("PUT", remotePath, resp.StatusCode, http.StatusCreated, http.StatusNoContent, http.StatusOK)
+}
+
+// Get 下载文件，header中的Range等请求头会转发给服务器，调用方负责关闭Body
+func (c *Client) Get(ctx context.Context, p string, header http.Header) (*http.Response, error) {
+	req, err := c.newRequest(ctx, http.MethodGet, cleanPath(p), nil)
+	if err != nil {
+		return nil, err
+	}
+	for _, k := range []string{"Range", "If-Range", "If-Modified-Since", "If-None-Match"} {
+		if v := header.Get(k); v != "" {
+			req.Header.Set(k, v)
+		}
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("GET %s 请求失败: %w", p, err)
+	}
+	if err := checkStatus("GET", p, resp.StatusCode, http.StatusOK, http.StatusPartialContent, http.StatusNotModified); err != nil {
+		resp.Body.Close()
+		return nil, err
+	}
+	return resp, nil
+}
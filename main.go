@@ -2,14 +2,18 @@ package main
 
 import (
 	"Q115-STRM/emby302/config"
+	"Q115-STRM/emby302/service/openlist/localtree"
 	"Q115-STRM/emby302/util/logs/colors"
 	"Q115-STRM/emby302/web"
 	"Q115-STRM/internal/controllers"
 	"Q115-STRM/internal/db"
 	"Q115-STRM/internal/db/database"
 	"Q115-STRM/internal/helpers"
+	"Q115-STRM/internal/linkcache"
 	"Q115-STRM/internal/models"
+	"Q115-STRM/internal/scheduler"
 	"Q115-STRM/internal/synccron"
+	"Q115-STRM/internal/syncstrm"
 	"Q115-STRM/internal/v115open"
 	"context"
 	_ "embed"
@@ -318,6 +322,7 @@ var s string
 
 func startEmby302() {
 	dataRoot := helpers.ConfigDir
+	config.BasePath = dataRoot // 配置初始化时 ffmpeg 会下载到 BasePath 下
 	if err := config.ReadFromFile([]byte(s)); err != nil {
 		log.Fatal(err)
 	}
@@ -335,10 +340,12 @@ func startEmby302() {
 		config.C.Ssl.Crt = "server.crt"
 		config.C.Ssl.Key = "server.key"
 	}
-	config.BasePath = dataRoot
 	config.C.Emby.LocalMediaRoot = "/"
 	config.C.VideoPreview.Enable = true
 	config.C.VideoPreview.Containers = []string{"strm"}
+	if err := localtree.Init(); err != nil {
+		helpers.AppLogger.Errorf("启动openlist本地目录树同步失败: %v", err)
+	}
 	go func() {
 		if err := web.Listen(); err != nil {
 			log.Fatal(colors.ToRed(err.Error()))
@@ -367,30 +374,39 @@ func initOthers() {
 	if qps <= 0 {
 		qps = 2
 	}
-	v115open.SetGlobalExecutorConfig(qps, qps*60, qps*3600)
-	models.LoadScrapeSettings()          // 从数据库加载刮削设置
-	models.InitDQ()                      // 初始化下载队列
-	models.InitUQ()                      // 初始化上传队列
-	models.InitNotificationManager()     // 初始化通知管理器
-	controllers.StartListenTelegramBot() // 初始化TelegramBot监听
-	models.GetEmbyConfig()               // 加载Emby配置
+	scheduler.SetLimitLoader(models.LoadLearnedLimits) // 创建调度器时恢复学习到的速率
+	v115open.SetExecutorConfig(qps, qps*60, qps*3600)
+	scheduler.SetDefaultLimits(scheduler.SOURCE_OPENLIST, scheduler.Limits{QPS: models.SettingsGlobal.OpenlistQPS})
+	scheduler.SetLimitSaver(models.SaveLearnedLimits) // 保存调度器学习到的速率
+	models.LoadScrapeSettings()                       // 从数据库加载刮削设置
+	models.InitDQ()                                   // 初始化下载队列
+	models.InitUQ()                                   // 初始化上传队列
+	models.InitNotificationManager()                  // 初始化通知管理器
+	controllers.StartListenTelegramBot()              // 初始化TelegramBot监听
+	models.GetEmbyConfig()                            // 加载Emby配置
+	if helpers.GlobalConfig.LinkCache != "memory" {
+		linkcache.SetStore(models.DirectLinkStore{}) // 直链缓存持久化到数据库，重启后继续使用
+	}
 	helpers.SubscribeSync(helpers.V115TokenInValidEvent, models.HandleV115TokenInvalid)
 	helpers.SubscribeSync(helpers.SaveOpenListTokenEvent, models.HandleOpenListTokenSaveSync)
+	helpers.SubscribeSync(helpers.Save123TokenEvent, models.Handle123TokenSaveSync)
 	models.FailAllRunningSyncTasks()   // 将所有运行中的同步任务设置为失败状态
+	syncstrm.ClearDiskSyncCache()      // 删除上次异常退出时留下的磁盘同步缓存
 	synccron.RefreshOAuthAccessToken() // 启动时刷新一次115的访问凭证，防止有过期的token导致同步失败
 
-	// 设置115请求队列的统计保存回调函数
-	v115open.SetGlobalExecutorStatSaver(func(requestTime int64, url, method string, duration int64, isThrottled bool) {
+	// 设置网盘请求调度器的统计保存回调函数
+	scheduler.SetStatSaver(func(source string, accountId uint, requestTime int64, url, method string, duration int64, isThrottled bool) {
 		stat := &models.RequestStat{
 			RequestTime: requestTime,
 			URL:         url,
 			Method:      method,
 			Duration:    duration,
 			IsThrottled: isThrottled,
-			AccountID:   0, // 可以后续扩展传入账号ID
+			AccountID:   accountId,
+			Source:      source,
 		}
 		if err := models.CreateRequestStat(stat); err != nil {
-			helpers.V115Log.Errorf("写入请求统计失败: %v", err)
+			helpers.AppLogger.Errorf("写入请求统计失败: %v", err)
 		}
 	})
 
@@ -398,6 +414,8 @@ func initOthers() {
 	// 启动同步任务队列管理器
 	synccron.InitNewSyncQueueManager()
 	synccron.InitCron() // 初始化定时任务（包含备份定时任务）
+	// 启动本地目录实时监控
+	synccron.StartLocalWatchers()
 	// 初始化备份服务
 	models.InitBackupService()
 	// }
@@ -427,13 +445,23 @@ func setRouter(r *gin.Engine) {
 	})
 	r.POST("/emby/webhook", controllers.Webhook)
 	r.POST("/api/login", controllers.LoginAction)
-	r.GET("/115/url/*filename", controllers.Get115UrlByPickCode)           // 查询115直链 by pickcode 支持iso，路径最后一部分是.扩展名格式
-	r.GET("/115/newurl", controllers.Get115UrlByPickCode)                  // 查询115直链 by pickcode
-	r.GET("/baidupan/url/*filename", controllers.GetBaiduPanUrlByPickCode) // 查询百度网盘直链 by fsid 支持iso，路径最后一部分是.扩展名格式
-
-	r.GET("/openlist/url", controllers.GetOpenListFileUrl) // 查询OpenList直链
-
-	r.GET("/proxy-115", controllers.Proxy115) // 115CDN反代路由
+	// 直链接口不需要登录，STRM地址签名后只接受签名正确的请求
+	strm := r.Group("", controllers.StrmSignAuth())
+	strm.GET("/115/url/*filename", controllers.Get115UrlByPickCode)           // 查询115直链 by pickcode 支持iso，路径最后一部分是.扩展名格式
+	strm.GET("/115/newurl", controllers.Get115UrlByPickCode)                  // 查询115直链 by pickcode
+	strm.GET("/baidupan/url/*filename", controllers.GetBaiduPanUrlByPickCode) // 查询百度网盘直链 by fsid 支持iso，路径最后一部分是.扩展名格式
+	strm.GET("/123/url/*filename", controllers.Get123UrlByPickCode)           // 查询123云盘直链 by fileID 支持iso，路径最后一部分是.扩展名格式
+
+	strm.GET("/openlist/url", controllers.GetOpenListFileUrl)       // 查询OpenList直链
+	strm.GET("/webdav/url/*filename", controllers.GetWebDAVFileUrl) // 播放WebDAV文件，路径最后一部分是.扩展名格式
+	strm.GET("/s3/url/*filename", controllers.GetS3FileUrl)         // 播放S3文件，重新签名后302跳转，路径最后一部分是.扩展名格式
+
+	r.GET("/proxy-115", controllers.Proxy115) // 115CDN反代路由，只接受QMS生成的带token的地址
+
+	// 只读WebDAV媒体库，自带认证
+	for _, method := range []string{http.MethodOptions, http.MethodGet, http.MethodHead, "PROPFIND"} {
+		r.Handle(method, "/dav/*path", controllers.ServeLibraryDAV)
+	}
 
 	r.GET("/api/scrape/tmp-image", controllers.ScrapeTmpImage)           // 获取临时图片
 	r.GET("/api/scrape/records/export", controllers.ExportScrapeRecords) // 导出刮削记录
@@ -452,17 +480,32 @@ func setRouter(r *gin.Engine) {
 				"isRelease": helpers.IsRelease,
 			})
 		})
-		api.GET("/115/oauth-url", controllers.GetOAuthUrl)               // 获取115 OAuth登录地址
-		api.POST("115/oauth-confirm", controllers.ConfirmOAuthCode)      // 确认OAuth登录
-		api.GET("/115/queue/stats", controllers.GetQueueStats)           // 获取115 OpenAPI请求队列统计数据
-		api.POST("/115/queue/rate-limit", controllers.SetQueueRateLimit) // 设置115 OpenAPI请求队列速率限制
-		api.GET("/115/stats/daily", controllers.GetRequestStatsByDay)    // 获取115请求统计（按天）
-		api.GET("/115/stats/hourly", controllers.GetRequestStatsByHour)  // 获取115请求统计（按小时）
-		api.POST("/115/stats/clean", controllers.CleanOldRequestStats)   // 清理旧的请求统计数据
+		api.GET("/115/oauth-url", controllers.GetOAuthUrl)                                              // 获取115 OAuth登录地址
+		api.POST("115/oauth-confirm", controllers.ConfirmOAuthCode)                                     // 确认OAuth登录
+		api.GET("/115/queue/stats", controllers.GetQueueStats)                                          // 获取115 OpenAPI请求队列统计数据
+		api.POST("/115/queue/rate-limit", controllers.SetQueueRateLimit)                                // 设置115 OpenAPI请求队列速率限制
+		api.GET("/115/stats/daily", controllers.GetRequestStatsByDay(models.SourceType115))             // 获取115请求统计（按天）
+		api.GET("/115/stats/hourly", controllers.GetRequestStatsByHour(models.SourceType115))           // 获取115请求统计（按小时）
+		api.GET("/openlist/stats/daily", controllers.GetRequestStatsByDay(models.SourceTypeOpenList))   // 获取OpenList请求统计（按天）
+		api.GET("/openlist/stats/hourly", controllers.GetRequestStatsByHour(models.SourceTypeOpenList)) // 获取OpenList请求统计（按小时）
+		api.GET("/baidupan/stats/daily", controllers.GetRequestStatsByDay(models.SourceTypeBaiduPan))   // 获取百度网盘请求统计（按天）
+		api.GET("/baidupan/stats/hourly", controllers.GetRequestStatsByHour(models.SourceTypeBaiduPan)) // 获取百度网盘请求统计（按小时）
+		api.GET("/123/stats/daily", controllers.GetRequestStatsByDay(models.SourceType123))             // 获取123云盘请求统计（按天）
+		api.GET("/123/stats/hourly", controllers.GetRequestStatsByHour(models.SourceType123))           // 获取123云盘请求统计（按小时）
+		api.GET("/scheduler/stats", controllers.GetSchedulerStats)                                      // 获取各来源请求调度器的统计数据
+		api.POST("/account/rate-limit", controllers.SetAccountRateLimit)                                // 设置账号单独的速率限制
+		api.POST("/account/failover-order", controllers.SetFailoverOrder)                               // 设置115直链故障转移的账号顺序
+		api.POST("/115/stats/clean", controllers.CleanOldRequestStats)                                  // 清理旧的请求统计数据
+		api.GET("/link-cache", controllers.GetLinkCacheList)                                            // 查询缓存的网盘直链
+		api.POST("/link-cache/evict", controllers.EvictLinkCache)                                       // 删除文件的缓存直链
+		api.POST("/link-cache/purge", controllers.PurgeLinkCache)                                       // 清空直链缓存
+		api.GET("/proxy/stats", controllers.GetProxyStats)                                              // 查询本地反代的统计数据
 		// 百度网盘相关路由
 		api.GET("/baidupan/oauth-url", controllers.GetBaiDuPanOAuthUrl)           // 获取百度网盘OAuth登录地址
 		api.POST("/baidupan/oauth-confirm", controllers.ConfirmBaiDuPanOAuthCode) // 确认百度网盘OAuth登录
 		api.GET("/baidupan/status", controllers.GetBaiDuPanStatus)                // 查询百度网盘状态
+		// 123云盘相关路由
+		api.GET("/123/status", controllers.Get123Status) // 查询123云盘状态
 
 		api.GET("/update/last", controllers.GetLastRelease)         // 获取最新版本
 		api.POST("/update/to-version", controllers.UpdateToVersion) // 获取更新版本
@@ -511,11 +554,14 @@ func setRouter(r *gin.Engine) {
 		api.POST("/setting/emby-config", controllers.UpdateEmbyConfig)                             // 更新新的Emby配置
 		api.POST("/setting/threads", controllers.UpdateThreads)                                    // 更新线程数
 		api.GET("/setting/threads", controllers.GetThreads)                                        // 获取线程数
+		api.POST("/setting/strm-sign", controllers.UpdateStrmSignRequired)                         // 更改直链接口是否必须签名
+		api.GET("/setting/strm-sign", controllers.GetStrmSignRequired)                             // 获取直链接口是否必须签名
 		api.POST("/emby/sync/start", controllers.StartEmbySync)                                    // 手动启动Emby同步
 		api.GET("/emby/sync/status", controllers.GetEmbySyncStatus)                                // 获取Emby同步状态           // 删除媒体库与同步目录关联
 		api.POST("/sync/start", controllers.StartSync)                                             // 启动同步
 		api.GET("/sync/records", controllers.GetSyncRecords)                                       // 同步列表
 		api.GET("/sync/task", controllers.GetSyncTask)                                             // 获取同步任务详情
+		api.GET("/sync/task/changes", controllers.GetSyncTaskChanges)                              // 获取同步任务的变更记录，支持导出CSV
 		api.GET("/sync/path-list", controllers.GetSyncPathList)                                    // 获取同步路径列表
 		api.POST("/sync/path-add", controllers.AddSyncPath)                                        // 创建同步路径
 		api.POST("/sync/path-update", controllers.UpdateSyncPath)                                  // 更新同步路径
@@ -525,10 +571,29 @@ func setRouter(r *gin.Engine) {
 		api.POST("/sync/path/full-start", controllers.FullStart115Sync)                            // 启动115的全量同步任务
 		api.POST("/sync/delete-records", controllers.DelSyncRecords)                               // 批量删除同步记录
 		api.POST("/sync/path/toggle-cron", controllers.ToggleSyncByPath)                           // 关闭或开启同步目录的定时同步
+		api.POST("/sync/path/toggle-watch", controllers.ToggleWatchByPath)                         // 关闭或开启本地同步目录的实时监控
+		api.POST("/sync/path/plan", controllers.PlanSyncByPath)                                    // 预览同步路径的同步任务
+		api.POST("/sync/path/strm-sign", controllers.UpdateSyncPathStrmSign)                       // 修改同步路径的STRM签名设置
+		api.POST("/sync/path/strm-sign/rotate", controllers.RotateSyncPathStrmSign)                // 轮换STRM签名密钥并重写STRM文件
+		api.POST("/sync/path/strm-sign/resign", controllers.ResignSyncPathStrm)                    // 按当前签名设置重写STRM文件
+		api.GET("/sync/path/plan", controllers.GetSyncPathPlan)                                    // 获取预览同步的结果
+		api.GET("/sync/path/target/list", controllers.GetSyncPathTargets)                          // 获取同步目录的目标目录
+		api.POST("/sync/path/target/add", controllers.AddSyncPathTarget)                           // 添加目标目录
+		api.POST("/sync/path/target/update", controllers.UpdateSyncPathTarget)                     // 修改目标目录
+		api.POST("/sync/path/target/delete", controllers.DeleteSyncPathTarget)                     // 删除目标目录
+		api.GET("/sync/delete-batch/list", controllers.GetSyncDeleteBatches)                       // 获取触发删除保护的删除批次
+		api.GET("/sync/delete-batch/items", controllers.GetSyncDeleteBatchItems)                   // 获取删除批次中的文件
+		api.POST("/sync/delete-batch/approve", controllers.ApproveSyncDeleteBatch)                 // 批准删除批次
+		api.POST("/sync/delete-batch/reject", controllers.RejectSyncDeleteBatch)                   // 拒绝删除批次
+		api.GET("/sync/trash/list", controllers.GetSyncTrashItems)                                 // 获取回收站中的文件
+		api.POST("/sync/trash/restore", controllers.RestoreSyncTrashItems)                         // 还原回收站中的文件
 		api.GET("/account/list", controllers.GetAccountList)                                       // 获取开放平台账号列表
 		api.POST("/account/add", controllers.CreateTmpAccount)                                     // 创建开放平台账号
 		api.POST("/account/delete", controllers.DeleteAccount)                                     // 删除开放平台账号
 		api.POST("/account/openlist", controllers.CreateOpenListAccount)                           // 创建openlist账号
+		api.POST("/account/123", controllers.Create123Account)                                     // 创建123云盘账号
+		api.POST("/account/webdav", controllers.CreateWebDAVAccount)                               // 创建或更新WebDAV账号
+		api.POST("/account/s3", controllers.CreateS3Account)                                       // 创建或更新S3账号
 
 		// API Key管理接口
 		api.POST("/api-keys", controllers.CreateAPIKey)                 // 创建API Key